@@ -0,0 +1,177 @@
+// Package abi parses contract ABIs and decodes the event logs emitted by contracts and the calls made to them
+// It only implements the subset of the Solidity ABI specification that's needed to decode log entries and transaction
+// payloads: events, functions, their inputs and the encoding of the values
+package abi
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// Argument is an input of an event
+type Argument struct {
+	Name    string
+	Type    Type
+	Indexed bool
+}
+
+// Event is an event definition from a contract ABI
+type Event struct {
+	Name      string
+	Inputs    []Argument
+	Anonymous bool
+}
+
+// Method is a function definition from a contract ABI
+type Method struct {
+	Name   string
+	Inputs []Argument
+}
+
+// ABI holds the events defined by a contract, keyed by topic 0, and its functions, keyed by selector
+type ABI struct {
+	Events  map[string]Event
+	Methods map[string]Method
+}
+
+type jsonArgument struct {
+	Name       string         `json:"name"`
+	Type       string         `json:"type"`
+	Indexed    bool           `json:"indexed"`
+	Components []jsonArgument `json:"components"`
+}
+
+type jsonEntry struct {
+	Type      string         `json:"type"`
+	Name      string         `json:"name"`
+	Inputs    []jsonArgument `json:"inputs"`
+	Anonymous bool           `json:"anonymous"`
+}
+
+// Parse parses a contract ABI in the JSON format produced by the Solidity compiler
+// Only the events and the functions are kept; anonymous events can't be identified by their topic 0, so they are
+// ignored
+func Parse(data []byte) (*ABI, error) {
+	var entries []jsonEntry
+	err := json.Unmarshal(data, &entries)
+	if err != nil {
+		return nil, fmt.Errorf("invalid abi: %s", err)
+	}
+
+	a := &ABI{
+		Events:  make(map[string]Event),
+		Methods: make(map[string]Method),
+	}
+	for _, entry := range entries {
+		// the type can be omitted for functions
+		if entry.Type == "" {
+			entry.Type = "function"
+		}
+
+		if entry.Type != "function" && (entry.Type != "event" || entry.Anonymous) {
+			continue
+		}
+
+		inputs, err := parseArguments(entry.Inputs)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %s: %s", entry.Type, entry.Name, err)
+		}
+
+		if entry.Type == "function" {
+			m := Method{Name: entry.Name, Inputs: inputs}
+			a.Methods[m.Selector()] = m
+		} else {
+			e := Event{Name: entry.Name, Inputs: inputs}
+			a.Events[e.Topic()] = e
+		}
+	}
+
+	return a, nil
+}
+
+func parseArguments(args []jsonArgument) ([]Argument, error) {
+	var arguments []Argument
+	for _, arg := range args {
+		components, err := parseArguments(arg.Components)
+		if err != nil {
+			return nil, err
+		}
+
+		t, err := ParseType(arg.Type, components)
+		if err != nil {
+			return nil, err
+		}
+
+		arguments = append(arguments, Argument{
+			Name:    arg.Name,
+			Type:    t,
+			Indexed: arg.Indexed,
+		})
+	}
+
+	return arguments, nil
+}
+
+// Signature returns the canonical signature of the event, e.g. Transfer(address,address,uint256)
+func (e Event) Signature() string {
+	return signature(e.Name, e.Inputs)
+}
+
+// Topic returns the topic 0 of the logs emitted by the event as a hex string without the 0x prefix
+func (e Event) Topic() string {
+	return hex.EncodeToString(Keccak256([]byte(e.Signature())))
+}
+
+// indexedCount returns the number of indexed inputs, which determines the number of topics of the logs
+func (e Event) indexedCount() int {
+	var count int
+	for _, input := range e.Inputs {
+		if input.Indexed {
+			count++
+		}
+	}
+
+	return count
+}
+
+// method returns the function of the ABI with the given selector; the ABI can be nil
+func (a *ABI) method(selector string) (Method, bool) {
+	if a == nil {
+		return Method{}, false
+	}
+
+	m, ok := a.Methods[selector]
+	return m, ok
+}
+
+// Signature returns the canonical signature of the function, e.g. transfer(address,uint256)
+func (m Method) Signature() string {
+	return signature(m.Name, m.Inputs)
+}
+
+// Selector returns the first 4 bytes of the hash of the signature, which prefix the payload of the calls to the
+// function, as a hex string without the 0x prefix
+func (m Method) Selector() string {
+	return hex.EncodeToString(Keccak256([]byte(m.Signature()))[:4])
+}
+
+func signature(name string, inputs []Argument) string {
+	types := make([]string, len(inputs))
+	for i, input := range inputs {
+		types[i] = input.Type.String()
+	}
+
+	return fmt.Sprintf("%s(%s)", name, strings.Join(types, ","))
+}
+
+// Keccak256 returns the Keccak-256 hash of the data, as used by Ethereum
+func Keccak256(data []byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(data)
+
+	return h.Sum(nil)
+}
@@ -0,0 +1,289 @@
+package abi
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+)
+
+const wordSize = 32
+
+// Param is a decoded input of an event or argument of a function call
+type Param struct {
+	Name    string
+	Type    string
+	Indexed bool
+
+	// Value holds addresses, byte arrays and hashed indexed values as 0x-prefixed hex strings, integers as decimal
+	// strings, arrays as []interface{} and tuples as map[string]interface{} keyed by component name
+	Value interface{}
+}
+
+// Decode decodes the topics (without topic 0) and the data of a log emitted by the event
+// Indexed values of dynamic types, arrays or tuples can't be recovered from the log since only their hash is stored
+// in the topic, so the raw topic is returned instead
+func (e Event) Decode(topics []string, data []byte) ([]Param, error) {
+	if len(topics) != e.indexedCount() {
+		return nil, fmt.Errorf("%s expects %d indexed inputs, the log has %d", e.Signature(), e.indexedCount(), len(topics))
+	}
+
+	var nonIndexed []Type
+	for _, input := range e.Inputs {
+		if !input.Indexed {
+			nonIndexed = append(nonIndexed, input.Type)
+		}
+	}
+
+	values, err := decodeSequence(nonIndexed, data)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode the data of %s: %s", e.Signature(), err)
+	}
+
+	var params []Param
+	var topicIndex, valueIndex int
+	for _, input := range e.Inputs {
+		param := Param{
+			Name:    input.Name,
+			Type:    input.Type.String(),
+			Indexed: input.Indexed,
+		}
+
+		if input.Indexed {
+			topic, err := hex.DecodeString(topics[topicIndex])
+			if err != nil || len(topic) != wordSize {
+				return nil, fmt.Errorf("invalid topic %q", topics[topicIndex])
+			}
+			topicIndex++
+
+			if isHashedInTopic(input.Type) {
+				param.Value = "0x" + hex.EncodeToString(topic)
+			} else {
+				param.Value, err = decodeStatic(input.Type, topic)
+				if err != nil {
+					return nil, fmt.Errorf("could not decode the indexed input %s of %s: %s", input.Name, e.Signature(), err)
+				}
+			}
+		} else {
+			param.Value = values[valueIndex]
+			valueIndex++
+		}
+
+		params = append(params, param)
+	}
+
+	return params, nil
+}
+
+// Decode decodes the arguments of a call to the function, given the payload without the selector
+func (m Method) Decode(data []byte) ([]Param, error) {
+	types := make([]Type, len(m.Inputs))
+	for i, input := range m.Inputs {
+		types[i] = input.Type
+	}
+
+	values, err := decodeSequence(types, data)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode the arguments of %s: %s", m.Signature(), err)
+	}
+
+	var params []Param
+	for i, input := range m.Inputs {
+		params = append(params, Param{
+			Name:  input.Name,
+			Type:  input.Type.String(),
+			Value: values[i],
+		})
+	}
+
+	return params, nil
+}
+
+func isHashedInTopic(t Type) bool {
+	switch t.Kind {
+	case KindBytes, KindString, KindSlice, KindArray, KindTuple:
+		return true
+	}
+
+	return false
+}
+
+// decodeSequence decodes consecutive values laid out the way the inputs of a tuple are encoded
+// Dynamic values are stored behind offsets that are relative to the start of the sequence
+func decodeSequence(types []Type, data []byte) ([]interface{}, error) {
+	var values []interface{}
+	var pos int
+	for _, t := range types {
+		size := t.headSize()
+		if pos+size > len(data) {
+			return nil, fmt.Errorf("data too short for %s", t)
+		}
+
+		var value interface{}
+		var err error
+		if t.IsDynamic() {
+			var offset int
+			offset, err = readLength(data, pos)
+			if err == nil {
+				value, err = decodeDynamic(t, data[offset:])
+			}
+		} else {
+			value, err = decodeStatic(t, data[pos:pos+size])
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, value)
+		pos += size
+	}
+
+	return values, nil
+}
+
+func decodeDynamic(t Type, data []byte) (interface{}, error) {
+	switch t.Kind {
+	case KindBytes, KindString:
+		length, err := readLength(data, 0)
+		if err != nil {
+			return nil, err
+		}
+		if wordSize+length > len(data) {
+			return nil, fmt.Errorf("data too short for %s of length %d", t, length)
+		}
+
+		value := data[wordSize : wordSize+length]
+		if t.Kind == KindString {
+			return string(value), nil
+		}
+
+		return "0x" + hex.EncodeToString(value), nil
+	case KindSlice:
+		length, err := readLength(data, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		// every element takes at least one word, which bounds the allocation below
+		if length > (len(data)-wordSize)/wordSize {
+			return nil, fmt.Errorf("data too short for %s of length %d", t, length)
+		}
+
+		return decodeSequence(repeat(*t.Elem, length), data[wordSize:])
+	case KindArray:
+		return decodeSequence(repeat(*t.Elem, t.Length), data)
+	case KindTuple:
+		return decodeTuple(t, data)
+	}
+
+	return nil, fmt.Errorf("%s is not a dynamic type", t)
+}
+
+func decodeStatic(t Type, word []byte) (interface{}, error) {
+	switch t.Kind {
+	case KindArray:
+		return decodeSequence(repeat(*t.Elem, t.Length), word)
+	case KindTuple:
+		return decodeTuple(t, word)
+	}
+
+	if len(word) < wordSize {
+		return nil, fmt.Errorf("data too short for %s", t)
+	}
+	word = word[:wordSize]
+
+	switch t.Kind {
+	case KindUint:
+		value := new(big.Int).SetBytes(word)
+		if value.BitLen() > t.Size {
+			return nil, fmt.Errorf("value out of range for %s", t)
+		}
+
+		return value.String(), nil
+	case KindInt:
+		value := new(big.Int).SetBytes(word)
+		if word[0]&0x80 != 0 {
+			value.Sub(value, new(big.Int).Lsh(big.NewInt(1), 256))
+		}
+
+		limit := new(big.Int).Lsh(big.NewInt(1), uint(t.Size-1))
+		if value.Cmp(limit) >= 0 || value.Cmp(new(big.Int).Neg(limit)) < 0 {
+			return nil, fmt.Errorf("value out of range for %s", t)
+		}
+
+		return value.String(), nil
+	case KindAddress:
+		if !isZero(word[:12]) {
+			return nil, fmt.Errorf("value out of range for %s", t)
+		}
+
+		return "0x" + hex.EncodeToString(word[12:]), nil
+	case KindBool:
+		if !isZero(word[:wordSize-1]) || word[wordSize-1] > 1 {
+			return nil, fmt.Errorf("value out of range for %s", t)
+		}
+
+		return word[wordSize-1] == 1, nil
+	case KindFixedBytes:
+		return "0x" + hex.EncodeToString(word[:t.Size]), nil
+	}
+
+	return nil, fmt.Errorf("%s is not a static type", t)
+}
+
+func decodeTuple(t Type, data []byte) (interface{}, error) {
+	types := make([]Type, len(t.Components))
+	for i, c := range t.Components {
+		types[i] = c.Type
+	}
+
+	values, err := decodeSequence(types, data)
+	if err != nil {
+		return nil, err
+	}
+
+	tuple := make(map[string]interface{}, len(values))
+	for i, c := range t.Components {
+		name := c.Name
+		if name == "" {
+			name = strconv.Itoa(i)
+		}
+
+		tuple[name] = values[i]
+	}
+
+	return tuple, nil
+}
+
+// readLength reads the word at pos as an offset or a length, which must fit in the data
+func readLength(data []byte, pos int) (int, error) {
+	if pos+wordSize > len(data) {
+		return 0, fmt.Errorf("data too short")
+	}
+
+	value := new(big.Int).SetBytes(data[pos : pos+wordSize])
+	if !value.IsInt64() || value.Int64() > int64(len(data)) {
+		return 0, fmt.Errorf("offset or length %s out of bounds", value)
+	}
+
+	return int(value.Int64()), nil
+}
+
+func repeat(t Type, n int) []Type {
+	types := make([]Type, n)
+	for i := range types {
+		types[i] = t
+	}
+
+	return types
+}
+
+func isZero(b []byte) bool {
+	for _, v := range b {
+		if v != 0 {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,125 @@
+package abi
+
+import (
+	"encoding/hex"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func encodeWord(n int64) string {
+	b := big.NewInt(n).Bytes()
+	return strings.Repeat("00", wordSize-len(b)) + hex.EncodeToString(b)
+}
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b
+}
+
+func TestEventDecode(t *testing.T) {
+	e, err := ParseSignature("Transfer(address indexed from, address indexed to, uint256 value)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e.Topic() != "ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef" {
+		t.Fatalf("unexpected topic %s", e.Topic())
+	}
+
+	from := strings.Repeat("00", 12) + strings.Repeat("11", 20)
+	to := strings.Repeat("00", 12) + strings.Repeat("22", 20)
+
+	params, err := e.Decode([]string{from, to}, mustDecodeHex(t, encodeWord(1000)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Param{
+		{Name: "from", Type: "address", Indexed: true, Value: "0x" + strings.Repeat("11", 20)},
+		{Name: "to", Type: "address", Indexed: true, Value: "0x" + strings.Repeat("22", 20)},
+		{Name: "value", Type: "uint256", Value: "1000"},
+	}
+	if !reflect.DeepEqual(params, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, params)
+	}
+
+	if _, err := e.Decode([]string{from}, mustDecodeHex(t, encodeWord(1000))); err == nil {
+		t.Error("expected an error for a missing topic")
+	}
+
+	if _, err := e.Decode([]string{from, to}, nil); err == nil {
+		t.Error("expected an error for missing data")
+	}
+}
+
+func TestEventDecodeHashedTopic(t *testing.T) {
+	e, err := ParseSignature("Named(string indexed name)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	topic := strings.Repeat("ab", wordSize)
+	params, err := e.Decode([]string{topic}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if params[0].Value != "0x"+topic {
+		t.Fatalf("expected the raw topic, got %v", params[0].Value)
+	}
+}
+
+func TestMethodDecodeDynamic(t *testing.T) {
+	m, err := ParseMethodSignature("f(string s, uint8[] xs, (bool,bytes2) t)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// head: offset of s, offset of xs, t.0, t.1; then s and xs
+	data := encodeWord(128) + encodeWord(192) + encodeWord(1) + "beef" + strings.Repeat("00", 30) +
+		encodeWord(2) + hex.EncodeToString([]byte("hi")) + strings.Repeat("00", 30) +
+		encodeWord(2) + encodeWord(7) + encodeWord(9)
+
+	params, err := m.Decode(mustDecodeHex(t, data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []interface{}{
+		"hi",
+		[]interface{}{"7", "9"},
+		map[string]interface{}{"0": true, "1": "0xbeef"},
+	}
+	for i, p := range params {
+		if !reflect.DeepEqual(p.Value, expected[i]) {
+			t.Errorf("param %d: expected %v, got %v", i, expected[i], p.Value)
+		}
+	}
+}
+
+func TestDecodeRejectsMalformedData(t *testing.T) {
+	m, err := ParseMethodSignature("f(uint8 a, bool b, uint256[] c)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]string{
+		"uint8 out of range":   encodeWord(256) + encodeWord(1) + encodeWord(96) + encodeWord(0),
+		"bool out of range":    encodeWord(1) + encodeWord(2) + encodeWord(96) + encodeWord(0),
+		"offset out of bounds": encodeWord(1) + encodeWord(1) + encodeWord(1<<40) + encodeWord(0),
+		"length out of bounds": encodeWord(1) + encodeWord(1) + encodeWord(96) + encodeWord(1<<40),
+		"truncated":            encodeWord(1),
+	}
+
+	for name, data := range cases {
+		if _, err := m.Decode(mustDecodeHex(t, data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package abi
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// SelectorLength is the length of a function selector as a hex string
+const SelectorLength = 8
+
+// builtinMethodSignatures are the functions of common standards and contracts, used to decode the calls to contracts
+// that don't have an ABI of their own in the registry
+var builtinMethodSignatures = []string{
+	// ERC20
+	"transfer(address to, uint256 value)",
+	"transferFrom(address from, address to, uint256 value)",
+	"approve(address spender, uint256 value)",
+	"increaseAllowance(address spender, uint256 addedValue)",
+	"decreaseAllowance(address spender, uint256 subtractedValue)",
+
+	// ERC721
+	"safeTransferFrom(address from, address to, uint256 tokenId)",
+	"safeTransferFrom(address from, address to, uint256 tokenId, bytes data)",
+	"setApprovalForAll(address operator, bool approved)",
+	"mint(address to, uint256 amount)",
+	"burn(uint256 amount)",
+
+	// ERC1155
+	"safeTransferFrom(address from, address to, uint256 id, uint256 value, bytes data)",
+	"safeBatchTransferFrom(address from, address to, uint256[] ids, uint256[] values, bytes data)",
+
+	// WETH
+	"deposit()",
+	"withdraw(uint256 wad)",
+
+	// Uniswap V2 router
+	"swapExactTokensForTokens(uint256 amountIn, uint256 amountOutMin, address[] path, address to, uint256 deadline)",
+	"swapTokensForExactTokens(uint256 amountOut, uint256 amountInMax, address[] path, address to, uint256 deadline)",
+	"swapExactETHForTokens(uint256 amountOutMin, address[] path, address to, uint256 deadline)",
+	"swapETHForExactTokens(uint256 amountOut, address[] path, address to, uint256 deadline)",
+	"swapExactTokensForETH(uint256 amountIn, uint256 amountOutMin, address[] path, address to, uint256 deadline)",
+	"swapTokensForExactETH(uint256 amountOut, uint256 amountInMax, address[] path, address to, uint256 deadline)",
+	"addLiquidity(address tokenA, address tokenB, uint256 amountADesired, uint256 amountBDesired, uint256 amountAMin, uint256 amountBMin, address to, uint256 deadline)",
+	"addLiquidityETH(address token, uint256 amountTokenDesired, uint256 amountTokenMin, uint256 amountETHMin, address to, uint256 deadline)",
+	"removeLiquidity(address tokenA, address tokenB, uint256 liquidity, uint256 amountAMin, uint256 amountBMin, address to, uint256 deadline)",
+	"removeLiquidityETH(address token, uint256 liquidity, uint256 amountTokenMin, uint256 amountETHMin, address to, uint256 deadline)",
+
+	// multicall
+	"multicall(bytes[] data)",
+	"multicall(uint256 deadline, bytes[] data)",
+	"aggregate((address,bytes)[] calls)",
+
+	// OpenZeppelin
+	"transferOwnership(address newOwner)",
+	"renounceOwnership()",
+	"grantRole(bytes32 role, address account)",
+	"revokeRole(bytes32 role, address account)",
+	"pause()",
+	"unpause()",
+	"upgradeTo(address newImplementation)",
+	"upgradeToAndCall(address newImplementation, bytes data)",
+}
+
+// builtinMethods holds the parsed builtinMethodSignatures keyed by selector
+var builtinMethods = make(map[string][]Method)
+
+func init() {
+	for _, signature := range builtinMethodSignatures {
+		m, err := ParseMethodSignature(signature)
+		if err != nil {
+			panic(err)
+		}
+
+		builtinMethods[m.Selector()] = append(builtinMethods[m.Selector()], m)
+	}
+}
+
+// ParseMethodSignature parses a human readable function signature like "transfer(address to, uint256)"
+// Argument names are optional; tuples are written as a parenthesized list of types
+func ParseMethodSignature(signature string) (Method, error) {
+	name, inputs, err := parseSignature(signature, false)
+	if err != nil {
+		return Method{}, err
+	}
+
+	return Method{Name: name, Inputs: inputs}, nil
+}
+
+// Methods returns the functions known for a selector: the built-in ones followed by the ones in the signature
+// database
+// Different signatures can share a selector, so there can be more than one
+func Methods(db *sql.DB, selector string) ([]Method, error) {
+	methods := append([]Method(nil), builtinMethods[selector]...)
+
+	rows, err := db.Query(`select signature from function_signatures where selector = $1 order by signature`, selector)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var signature string
+		err := rows.Scan(&signature)
+		if err != nil {
+			return nil, err
+		}
+
+		m, err := ParseMethodSignature(signature)
+		if err != nil {
+			return nil, err
+		}
+
+		if !hasMethod(methods, m) {
+			methods = append(methods, m)
+		}
+	}
+
+	return methods, rows.Err()
+}
+
+func hasMethod(methods []Method, m Method) bool {
+	for _, method := range methods {
+		if method.Signature() == m.Signature() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SaveSignatures adds function signatures to the signature database and returns the number of new ones
+// The signatures are stored in their canonical form, so argument names are dropped
+func SaveSignatures(db *sql.DB, signatures []string) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var count int
+	for _, signature := range signatures {
+		m, err := ParseMethodSignature(signature)
+		if err != nil {
+			return 0, err
+		}
+
+		res, err := tx.Exec(`insert into function_signatures (selector, signature) values ($1, $2) on conflict do nothing`, m.Selector(), m.Signature())
+		if err != nil {
+			return 0, err
+		}
+
+		inserted, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		count += int(inserted)
+	}
+
+	return count, tx.Commit()
+}
+
+// ReadSignatures reads the function signatures from a 4byte-style dump
+// Both the JSON export of the 4byte directory (a list of objects with a text_signature, optionally wrapped in the
+// results field of a page) and text files with a signature per line, optionally preceded by its selector, are
+// supported
+// The selectors in the dump are ignored since they are computed from the signatures
+func ReadSignatures(r io.Reader) ([]string, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+		return readJSONSignatures(trimmed)
+	}
+
+	var signatures []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// selector and signature separated by a comma, a tab or spaces
+		if i := strings.IndexAny(line, ", \t"); i >= 0 && i < strings.Index(line, "(") {
+			line = strings.TrimSpace(line[i+1:])
+		}
+
+		signatures = append(signatures, line)
+	}
+
+	return signatures, scanner.Err()
+}
+
+type fourByteSignature struct {
+	TextSignature string `json:"text_signature"`
+}
+
+func readJSONSignatures(data []byte) ([]string, error) {
+	var entries []fourByteSignature
+	if data[0] == '{' {
+		var page struct {
+			Results []fourByteSignature `json:"results"`
+		}
+
+		err := json.Unmarshal(data, &page)
+		if err != nil {
+			return nil, err
+		}
+		entries = page.Results
+	} else {
+		err := json.Unmarshal(data, &entries)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var signatures []string
+	for _, e := range entries {
+		if e.TextSignature != "" {
+			signatures = append(signatures, e.TextSignature)
+		}
+	}
+
+	return signatures, nil
+}
@@ -0,0 +1,153 @@
+package abi
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseMethodSignature(t *testing.T) {
+	cases := map[string]struct{ signature, selector string }{
+		"transfer(address to, uint256 value)": {"transfer(address,uint256)", "a9059cbb"},
+		"balanceOf(address)":                  {"balanceOf(address)", "70a08231"},
+		" deposit() ":                         {"deposit()", "d0e30db0"},
+		"aggregate((address,bytes)[] calls)":  {"aggregate((address,bytes)[])", "252dba42"},
+	}
+
+	for input, expected := range cases {
+		m, err := ParseMethodSignature(input)
+		if err != nil {
+			t.Errorf("%s: %s", input, err)
+			continue
+		}
+
+		if m.Signature() != expected.signature || m.Selector() != expected.selector {
+			t.Errorf("%s: expected %s (%s), got %s (%s)", input, expected.signature, expected.selector, m.Signature(), m.Selector())
+		}
+	}
+
+	invalid := []string{
+		"transfer",
+		"(address)",
+		"transfer(address to",
+		"transfer(address indexed to)",
+		"transfer(address to extra)",
+		"transfer(uint7)",
+		"f(()[2])",
+	}
+
+	for _, input := range invalid {
+		if _, err := ParseMethodSignature(input); err == nil {
+			t.Errorf("%s: expected an error", input)
+		}
+	}
+}
+
+func TestReadSignatures(t *testing.T) {
+	expected := []string{"transfer(address,uint256)", "approve(address,uint256)"}
+
+	dumps := map[string]string{
+		"text":           "# a comment\ntransfer(address,uint256)\n\napprove(address,uint256)\n",
+		"text selectors": "0xa9059cbb,transfer(address,uint256)\n0x095ea7b3\tapprove(address,uint256)\n",
+		"json list":      `[{"id": 1, "text_signature": "transfer(address,uint256)"}, {"id": 2, "text_signature": "approve(address,uint256)"}, {"id": 3}]`,
+		"json page":      `{"count": 2, "results": [{"text_signature": "transfer(address,uint256)"}, {"text_signature": "approve(address,uint256)"}]}`,
+	}
+
+	for name, dump := range dumps {
+		signatures, err := ReadSignatures(strings.NewReader(dump))
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+
+		if strings.Join(signatures, ";") != strings.Join(expected, ";") {
+			t.Errorf("%s: expected %v, got %v", name, expected, signatures)
+		}
+	}
+
+	if _, err := ReadSignatures(strings.NewReader(`[{"text_signature": 1}]`)); err == nil {
+		t.Error("expected an error for malformed json")
+	}
+}
+
+func TestSignatureDatabase(t *testing.T) {
+	db, cleanup := newTestDB(t)
+	defer cleanup()
+
+	// burn(uint256) is built in and shares its selector with collate_propagate_storage(bytes16)
+	added, err := SaveSignatures(db, []string{"collate_propagate_storage(bytes16 x)", "store(uint64 a, string b)", "store(uint64,string)"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if added != 2 {
+		t.Fatalf("expected 2 new signatures, got %d", added)
+	}
+
+	if _, err := SaveSignatures(db, []string{"ok()", "bad("}); err == nil {
+		t.Fatal("expected an error for an invalid signature")
+	}
+
+	methods, err := Methods(db, "42966c68")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(methods) != 2 || methods[0].Signature() != "burn(uint256)" || methods[1].Signature() != "collate_propagate_storage(bytes16)" {
+		t.Fatalf("unexpected methods %+v", methods)
+	}
+}
+
+func TestDecodeCall(t *testing.T) {
+	db, cleanup := newTestDB(t)
+	defer cleanup()
+
+	contract := strings.Repeat("ab", 20)
+	other := strings.Repeat("cd", 20)
+
+	// the ABI names the argument of store differently than the signature database
+	err := Save(db, contract, "Store", []byte(testABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = SaveSignatures(db, []string{"store(uint64 value)", "f(string)"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, _ := ParseMethodSignature("store(uint64)")
+	d := NewDecoder(db)
+
+	call, err := d.DecodeCall(contract, store.Selector()+encodeWord(42))
+	if err != nil || call == nil || call.Name != "store" || call.Params[0].Name != "a" || call.Params[0].Value != "42" {
+		t.Fatalf("expected the call to be decoded with the abi, got %+v, %v", call, err)
+	}
+
+	call, err = d.DecodeCall(other, store.Selector()+encodeWord(42))
+	if err != nil || call == nil || call.Params[0].Name != "" || call.Params[0].Value != "42" {
+		t.Fatalf("expected the call to be decoded with the signature database, got %+v, %v", call, err)
+	}
+
+	transfer, _ := ParseMethodSignature("transfer(address,uint256)")
+	call, err = d.DecodeCall(other, transfer.Selector()+encodeWord(1)+encodeWord(2))
+	if err != nil || call == nil || call.Signature != "transfer(address,uint256)" || call.Params[1].Name != "value" {
+		t.Fatalf("expected a built-in transfer, got %+v, %v", call, err)
+	}
+
+	// uint64 doesn't fit the argument, so the known function is returned with the error
+	call, err = d.DecodeCall(other, store.Selector()+strings.Repeat("ff", 32))
+	if err == nil || call == nil || call.Name != "store" || call.Params != nil {
+		t.Fatalf("expected a decoding error, got %+v, %v", call, err)
+	}
+
+	for _, payload := range []string{"", "a905", "12345678" + encodeWord(1)} {
+		call, err = d.DecodeCall(other, payload)
+		if err != nil || call != nil {
+			t.Errorf("%q: expected no call, got %+v, %v", payload, call, err)
+		}
+	}
+
+	if _, err := d.DecodeCall(other, "zz345678"); err == nil {
+		t.Error("expected an error for an invalid payload")
+	}
+}
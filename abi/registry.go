@@ -0,0 +1,235 @@
+package abi
+
+import (
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// Contract is an ABI stored in the registry
+type Contract struct {
+	Address   string
+	Name      string
+	ABI       string
+	CreatedAt time.Time
+}
+
+// Save validates an ABI and stores it for the given address (lowercase hex without 0x), replacing the existing one
+func Save(db *sql.DB, address, name string, abiJSON []byte) error {
+	_, err := Parse(abiJSON)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		insert into contract_abis (address, name, abi) values ($1, $2, $3)
+		on conflict (address) do update set name = excluded.name, abi = excluded.abi, created_at = current_timestamp
+	`, address, name, string(abiJSON))
+
+	return err
+}
+
+// Get returns the ABI stored for the given address; the contract is nil if there's none
+func Get(db *sql.DB, address string) (*Contract, error) {
+	c := &Contract{}
+	err := db.QueryRow(`select address, name, abi, created_at from contract_abis where address = $1`, address).Scan(&c.Address, &c.Name, &c.ABI, &c.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// List returns all the contracts in the registry, without their ABI
+func List(db *sql.DB) ([]Contract, error) {
+	rows, err := db.Query(`select address, name, created_at from contract_abis order by address`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contracts []Contract
+	for rows.Next() {
+		var c Contract
+		err := rows.Scan(&c.Address, &c.Name, &c.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		contracts = append(contracts, c)
+	}
+
+	return contracts, rows.Err()
+}
+
+// Delete removes the ABI of the given address from the registry and reports whether there was one
+func Delete(db *sql.DB, address string) (bool, error) {
+	res, err := db.Exec(`delete from contract_abis where address = $1`, address)
+	if err != nil {
+		return false, err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// DecodedEvent is a log entry decoded with the ABI of the contract that emitted it or with a built-in signature
+type DecodedEvent struct {
+	Name      string
+	Signature string
+	Params    []Param
+}
+
+// DecodedCall is a transaction payload decoded with the ABI of the contract that was called or with the signature
+// database
+type DecodedCall struct {
+	Name      string
+	Signature string
+	Params    []Param
+}
+
+// Decoder decodes log entries and transaction payloads, loading the ABI of each contract from the registry only once
+// It's meant to be short-lived (e.g. one per request) so ABI updates are picked up
+type Decoder struct {
+	db   *sql.DB
+	abis map[string]*ABI
+}
+
+func NewDecoder(db *sql.DB) *Decoder {
+	return &Decoder{
+		db:   db,
+		abis: make(map[string]*ABI),
+	}
+}
+
+// Decode decodes a log entry emitted by the contract at address, given its topics (topic 0 included) and data
+// The event is looked up in the ABI of the contract first, then in the built-in signatures; if neither knows the
+// topic 0, nil is returned without an error
+func (d *Decoder) Decode(address string, topics []string, data string) (*DecodedEvent, error) {
+	if len(topics) == 0 {
+		return nil, nil
+	}
+
+	event, found, err := d.find(address, topics[0], len(topics)-1)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	rawData, err := hex.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid log data: %s", err)
+	}
+
+	params, err := event.Decode(topics[1:], rawData)
+	if err != nil {
+		return &DecodedEvent{Name: event.Name, Signature: event.Signature()}, err
+	}
+
+	return &DecodedEvent{
+		Name:      event.Name,
+		Signature: event.Signature(),
+		Params:    params,
+	}, nil
+}
+
+func (d *Decoder) find(address, topic0 string, indexed int) (Event, bool, error) {
+	contractABI, err := d.load(address)
+	if err != nil {
+		return Event{}, false, err
+	}
+
+	if contractABI != nil {
+		if e, ok := contractABI.Events[topic0]; ok {
+			return e, true, nil
+		}
+	}
+
+	e, ok := Builtin(topic0, indexed)
+	return e, ok, nil
+}
+
+// DecodeCall decodes the payload of a transaction sent to the contract at address
+// The function is looked up in the ABI of the contract first, then in the signature database; since signatures can
+// share a selector, the first one the arguments can be decoded with is used. If the selector is unknown, nil is
+// returned without an error
+func (d *Decoder) DecodeCall(address string, payload string) (*DecodedCall, error) {
+	if len(payload) < SelectorLength {
+		return nil, nil
+	}
+
+	rawPayload, err := hex.DecodeString(payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payload: %s", err)
+	}
+
+	selector := payload[:SelectorLength]
+	var methods []Method
+
+	contractABI, err := d.load(address)
+	if err != nil {
+		return nil, err
+	}
+
+	if m, ok := contractABI.method(selector); ok {
+		methods = append(methods, m)
+	} else {
+		methods, err = Methods(d.db, selector)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var decodeErr error
+	for _, m := range methods {
+		params, err := m.Decode(rawPayload[SelectorLength/2:])
+		if err != nil {
+			if decodeErr == nil {
+				decodeErr = err
+			}
+			continue
+		}
+
+		return &DecodedCall{
+			Name:      m.Name,
+			Signature: m.Signature(),
+			Params:    params,
+		}, nil
+	}
+
+	if decodeErr != nil {
+		return &DecodedCall{Name: methods[0].Name, Signature: methods[0].Signature()}, decodeErr
+	}
+
+	return nil, nil
+}
+
+// load returns the ABI of the contract at address from the registry, or nil if it doesn't have one
+func (d *Decoder) load(address string) (*ABI, error) {
+	if contractABI, loaded := d.abis[address]; loaded {
+		return contractABI, nil
+	}
+
+	var contractABI *ABI
+	c, err := Get(d.db, address)
+	if err != nil {
+		return nil, err
+	}
+
+	if c != nil {
+		contractABI, err = Parse([]byte(c.ABI))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	d.abis[address] = contractABI
+	return contractABI, nil
+}
@@ -0,0 +1,123 @@
+package abi
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Alethio/memento/storage"
+)
+
+// newTestDB returns a migrated sqlite database along with the function that removes it
+func newTestDB(t *testing.T) (*sql.DB, func()) {
+	dir, err := ioutil.TempDir("", "memento-abi")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backend, err := storage.New(storage.Config{Driver: storage.DriverSQLite, SQLitePath: filepath.Join(dir, "test.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cleanup := func() {
+		backend.Close()
+		os.RemoveAll(dir)
+	}
+
+	err = backend.Migrate()
+	if err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+
+	return backend.DB(), cleanup
+}
+
+const testABI = `[
+	{"type": "event", "name": "Stored", "inputs": [
+		{"name": "who", "type": "address", "indexed": true},
+		{"name": "value", "type": "tuple", "components": [{"name": "a", "type": "uint64"}, {"name": "b", "type": "string"}]}
+	]},
+	{"type": "event", "name": "Hidden", "anonymous": true, "inputs": []},
+	{"type": "function", "name": "store", "inputs": [{"name": "a", "type": "uint64"}]}
+]`
+
+func TestDecoderUsesTheRegistry(t *testing.T) {
+	db, cleanup := newTestDB(t)
+	defer cleanup()
+
+	contract := strings.Repeat("ab", 20)
+	other := strings.Repeat("cd", 20)
+
+	err := Save(db, contract, "Store", []byte(testABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Save(db, other, "", []byte(`[{"type": "event", "name": "Bad", "inputs": [{"type": "tuple()[2]"}]}]`)); err == nil {
+		t.Fatal("expected the invalid abi to be rejected")
+	}
+
+	stored, err := ParseSignature("Stored(address indexed who, (uint64,string) value)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	who := strings.Repeat("00", 12) + strings.Repeat("11", 20)
+	// the tuple is dynamic, so the data holds its offset, then a, the offset of b relative to the tuple and b
+	data := encodeWord(32) + encodeWord(7) + encodeWord(64) + encodeWord(2) + "6869" + strings.Repeat("00", 30)
+
+	d := NewDecoder(db)
+
+	event, err := d.Decode(contract, []string{stored.Topic(), who}, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if event == nil || event.Name != "Stored" || event.Signature != "Stored(address,(uint64,string))" || len(event.Params) != 2 {
+		t.Fatalf("unexpected event %+v", event)
+	}
+
+	value := event.Params[1].Value.(map[string]interface{})
+	if event.Params[0].Value != "0x"+strings.Repeat("11", 20) || value["a"] != "7" || value["b"] != "hi" {
+		t.Fatalf("unexpected params %+v", event.Params)
+	}
+
+	// the contracts without an ABI only get the built-in events
+	event, err = d.Decode(other, []string{stored.Topic(), who}, data)
+	if err != nil || event != nil {
+		t.Fatalf("expected the event to be unknown, got %+v, %v", event, err)
+	}
+
+	transfer := "ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+	event, err = d.Decode(other, []string{transfer, who, who}, encodeWord(5))
+	if err != nil || event == nil || event.Name != "Transfer" || event.Params[2].Value != "5" {
+		t.Fatalf("expected a decoded transfer, got %+v, %v", event, err)
+	}
+
+	// the ERC-721 transfer has the same topic 0, but one more indexed input
+	event, err = d.Decode(other, []string{transfer, who, who, encodeWord(9)}, "")
+	if err != nil || event == nil || event.Params[2].Name != "tokenId" || event.Params[2].Value != "9" {
+		t.Fatalf("expected a decoded ERC-721 transfer, got %+v, %v", event, err)
+	}
+
+	// a known event with undecodable data is named, with the error
+	event, err = d.Decode(contract, []string{stored.Topic(), who}, encodeWord(32))
+	if err == nil || event == nil || event.Name != "Stored" {
+		t.Fatalf("expected a decoding error, got %+v, %v", event, err)
+	}
+
+	deleted, err := Delete(db, contract)
+	if err != nil || !deleted {
+		t.Fatalf("the abi wasn't deleted: %v", err)
+	}
+
+	event, err = NewDecoder(db).Decode(contract, []string{stored.Topic(), who}, data)
+	if err != nil || event != nil {
+		t.Fatalf("expected the event to be unknown once the abi is deleted, got %+v, %v", event, err)
+	}
+}
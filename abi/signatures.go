@@ -0,0 +1,194 @@
+package abi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// builtinSignatures are the events of common standards and contracts, decoded for every contract that doesn't have an
+// ABI of its own in the registry
+// Some events share their signature across standards (e.g. the ERC20 and ERC721 Transfer); they are told apart by the
+// number of indexed inputs
+var builtinSignatures = []string{
+	// ERC20
+	"Transfer(address indexed from, address indexed to, uint256 value)",
+	"Approval(address indexed owner, address indexed spender, uint256 value)",
+
+	// ERC721
+	"Transfer(address indexed from, address indexed to, uint256 indexed tokenId)",
+	"Approval(address indexed owner, address indexed approved, uint256 indexed tokenId)",
+	"ApprovalForAll(address indexed owner, address indexed operator, bool approved)",
+
+	// ERC1155
+	"TransferSingle(address indexed operator, address indexed from, address indexed to, uint256 id, uint256 value)",
+	"TransferBatch(address indexed operator, address indexed from, address indexed to, uint256[] ids, uint256[] values)",
+	"URI(string value, uint256 indexed id)",
+
+	// WETH
+	"Deposit(address indexed dst, uint256 wad)",
+	"Withdrawal(address indexed src, uint256 wad)",
+
+	// Uniswap
+	"PairCreated(address indexed token0, address indexed token1, address pair, uint256 index)",
+	"Mint(address indexed sender, uint256 amount0, uint256 amount1)",
+	"Burn(address indexed sender, uint256 amount0, uint256 amount1, address indexed to)",
+	"Swap(address indexed sender, uint256 amount0In, uint256 amount1In, uint256 amount0Out, uint256 amount1Out, address indexed to)",
+	"Sync(uint112 reserve0, uint112 reserve1)",
+	"Swap(address indexed sender, address indexed recipient, int256 amount0, int256 amount1, uint160 sqrtPriceX96, uint128 liquidity, int24 tick)",
+
+	// OpenZeppelin
+	"OwnershipTransferred(address indexed previousOwner, address indexed newOwner)",
+	"Paused(address account)",
+	"Unpaused(address account)",
+	"RoleGranted(bytes32 indexed role, address indexed account, address indexed sender)",
+	"RoleRevoked(bytes32 indexed role, address indexed account, address indexed sender)",
+	"Upgraded(address indexed implementation)",
+	"AdminChanged(address previousAdmin, address newAdmin)",
+}
+
+// builtins holds the parsed builtinSignatures keyed by topic 0
+var builtins = make(map[string][]Event)
+
+func init() {
+	for _, signature := range builtinSignatures {
+		e, err := ParseSignature(signature)
+		if err != nil {
+			panic(err)
+		}
+
+		builtins[e.Topic()] = append(builtins[e.Topic()], e)
+	}
+}
+
+// ParseSignature parses a human readable event signature like "Transfer(address indexed from, address to, uint256)"
+// Input names are optional; tuples are written as a parenthesized list of types
+func ParseSignature(signature string) (Event, error) {
+	name, inputs, err := parseSignature(signature, true)
+	if err != nil {
+		return Event{}, err
+	}
+
+	return Event{Name: name, Inputs: inputs}, nil
+}
+
+func parseSignature(signature string, allowIndexed bool) (string, []Argument, error) {
+	signature = strings.TrimSpace(signature)
+	open := strings.Index(signature, "(")
+	if open <= 0 || !strings.HasSuffix(signature, ")") {
+		return "", nil, fmt.Errorf("invalid signature %q", signature)
+	}
+
+	name := strings.TrimSpace(signature[:open])
+	params := strings.TrimSpace(signature[open+1 : len(signature)-1])
+	if params == "" {
+		return name, nil, nil
+	}
+
+	parts, err := splitParams(params)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid signature %q", signature)
+	}
+
+	var inputs []Argument
+	for _, param := range parts {
+		fields := strings.Fields(param)
+		if len(fields) == 0 || len(fields) > 3 {
+			return "", nil, fmt.Errorf("invalid input %q in signature %q", param, signature)
+		}
+
+		t, err := parseSignatureType(fields[0])
+		if err != nil {
+			return "", nil, err
+		}
+
+		arg := Argument{Type: t}
+		fields = fields[1:]
+		if allowIndexed && len(fields) > 0 && fields[0] == "indexed" {
+			arg.Indexed = true
+			fields = fields[1:]
+		}
+		if len(fields) == 1 {
+			arg.Name = fields[0]
+		} else if len(fields) > 1 {
+			return "", nil, fmt.Errorf("invalid input %q in signature %q", param, signature)
+		}
+
+		inputs = append(inputs, arg)
+	}
+
+	return name, inputs, nil
+}
+
+// parseSignatureType parses a type as it appears in a signature, where tuples are written as (type,...)
+func parseSignatureType(t string) (Type, error) {
+	if !strings.HasPrefix(t, "(") {
+		return ParseType(t, nil)
+	}
+
+	end := strings.LastIndex(t, ")")
+	if end < 0 {
+		return Type{}, fmt.Errorf("invalid type %q", t)
+	}
+
+	parts, err := splitParams(t[1:end])
+	if err != nil {
+		return Type{}, fmt.Errorf("invalid type %q", t)
+	}
+
+	var components []Argument
+	for _, part := range parts {
+		component, err := parseSignatureType(strings.TrimSpace(part))
+		if err != nil {
+			return Type{}, err
+		}
+
+		components = append(components, Argument{Type: component})
+	}
+
+	return ParseType("tuple"+t[end+1:], components)
+}
+
+// splitParams splits a list of comma separated types, leaving the commas inside tuples alone
+func splitParams(s string) ([]string, error) {
+	var parts []string
+	var depth, start int
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unbalanced parentheses")
+			}
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced parentheses")
+	}
+
+	return append(parts, s[start:]), nil
+}
+
+// Builtin returns the built-in event with the given topic 0 that matches the number of indexed inputs of a log
+func Builtin(topic0 string, indexed int) (Event, bool) {
+	candidates := builtins[topic0]
+	for _, e := range candidates {
+		if e.indexedCount() == indexed {
+			return e, true
+		}
+	}
+
+	// when no candidate matches, the first one is still returned so the error says which event failed to decode
+	if len(candidates) > 0 {
+		return candidates[0], true
+	}
+
+	return Event{}, false
+}
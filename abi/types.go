@@ -0,0 +1,193 @@
+package abi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Kinds of ABI types
+const (
+	KindUint = iota
+	KindInt
+	KindAddress
+	KindBool
+	KindFixedBytes
+	KindBytes
+	KindString
+	KindSlice
+	KindArray
+	KindTuple
+)
+
+// maxArraySize bounds the size of the encoding of fixed size arrays, so computing it can't overflow
+const maxArraySize = 1 << 24
+
+// Type is a parsed Solidity ABI type
+type Type struct {
+	Kind int
+
+	// Size is the number of bits of integers and the number of bytes of fixed size byte arrays
+	Size int
+
+	// Elem is the type of the elements of slices and arrays; Length is the number of elements of arrays
+	Elem   *Type
+	Length int
+
+	// Components are the fields of tuples
+	Components []Argument
+}
+
+// ParseType parses a type as it appears in a JSON ABI; components are only used by tuples
+func ParseType(t string, components []Argument) (Type, error) {
+	if strings.HasSuffix(t, "]") {
+		open := strings.LastIndex(t, "[")
+		if open < 0 {
+			return Type{}, fmt.Errorf("invalid type %q", t)
+		}
+
+		elem, err := ParseType(t[:open], components)
+		if err != nil {
+			return Type{}, err
+		}
+
+		size := t[open+1 : len(t)-1]
+		if size == "" {
+			return Type{Kind: KindSlice, Elem: &elem}, nil
+		}
+
+		length, err := strconv.Atoi(size)
+		if err != nil || length <= 0 || elem.headSize() == 0 || length > maxArraySize/elem.headSize() {
+			return Type{}, fmt.Errorf("invalid array length in type %q", t)
+		}
+
+		return Type{Kind: KindArray, Elem: &elem, Length: length}, nil
+	}
+
+	switch {
+	case t == "address":
+		return Type{Kind: KindAddress, Size: 160}, nil
+	case t == "bool":
+		return Type{Kind: KindBool}, nil
+	case t == "string":
+		return Type{Kind: KindString}, nil
+	case t == "bytes":
+		return Type{Kind: KindBytes}, nil
+	case t == "tuple":
+		// an empty tuple takes no space, which arrays of it can't be sized or decoded with; Solidity doesn't allow
+		// them anyway
+		if len(components) == 0 {
+			return Type{}, fmt.Errorf("invalid type %q: tuples must have components", t)
+		}
+
+		return Type{Kind: KindTuple, Components: components}, nil
+	case strings.HasPrefix(t, "bytes"):
+		size, err := strconv.Atoi(strings.TrimPrefix(t, "bytes"))
+		if err != nil || size < 1 || size > 32 {
+			return Type{}, fmt.Errorf("invalid type %q", t)
+		}
+
+		return Type{Kind: KindFixedBytes, Size: size}, nil
+	case strings.HasPrefix(t, "uint"):
+		size, err := intSize(strings.TrimPrefix(t, "uint"))
+		if err != nil {
+			return Type{}, fmt.Errorf("invalid type %q", t)
+		}
+
+		return Type{Kind: KindUint, Size: size}, nil
+	case strings.HasPrefix(t, "int"):
+		size, err := intSize(strings.TrimPrefix(t, "int"))
+		if err != nil {
+			return Type{}, fmt.Errorf("invalid type %q", t)
+		}
+
+		return Type{Kind: KindInt, Size: size}, nil
+	}
+
+	return Type{}, fmt.Errorf("unsupported type %q", t)
+}
+
+func intSize(s string) (int, error) {
+	if s == "" {
+		return 256, nil
+	}
+
+	size, err := strconv.Atoi(s)
+	if err != nil || size < 8 || size > 256 || size%8 != 0 {
+		return 0, fmt.Errorf("invalid integer size %q", s)
+	}
+
+	return size, nil
+}
+
+// String returns the canonical form of the type, as used in signatures
+func (t Type) String() string {
+	switch t.Kind {
+	case KindUint:
+		return fmt.Sprintf("uint%d", t.Size)
+	case KindInt:
+		return fmt.Sprintf("int%d", t.Size)
+	case KindAddress:
+		return "address"
+	case KindBool:
+		return "bool"
+	case KindFixedBytes:
+		return fmt.Sprintf("bytes%d", t.Size)
+	case KindBytes:
+		return "bytes"
+	case KindString:
+		return "string"
+	case KindSlice:
+		return t.Elem.String() + "[]"
+	case KindArray:
+		return fmt.Sprintf("%s[%d]", t.Elem.String(), t.Length)
+	case KindTuple:
+		types := make([]string, len(t.Components))
+		for i, c := range t.Components {
+			types[i] = c.Type.String()
+		}
+
+		return "(" + strings.Join(types, ",") + ")"
+	}
+
+	return ""
+}
+
+// IsDynamic returns true if the encoding of the type is stored out of place, behind an offset
+func (t Type) IsDynamic() bool {
+	switch t.Kind {
+	case KindBytes, KindString, KindSlice:
+		return true
+	case KindArray:
+		return t.Elem.IsDynamic()
+	case KindTuple:
+		for _, c := range t.Components {
+			if c.Type.IsDynamic() {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// headSize returns the number of bytes the type takes in the head of the enclosing tuple
+func (t Type) headSize() int {
+	if t.IsDynamic() {
+		return 32
+	}
+
+	switch t.Kind {
+	case KindArray:
+		return t.Length * t.Elem.headSize()
+	case KindTuple:
+		var size int
+		for _, c := range t.Components {
+			size += c.Type.headSize()
+		}
+
+		return size
+	}
+
+	return 32
+}
@@ -0,0 +1,77 @@
+package abi
+
+import "testing"
+
+func TestParseType(t *testing.T) {
+	valid := map[string]string{
+		"uint":        "uint256",
+		"int8":        "int8",
+		"address":     "address",
+		"bytes32":     "bytes32",
+		"bytes":       "bytes",
+		"string[]":    "string[]",
+		"uint256[3]":  "uint256[3]",
+		"bool[2][]":   "bool[2][]",
+		"address[][]": "address[][]",
+	}
+
+	for input, expected := range valid {
+		typ, err := ParseType(input, nil)
+		if err != nil {
+			t.Errorf("%s: %s", input, err)
+			continue
+		}
+
+		if typ.String() != expected {
+			t.Errorf("%s: expected %s, got %s", input, expected, typ)
+		}
+	}
+
+	invalid := []string{
+		"uint7",
+		"uint264",
+		"bytes0",
+		"bytes33",
+		"foo",
+		"uint256[0]",
+		"uint256[-1]",
+		"uint256[x]",
+		"uint256]",
+		"uint256[16777217]",
+		"tuple",
+		"tuple[2]",
+		"tuple[]",
+	}
+
+	for _, input := range invalid {
+		if _, err := ParseType(input, nil); err == nil {
+			t.Errorf("%s: expected an error", input)
+		}
+	}
+}
+
+func TestParseTypeTuple(t *testing.T) {
+	components := []Argument{{Name: "a", Type: Type{Kind: KindUint, Size: 256}}, {Name: "b", Type: Type{Kind: KindString}}}
+
+	typ, err := ParseType("tuple[2]", components)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if typ.String() != "(uint256,string)[2]" || !typ.IsDynamic() {
+		t.Fatalf("unexpected type %s", typ)
+	}
+}
+
+func TestEmptyTupleArraysAreRejected(t *testing.T) {
+	for _, signature := range []string{"Foo(()[2])", "Foo(()[])", "Foo(())"} {
+		if _, err := ParseSignature(signature); err == nil {
+			t.Errorf("%s: expected an error", signature)
+		}
+	}
+
+	_, err := Parse([]byte(`[{"type":"event","name":"Foo","inputs":[{"name":"x","type":"tuple[2]","components":[]}]}]`))
+	if err == nil {
+		t.Error("expected the abi to be rejected")
+	}
+}
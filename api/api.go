@@ -1,7 +1,9 @@
 package api
 
 import (
-	"github.com/Alethio/memento/core"
+	"database/sql"
+
+	"github.com/Alethio/memento/feed"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -14,19 +16,41 @@ type Config struct {
 	DevCorsEnabled bool
 	DevCorsHost    string
 	EthClientURL   string
+
+	// ReadOnly leaves out the endpoints that write to the database (ABIs, signatures, webhooks), for APIs running
+	// against a read replica
+	ReadOnly bool
+
+	// AdminToken is the bearer token required by the webhook endpoints and by the ones that change the ABIs and
+	// signatures; without one, they are not served
+	AdminToken string
+}
+
+// Backend is what the API needs to serve its requests; it's implemented by core.Core when the API runs alongside the
+// indexer and by Replica when it runs on its own
+type Backend interface {
+	// DB returns the database the queries are executed on
+	DB() *sql.DB
+
+	// LatestBlock returns the best block of the network (the highest stored block when the API runs on its own), or 0
+	// if it's not known
+	LatestBlock() int64
+
+	// Feed returns the hub of the live events, or nil if the API runs without the indexer
+	Feed() *feed.Hub
 }
 
 type API struct {
 	config Config
 	engine *gin.Engine
 
-	core *core.Core
+	backend Backend
 }
 
-func New(core *core.Core, config Config) *API {
+func New(backend Backend, config Config) *API {
 	return &API{
-		config: config,
-		core:   core,
+		config:  config,
+		backend: backend,
 	}
 }
 
@@ -36,7 +60,7 @@ func (a *API) Run() {
 	if a.config.DevCorsEnabled {
 		a.engine.Use(cors.New(cors.Config{
 			AllowOrigins:     []string{a.config.DevCorsHost},
-			AllowMethods:     []string{"PUT", "PATCH", "GET", "POST"},
+			AllowMethods:     []string{"PUT", "PATCH", "GET", "POST", "DELETE"},
 			AllowHeaders:     []string{"Origin"},
 			ExposeHeaders:    []string{"Content-Length"},
 			AllowCredentials: true,
@@ -0,0 +1,23 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requireAdmin rejects the requests that don't carry the admin token in an "Authorization: Bearer <token>" header
+func (a *API) requireAdmin(c *gin.Context) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if a.config.AdminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(a.config.AdminToken)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, map[string]interface{}{
+			"status": http.StatusUnauthorized,
+			"data":   "a valid admin token is required",
+		})
+		return
+	}
+
+	c.Next()
+}
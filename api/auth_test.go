@@ -0,0 +1,73 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTestAPI returns an API without a database, for the requests that don't reach a handler
+func newTestAPI(config Config) *API {
+	return newTestDBAPI(nil, config)
+}
+
+func request(a *API, method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	a.engine.ServeHTTP(w, req)
+
+	return w
+}
+
+func TestWebhookRoutesRequireAdminToken(t *testing.T) {
+	routes := []struct{ method, path string }{
+		{http.MethodGet, "/api/webhooks"},
+		{http.MethodGet, "/api/webhooks/1"},
+		{http.MethodGet, "/api/webhooks/1/deliveries"},
+		{http.MethodPost, "/api/webhooks"},
+		{http.MethodDelete, "/api/webhooks/1"},
+	}
+
+	// without a token, the routes are not served at all
+	a := newTestAPI(Config{})
+	for _, r := range routes {
+		if code := request(a, r.method, r.path, "", "").Code; code != http.StatusNotFound {
+			t.Errorf("%s %s without an admin token configured: expected 404, got %d", r.method, r.path, code)
+		}
+	}
+
+	a = newTestAPI(Config{AdminToken: "t0ken"})
+	for _, r := range routes {
+		for _, token := range []string{"", "wrong", "t0ken2"} {
+			if code := request(a, r.method, r.path, token, "").Code; code != http.StatusUnauthorized {
+				t.Errorf("%s %s with token %q: expected 401, got %d", r.method, r.path, token, code)
+			}
+		}
+	}
+
+	// the token lets the request through to the handler, which rejects the invalid webhook before using the database
+	w := request(a, http.MethodPost, "/api/webhooks", "t0ken", `{"url": "http://169.254.169.254/", "address": "0x0000000000000000000000000000000000000001"}`)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "link-local") {
+		t.Errorf("expected the webhook to be rejected, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestReadOnlyLeavesOutWebhookWrites(t *testing.T) {
+	a := newTestAPI(Config{AdminToken: "t0ken", ReadOnly: true})
+
+	for _, method := range []string{http.MethodPost, http.MethodDelete} {
+		path := "/api/webhooks"
+		if method == http.MethodDelete {
+			path += "/1"
+		}
+
+		if code := request(a, method, path, "t0ken", "{}").Code; code != http.StatusNotFound {
+			t.Errorf("%s %s on a read-only api: expected 404, got %d", method, path, code)
+		}
+	}
+}
@@ -1,3 +1,15 @@
 package api
 
 const MaxBlocksInRange = 300
+
+// MaxLogsBlocksInRange is the maximum number of blocks a single eth_getLogs request or log search can span
+const MaxLogsBlocksInRange = 10000
+
+// MaxRPCBatchSize is the maximum number of requests in a single JSON-RPC batch
+const MaxRPCBatchSize = 100
+
+// DefaultPageSize and MaxPageSize bound the number of rows returned by the paginated endpoints
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 100
+)
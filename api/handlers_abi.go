@@ -0,0 +1,182 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/Alethio/memento/abi"
+	"github.com/Alethio/memento/api/types"
+	"github.com/Alethio/memento/data/storable"
+	"github.com/Alethio/memento/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// maxABISize limits the size of the uploaded ABIs
+const maxABISize = 1 << 20
+
+func (a *API) ABIListHandler(c *gin.Context) {
+	contracts, err := abi.List(a.backend.DB())
+	if err != nil {
+		Error(c, err)
+		return
+	}
+
+	if len(contracts) == 0 {
+		NotFound(c)
+		return
+	}
+
+	var list []types.ContractABI
+	for _, contract := range contracts {
+		list = append(list, types.ContractABI{
+			Address:   contract.Address,
+			Name:      contract.Name,
+			CreatedAt: storable.DatetimeToJSONUnix(contract.CreatedAt),
+		})
+	}
+
+	OK(c, list)
+}
+
+func (a *API) ABIHandler(c *gin.Context) {
+	address, err := utils.ValidateAccount(c.Param("address"))
+	if err != nil {
+		BadRequest(c, fmt.Errorf("bad request: address is malformed"))
+		return
+	}
+
+	contract, err := abi.Get(a.backend.DB(), address)
+	if err != nil {
+		Error(c, err)
+		return
+	}
+
+	if contract == nil {
+		NotFound(c)
+		return
+	}
+
+	OK(c, types.ContractABI{
+		Address:   contract.Address,
+		Name:      contract.Name,
+		ABI:       []byte(contract.ABI),
+		CreatedAt: storable.DatetimeToJSONUnix(contract.CreatedAt),
+	})
+}
+
+// ABIUploadHandler stores the ABI sent as the request body for the contract; the optional name query parameter is a
+// label for the contract
+func (a *API) ABIUploadHandler(c *gin.Context) {
+	address, err := utils.ValidateAccount(c.Param("address"))
+	if err != nil {
+		BadRequest(c, fmt.Errorf("bad request: address is malformed"))
+		return
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(c.Request.Body, maxABISize+1))
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	if len(body) > maxABISize {
+		BadRequest(c, fmt.Errorf("bad request: abi is larger than %d bytes", maxABISize))
+		return
+	}
+
+	_, err = abi.Parse(body)
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	err = abi.Save(a.backend.DB(), address, c.Query("name"), body)
+	if err != nil {
+		Error(c, err)
+		return
+	}
+
+	a.ABIHandler(c)
+}
+
+func (a *API) ABIDeleteHandler(c *gin.Context) {
+	address, err := utils.ValidateAccount(c.Param("address"))
+	if err != nil {
+		BadRequest(c, fmt.Errorf("bad request: address is malformed"))
+		return
+	}
+
+	deleted, err := abi.Delete(a.backend.DB(), address)
+	if err != nil {
+		Error(c, err)
+		return
+	}
+
+	if !deleted {
+		NotFound(c)
+		return
+	}
+
+	OK(c, nil)
+}
+
+func (a *API) SignaturesHandler(c *gin.Context) {
+	selector, err := parseMethodSelector(c.Param("selector"))
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	methods, err := abi.Methods(a.backend.DB(), selector)
+	if err != nil {
+		Error(c, err)
+		return
+	}
+
+	if len(methods) == 0 {
+		NotFound(c)
+		return
+	}
+
+	var signatures []types.FunctionSignature
+	for _, m := range methods {
+		signatures = append(signatures, types.FunctionSignature{
+			Selector:  m.Selector(),
+			Signature: m.Signature(),
+		})
+	}
+
+	OK(c, signatures)
+}
+
+// SignaturesUploadHandler adds the function signatures sent as {"signatures": [...]} to the signature database
+func (a *API) SignaturesUploadHandler(c *gin.Context) {
+	var req struct {
+		Signatures []string `json:"signatures"`
+	}
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	for _, signature := range req.Signatures {
+		_, err := abi.ParseMethodSignature(signature)
+		if err != nil {
+			BadRequest(c, err)
+			return
+		}
+	}
+
+	added, err := abi.SaveSignatures(a.backend.DB(), req.Signatures)
+	if err != nil {
+		Error(c, err)
+		return
+	}
+
+	OK(c, map[string]interface{}{
+		"added": added,
+	})
+}
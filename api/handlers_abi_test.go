@@ -0,0 +1,76 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestABIRoutesRequireAdminToken(t *testing.T) {
+	db, cleanup := newTestDB(t)
+	defer cleanup()
+
+	path := "/api/abi/0x" + strings.Repeat("ab", 20)
+	body := `[{"type": "event", "name": "Stored", "inputs": [{"name": "value", "type": "uint256"}]}]`
+
+	a := newTestDBAPI(db, Config{})
+	for _, method := range []string{http.MethodPut, http.MethodDelete} {
+		if code := request(a, method, path, "", body).Code; code != http.StatusNotFound {
+			t.Errorf("%s without an admin token configured: expected 404, got %d", method, code)
+		}
+	}
+
+	a = newTestDBAPI(db, Config{AdminToken: "t0ken"})
+	for _, method := range []string{http.MethodPut, http.MethodDelete} {
+		if code := request(a, method, path, "wrong", body).Code; code != http.StatusUnauthorized {
+			t.Errorf("%s with a wrong token: expected 401, got %d", method, code)
+		}
+	}
+
+	w := request(a, http.MethodPut, path, "t0ken", body)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"Stored"`) {
+		t.Fatalf("unexpected upload response %d %s", w.Code, w.Body.String())
+	}
+
+	// reading the ABIs needs no token
+	w = request(a, http.MethodGet, path, "", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"Stored"`) {
+		t.Fatalf("unexpected abi %d %s", w.Code, w.Body.String())
+	}
+
+	w = request(a, http.MethodPut, path, "t0ken", `[{"type": "event", "name": "Bad", "inputs": [{"type": "tuple()[2]"}]}]`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected the abi to be rejected, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = request(a, http.MethodDelete, path, "t0ken", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected deletion response %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestSignaturesUploadRequiresAdminToken(t *testing.T) {
+	db, cleanup := newTestDB(t)
+	defer cleanup()
+
+	body := `{"signatures": ["store(uint64 value)"]}`
+
+	if code := request(newTestDBAPI(db, Config{}), http.MethodPost, "/api/signatures", "", body).Code; code != http.StatusNotFound {
+		t.Errorf("without an admin token configured: expected 404, got %d", code)
+	}
+
+	a := newTestDBAPI(db, Config{AdminToken: "t0ken"})
+	if code := request(a, http.MethodPost, "/api/signatures", "", body).Code; code != http.StatusUnauthorized {
+		t.Errorf("without a token: expected 401, got %d", code)
+	}
+
+	w := request(a, http.MethodPost, "/api/signatures", "t0ken", body)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"added":1`) {
+		t.Fatalf("unexpected upload response %d %s", w.Code, w.Body.String())
+	}
+
+	w = request(a, http.MethodGet, "/api/signatures/0x1d9a3bdd", "", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "store(uint64)") {
+		t.Fatalf("unexpected signatures %d %s", w.Code, w.Body.String())
+	}
+}
@@ -3,7 +3,12 @@ package api
 import (
 	"encoding/hex"
 	"fmt"
+	"strconv"
+	"strings"
 
+	"github.com/Alethio/memento/abi"
+	"github.com/Alethio/memento/api/types"
+	"github.com/Alethio/memento/data/storable"
 	"github.com/Alethio/memento/utils"
 	"github.com/alethio/web3-go/ethrpc"
 	"github.com/gin-gonic/gin"
@@ -33,6 +38,9 @@ func (a *API) AccountCodeHandler(c *gin.Context) {
 	})
 }
 
+// AccountBalanceHandler returns the balance of an account, as reported by the node at the latest indexed block or, if
+// the block query parameter is set, as recorded in the balance_changes table at that block (flagged as incomplete if
+// some blocks up to it were stored without their traces)
 func (a *API) AccountBalanceHandler(c *gin.Context) {
 	address := utils.CleanUpHex(c.Param("address"))
 	if len(address) != 40 {
@@ -40,13 +48,23 @@ func (a *API) AccountBalanceHandler(c *gin.Context) {
 		return
 	}
 
+	if c.Query("block") != "" {
+		a.accountBalanceAtBlock(c, address)
+		return
+	}
+
 	eth, err := ethrpc.NewWithDefaults(a.config.EthClientURL)
 	if err != nil {
 		Error(c, err)
 		return
 	}
 
-	balance, err := eth.GetBalanceAtBlock(fmt.Sprintf("0x%s", address), fmt.Sprintf("0x%x", a.core.Metrics().GetLatestBLock()))
+	block := "latest"
+	if latest := a.backend.LatestBlock(); latest > 0 {
+		block = fmt.Sprintf("0x%x", latest)
+	}
+
+	balance, err := eth.GetBalanceAtBlock(fmt.Sprintf("0x%s", address), block)
 	if err != nil {
 		Error(c, err)
 		return
@@ -56,3 +74,125 @@ func (a *API) AccountBalanceHandler(c *gin.Context) {
 		"balance": balance.String(),
 	})
 }
+
+// AccountCallsHandler returns the transactions that called a function of the contract at address, identified by the
+// method query parameter, which is either a selector or a function signature
+func (a *API) AccountCallsHandler(c *gin.Context) {
+	address, err := utils.ValidateAccount(c.Param("address"))
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	selector, err := parseMethodSelector(c.Query("method"))
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	limit, err := parseLimit(c, DefaultPageSize, MaxPageSize)
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	var filters string
+	params := []interface{}{storable.ByteArray(address), selector, limit}
+
+	if block := c.Query("includedInBlock"); block != "" {
+		includedInBlock, err := strconv.ParseInt(block, 10, 64)
+		if err != nil {
+			BadRequest(c, fmt.Errorf("includedInBlock must be a positive integer"))
+			return
+		}
+
+		var txIndex int64
+		if index := c.Query("txIndex"); index != "" {
+			txIndex, err = strconv.ParseInt(index, 10, 64)
+			if err != nil {
+				BadRequest(c, fmt.Errorf("txIndex must be a positive integer"))
+				return
+			}
+		}
+
+		filters = "and (included_in_block < $4 or (included_in_block = $4 and tx_index < $5))"
+		params = append(params, includedInBlock, txIndex)
+	}
+
+	query := fmt.Sprintf(`select tx_hash, tx_index, included_in_block, "from", "to", value, block_creation_time, tx_gas_used, tx_gas_price, msg_status, method_selector
+				from txs
+				where "to" = $1 and method_selector = $2 %s
+				order by included_in_block desc, tx_index desc limit $3`, filters)
+
+	rows, err := a.backend.DB().Query(query, params...)
+	if err != nil {
+		Error(c, err)
+		return
+	}
+	defer rows.Close()
+
+	var txs = make([]types.Tx, 0)
+	for rows.Next() {
+		var (
+			tx                types.Tx
+			txHash            string
+			txIndex           int32
+			includedInBlock   int64
+			from, to          storable.ByteArray
+			value             string
+			blockCreationTime storable.DatetimeToJSONUnix
+			txGasUsed         string
+			txGasPrice        string
+			msgStatus         string
+			methodSelector    string
+		)
+
+		err := rows.Scan(&txHash, &txIndex, &includedInBlock, &from, &to, &value, &blockCreationTime, &txGasUsed, &txGasPrice, &msgStatus, &methodSelector)
+		if err != nil {
+			Error(c, err)
+			return
+		}
+
+		tx.TxHash = &txHash
+		tx.TxIndex = &txIndex
+		tx.IncludedInBlock = &includedInBlock
+		tx.From = &from
+		tx.To = &to
+		tx.Value = &value
+		tx.BlockCreationTime = &blockCreationTime
+		tx.TxGasUsed = &txGasUsed
+		tx.TxGasPrice = &txGasPrice
+		tx.MsgStatus = &msgStatus
+		tx.MethodSelector = &methodSelector
+
+		txs = append(txs, tx)
+	}
+
+	OK(c, txs)
+}
+
+// parseMethodSelector returns the selector of a function given either the selector itself or the function signature
+func parseMethodSelector(method string) (string, error) {
+	if method == "" {
+		return "", fmt.Errorf("method is required")
+	}
+
+	if strings.Contains(method, "(") {
+		m, err := abi.ParseMethodSignature(method)
+		if err != nil {
+			return "", err
+		}
+
+		return m.Selector(), nil
+	}
+
+	selector := utils.CleanUpHex(method)
+	if len(selector) != abi.SelectorLength {
+		return "", fmt.Errorf("method must be a function signature or a 4 bytes selector")
+	}
+	if _, err := hex.DecodeString(selector); err != nil {
+		return "", fmt.Errorf("method must be a function signature or a 4 bytes selector")
+	}
+
+	return selector, nil
+}
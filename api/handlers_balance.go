@@ -0,0 +1,224 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/Alethio/memento/api/types"
+	"github.com/Alethio/memento/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// accountBalanceAtBlock answers AccountBalanceHandler from the stored balance changes, without asking the node; the
+// balance is the sum of the changes up to the block, so it's only as complete as the indexed history, which the
+// complete field tells
+func (a *API) accountBalanceAtBlock(c *gin.Context, address string) {
+	highest, err := a.highestStoredBlock()
+	if err != nil {
+		Error(c, err)
+		return
+	}
+
+	block, err := queryBlock(c, "block", highest)
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	if block > highest {
+		BadRequest(c, fmt.Errorf("block %d is not indexed yet; the highest indexed block is %d", block, highest))
+		return
+	}
+
+	balance, err := a.balanceAt(address, block)
+	if err != nil {
+		Error(c, err)
+		return
+	}
+
+	complete, err := a.balancesComplete(block)
+	if err != nil {
+		Error(c, err)
+		return
+	}
+
+	OK(c, map[string]interface{}{
+		"balance":  balance.String(),
+		"block":    block,
+		"complete": complete,
+	})
+}
+
+// AccountBalanceHistoryHandler returns the balance of an account after each block that changed it between fromBlock
+// and toBlock (the whole indexed history by default), oldest first; the next cursor in the meta continues with the
+// later blocks, and its complete field is false if some blocks up to toBlock were stored without their traces
+func (a *API) AccountBalanceHistoryHandler(c *gin.Context) {
+	address, err := utils.ValidateAccount(c.Param("address"))
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	limit, err := parseLimit(c, DefaultPageSize, MaxPageSize)
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	highest, err := a.highestStoredBlock()
+	if err != nil {
+		Error(c, err)
+		return
+	}
+
+	from, err := queryBlock(c, "fromBlock", 0)
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	to, err := queryBlock(c, "toBlock", highest)
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	if to < from {
+		BadRequest(c, fmt.Errorf("toBlock must not be lower than fromBlock"))
+		return
+	}
+
+	if token := c.Query("cursor"); token != "" {
+		prev, key, err := decodeCursor(token, 1)
+		if err != nil || prev {
+			BadRequest(c, errInvalidCursor)
+			return
+		}
+
+		after, err := strconv.ParseInt(key[0], 10, 64)
+		if err != nil {
+			BadRequest(c, errInvalidCursor)
+			return
+		}
+
+		if after+1 > from {
+			from = after + 1
+		}
+	}
+
+	// the balance before the first block of the page is carried over from all the changes before it
+	opening, err := a.balanceAt(address, from-1)
+	if err != nil {
+		Error(c, err)
+		return
+	}
+
+	complete, err := a.balancesComplete(to)
+	if err != nil {
+		Error(c, err)
+		return
+	}
+
+	// one more row than needed tells whether there's a page after this one
+	rows, err := a.backend.DB().Query(`
+		select included_in_block, delta from balance_changes
+		where address = $1 and included_in_block between $2 and $3
+		order by included_in_block
+		limit $4
+	`, address, from, to, limit+1)
+	if err != nil {
+		Error(c, err)
+		return
+	}
+	defer rows.Close()
+
+	var points = make([]types.BalancePoint, 0)
+	for rows.Next() {
+		var block int64
+		var delta string
+		err := rows.Scan(&block, &delta)
+		if err != nil {
+			Error(c, err)
+			return
+		}
+
+		change, err := parseDelta(delta)
+		if err != nil {
+			Error(c, err)
+			return
+		}
+
+		opening.Add(opening, change)
+		points = append(points, types.BalancePoint{
+			Block:   block,
+			Change:  change.String(),
+			Balance: opening.String(),
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		Error(c, err)
+		return
+	}
+
+	hasMore := len(points) > limit
+	if hasMore {
+		points = points[:limit]
+	}
+
+	var first, last string
+	if len(points) > 0 {
+		first = encodeCursor(false, points[0].Block)
+		last = encodeCursor(false, points[len(points)-1].Block)
+	}
+
+	meta := pageMeta(limit, first, last, hasMore, false, false)
+	meta["complete"] = complete
+
+	OK(c, points, meta)
+}
+
+func (a *API) highestStoredBlock() (int64, error) {
+	var highest int64
+	err := a.backend.DB().QueryRow(`select number from blocks order by number desc limit 1`).Scan(&highest)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	return highest, nil
+}
+
+// balanceAt returns the sum of the balance changes of the address up to the block
+// The sum is done by the database (exactly, since the changes are numeric(78)) so the changes don't have to be read
+func (a *API) balanceAt(address string, block int64) (*big.Int, error) {
+	var sum string
+	err := a.backend.DB().QueryRow(`select coalesce(sum(delta), 0) from balance_changes where address = $1 and included_in_block <= $2`, address, block).Scan(&sum)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseDelta(sum)
+}
+
+// balancesComplete returns false if some of the blocks up to the given one were stored without their traces, in
+// which case the balances miss the value moved by internal transactions
+func (a *API) balancesComplete(block int64) (bool, error) {
+	var untraced bool
+	err := a.backend.DB().QueryRow(`select exists (select 1 from blocks where number <= $1 and not has_traces)`, block).Scan(&untraced)
+	if err != nil {
+		return false, err
+	}
+
+	return !untraced, nil
+}
+
+func parseDelta(delta string) (*big.Int, error) {
+	n, ok := new(big.Int).SetString(delta, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid balance change %q", delta)
+	}
+
+	return n, nil
+}
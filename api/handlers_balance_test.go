@@ -0,0 +1,199 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Alethio/memento/api/types"
+	"github.com/Alethio/memento/data"
+	"github.com/Alethio/memento/eth/extra"
+	web3types "github.com/alethio/web3-go/types"
+)
+
+const (
+	balanceAlice = "a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1"
+	balanceBob   = "b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0"
+)
+
+func transfer(number int64, from, to, value string) web3types.Transaction {
+	var tx web3types.Transaction
+	tx.Hash = testHash(number)
+	tx.From = "0x" + from
+	tx.To = "0x" + to
+	tx.Value = value
+
+	return tx
+}
+
+// storeBalanceHistory stores 4 blocks: Alice gets a 1 gwei withdrawal and sends 1000 wei to Bob in block 1, block 2
+// doesn't involve her, Bob sends her 300 wei back in block 3 and she sends him 100 wei in block 4; each transfer costs
+// its sender 21000 wei; the blocks are stored with their traces unless their number is in untraced
+func storeBalanceHistory(t *testing.T, untraced ...int64) (*API, func()) {
+	backend, cleanup := newTestBackend(t)
+
+	blocks := [][]web3types.Transaction{
+		{transfer(1, balanceAlice, balanceBob, "0x3e8")},
+		{},
+		{transfer(3, balanceBob, balanceAlice, "0x12c")},
+		{transfer(4, balanceAlice, balanceBob, "0x64")},
+	}
+
+	for i, txs := range blocks {
+		number := int64(i + 1)
+		b, receipts := newTestBlock(number, txs...)
+
+		fb := &data.FullBlock{Block: b, Receipts: receipts, Traced: true}
+		for _, u := range untraced {
+			if u == number {
+				fb.Traced = false
+			}
+		}
+		if number == 1 {
+			fb.BlockExtra.Withdrawals = []extra.Withdrawal{{Index: "0x0", ValidatorIndex: "0x0", Address: "0x" + balanceAlice, Amount: "0x1"}}
+		}
+
+		storeTestBlock(t, backend, fb)
+	}
+
+	return newTestDBAPI(backend.DB(), Config{}), cleanup
+}
+
+type balanceResponse struct {
+	Status int `json:"status"`
+	Data   struct {
+		Balance  string `json:"balance"`
+		Block    int64  `json:"block"`
+		Complete bool   `json:"complete"`
+	} `json:"data"`
+}
+
+func TestAccountBalanceAtBlock(t *testing.T) {
+	a, cleanup := storeBalanceHistory(t)
+	defer cleanup()
+
+	testCases := []struct {
+		block   string
+		balance string
+	}{
+		{"0", "0"},
+		{"1", "999978000"},
+		{"2", "999978000"},
+		{"3", "999978300"},
+		{"4", "999957200"},
+	}
+
+	for _, tc := range testCases {
+		w := request(a, http.MethodGet, "/api/explorer/account/0x"+balanceAlice+"/balance?block="+tc.block, "", "")
+
+		var resp balanceResponse
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if resp.Status != http.StatusOK || resp.Data.Balance != tc.balance || !resp.Data.Complete {
+			t.Errorf("block %s: expected a balance of %s, got %s", tc.block, tc.balance, w.Body.String())
+		}
+	}
+
+	w := request(a, http.MethodGet, "/api/explorer/account/0x"+balanceAlice+"/balance?block=5", "", "")
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "not indexed yet") {
+		t.Errorf("expected blocks above the highest stored one to be refused, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+type balanceHistoryResponse struct {
+	Status int                  `json:"status"`
+	Data   []types.BalancePoint `json:"data"`
+	Meta   struct {
+		Next     *string `json:"next"`
+		Complete bool    `json:"complete"`
+	} `json:"meta"`
+}
+
+func balanceHistory(t *testing.T, a *API, query string) balanceHistoryResponse {
+	w := request(a, http.MethodGet, "/api/explorer/account/0x"+balanceAlice+"/balance/history"+query, "", "")
+
+	var resp balanceHistoryResponse
+	err := json.Unmarshal(w.Body.Bytes(), &resp)
+	if err != nil || resp.Status != http.StatusOK {
+		t.Fatalf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+
+	return resp
+}
+
+func TestAccountBalanceHistory(t *testing.T) {
+	a, cleanup := storeBalanceHistory(t)
+	defer cleanup()
+
+	resp := balanceHistory(t, a, "")
+	expected := []types.BalancePoint{
+		{Block: 1, Change: "999978000", Balance: "999978000"},
+		{Block: 3, Change: "300", Balance: "999978300"},
+		{Block: 4, Change: "-21100", Balance: "999957200"},
+	}
+	if len(resp.Data) != len(expected) {
+		t.Fatalf("expected %d points, got %+v", len(expected), resp.Data)
+	}
+	for i, p := range expected {
+		if resp.Data[i] != p {
+			t.Errorf("point %d: expected %+v, got %+v", i, p, resp.Data[i])
+		}
+	}
+	if resp.Meta.Next != nil {
+		t.Errorf("expected no next page, got %s", *resp.Meta.Next)
+	}
+	if !resp.Meta.Complete {
+		t.Errorf("expected the history of traced blocks to be complete")
+	}
+
+	// the pages carry the balance over from the changes before them
+	resp = balanceHistory(t, a, "?limit=1&fromBlock=2")
+	if len(resp.Data) != 1 || resp.Data[0] != expected[1] || resp.Meta.Next == nil {
+		t.Fatalf("unexpected first page %+v", resp)
+	}
+
+	resp = balanceHistory(t, a, "?limit=1&fromBlock=2&cursor="+*resp.Meta.Next)
+	if len(resp.Data) != 1 || resp.Data[0] != expected[2] || resp.Meta.Next != nil {
+		t.Fatalf("unexpected second page %+v", resp)
+	}
+
+	resp = balanceHistory(t, a, "?toBlock=2")
+	if len(resp.Data) != 1 || resp.Data[0] != expected[0] {
+		t.Errorf("unexpected history up to block 2 %+v", resp.Data)
+	}
+
+	w := request(a, http.MethodGet, "/api/explorer/account/0x"+balanceAlice+"/balance/history?fromBlock=3&toBlock=2", "", "")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected an inverted range to be refused, got %d", w.Code)
+	}
+}
+
+func TestBalancesOfUntracedBlocksAreIncomplete(t *testing.T) {
+	a, cleanup := storeBalanceHistory(t, 3)
+	defer cleanup()
+
+	for block, complete := range map[string]bool{"2": true, "3": false, "4": false} {
+		w := request(a, http.MethodGet, "/api/explorer/account/0x"+balanceAlice+"/balance?block="+block, "", "")
+
+		var resp balanceResponse
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if resp.Data.Complete != complete {
+			t.Errorf("block %s: expected complete to be %t, got %s", block, complete, w.Body.String())
+		}
+	}
+
+	if balanceHistory(t, a, "?toBlock=2").Meta.Complete != true {
+		t.Errorf("expected the history up to block 2 to be complete")
+	}
+	if balanceHistory(t, a, "").Meta.Complete != false {
+		t.Errorf("expected the whole history to be incomplete")
+	}
+}
@@ -19,7 +19,7 @@ func (a *API) BlockHandler(c *gin.Context) {
 	)
 
 	if c.Param("block") == "latest" {
-		err := a.core.DB().QueryRow("select number from blocks order by number desc limit 1").Scan(&blockNumber)
+		err := a.backend.DB().QueryRow("select number from blocks order by number desc limit 1").Scan(&blockNumber)
 		if err != nil {
 			Error(c, err)
 			return
@@ -33,7 +33,7 @@ func (a *API) BlockHandler(c *gin.Context) {
 	}
 
 	var block types.Block
-	err = a.core.DB().QueryRow("select number, block_hash, parent_block_hash, block_creation_time, block_gas_limit, block_gas_used, block_difficulty, total_block_difficulty, block_extra_data, block_mix_hash, block_nonce, block_size, block_logs_bloom, includes_uncle, has_beneficiary, has_receipts_trie, has_tx_trie, sha3_uncles, number_of_uncles, number_of_txs from blocks where number = $1 limit 1", blockNumber).Scan(
+	err = a.backend.DB().QueryRow("select number, block_hash, parent_block_hash, block_creation_time, block_gas_limit, block_gas_used, block_difficulty, total_block_difficulty, block_extra_data, block_mix_hash, block_nonce, block_size, block_logs_bloom, includes_uncle, has_beneficiary, has_receipts_trie, has_tx_trie, sha3_uncles, number_of_uncles, number_of_txs, finality, base_fee_per_gas, burnt_fees, priority_fees, withdrawals_root, coalesce(number_of_withdrawals, 0), blob_gas_used, excess_blob_gas, parent_beacon_block_root from blocks where number = $1 limit 1", blockNumber).Scan(
 		&block.Number,
 		&block.BlockHash,
 		&block.ParentBlockHash,
@@ -54,6 +54,15 @@ func (a *API) BlockHandler(c *gin.Context) {
 		&block.Sha3Uncles,
 		&block.NumberOfUncles,
 		&block.NumberOfTxs,
+		&block.Finality,
+		&block.BaseFeePerGas,
+		&block.BurntFees,
+		&block.PriorityFees,
+		&block.WithdrawalsRoot,
+		&block.NumberOfWithdrawals,
+		&block.BlobGasUsed,
+		&block.ExcessBlobGas,
+		&block.ParentBeaconBlockRoot,
 	)
 	if err != nil && err != sql.ErrNoRows {
 		Error(c, err)
@@ -91,7 +100,7 @@ func (a *API) BlockRangeHandler(c *gin.Context) {
 		return
 	}
 
-	rows, err := a.core.DB().Query("select number, block_creation_time, has_beneficiary, number_of_txs from blocks where number between $1 and $2 order by number desc", start, end)
+	rows, err := a.backend.DB().Query("select number, block_creation_time, has_beneficiary, number_of_txs, finality from blocks where number between $1 and $2 order by number desc", start, end)
 	if err != nil && err != sql.ErrNoRows {
 		Error(c, err)
 		return
@@ -106,9 +115,10 @@ func (a *API) BlockRangeHandler(c *gin.Context) {
 			creationTime   storable.DatetimeToJSONUnix
 			hasBeneficiary storable.ByteArray
 			numberOfTxs    int32
+			finality       *string
 		)
 
-		err := rows.Scan(&number, &creationTime, &hasBeneficiary, &numberOfTxs)
+		err := rows.Scan(&number, &creationTime, &hasBeneficiary, &numberOfTxs, &finality)
 		if err != nil {
 			Error(c, err)
 			return
@@ -119,6 +129,7 @@ func (a *API) BlockRangeHandler(c *gin.Context) {
 		block["blockCreationTime"] = creationTime
 		block["hasBeneficiary"] = hasBeneficiary
 		block["numberOfTxs"] = numberOfTxs
+		block["finality"] = finality
 
 		blockList = append(blockList, block)
 	}
@@ -130,3 +141,38 @@ func (a *API) BlockRangeHandler(c *gin.Context) {
 
 	OK(c, blockList)
 }
+
+func (a *API) BlockWithdrawalsHandler(c *gin.Context) {
+	blockNumber, err := strconv.ParseInt(c.Param("block"), 10, 64)
+	if err != nil {
+		BadRequest(c, fmt.Errorf("invalid request: block number must be numeric"))
+		return
+	}
+
+	rows, err := a.backend.DB().Query("select included_in_block, withdrawal_index, validator_index, address, amount, block_creation_time from withdrawals where included_in_block = $1 order by withdrawal_index", blockNumber)
+	if err != nil && err != sql.ErrNoRows {
+		Error(c, err)
+		return
+	}
+	defer rows.Close()
+
+	var withdrawals []types.Withdrawal
+	for rows.Next() {
+		var w types.Withdrawal
+
+		err := rows.Scan(&w.IncludedInBlock, &w.WithdrawalIndex, &w.ValidatorIndex, &w.Address, &w.Amount, &w.BlockCreationTime)
+		if err != nil {
+			Error(c, err)
+			return
+		}
+
+		withdrawals = append(withdrawals, w)
+	}
+
+	if len(withdrawals) == 0 {
+		NotFound(c)
+		return
+	}
+
+	OK(c, withdrawals)
+}
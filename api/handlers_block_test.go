@@ -0,0 +1,57 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/Alethio/memento/data"
+)
+
+func TestBlockFinality(t *testing.T) {
+	backend, cleanup := newTestBackend(t)
+	defer cleanup()
+
+	for _, number := range []int64{1, 2} {
+		b, receipts := newTestBlock(number)
+		storeTestBlock(t, backend, &data.FullBlock{Block: b, Receipts: receipts, Finality: "finalized"})
+	}
+
+	// block 1 was stored before the statuses were recorded
+	_, err := backend.DB().Exec(`update blocks set finality = null where number = 1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := newTestDBAPI(backend.DB(), Config{})
+
+	for number, expected := range map[string]interface{}{"1": nil, "2": "finalized"} {
+		w := request(a, http.MethodGet, "/api/explorer/block/"+number, "", "")
+
+		var resp struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if finality, ok := resp.Data["finality"]; !ok || finality != expected {
+			t.Errorf("block %s: expected the finality to be %v, got %s", number, expected, w.Body.String())
+		}
+	}
+
+	w := request(a, http.MethodGet, "/api/explorer/block-range/0/2", "", "")
+
+	var resp struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	err = json.Unmarshal(w.Body.Bytes(), &resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(resp.Data) != 2 || resp.Data[0]["finality"] != "finalized" || resp.Data[1]["finality"] != nil {
+		t.Errorf("unexpected block range %s", w.Body.String())
+	}
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Alethio/memento/api/types"
+	"github.com/Alethio/memento/data/storable"
+	"github.com/Alethio/memento/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const contractColumns = `address, creator, tx_hash, included_in_block, tx_index, trace_address, code_hash, block_creation_time`
+
+// AccountHandler returns a summary of an account; it's a contract if its creation was indexed, which doesn't cover
+// the contracts created by other contracts when traces are disabled, nor the ones in the genesis block
+func (a *API) AccountHandler(c *gin.Context) {
+	address, err := utils.ValidateAccount(c.Param("address"))
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	account := types.Account{Address: address}
+
+	// an address can be reused by a contract created with create2 after the previous one self-destructed
+	row := a.backend.DB().QueryRow(`select `+contractColumns+` from contracts where address = $1 order by included_in_block desc, tx_index desc limit 1`, address)
+	contract, err := scanContract(row)
+	if err != nil && err != sql.ErrNoRows {
+		Error(c, err)
+		return
+	}
+
+	if err == nil {
+		account.IsContract = true
+		account.Creation = &contract
+	}
+
+	OK(c, account)
+}
+
+// ContractsHandler returns the contracts, newest first, one page at a time; they can be filtered by creator
+// The pages are linked by the opaque next and prev cursors returned in the meta
+func (a *API) ContractsHandler(c *gin.Context) {
+	limit, err := parseLimit(c, DefaultPageSize, MaxPageSize)
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	var cur *contractsCursor
+	if token := c.Query("cursor"); token != "" {
+		decoded, err := decodeContractsCursor(token)
+		if err != nil {
+			BadRequest(c, err)
+			return
+		}
+
+		cur = &decoded
+	}
+
+	var conditions []string
+	var args []interface{}
+
+	if creator := c.Query("creator"); creator != "" {
+		creator, err := utils.ValidateAccount(creator)
+		if err != nil {
+			BadRequest(c, fmt.Errorf("creator is malformed"))
+			return
+		}
+
+		args = append(args, creator)
+		conditions = append(conditions, fmt.Sprintf("creator = $%d", len(args)))
+	}
+
+	order := "desc"
+	if cur != nil {
+		operator := "<"
+		if cur.Prev {
+			operator = ">"
+			order = "asc"
+		}
+
+		args = append(args, cur.IncludedInBlock, cur.TxIndex, cur.TraceAddress)
+		conditions = append(conditions, fmt.Sprintf("(included_in_block, tx_index, trace_address) %s ($%d, $%d, $%d)", operator, len(args)-2, len(args)-1, len(args)))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "where " + strings.Join(conditions, " and ")
+	}
+
+	// one more row than needed tells whether there's a page after this one
+	args = append(args, limit+1)
+
+	rows, err := a.backend.DB().Query(fmt.Sprintf(`select `+contractColumns+` from contracts %[1]s order by included_in_block %[2]s, tx_index %[2]s, trace_address %[2]s limit $%[3]d`, where, order, len(args)), args...)
+	if err != nil {
+		Error(c, err)
+		return
+	}
+	defer rows.Close()
+
+	var contracts = make([]types.Contract, 0)
+	for rows.Next() {
+		contract, err := scanContract(rows)
+		if err != nil {
+			Error(c, err)
+			return
+		}
+
+		contracts = append(contracts, contract)
+	}
+
+	if err := rows.Err(); err != nil {
+		Error(c, err)
+		return
+	}
+
+	hasMore := len(contracts) > limit
+	if hasMore {
+		contracts = contracts[:limit]
+	}
+
+	// the previous pages are fetched oldest first, so they are flipped back to the usual order
+	backwards := cur != nil && cur.Prev
+	if backwards {
+		for i, j := 0, len(contracts)-1; i < j; i, j = i+1, j-1 {
+			contracts[i], contracts[j] = contracts[j], contracts[i]
+		}
+	}
+
+	var first, last string
+	if len(contracts) > 0 {
+		first = contractCursor(contracts[0], true).encode()
+		last = contractCursor(contracts[len(contracts)-1], false).encode()
+	}
+
+	OK(c, contracts, pageMeta(limit, first, last, hasMore, backwards, cur != nil))
+}
+
+func contractCursor(contract types.Contract, prev bool) contractsCursor {
+	return contractsCursor{
+		IncludedInBlock: contract.IncludedInBlock,
+		TxIndex:         int64(contract.TxIndex),
+		TraceAddress:    contract.TraceAddress,
+		Prev:            prev,
+	}
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanContract(row scanner) (types.Contract, error) {
+	var (
+		contract          types.Contract
+		blockCreationTime *time.Time
+	)
+
+	err := row.Scan(&contract.Address, &contract.Creator, &contract.TxHash, &contract.IncludedInBlock, &contract.TxIndex, &contract.TraceAddress, &contract.CodeHash, &blockCreationTime)
+	if err != nil {
+		return contract, err
+	}
+
+	if blockCreationTime != nil {
+		t := storable.DatetimeToJSONUnix(*blockCreationTime)
+		contract.BlockCreationTime = &t
+	}
+
+	return contract, nil
+}
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Alethio/memento/feed"
+	"github.com/Alethio/memento/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	feedWriteTimeout = 10 * time.Second
+	feedPongTimeout  = 60 * time.Second
+	feedPingInterval = 50 * time.Second
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin: func(r *http.Request) bool {
+		return true
+	},
+}
+
+// FeedWSHandler streams the live events matching the query filters as JSON messages over a websocket
+func (a *API) FeedWSHandler(c *gin.Context) {
+	hub := a.backend.Feed()
+	if hub == nil {
+		NotFound(c)
+		return
+	}
+
+	filter, err := feedFilter(c)
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	defer conn.Close()
+
+	sub := hub.Subscribe(filter)
+	defer sub.Close()
+
+	// the client isn't expected to send anything, but the connection must be read in order to process the control
+	// messages and to find out when it's closed
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+
+		conn.SetReadLimit(512)
+		conn.SetReadDeadline(time.Now().Add(feedPongTimeout))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(feedPongTimeout))
+		})
+
+		for {
+			_, _, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(feedPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case e, ok := <-sub.C:
+			conn.SetWriteDeadline(time.Now().Add(feedWriteTimeout))
+			if !ok {
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "subscriber too slow"))
+				return
+			}
+
+			err := conn.WriteJSON(e)
+			if err != nil {
+				return
+			}
+		case <-ping.C:
+			conn.SetWriteDeadline(time.Now().Add(feedWriteTimeout))
+			err := conn.WriteMessage(websocket.PingMessage, nil)
+			if err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+// FeedSSEHandler streams the live events matching the query filters as server-sent events named after their type
+func (a *API) FeedSSEHandler(c *gin.Context) {
+	hub := a.backend.Feed()
+	if hub == nil {
+		NotFound(c)
+		return
+	}
+
+	filter, err := feedFilter(c)
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	sub := hub.Subscribe(filter)
+	defer sub.Close()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+
+	ping := time.NewTicker(feedPingInterval)
+	defer ping.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case e, ok := <-sub.C:
+			if !ok {
+				return false
+			}
+
+			c.SSEvent(e.Type, e)
+			return true
+		case <-ping.C:
+			c.SSEvent("ping", time.Now().Unix())
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+// feedFilter builds the subscription filter from the events, address and topic query parameters, which can be
+// repeated or hold comma-separated lists
+func feedFilter(c *gin.Context) (feed.Filter, error) {
+	events := queryList(c, "events")
+	for _, e := range events {
+		if e != feed.EventBlock && e != feed.EventTx && e != feed.EventReorg {
+			return feed.Filter{}, fmt.Errorf("bad request: unknown event type %q", e)
+		}
+	}
+
+	addresses := queryList(c, "address")
+	for i, a := range addresses {
+		addresses[i] = utils.CleanUpHex(a)
+		if len(addresses[i]) != 40 {
+			return feed.Filter{}, fmt.Errorf("bad request: address %q is malformed", a)
+		}
+	}
+
+	topics := queryList(c, "topic")
+	for i, t := range topics {
+		topics[i] = utils.CleanUpHex(t)
+		if len(topics[i]) != 64 {
+			return feed.Filter{}, fmt.Errorf("bad request: topic %q is malformed", t)
+		}
+	}
+
+	return feed.NewFilter(events, addresses, topics), nil
+}
+
+func queryList(c *gin.Context, key string) []string {
+	var list []string
+	for _, v := range c.QueryArray(key) {
+		for _, item := range strings.Split(v, ",") {
+			item = strings.TrimSpace(item)
+			if item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+
+	return list
+}
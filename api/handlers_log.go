@@ -0,0 +1,243 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Alethio/memento/abi"
+	"github.com/Alethio/memento/api/types"
+	"github.com/Alethio/memento/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// logEntryBlockIndex and logEntryBlockHash select the position of a log entry within its block and the hash of the
+// block; the entries stored before they were recorded get them from the txs and blocks tables, which requires txs to
+// be joined as t
+const (
+	logEntryBlockIndex = `coalesce(l.block_log_index, (select coalesce(sum(t2.log_entries_triggered), 0) from txs t2 where t2.included_in_block = l.included_in_block and t2.tx_index < t.tx_index) + l.log_index)`
+	logEntryBlockHash  = `coalesce(l.block_hash, (select b.block_hash from blocks b where b.number = l.included_in_block))`
+)
+
+// logEntriesQuery selects the log entries matching a condition (first verb) in the given order (second verb); the
+// transaction index comes from txs, since the log entries only reference their transaction by hash
+const logEntriesQuery = `select l.tx_hash, ` + logEntryBlockIndex + `, l.log_index, l.log_data, l.logged_by, l.topic_0, l.topic_1, l.topic_2, l.topic_3, l.included_in_block, ` + logEntryBlockHash + `, t.tx_index
+	from log_entries l
+	join txs t on t.tx_hash = l.tx_hash and t.included_in_block = l.included_in_block
+	where %s
+	order by %s`
+
+// LogsHandler searches the log entries by emitting contract (address) and topics (topic0 to topic3) between fromBlock
+// and toBlock, newest first, one page at a time
+// toBlock defaults to the highest stored block and fromBlock to the start of the widest range allowed, which is
+// MaxLogsBlocksInRange blocks; the pages are linked by the opaque next and prev cursors returned in the meta
+func (a *API) LogsHandler(c *gin.Context) {
+	limit, err := parseLimit(c, DefaultPageSize, MaxPageSize)
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	var cur *logEntriesCursor
+	if token := c.Query("cursor"); token != "" {
+		decoded, err := decodeLogEntriesCursor(token)
+		if err != nil {
+			BadRequest(c, err)
+			return
+		}
+
+		cur = &decoded
+	}
+
+	highest, err := a.highestStoredBlock()
+	if err != nil {
+		Error(c, err)
+		return
+	}
+
+	to, err := queryBlock(c, "toBlock", highest)
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	from, err := queryBlock(c, "fromBlock", to-MaxLogsBlocksInRange+1)
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+	if from < 0 {
+		from = 0
+	}
+
+	if to < from {
+		BadRequest(c, fmt.Errorf("toBlock must not be lower than fromBlock"))
+		return
+	}
+
+	if to-from >= MaxLogsBlocksInRange {
+		BadRequest(c, fmt.Errorf("block range too wide; at most %d blocks can be searched at once", MaxLogsBlocksInRange))
+		return
+	}
+
+	args := []interface{}{from, to}
+	conditions := []string{"l.included_in_block between $1 and $2"}
+
+	if address := c.Query("address"); address != "" {
+		address, err := utils.ValidateAccount(address)
+		if err != nil {
+			BadRequest(c, fmt.Errorf("address is malformed"))
+			return
+		}
+
+		args = append(args, address)
+		conditions = append(conditions, fmt.Sprintf("l.logged_by = $%d", len(args)))
+	}
+
+	for i := 0; i < 4; i++ {
+		key := fmt.Sprintf("topic%d", i)
+		topic := c.Query(key)
+		if topic == "" {
+			continue
+		}
+
+		topic = utils.CleanUpHex(topic)
+		if len(topic) != 64 {
+			BadRequest(c, fmt.Errorf("%s is malformed", key))
+			return
+		}
+
+		args = append(args, topic)
+		conditions = append(conditions, fmt.Sprintf("l.topic_%d = $%d", i, len(args)))
+	}
+
+	order := "desc"
+	if cur != nil {
+		operator := "<"
+		if cur.Prev {
+			operator = ">"
+			order = "asc"
+		}
+
+		args = append(args, cur.IncludedInBlock, cur.TxIndex, cur.LogIndex)
+		conditions = append(conditions, fmt.Sprintf("(l.included_in_block, t.tx_index, l.log_index) %s ($%d, $%d, $%d)", operator, len(args)-2, len(args)-1, len(args)))
+	}
+
+	// one more row than needed tells whether there's a page after this one
+	args = append(args, limit+1)
+	orderBy := fmt.Sprintf("l.included_in_block %[1]s, t.tx_index %[1]s, l.log_index %[1]s limit $%[2]d", order, len(args))
+
+	rows, err := a.backend.DB().Query(fmt.Sprintf(logEntriesQuery, strings.Join(conditions, " and "), orderBy), args...)
+	if err != nil {
+		Error(c, err)
+		return
+	}
+	defer rows.Close()
+
+	decoder := abi.NewDecoder(a.backend.DB())
+
+	var logEntries = make([]types.LogEntry, 0)
+	for rows.Next() {
+		le, err := scanLogEntry(rows, decoder)
+		if err != nil {
+			Error(c, err)
+			return
+		}
+
+		logEntries = append(logEntries, le)
+	}
+
+	if err := rows.Err(); err != nil {
+		Error(c, err)
+		return
+	}
+
+	hasMore := len(logEntries) > limit
+	if hasMore {
+		logEntries = logEntries[:limit]
+	}
+
+	// the previous pages are fetched oldest first, so they are flipped back to the usual order
+	backwards := cur != nil && cur.Prev
+	if backwards {
+		for i, j := 0, len(logEntries)-1; i < j; i, j = i+1, j-1 {
+			logEntries[i], logEntries[j] = logEntries[j], logEntries[i]
+		}
+	}
+
+	var first, last string
+	if len(logEntries) > 0 {
+		first = logEntryCursor(logEntries[0], true).encode()
+		last = logEntryCursor(logEntries[len(logEntries)-1], false).encode()
+	}
+
+	meta := pageMeta(limit, first, last, hasMore, backwards, cur != nil)
+	meta["fromBlock"] = from
+	meta["toBlock"] = to
+
+	OK(c, logEntries, meta)
+}
+
+func logEntryCursor(le types.LogEntry, prev bool) logEntriesCursor {
+	return logEntriesCursor{
+		IncludedInBlock: le.IncludedInBlock,
+		TxIndex:         int64(le.TxIndex),
+		LogIndex:        int64(le.TxLogIndex),
+		Prev:            prev,
+	}
+}
+
+// queryBlock reads a block number from the query parameter key, returning def if it's missing
+func queryBlock(c *gin.Context, key string, def int64) (int64, error) {
+	value := c.Query(key)
+	if value == "" {
+		return def, nil
+	}
+
+	number, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("%s must be a positive integer", key)
+	}
+
+	return number, nil
+}
+
+// scanLogEntry reads a row of logEntriesQuery and decodes the event using the known ABIs
+func scanLogEntry(row scanner, decoder *abi.Decoder) (types.LogEntry, error) {
+	var (
+		le                             types.LogEntry
+		topic0, topic1, topic2, topic3 string
+	)
+
+	err := row.Scan(&le.TxHash, &le.LogIndex, &le.TxLogIndex, &le.LogData, &le.LoggedBy, &topic0, &topic1, &topic2, &topic3, &le.IncludedInBlock, &le.BlockHash, &le.TxIndex)
+	if err != nil {
+		return le, err
+	}
+
+	// we can do this since it is not possible, for example, to have an empty topic2 and a non-empty topic3
+	le.HasLogTopics = utils.AppendNotEmpty(le.HasLogTopics, topic0)
+	le.HasLogTopics = utils.AppendNotEmpty(le.HasLogTopics, topic1)
+	le.HasLogTopics = utils.AppendNotEmpty(le.HasLogTopics, topic2)
+	le.HasLogTopics = utils.AppendNotEmpty(le.HasLogTopics, topic3)
+
+	le.EventDecoded = make(map[string]interface{})
+	le.EventDecoded["topic0"] = fmt.Sprintf("0x%s", topic0)
+	le.EventDecoded["event"] = ""
+
+	event, err := decoder.Decode(le.LoggedBy, le.HasLogTopics, le.LogData.String())
+	if event != nil {
+		le.EventDecoded["event"] = event.Name
+		le.EventDecoded["signature"] = event.Signature
+	}
+
+	if err == nil && event != nil {
+		le.EventDecoded["inputs"] = decodedParams(event.Params)
+	} else {
+		if err != nil {
+			le.EventDecodedError = err.Error()
+		}
+		le.EventDecoded["inputs"] = rawEventInputs(le)
+	}
+
+	return le, nil
+}
@@ -17,7 +17,7 @@ func (a *API) SearchHandler(c *gin.Context) {
 	}
 
 	var count int
-	err := a.core.DB().QueryRow(`select count(*) from txs where tx_hash = $1`, query).Scan(&count)
+	err := a.backend.DB().QueryRow(`select count(*) from txs where tx_hash = $1`, query).Scan(&count)
 	if err != nil {
 		Error(c, err)
 		return
@@ -31,7 +31,7 @@ func (a *API) SearchHandler(c *gin.Context) {
 	}
 
 	var number int64
-	err = a.core.DB().QueryRow(`select number from blocks where block_hash = $1`, query).Scan(&number)
+	err = a.backend.DB().QueryRow(`select number from blocks where block_hash = $1`, query).Scan(&number)
 	if err != nil && err != sql.ErrNoRows {
 		Error(c, err)
 		return
@@ -46,7 +46,7 @@ func (a *API) SearchHandler(c *gin.Context) {
 		return
 	}
 
-	err = a.core.DB().QueryRow(`select count(*) from uncles where block_hash = $1`, query).Scan(&count)
+	err = a.backend.DB().QueryRow(`select count(*) from uncles where block_hash = $1`, query).Scan(&count)
 	if err != nil {
 		Error(c, err)
 		return
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/Alethio/memento/api/types"
+	"github.com/Alethio/memento/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func (a *API) AccountTokenTransfersHandler(c *gin.Context) {
+	accountAddress, err := utils.ValidateAccount(c.Param("address"))
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	a.tokenTransfers(c, `(tt."from" = $1 or tt."to" = $1)`, accountAddress)
+}
+
+func (a *API) TokenTransfersHandler(c *gin.Context) {
+	tokenAddress, err := utils.ValidateAccount(c.Param("address"))
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	a.tokenTransfers(c, `tt.token_address = $1`, tokenAddress)
+}
+
+// tokenTransferBlockLogIndex selects the position of a transfer's log entry within its block; the transfers stored
+// before the block log index was recorded get it from the txs table, like logEntryBlockIndex
+const tokenTransferBlockLogIndex = `coalesce((select l.block_log_index from log_entries l where l.tx_hash = tt.tx_hash and l.log_index = tt.log_index), (select coalesce(sum(t2.log_entries_triggered), 0) from txs t2 where t2.included_in_block = tt.included_in_block and t2.tx_index < tt.tx_index) + tt.log_index)`
+
+// tokenTransfers responds with the token transfers matching the given condition, newest first, one page at a time
+// The pages are linked by the opaque next and prev cursors returned in the meta, like the ones of AccountTxsHandler
+func (a *API) tokenTransfers(c *gin.Context, condition string, address string) {
+	limit, err := parseLimit(c, DefaultPageSize, MaxPageSize)
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	var cur *tokenTransfersCursor
+	if token := c.Query("cursor"); token != "" {
+		decoded, err := decodeTokenTransfersCursor(token)
+		if err != nil {
+			BadRequest(c, err)
+			return
+		}
+
+		cur = &decoded
+	}
+
+	args := []interface{}{address}
+	conditions := []string{condition}
+
+	order := "desc"
+	if cur != nil {
+		operator := "<"
+		if cur.Prev {
+			operator = ">"
+			order = "asc"
+		}
+
+		args = append(args, cur.IncludedInBlock, cur.TxIndex, cur.TxLogIndex, cur.BatchIndex)
+		conditions = append(conditions, fmt.Sprintf("(tt.included_in_block, tt.tx_index, tt.log_index, tt.batch_index) %s ($%d, $%d, $%d, $%d)", operator, len(args)-3, len(args)-2, len(args)-1, len(args)))
+	}
+
+	// one more row than needed tells whether there's a page after this one
+	args = append(args, limit+1)
+
+	query := fmt.Sprintf(`select tt.tx_hash, tt.included_in_block, tt.tx_index, `+tokenTransferBlockLogIndex+`, tt.log_index, tt.batch_index, tt.token_address, tt.token_standard, coalesce(tt.operator, ''), tt."from", tt."to", tt.amount, cast(tt.token_id as text)
+				from token_transfers tt
+				where %[1]s
+				order by tt.included_in_block %[2]s, tt.tx_index %[2]s, tt.log_index %[2]s, tt.batch_index %[2]s limit $%[3]d`, strings.Join(conditions, " and "), order, len(args))
+
+	rows, err := a.backend.DB().Query(query, args...)
+	if err != nil {
+		Error(c, err)
+		return
+	}
+	defer rows.Close()
+
+	var transfers = make([]types.TokenTransfer, 0)
+	for rows.Next() {
+		var (
+			tt      types.TokenTransfer
+			tokenID sql.NullString
+		)
+
+		err := rows.Scan(&tt.TxHash, &tt.IncludedInBlock, &tt.TxIndex, &tt.LogIndex, &tt.TxLogIndex, &tt.BatchIndex, &tt.TokenAddress, &tt.TokenStandard, &tt.Operator, &tt.From, &tt.To, &tt.Amount, &tokenID)
+		if err != nil {
+			Error(c, err)
+			return
+		}
+
+		if tokenID.Valid {
+			tt.TokenID = &tokenID.String
+		}
+
+		transfers = append(transfers, tt)
+	}
+
+	if err := rows.Err(); err != nil {
+		Error(c, err)
+		return
+	}
+
+	hasMore := len(transfers) > limit
+	if hasMore {
+		transfers = transfers[:limit]
+	}
+
+	// the previous pages are fetched oldest first, so they are flipped back to the usual order
+	backwards := cur != nil && cur.Prev
+	if backwards {
+		for i, j := 0, len(transfers)-1; i < j; i, j = i+1, j-1 {
+			transfers[i], transfers[j] = transfers[j], transfers[i]
+		}
+	}
+
+	var first, last string
+	if len(transfers) > 0 {
+		first = tokenTransferCursor(transfers[0], true).encode()
+		last = tokenTransferCursor(transfers[len(transfers)-1], false).encode()
+	}
+
+	OK(c, transfers, pageMeta(limit, first, last, hasMore, backwards, cur != nil))
+}
+
+func tokenTransferCursor(tt types.TokenTransfer, prev bool) tokenTransfersCursor {
+	return tokenTransfersCursor{
+		IncludedInBlock: tt.IncludedInBlock,
+		TxIndex:         int64(tt.TxIndex),
+		TxLogIndex:      int64(tt.TxLogIndex),
+		BatchIndex:      int64(tt.BatchIndex),
+		Prev:            prev,
+	}
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Alethio/memento/api/types"
+	"github.com/Alethio/memento/data"
+	"github.com/Alethio/memento/data/storable"
+	web3types "github.com/alethio/web3-go/types"
+)
+
+const testToken = "7070707070707070707070707070707070707070"
+
+// erc20Transfer returns a Transfer log of testToken sending amount from from to to
+func erc20Transfer(from, to string, amount int64) web3types.Log {
+	var l web3types.Log
+	l.Address = "0x" + testToken
+	l.Topics = []string{"0x" + storable.TransferEventTopic, "0x" + strings.Repeat("0", 24) + from, "0x" + strings.Repeat("0", 24) + to}
+	l.Data = fmt.Sprintf("0x%064x", amount)
+
+	return l
+}
+
+type tokenTransfersResponse struct {
+	Status int                   `json:"status"`
+	Data   []types.TokenTransfer `json:"data"`
+	Meta   struct {
+		Next *string `json:"next"`
+		Prev *string `json:"prev"`
+	} `json:"meta"`
+}
+
+func tokenTransfers(t *testing.T, a *API, query string) tokenTransfersResponse {
+	w := request(a, http.MethodGet, "/api/explorer/token/0x"+testToken+"/transfers"+query, "", "")
+
+	var resp tokenTransfersResponse
+	err := json.Unmarshal(w.Body.Bytes(), &resp)
+	if err != nil || resp.Status != http.StatusOK {
+		t.Fatalf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+
+	return resp
+}
+
+func TestTokenTransfers(t *testing.T) {
+	backend, cleanup := newTestBackend(t)
+	defer cleanup()
+
+	// the first transaction logs something else before its transfer, so the block and transaction log indexes differ
+	b, receipts := newTestBlock(1, transfer(1, balanceAlice, testToken, "0x0"), transfer(2, balanceBob, testToken, "0x0"))
+
+	var other web3types.Log
+	other.Address = "0x" + testToken
+	other.Topics = []string{testHash(7)}
+	other.Data = "0x"
+
+	receipts[0].Logs = []web3types.Log{other, erc20Transfer(balanceAlice, balanceBob, 1)}
+	receipts[1].Logs = []web3types.Log{erc20Transfer(balanceBob, balanceAlice, 2), erc20Transfer(balanceBob, balanceAlice, 3)}
+	for i, index := 0, 0; i < len(receipts); i++ {
+		for j := range receipts[i].Logs {
+			receipts[i].Logs[j].LogIndex = fmt.Sprintf("0x%x", index)
+			index++
+		}
+	}
+
+	storeTestBlock(t, backend, &data.FullBlock{Block: b, Receipts: receipts})
+
+	a := newTestDBAPI(backend.DB(), Config{})
+
+	// newest first, with the position of the log entries in the block and in the transaction
+	expected := []struct {
+		amount     string
+		logIndex   int64
+		txLogIndex int32
+	}{
+		{"3", 3, 1},
+		{"2", 2, 0},
+		{"1", 1, 1},
+	}
+
+	resp := tokenTransfers(t, a, "")
+	if len(resp.Data) != len(expected) {
+		t.Fatalf("expected %d transfers, got %+v", len(expected), resp.Data)
+	}
+	for i, e := range expected {
+		tt := resp.Data[i]
+		if tt.Amount != e.amount || tt.LogIndex != e.logIndex || tt.TxLogIndex != e.txLogIndex {
+			t.Errorf("transfer %d: expected %+v, got %+v", i, e, tt)
+		}
+	}
+
+	first := tokenTransfers(t, a, "?limit=2")
+	if len(first.Data) != 2 || first.Data[0].Amount != "3" || first.Meta.Next == nil || first.Meta.Prev != nil {
+		t.Fatalf("unexpected first page %+v", first)
+	}
+
+	second := tokenTransfers(t, a, "?limit=2&cursor="+*first.Meta.Next)
+	if len(second.Data) != 1 || second.Data[0].Amount != "1" || second.Meta.Next != nil || second.Meta.Prev == nil {
+		t.Fatalf("unexpected second page %+v", second)
+	}
+
+	back := tokenTransfers(t, a, "?limit=2&cursor="+*second.Meta.Prev)
+	if len(back.Data) != 2 || back.Data[0].Amount != "3" || back.Data[1].Amount != "2" {
+		t.Errorf("expected the previous page to be the first one, got %+v", back.Data)
+	}
+
+	w := request(a, http.MethodGet, "/api/explorer/token/0x"+testToken+"/transfers?cursor=bad", "", "")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected an invalid cursor to be refused, got %d", w.Code)
+	}
+}
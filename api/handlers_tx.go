@@ -4,7 +4,10 @@ import (
 	"database/sql"
 	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/Alethio/memento/abi"
 	"github.com/Alethio/memento/api/types"
 	"github.com/Alethio/memento/data/storable"
 	"github.com/Alethio/memento/utils"
@@ -15,25 +18,33 @@ func (a *API) TxDetailsHandler(c *gin.Context) {
 	searchHash := utils.CleanUpHex(c.Param("txHash"))
 
 	var (
-		txHash              string
-		includedInBlock     int64
-		txIndex             int32
-		from                storable.ByteArray
-		to                  storable.ByteArray
-		value               string
-		txNonce             int64
-		msgGasLimit         string
-		txGasUsed           string
-		txGasPrice          string
-		cumulativeGasUsed   string
-		msgPayload          storable.ByteArray
-		msgStatus           string
-		creates             storable.ByteArray
-		txLogsBloom         storable.ByteArray
-		blockCreationTime   storable.DatetimeToJSONUnix
-		logEntriesTriggered int32
+		txHash               string
+		includedInBlock      int64
+		txIndex              int32
+		from                 storable.ByteArray
+		to                   storable.ByteArray
+		value                string
+		txNonce              int64
+		msgGasLimit          string
+		txGasUsed            string
+		txGasPrice           string
+		cumulativeGasUsed    string
+		msgPayload           storable.ByteArray
+		msgStatus            string
+		creates              storable.ByteArray
+		txLogsBloom          storable.ByteArray
+		blockCreationTime    storable.DatetimeToJSONUnix
+		logEntriesTriggered  int32
+		txType               int32
+		maxFeePerGas         *string
+		maxPriorityFeePerGas *string
+		effectiveGasPrice    string
+		maxFeePerBlobGas     *string
+		blobGasUsed          *string
+		blobGasPrice         *string
+		methodSelector       *string
 	)
-	err := a.core.DB().QueryRow(`select tx_hash, included_in_block, tx_index, "from", "to", value, tx_nonce, msg_gas_limit, tx_gas_used, tx_gas_price, cumulative_gas_used, msg_payload, msg_status, creates, tx_logs_bloom, block_creation_time, log_entries_triggered from txs where tx_hash = $1 limit 1`, searchHash).Scan(&txHash, &includedInBlock, &txIndex, &from, &to, &value, &txNonce, &msgGasLimit, &txGasUsed, &txGasPrice, &cumulativeGasUsed, &msgPayload, &msgStatus, &creates, &txLogsBloom, &blockCreationTime, &logEntriesTriggered)
+	err := a.backend.DB().QueryRow(`select tx_hash, included_in_block, tx_index, "from", "to", value, tx_nonce, msg_gas_limit, tx_gas_used, tx_gas_price, cumulative_gas_used, msg_payload, msg_status, creates, tx_logs_bloom, block_creation_time, log_entries_triggered, tx_type, max_fee_per_gas, max_priority_fee_per_gas, coalesce(effective_gas_price, tx_gas_price), max_fee_per_blob_gas, blob_gas_used, blob_gas_price, method_selector from txs where tx_hash = $1 limit 1`, searchHash).Scan(&txHash, &includedInBlock, &txIndex, &from, &to, &value, &txNonce, &msgGasLimit, &txGasUsed, &txGasPrice, &cumulativeGasUsed, &msgPayload, &msgStatus, &creates, &txLogsBloom, &blockCreationTime, &logEntriesTriggered, &txType, &maxFeePerGas, &maxPriorityFeePerGas, &effectiveGasPrice, &maxFeePerBlobGas, &blobGasUsed, &blobGasPrice, &methodSelector)
 	if err != nil && err != sql.ErrNoRows {
 		Error(c, err)
 		return
@@ -45,23 +56,53 @@ func (a *API) TxDetailsHandler(c *gin.Context) {
 	}
 
 	tx := types.Tx{
-		TxHash:              &txHash,
-		IncludedInBlock:     &includedInBlock,
-		TxIndex:             &txIndex,
-		From:                &from,
-		To:                  &to,
-		Value:               &value,
-		TxNonce:             &txNonce,
-		MsgGasLimit:         &msgGasLimit,
-		TxGasUsed:           &txGasUsed,
-		TxGasPrice:          &txGasPrice,
-		CumulativeGasUsed:   &cumulativeGasUsed,
-		MsgPayload:          &msgPayload,
-		MsgStatus:           &msgStatus,
-		Creates:             &creates,
-		TxLogsBloom:         &txLogsBloom,
-		BlockCreationTime:   &blockCreationTime,
-		LogEntriesTriggered: &logEntriesTriggered,
+		TxHash:               &txHash,
+		IncludedInBlock:      &includedInBlock,
+		TxIndex:              &txIndex,
+		From:                 &from,
+		To:                   &to,
+		Value:                &value,
+		TxNonce:              &txNonce,
+		MsgGasLimit:          &msgGasLimit,
+		TxGasUsed:            &txGasUsed,
+		TxGasPrice:           &txGasPrice,
+		CumulativeGasUsed:    &cumulativeGasUsed,
+		MsgPayload:           &msgPayload,
+		MsgStatus:            &msgStatus,
+		Creates:              &creates,
+		TxLogsBloom:          &txLogsBloom,
+		BlockCreationTime:    &blockCreationTime,
+		LogEntriesTriggered:  &logEntriesTriggered,
+		TxType:               &txType,
+		MaxFeePerGas:         maxFeePerGas,
+		MaxPriorityFeePerGas: maxPriorityFeePerGas,
+		EffectiveGasPrice:    &effectiveGasPrice,
+		MaxFeePerBlobGas:     maxFeePerBlobGas,
+		BlobGasUsed:          blobGasUsed,
+		BlobGasPrice:         blobGasPrice,
+		MethodSelector:       methodSelector,
+	}
+
+	tx.AccessList, err = a.getTxAccessList(txHash)
+	if err != nil {
+		Error(c, err)
+		return
+	}
+
+	if methodSelector != nil {
+		call, err := abi.NewDecoder(a.backend.DB()).DecodeCall(to.String(), msgPayload.String())
+		if call != nil {
+			tx.MethodDecoded = map[string]interface{}{
+				"method":    call.Name,
+				"signature": call.Signature,
+				"inputs":    decodedParams(call.Params),
+			}
+		}
+
+		if err != nil {
+			decodeError := err.Error()
+			tx.MethodDecodedError = &decodeError
+		}
 	}
 
 	var msgError bool
@@ -77,74 +118,172 @@ func (a *API) TxDetailsHandler(c *gin.Context) {
 	OK(c, tx)
 }
 
+func (a *API) getTxAccessList(txHash string) ([]types.AccessListEntry, error) {
+	rows, err := a.backend.DB().Query(`select address, storage_keys from tx_access_lists where tx_hash = $1 order by entry_index`, txHash)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accessList []types.AccessListEntry
+	for rows.Next() {
+		var entry types.AccessListEntry
+
+		err := rows.Scan(&entry.Address, &entry.StorageKeys)
+		if err != nil {
+			return nil, err
+		}
+
+		accessList = append(accessList, entry)
+	}
+
+	return accessList, rows.Err()
+}
+
 func (a *API) TxLogEntriesHandler(c *gin.Context) {
 	txHash := utils.CleanUpHex(c.Param("txHash"))
 
-	rows, err := a.core.DB().Query(`select tx_hash, log_index, log_data, logged_by, topic_0, topic_1, topic_2, topic_3 from log_entries where tx_hash = $1 order by log_index`, txHash)
+	rows, err := a.backend.DB().Query(fmt.Sprintf(logEntriesQuery, "l.tx_hash = $1", "l.log_index"), txHash)
 	if err != nil && err != sql.ErrNoRows {
 		Error(c, err)
 		return
 	}
 	defer rows.Close()
 
+	decoder := abi.NewDecoder(a.backend.DB())
+
 	var logEntries []types.LogEntry
 	for rows.Next() {
-		var (
-			le                             types.LogEntry
-			topic0, topic1, topic2, topic3 string
-		)
-
-		err := rows.Scan(&le.TxHash, &le.LogIndex, &le.LogData, &le.LoggedBy, &topic0, &topic1, &topic2, &topic3)
+		le, err := scanLogEntry(rows, decoder)
 		if err != nil {
 			Error(c, err)
 			return
 		}
 
-		// we can do this since it is not possible, for example, to have an empty topic2 and a non-empty topic3
-		le.HasLogTopics = utils.AppendNotEmpty(le.HasLogTopics, topic0)
-		le.HasLogTopics = utils.AppendNotEmpty(le.HasLogTopics, topic1)
-		le.HasLogTopics = utils.AppendNotEmpty(le.HasLogTopics, topic2)
-		le.HasLogTopics = utils.AppendNotEmpty(le.HasLogTopics, topic3)
-
-		le.EventDecoded = make(map[string]interface{})
-		le.EventDecoded["topic0"] = fmt.Sprintf("0x%s", topic0)
-		le.EventDecoded["event"] = ""
-
-		var inputs []map[string]interface{}
-		for i := 1; i <= 3; i++ {
-			if len(le.HasLogTopics) > i {
-				inputs = append(inputs, map[string]interface{}{
-					"name":    fmt.Sprintf("topic%d", i),
-					"type":    "raw",
-					"indexed": true,
-					"value":   le.HasLogTopics[i],
-				})
-			}
+		logEntries = append(logEntries, le)
+	}
+
+	if len(logEntries) == 0 {
+		NotFound(c)
+		return
+	}
+
+	OK(c, logEntries)
+}
+
+// decodedParams formats the decoded inputs of an event or arguments of a call; the indexed flag is only set for events
+func decodedParams(params []abi.Param) []map[string]interface{} {
+	var inputs []map[string]interface{}
+	for _, p := range params {
+		input := map[string]interface{}{
+			"name":  p.Name,
+			"type":  p.Type,
+			"value": p.Value,
+		}
+		if p.Indexed {
+			input["indexed"] = true
 		}
 
-		data := le.LogData.String()
-		counter := 0
-		for i := 0; i < len(data); i += 64 {
+		inputs = append(inputs, input)
+	}
+
+	return inputs
+}
+
+// rawEventInputs splits the topics and the data of a log entry that could not be decoded into 32-byte chunks
+func rawEventInputs(le types.LogEntry) []map[string]interface{} {
+	var inputs []map[string]interface{}
+	for i := 1; i <= 3; i++ {
+		if len(le.HasLogTopics) > i {
 			inputs = append(inputs, map[string]interface{}{
-				"name":  fmt.Sprintf("data%d", counter),
-				"type":  "raw",
-				"value": data[i : i+64],
+				"name":    fmt.Sprintf("topic%d", i),
+				"type":    "raw",
+				"indexed": true,
+				"value":   le.HasLogTopics[i],
 			})
-			counter++
 		}
-		le.EventDecoded["inputs"] = inputs
+	}
 
-		logEntries = append(logEntries, le)
+	data := le.LogData.String()
+	counter := 0
+	for i := 0; i+64 <= len(data); i += 64 {
+		inputs = append(inputs, map[string]interface{}{
+			"name":  fmt.Sprintf("data%d", counter),
+			"type":  "raw",
+			"value": data[i : i+64],
+		})
+		counter++
 	}
 
-	if len(logEntries) == 0 {
+	return inputs
+}
+
+func (a *API) TxBlobsHandler(c *gin.Context) {
+	txHash := utils.CleanUpHex(c.Param("txHash"))
+
+	rows, err := a.backend.DB().Query(`select tx_hash, included_in_block, tx_index, blob_index, versioned_hash from blob_hashes where tx_hash = $1 order by blob_index`, txHash)
+	if err != nil && err != sql.ErrNoRows {
+		Error(c, err)
+		return
+	}
+	defer rows.Close()
+
+	var blobs []types.Blob
+	for rows.Next() {
+		var b types.Blob
+
+		err := rows.Scan(&b.TxHash, &b.IncludedInBlock, &b.TxIndex, &b.BlobIndex, &b.VersionedHash)
+		if err != nil {
+			Error(c, err)
+			return
+		}
+
+		blobs = append(blobs, b)
+	}
+
+	if len(blobs) == 0 {
 		NotFound(c)
 		return
 	}
 
-	OK(c, logEntries)
+	OK(c, blobs)
 }
 
+func (a *API) TxInternalTxsHandler(c *gin.Context) {
+	txHash := utils.CleanUpHex(c.Param("txHash"))
+
+	rows, err := a.backend.DB().Query(`select tx_hash, included_in_block, tx_index, trace_index, trace_address, type, coalesce(call_type, ''), "from", "to", value, coalesce(msg_gas_limit, 0), coalesce(msg_gas_used, 0), msg_payload, coalesce(msg_error, '') from internal_txs where tx_hash = $1 order by trace_index`, txHash)
+	if err != nil && err != sql.ErrNoRows {
+		Error(c, err)
+		return
+	}
+	defer rows.Close()
+
+	var internalTxs []types.InternalTx
+	for rows.Next() {
+		var it types.InternalTx
+
+		err := rows.Scan(&it.TxHash, &it.IncludedInBlock, &it.TxIndex, &it.TraceIndex, &it.TraceAddress, &it.Type, &it.CallType, &it.From, &it.To, &it.Value, &it.MsgGasLimit, &it.MsgGasUsed, &it.MsgPayload, &it.MsgError)
+		if err != nil {
+			Error(c, err)
+			return
+		}
+
+		internalTxs = append(internalTxs, it)
+	}
+
+	if len(internalTxs) == 0 {
+		NotFound(c)
+		return
+	}
+
+	OK(c, internalTxs)
+}
+
+// AccountTxsHandler returns the history of an account, newest first, one page at a time
+// The pages are linked by the opaque next and prev cursors returned in the meta; the results can be filtered by
+// direction (in or out), counterparty, block range (fromBlock and toBlock), date range (fromDate and toDate, as unix
+// timestamps) and status (success or failed)
 func (a *API) AccountTxsHandler(c *gin.Context) {
 	accountAddress, err := utils.ValidateAccount(c.Param("address"))
 	if err != nil {
@@ -152,60 +291,137 @@ func (a *API) AccountTxsHandler(c *gin.Context) {
 		return
 	}
 
-	limit := c.DefaultQuery("limit", "50")
-	limitInt, err := strconv.Atoi(limit)
+	limit, err := parseLimit(c, DefaultPageSize, MaxPageSize)
 	if err != nil {
-		BadRequest(c, fmt.Errorf("limit value should by numeric"))
+		BadRequest(c, err)
 		return
 	}
 
-	var includedInBlock, txIndex *int64
+	var cur *accountTxsCursor
+	if token := c.Query("cursor"); token != "" {
+		decoded, err := decodeAccountTxsCursor(token)
+		if err != nil {
+			BadRequest(c, err)
+			return
+		}
 
-	index := c.Query("txIndex")
-	if index != "" {
-		indexInt, err := strconv.ParseInt(index, 10, 64)
+		cur = &decoded
+	} else if block := c.Query("includedInBlock"); block != "" {
+		// the pagination used before cursors: the rows before the given transaction
+		blockInt, err := strconv.ParseInt(block, 10, 64)
 		if err != nil {
-			BadRequest(c, fmt.Errorf("txIndex must be a positive integer"))
+			BadRequest(c, fmt.Errorf("includedInBlock must be a positive integer"))
 			return
 		}
 
-		txIndex = &indexInt
+		var indexInt int64
+		if index := c.Query("txIndex"); index != "" {
+			indexInt, err = strconv.ParseInt(index, 10, 64)
+			if err != nil {
+				BadRequest(c, fmt.Errorf("txIndex must be a positive integer"))
+				return
+			}
+		}
+
+		cur = &accountTxsCursor{IncludedInBlock: blockInt, TxIndex: indexInt}
 	}
 
-	block := c.Query("includedInBlock")
-	if block != "" {
-		blockInt, err := strconv.ParseInt(block, 10, 64)
+	var conditions []string
+	var args []interface{}
+	addCondition := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	addCondition("t1.address = $%d", accountAddress)
+
+	switch c.Query("direction") {
+	case "":
+	case "in":
+		addCondition("t1.out = $%d", false)
+	case "out":
+		addCondition("t1.out = $%d", true)
+	default:
+		BadRequest(c, fmt.Errorf("direction must be in or out"))
+		return
+	}
+
+	if counterparty := c.Query("counterparty"); counterparty != "" {
+		counterparty, err := utils.ValidateAccount(counterparty)
 		if err != nil {
-			BadRequest(c, fmt.Errorf("includedInBlock must be a positive integer"))
+			BadRequest(c, fmt.Errorf("counterparty is malformed"))
 			return
 		}
 
-		includedInBlock = &blockInt
+		addCondition("t1.counterparty = $%d", counterparty)
 	}
 
-	var filters string
-	var params []interface{}
-	params = append(params, accountAddress, limitInt)
+	for _, bound := range []struct{ param, condition string }{
+		{"fromBlock", "t1.included_in_block >= $%d"},
+		{"toBlock", "t1.included_in_block <= $%d"},
+	} {
+		if value := c.Query(bound.param); value != "" {
+			block, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || block < 0 {
+				BadRequest(c, fmt.Errorf("%s must be a positive integer", bound.param))
+				return
+			}
 
-	if includedInBlock != nil {
-		if txIndex == nil {
-			zero := int64(0)
-			txIndex = &zero
+			addCondition(bound.condition, block)
 		}
+	}
+
+	// the timestamps are bound the same way the storables write them, so they compare correctly on both backends
+	for _, bound := range []struct{ param, condition string }{
+		{"fromDate", "coalesce(t2.block_creation_time, w.block_creation_time) >= $%d"},
+		{"toDate", "coalesce(t2.block_creation_time, w.block_creation_time) <= $%d"},
+	} {
+		if value := c.Query(bound.param); value != "" {
+			unix, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				BadRequest(c, fmt.Errorf("%s must be a unix timestamp", bound.param))
+				return
+			}
+
+			addCondition(bound.condition, time.Unix(unix, 0))
+		}
+	}
+
+	switch c.Query("status") {
+	case "":
+	case "success":
+		// withdrawals can't fail and transactions from before byzantium have no status
+		addCondition("coalesce(t2.msg_status, '') <> $%d", "0x0")
+	case "failed":
+		addCondition("t2.msg_status = $%d", "0x0")
+	default:
+		BadRequest(c, fmt.Errorf("status must be success or failed"))
+		return
+	}
 
-		filters = "and (t1.included_in_block < $3 or (t1.included_in_block = $3 and t1.tx_index < $4))"
-		params = append(params, includedInBlock, txIndex)
+	order := "desc"
+	if cur != nil {
+		operator := "<"
+		if cur.Prev {
+			operator = ">"
+			order = "asc"
+		}
+
+		args = append(args, cur.IncludedInBlock, cur.TxIndex, cur.Out)
+		conditions = append(conditions, fmt.Sprintf("(t1.included_in_block, t1.tx_index, t1.out) %s ($%d, $%d, $%d)", operator, len(args)-2, len(args)-1, len(args)))
 	}
 
-	query := `select t2.tx_hash, t2.tx_index, "from", "to", value, block_creation_time, t2.included_in_block, tx_gas_used, tx_gas_price
+	// one more row than needed tells whether there's a page after this one
+	args = append(args, limit+1)
+
+	query := fmt.Sprintf(`select t1.tx_hash, t1.tx_index, t1.included_in_block, t1.out, t1.withdrawal_index, t2."from", t2."to", coalesce(t2.value, w.amount), t2.block_creation_time, t2.tx_gas_used, t2.tx_gas_price, w.address, w.block_creation_time
 				from account_txs as t1
 				left join txs as t2 on (t2.tx_hash = t1.tx_hash)
-				where t1.address = $1 %s
-				order by t1.included_in_block desc, t1.tx_index desc limit $2`
+				left join withdrawals as w on (w.withdrawal_index = t1.withdrawal_index)
+				where %[1]s
+				order by t1.included_in_block %[2]s, t1.tx_index %[2]s, t1.out %[2]s limit $%[3]d`, strings.Join(conditions, " and "), order, len(args))
 
-	query = fmt.Sprintf(query, filters)
-
-	rows, err := a.core.DB().Query(query, params...)
+	rows, err := a.backend.DB().Query(query, args...)
 	if err != nil && err != sql.ErrNoRows {
 		Error(c, err)
 		return
@@ -213,37 +429,91 @@ func (a *API) AccountTxsHandler(c *gin.Context) {
 	defer rows.Close()
 
 	var txs = make([]types.Tx, 0)
+	var keys []accountTxsCursor
 	for rows.Next() {
 		var (
 			txHash            string
 			txIndex           int32
+			includedInBlock   int64
+			out               bool
+			withdrawalIndex   *int64
 			from              storable.ByteArray
 			to                storable.ByteArray
 			value             string
-			blockCreationTime storable.DatetimeToJSONUnix
-			includedInBlock   int64
-			txGasUsed         string
-			txGasPrice        string
+			blockCreationTime *time.Time
+			txGasUsed         *string
+			txGasPrice        *string
+			withdrawalAddress *string
+			withdrawalTime    *time.Time
 		)
 
-		err := rows.Scan(&txHash, &txIndex, &from, &to, &value, &blockCreationTime, &includedInBlock, &txGasUsed, &txGasPrice)
+		// the timestamps are scanned separately rather than coalesced, since sqlite only converts them for plain columns
+		err := rows.Scan(&txHash, &txIndex, &includedInBlock, &out, &withdrawalIndex, &from, &to, &value, &blockCreationTime, &txGasUsed, &txGasPrice, &withdrawalAddress, &withdrawalTime)
 		if err != nil {
 			Error(c, err)
 			return
 		}
 
-		txs = append(txs, types.Tx{
-			TxHash:            &txHash,
-			TxIndex:           &txIndex,
-			From:              &from,
-			To:                &to,
-			Value:             &value,
-			BlockCreationTime: &blockCreationTime,
-			IncludedInBlock:   &includedInBlock,
-			TxGasUsed:         &txGasUsed,
-			TxGasPrice:        &txGasPrice,
-		})
+		tx := types.Tx{
+			TxIndex:         &txIndex,
+			Value:           &value,
+			IncludedInBlock: &includedInBlock,
+			TxGasUsed:       txGasUsed,
+			TxGasPrice:      txGasPrice,
+		}
+
+		if blockCreationTime == nil {
+			blockCreationTime = withdrawalTime
+		}
+		if blockCreationTime != nil {
+			t := storable.DatetimeToJSONUnix(*blockCreationTime)
+			tx.BlockCreationTime = &t
+		}
+
+		if withdrawalIndex != nil {
+			// withdrawals come from the beacon chain, so they have no hash and no sender
+			tx.WithdrawalIndex = withdrawalIndex
+			if withdrawalAddress != nil {
+				withdrawalTo := storable.ByteArray(*withdrawalAddress)
+				tx.To = &withdrawalTo
+			}
+		} else {
+			tx.TxHash = &txHash
+			tx.From = &from
+			tx.To = &to
+		}
+
+		txs = append(txs, tx)
+		keys = append(keys, accountTxsCursor{IncludedInBlock: includedInBlock, TxIndex: int64(txIndex), Out: out})
+	}
+
+	if err := rows.Err(); err != nil {
+		Error(c, err)
+		return
+	}
+
+	hasMore := len(txs) > limit
+	if hasMore {
+		txs, keys = txs[:limit], keys[:limit]
 	}
 
-	OK(c, txs)
+	// the previous pages are fetched oldest first, so they are flipped back to the usual order
+	backwards := cur != nil && cur.Prev
+	if backwards {
+		for i, j := 0, len(txs)-1; i < j; i, j = i+1, j-1 {
+			txs[i], txs[j] = txs[j], txs[i]
+			keys[i], keys[j] = keys[j], keys[i]
+		}
+	}
+
+	var first, last string
+	if len(keys) > 0 {
+		firstKey, lastKey := keys[0], keys[len(keys)-1]
+		firstKey.Prev, lastKey.Prev = true, false
+		first, last = firstKey.encode(), lastKey.encode()
+	}
+
+	meta := pageMeta(limit, first, last, hasMore, backwards, cur != nil)
+
+	OK(c, txs, meta)
 }
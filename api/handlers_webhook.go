@@ -0,0 +1,204 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/Alethio/memento/api/types"
+	"github.com/Alethio/memento/data/storable"
+	"github.com/Alethio/memento/webhook"
+	"github.com/gin-gonic/gin"
+)
+
+func (a *API) WebhookListHandler(c *gin.Context) {
+	webhooks, err := webhook.List(a.backend.DB())
+	if err != nil {
+		Error(c, err)
+		return
+	}
+
+	if len(webhooks) == 0 {
+		NotFound(c)
+		return
+	}
+
+	var list []types.Webhook
+	for _, w := range webhooks {
+		list = append(list, buildWebhook(w, false))
+	}
+
+	OK(c, list)
+}
+
+func (a *API) WebhookHandler(c *gin.Context) {
+	w, err := webhook.Get(a.backend.DB(), c.Param("id"))
+	if err != nil {
+		Error(c, err)
+		return
+	}
+
+	if w == nil {
+		NotFound(c)
+		return
+	}
+
+	OK(c, buildWebhook(*w, false))
+}
+
+// WebhookCreateHandler creates a webhook from {"url", "address", "topic0", "description", "secret"}, where only the
+// url and one of address and topic0 are required; the response is the only place the secret is returned
+func (a *API) WebhookCreateHandler(c *gin.Context) {
+	var req struct {
+		URL         string `json:"url"`
+		Address     string `json:"address"`
+		Topic0      string `json:"topic0"`
+		Description string `json:"description"`
+		Secret      string `json:"secret"`
+	}
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	w := &webhook.Webhook{
+		URL:         req.URL,
+		Address:     req.Address,
+		Topic0:      req.Topic0,
+		Description: req.Description,
+		Secret:      req.Secret,
+	}
+
+	err = w.Validate()
+	if err != nil {
+		BadRequest(c, fmt.Errorf("bad request: %s", err))
+		return
+	}
+
+	err = webhook.Create(a.backend.DB(), w)
+	if err != nil {
+		Error(c, err)
+		return
+	}
+
+	OK(c, buildWebhook(*w, true))
+}
+
+func (a *API) WebhookDeleteHandler(c *gin.Context) {
+	deleted, err := webhook.Delete(a.backend.DB(), c.Param("id"))
+	if err != nil {
+		Error(c, err)
+		return
+	}
+
+	if !deleted {
+		NotFound(c)
+		return
+	}
+
+	OK(c, nil)
+}
+
+// WebhookDeliveriesHandler returns the delivery log of a webhook, newest first, optionally filtered by status
+// The log only pages forward, following meta.next
+func (a *API) WebhookDeliveriesHandler(c *gin.Context) {
+	limit, err := parseLimit(c, DefaultPageSize, MaxPageSize)
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	status := c.Query("status")
+	switch status {
+	case "", webhook.StatusPending, webhook.StatusDelivered, webhook.StatusFailed:
+	default:
+		BadRequest(c, fmt.Errorf("status must be one of: %s, %s, %s", webhook.StatusPending, webhook.StatusDelivered, webhook.StatusFailed))
+		return
+	}
+
+	var before string
+	if token := c.Query("cursor"); token != "" {
+		prev, key, err := decodeCursor(token, 1)
+		if err != nil || prev {
+			BadRequest(c, errInvalidCursor)
+			return
+		}
+
+		before = key[0]
+	}
+
+	w, err := webhook.Get(a.backend.DB(), c.Param("id"))
+	if err != nil {
+		Error(c, err)
+		return
+	}
+
+	if w == nil {
+		NotFound(c)
+		return
+	}
+
+	deliveries, err := webhook.Deliveries(a.backend.DB(), w.ID, status, before, limit+1)
+	if err != nil {
+		Error(c, err)
+		return
+	}
+
+	hasMore := len(deliveries) > limit
+	if hasMore {
+		deliveries = deliveries[:limit]
+	}
+
+	var list = make([]types.WebhookDelivery, 0)
+	for _, d := range deliveries {
+		delivery := types.WebhookDelivery{
+			ID:           d.ID,
+			Kind:         d.Kind,
+			BlockNumber:  d.BlockNumber,
+			BlockHash:    d.BlockHash,
+			TxHash:       d.TxHash,
+			Status:       d.Status,
+			Attempts:     d.Attempts,
+			ResponseCode: d.ResponseCode,
+			Error:        d.Error,
+			Payload:      []byte(d.Payload),
+		}
+
+		if d.Status == webhook.StatusPending {
+			t := storable.DatetimeToJSONUnix(d.NextAttemptAt)
+			delivery.NextAttemptAt = &t
+		}
+
+		if d.DeliveredAt != nil {
+			t := storable.DatetimeToJSONUnix(*d.DeliveredAt)
+			delivery.DeliveredAt = &t
+		}
+
+		list = append(list, delivery)
+	}
+
+	var first, last string
+	if len(list) > 0 {
+		first = encodeCursor(false, list[0].ID)
+		last = encodeCursor(false, list[len(list)-1].ID)
+	}
+
+	OK(c, list, pageMeta(limit, first, last, hasMore, false, false))
+}
+
+func buildWebhook(w webhook.Webhook, withSecret bool) types.Webhook {
+	hook := types.Webhook{
+		ID:          w.ID,
+		URL:         w.URL,
+		Address:     w.Address,
+		Topic0:      w.Topic0,
+		Description: w.Description,
+		CreatedAt:   storable.DatetimeToJSONUnix(w.CreatedAt),
+	}
+
+	if withSecret {
+		hook.Secret = w.Secret
+	}
+
+	return hook
+}
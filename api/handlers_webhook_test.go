@@ -0,0 +1,61 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+type webhookResponse struct {
+	Status int `json:"status"`
+	Data   struct {
+		ID      string `json:"id"`
+		URL     string `json:"url"`
+		Secret  string `json:"secret"`
+		Address string `json:"address"`
+	} `json:"data"`
+}
+
+func TestWebhookHandlers(t *testing.T) {
+	db, cleanup := newTestDB(t)
+	defer cleanup()
+
+	a := newTestDBAPI(db, Config{AdminToken: "t0ken"})
+
+	w := request(a, http.MethodPost, "/api/webhooks", "t0ken", `{"url": "https://example.com/hook", "address": "0xABABABABABABABABABABABABABABABABABABABAB"}`)
+	var created webhookResponse
+	err := json.Unmarshal(w.Body.Bytes(), &created)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if created.Status != http.StatusOK || created.Data.ID == "" || created.Data.Secret == "" || created.Data.Address != "abababababababababababababababababababab" {
+		t.Fatalf("unexpected creation response %s", w.Body.String())
+	}
+
+	w = request(a, http.MethodGet, "/api/webhooks/"+created.Data.ID, "t0ken", "")
+	var fetched webhookResponse
+	err = json.Unmarshal(w.Body.Bytes(), &fetched)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fetched.Data.ID != created.Data.ID || fetched.Data.URL != "https://example.com/hook" || fetched.Data.Secret != "" {
+		t.Fatalf("unexpected webhook %s", w.Body.String())
+	}
+
+	w = request(a, http.MethodGet, "/api/webhooks/"+created.Data.ID+"/deliveries", "t0ken", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected deliveries response %d %s", w.Code, w.Body.String())
+	}
+
+	w = request(a, http.MethodDelete, "/api/webhooks/"+created.Data.ID, "t0ken", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected deletion response %d %s", w.Code, w.Body.String())
+	}
+
+	w = request(a, http.MethodGet, "/api/webhooks/"+created.Data.ID, "t0ken", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &fetched); err != nil || fetched.Status != http.StatusNotFound {
+		t.Fatalf("the webhook wasn't deleted: %s", w.Body.String())
+	}
+}
@@ -0,0 +1,223 @@
+package api
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errInvalidCursor = fmt.Errorf("invalid cursor")
+
+// encodeCursor returns an opaque string, passed back in the cursor query parameter, holding the sort key of a row and
+// whether the page it starts goes towards the newer rows (prev) or the older ones
+func encodeCursor(prev bool, key ...interface{}) string {
+	parts := []string{"n"}
+	if prev {
+		parts[0] = "p"
+	}
+
+	for _, k := range key {
+		parts = append(parts, fmt.Sprint(k))
+	}
+
+	return base64.RawURLEncoding.EncodeToString([]byte(strings.Join(parts, ".")))
+}
+
+// decodeCursor returns the direction and the sort key of a cursor, which must have size parts
+func decodeCursor(s string, size int) (bool, []string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return false, nil, errInvalidCursor
+	}
+
+	parts := strings.Split(string(raw), ".")
+	if len(parts) != size+1 || parts[0] != "n" && parts[0] != "p" {
+		return false, nil, errInvalidCursor
+	}
+
+	return parts[0] == "p", parts[1:], nil
+}
+
+// accountTxsCursor identifies a row of an account's history; it's the sort key of the account_txs table, since an
+// account can show up twice in the same transaction (once in each direction)
+type accountTxsCursor struct {
+	IncludedInBlock int64
+	TxIndex         int64
+	Out             bool
+
+	// Prev is set for cursors that page towards the newer rows
+	Prev bool
+}
+
+func (cur accountTxsCursor) encode() string {
+	return encodeCursor(cur.Prev, cur.IncludedInBlock, cur.TxIndex, cur.Out)
+}
+
+func decodeAccountTxsCursor(s string) (accountTxsCursor, error) {
+	prev, key, err := decodeCursor(s, 3)
+	if err != nil {
+		return accountTxsCursor{}, err
+	}
+
+	cur := accountTxsCursor{Prev: prev}
+	cur.IncludedInBlock, err = strconv.ParseInt(key[0], 10, 64)
+	if err != nil {
+		return accountTxsCursor{}, errInvalidCursor
+	}
+
+	cur.TxIndex, err = strconv.ParseInt(key[1], 10, 64)
+	if err != nil {
+		return accountTxsCursor{}, errInvalidCursor
+	}
+
+	cur.Out, err = strconv.ParseBool(key[2])
+	if err != nil {
+		return accountTxsCursor{}, errInvalidCursor
+	}
+
+	return cur, nil
+}
+
+// contractsCursor identifies a row of the contracts list
+type contractsCursor struct {
+	IncludedInBlock int64
+	TxIndex         int64
+	TraceAddress    string
+
+	// Prev is set for cursors that page towards the newer rows
+	Prev bool
+}
+
+func (cur contractsCursor) encode() string {
+	return encodeCursor(cur.Prev, cur.IncludedInBlock, cur.TxIndex, cur.TraceAddress)
+}
+
+func decodeContractsCursor(s string) (contractsCursor, error) {
+	prev, key, err := decodeCursor(s, 3)
+	if err != nil {
+		return contractsCursor{}, err
+	}
+
+	cur := contractsCursor{Prev: prev, TraceAddress: key[2]}
+	cur.IncludedInBlock, err = strconv.ParseInt(key[0], 10, 64)
+	if err != nil {
+		return contractsCursor{}, errInvalidCursor
+	}
+
+	cur.TxIndex, err = strconv.ParseInt(key[1], 10, 64)
+	if err != nil {
+		return contractsCursor{}, errInvalidCursor
+	}
+
+	return cur, nil
+}
+
+// pageMeta returns the meta of a page of a list sorted newest first, given the cursors of its first and last rows,
+// which are empty if the page is
+// Pages are fetched with one extra row, which tells whether there's more in the direction of the request (hasMore);
+// going forward, there are newer rows whenever a cursor was given, and going backwards, the older rows are the page
+// the cursor came from
+func pageMeta(limit int, first, last string, hasMore, backwards, fromCursor bool) map[string]interface{} {
+	meta := map[string]interface{}{
+		"limit": limit,
+		"next":  nil,
+		"prev":  nil,
+	}
+
+	if first == "" {
+		return meta
+	}
+
+	if hasMore || backwards {
+		meta["next"] = last
+	}
+
+	if backwards && hasMore || !backwards && fromCursor {
+		meta["prev"] = first
+	}
+
+	return meta
+}
+
+// parseLimit reads the limit query parameter, capping it to max
+func parseLimit(c *gin.Context, def, max int) (int, error) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(def)))
+	if err != nil || limit <= 0 {
+		return 0, fmt.Errorf("limit must be a positive integer")
+	}
+
+	if limit > max {
+		limit = max
+	}
+
+	return limit, nil
+}
+
+// logEntriesCursor identifies a row of a log search; LogIndex is the position within the transaction, since the block
+// log index isn't stored for the entries indexed before it was introduced
+type logEntriesCursor struct {
+	IncludedInBlock int64
+	TxIndex         int64
+	LogIndex        int64
+
+	// Prev is set for cursors that page towards the newer rows
+	Prev bool
+}
+
+func (cur logEntriesCursor) encode() string {
+	return encodeCursor(cur.Prev, cur.IncludedInBlock, cur.TxIndex, cur.LogIndex)
+}
+
+func decodeLogEntriesCursor(s string) (logEntriesCursor, error) {
+	prev, key, err := decodeCursor(s, 3)
+	if err != nil {
+		return logEntriesCursor{}, err
+	}
+
+	var values [3]int64
+	for i, k := range key {
+		values[i], err = strconv.ParseInt(k, 10, 64)
+		if err != nil {
+			return logEntriesCursor{}, errInvalidCursor
+		}
+	}
+
+	return logEntriesCursor{IncludedInBlock: values[0], TxIndex: values[1], LogIndex: values[2], Prev: prev}, nil
+}
+
+// tokenTransfersCursor identifies a row of a token transfers list; TxLogIndex is the position of the log entry within
+// the transaction, which is what the token_transfers table is sorted by, and BatchIndex the position within an ERC-1155
+// batch
+type tokenTransfersCursor struct {
+	IncludedInBlock int64
+	TxIndex         int64
+	TxLogIndex      int64
+	BatchIndex      int64
+
+	// Prev is set for cursors that page towards the newer rows
+	Prev bool
+}
+
+func (cur tokenTransfersCursor) encode() string {
+	return encodeCursor(cur.Prev, cur.IncludedInBlock, cur.TxIndex, cur.TxLogIndex, cur.BatchIndex)
+}
+
+func decodeTokenTransfersCursor(s string) (tokenTransfersCursor, error) {
+	prev, key, err := decodeCursor(s, 4)
+	if err != nil {
+		return tokenTransfersCursor{}, err
+	}
+
+	var values [4]int64
+	for i, k := range key {
+		values[i], err = strconv.ParseInt(k, 10, 64)
+		if err != nil {
+			return tokenTransfersCursor{}, errInvalidCursor
+		}
+	}
+
+	return tokenTransfersCursor{IncludedInBlock: values[0], TxIndex: values[1], TxLogIndex: values[2], BatchIndex: values[3], Prev: prev}, nil
+}
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"database/sql"
+
+	"github.com/Alethio/memento/feed"
+)
+
+// Replica is the Backend of an API that runs on its own, with nothing but a connection to a (possibly read-only)
+// database; the live feed is not available and the highest stored block stands in for the best block of the network
+type Replica struct {
+	db *sql.DB
+}
+
+func NewReplica(db *sql.DB) *Replica {
+	return &Replica{db: db}
+}
+
+func (r *Replica) DB() *sql.DB {
+	return r.db
+}
+
+// LatestBlock returns the highest stored block, or 0 if it can't be read
+func (r *Replica) LatestBlock() int64 {
+	var highest int64
+	err := r.db.QueryRow(`select number from blocks order by number desc limit 1`).Scan(&highest)
+	if err != nil && err != sql.ErrNoRows {
+		log.Error(err)
+	}
+
+	return highest
+}
+
+func (r *Replica) Feed() *feed.Hub {
+	return nil
+}
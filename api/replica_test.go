@@ -0,0 +1,26 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/Alethio/memento/data"
+)
+
+func TestReplicaLatestBlockIsTheHighestStoredBlock(t *testing.T) {
+	backend, cleanup := newTestBackend(t)
+	defer cleanup()
+
+	r := NewReplica(backend.DB())
+	if latest := r.LatestBlock(); latest != 0 {
+		t.Fatalf("expected 0 for an empty database, got %d", latest)
+	}
+
+	for _, number := range []int64{1, 2, 5} {
+		block, receipts := newTestBlock(number)
+		storeTestBlock(t, backend, &data.FullBlock{Block: block, Receipts: receipts})
+	}
+
+	if latest := r.LatestBlock(); latest != 5 {
+		t.Errorf("expected 5, got %d", latest)
+	}
+}
@@ -1,15 +1,63 @@
 package api
 
 func (a *API) setRoutes() {
+	a.engine.POST("/rpc", a.RPCHandler)
+
 	explorer := a.engine.Group("/api/explorer")
 	explorer.GET("/block/:block", a.BlockHandler)
+	explorer.GET("/block/:block/withdrawals", a.BlockWithdrawalsHandler)
 	explorer.GET("/block-range/:start/:end", a.BlockRangeHandler)
 	explorer.GET("/uncle/:hash", a.UncleDetailsHandler)
 	explorer.GET("/tx/:txHash", a.TxDetailsHandler)
 	explorer.GET("/tx/:txHash/log-entries", a.TxLogEntriesHandler)
+	explorer.GET("/tx/:txHash/internal", a.TxInternalTxsHandler)
+	explorer.GET("/tx/:txHash/blobs", a.TxBlobsHandler)
+	explorer.GET("/logs", a.LogsHandler)
 	explorer.GET("/search/:query", a.SearchHandler)
 
+	explorer.GET("/account/:address", a.AccountHandler)
 	explorer.GET("/account/:address/txs", a.AccountTxsHandler)
 	explorer.GET("/account/:address/code", a.AccountCodeHandler)
 	explorer.GET("/account/:address/balance", a.AccountBalanceHandler)
+	explorer.GET("/account/:address/balance/history", a.AccountBalanceHistoryHandler)
+	explorer.GET("/account/:address/token-transfers", a.AccountTokenTransfersHandler)
+	explorer.GET("/account/:address/calls", a.AccountCallsHandler)
+
+	explorer.GET("/token/:address/transfers", a.TokenTransfersHandler)
+
+	explorer.GET("/contracts", a.ContractsHandler)
+
+	abis := a.engine.Group("/api/abi")
+	abis.GET("", a.ABIListHandler)
+	abis.GET("/:address", a.ABIHandler)
+
+	signatures := a.engine.Group("/api/signatures")
+	signatures.GET("/:selector", a.SignaturesHandler)
+
+	// the uploaded ABIs and signatures decode the log entries and transactions of everyone, so only the admin can
+	// change them
+	if !a.config.ReadOnly && a.config.AdminToken != "" {
+		abisAdmin := abis.Group("", a.requireAdmin)
+		abisAdmin.PUT("/:address", a.ABIUploadHandler)
+		abisAdmin.DELETE("/:address", a.ABIDeleteHandler)
+
+		signatures.POST("", a.requireAdmin, a.SignaturesUploadHandler)
+	}
+
+	// the webhooks expose their urls and the payloads delivered to them, so they're only managed with the admin token
+	if a.config.AdminToken != "" {
+		webhooks := a.engine.Group("/api/webhooks", a.requireAdmin)
+		webhooks.GET("", a.WebhookListHandler)
+		webhooks.GET("/:id", a.WebhookHandler)
+		webhooks.GET("/:id/deliveries", a.WebhookDeliveriesHandler)
+
+		if !a.config.ReadOnly {
+			webhooks.POST("", a.WebhookCreateHandler)
+			webhooks.DELETE("/:id", a.WebhookDeleteHandler)
+		}
+	}
+
+	live := a.engine.Group("/api/feed")
+	live.GET("/ws", a.FeedWSHandler)
+	live.GET("/sse", a.FeedSSEHandler)
 }
@@ -0,0 +1,226 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JSON-RPC 2.0 error codes
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcInternalError  = -32603
+)
+
+// errNotIndexed is returned by the rpc methods when the requested data is not (yet) in the database, in which case
+// the request is proxied to the node
+var errNotIndexed = errors.New("not indexed")
+
+// rpcProxiedPrefixes are the namespaces that may be forwarded to the node; anything else (admin, debug, personal, ...)
+// is rejected so the node is never exposed through memento
+var rpcProxiedPrefixes = []string{"eth_", "net_", "web3_"}
+
+type rpcRequest struct {
+	JSONRPC string            `json:"jsonrpc"`
+	ID      json.RawMessage   `json:"id,omitempty"`
+	Method  string            `json:"method"`
+	Params  []json.RawMessage `json:"params"`
+}
+
+type rpcError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcMethod func(params []json.RawMessage) (interface{}, error)
+
+// rpcMethods returns the methods that are answered directly from the database
+func (a *API) rpcMethods() map[string]rpcMethod {
+	return map[string]rpcMethod{
+		"eth_getBlockByNumber":      a.rpcGetBlockByNumber,
+		"eth_getBlockByHash":        a.rpcGetBlockByHash,
+		"eth_getTransactionByHash":  a.rpcGetTransactionByHash,
+		"eth_getTransactionReceipt": a.rpcGetTransactionReceipt,
+		"eth_getLogs":               a.rpcGetLogs,
+	}
+}
+
+// RPCHandler serves a read-only subset of the Ethereum JSON-RPC API from the database
+// Both single and batch requests (of up to MaxRPCBatchSize requests) are supported. Requests for methods (or data) memento can't answer are proxied
+// to the node configured via EthClientURL
+func (a *API) RPCHandler(c *gin.Context) {
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusOK, rpcErrorResponse(nil, rpcParseError, err.Error()))
+		return
+	}
+
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var reqs []rpcRequest
+		err := json.Unmarshal(body, &reqs)
+		if err != nil {
+			c.JSON(http.StatusOK, rpcErrorResponse(nil, rpcParseError, err.Error()))
+			return
+		}
+
+		if len(reqs) == 0 {
+			c.JSON(http.StatusOK, rpcErrorResponse(nil, rpcInvalidRequest, "empty batch"))
+			return
+		}
+
+		if len(reqs) > MaxRPCBatchSize {
+			c.JSON(http.StatusOK, rpcErrorResponse(nil, rpcInvalidRequest, fmt.Sprintf("batch too large; at most %d requests are allowed", MaxRPCBatchSize)))
+			return
+		}
+
+		resps := make([]rpcResponse, len(reqs))
+		for i, req := range reqs {
+			resps[i] = a.execRPC(req)
+		}
+
+		c.JSON(http.StatusOK, resps)
+		return
+	}
+
+	var req rpcRequest
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		c.JSON(http.StatusOK, rpcErrorResponse(nil, rpcParseError, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, a.execRPC(req))
+}
+
+func (a *API) execRPC(req rpcRequest) rpcResponse {
+	if req.Method == "" {
+		return rpcErrorResponse(req.ID, rpcInvalidRequest, "missing method")
+	}
+
+	log := log.WithField("method", req.Method)
+
+	if method, ok := a.rpcMethods()[req.Method]; ok {
+		result, err := method(req.Params)
+		switch {
+		case err == nil:
+			return rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: result}
+		case err == errNotIndexed:
+			log.Trace("data not indexed; proxying request to node")
+		case isInvalidParams(err):
+			return rpcErrorResponse(req.ID, rpcInvalidParams, err.Error())
+		default:
+			log.Error(err)
+			return rpcErrorResponse(req.ID, rpcInternalError, err.Error())
+		}
+	}
+
+	if !isProxiedRPCMethod(req.Method) {
+		return rpcErrorResponse(req.ID, rpcMethodNotFound, "the method "+req.Method+" does not exist/is not available")
+	}
+
+	resp, err := a.proxyRPC(req)
+	if err != nil {
+		log.Error(err)
+		return rpcErrorResponse(req.ID, rpcInternalError, err.Error())
+	}
+
+	return resp
+}
+
+// proxyRPC forwards the request to the node and returns its response untouched
+func (a *API) proxyRPC(req rpcRequest) (rpcResponse, error) {
+	var resp rpcResponse
+
+	if req.Params == nil {
+		req.Params = []json.RawMessage{}
+	}
+	req.JSONRPC = "2.0"
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return resp, err
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	httpResp, err := client.Post(a.config.EthClientURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return resp, err
+	}
+	defer httpResp.Body.Close()
+
+	var raw struct {
+		JSONRPC string          `json:"jsonrpc"`
+		ID      json.RawMessage `json:"id"`
+		Result  json.RawMessage `json:"result"`
+		Error   *rpcError       `json:"error"`
+	}
+	err = json.NewDecoder(httpResp.Body).Decode(&raw)
+	if err != nil {
+		return resp, err
+	}
+
+	resp = rpcResponse{JSONRPC: "2.0", ID: req.ID, Error: raw.Error}
+	if raw.Error == nil {
+		// keep explicit nulls (e.g. unknown tx hash) in the response
+		if len(raw.Result) == 0 {
+			raw.Result = json.RawMessage("null")
+		}
+		resp.Result = raw.Result
+	}
+
+	return resp, nil
+}
+
+func isProxiedRPCMethod(method string) bool {
+	for _, prefix := range rpcProxiedPrefixes {
+		if strings.HasPrefix(method, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func rpcErrorResponse(id json.RawMessage, code int, message string) rpcResponse {
+	return rpcResponse{
+		JSONRPC: "2.0",
+		ID:      id,
+		Error: &rpcError{
+			Code:    code,
+			Message: message,
+		},
+	}
+}
+
+// invalidParamsError marks errors caused by the request parameters
+type invalidParamsError struct {
+	msg string
+}
+
+func (e invalidParamsError) Error() string {
+	return e.msg
+}
+
+func isInvalidParams(err error) bool {
+	_, ok := err.(invalidParamsError)
+	return ok
+}
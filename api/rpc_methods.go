@@ -0,0 +1,630 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Alethio/memento/data/storable"
+	"github.com/Alethio/memento/utils"
+)
+
+const rpcBlockQuery = `select number, block_hash, parent_block_hash, block_creation_time, block_gas_limit, block_gas_used, block_difficulty, total_block_difficulty, block_extra_data, block_mix_hash, block_nonce, block_size, block_logs_bloom, includes_uncle, has_beneficiary, has_receipts_trie, has_tx_trie, sha3_uncles from blocks where %s limit 1`
+
+const rpcTxQuery = `select t.tx_hash, t.included_in_block, b.block_hash, t.tx_index, t."from", t."to", t.value, t.tx_nonce, t.msg_gas_limit, t.tx_gas_price, t.msg_payload, t.creates, t.tx_type, t.max_fee_per_gas, t.max_priority_fee_per_gas, t.max_fee_per_blob_gas from txs t join blocks b on b.number = t.included_in_block where %s`
+
+// rpcAccessListsQuery and rpcBlobHashesQuery select the access list entries and the blob versioned hashes of the
+// transactions matching a condition on txs t, in order
+const (
+	rpcAccessListsQuery = `select a.tx_hash, a.address, a.storage_keys from tx_access_lists a join txs t on t.tx_hash = a.tx_hash and t.included_in_block = a.included_in_block where %s order by t.tx_index, a.entry_index`
+	rpcBlobHashesQuery  = `select h.tx_hash, h.versioned_hash from blob_hashes h join txs t on t.tx_hash = h.tx_hash and t.included_in_block = h.included_in_block where %s order by t.tx_index, h.blob_index`
+)
+
+const rpcLogsQuery = `select l.tx_hash, l.log_index, l.log_data, l.logged_by, l.topic_0, l.topic_1, l.topic_2, l.topic_3, l.included_in_block, t.tx_index,
+		` + logEntryBlockHash + `, ` + logEntryBlockIndex + `
+	from log_entries l
+	join txs t on t.tx_hash = l.tx_hash and t.included_in_block = l.included_in_block
+	where %s
+	order by l.included_in_block, t.tx_index, l.log_index`
+
+func (a *API) rpcGetBlockByNumber(params []json.RawMessage) (interface{}, error) {
+	if len(params) < 1 {
+		return nil, invalidParamsError{"missing block number"}
+	}
+
+	number, err := a.parseRPCBlockNumber(params[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return a.rpcBlock("number = $1", number, rpcFullTxsParam(params))
+}
+
+func (a *API) rpcGetBlockByHash(params []json.RawMessage) (interface{}, error) {
+	if len(params) < 1 {
+		return nil, invalidParamsError{"missing block hash"}
+	}
+
+	var hash string
+	err := json.Unmarshal(params[0], &hash)
+	if err != nil {
+		return nil, invalidParamsError{"invalid block hash"}
+	}
+
+	return a.rpcBlock("block_hash = $1", utils.CleanUpHex(hash), rpcFullTxsParam(params))
+}
+
+func (a *API) rpcGetTransactionByHash(params []json.RawMessage) (interface{}, error) {
+	hash, err := rpcHashParam(params)
+	if err != nil {
+		return nil, err
+	}
+
+	txs, err := a.rpcTxs("t.tx_hash = $1", hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(txs) == 0 {
+		return nil, errNotIndexed
+	}
+
+	return txs[0], nil
+}
+
+func (a *API) rpcGetTransactionReceipt(params []json.RawMessage) (interface{}, error) {
+	hash, err := rpcHashParam(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		txHash, blockHash, gasUsed, cumulativeGasUsed, status, effectiveGasPrice string
+		blockNumber, txType                                                      int64
+		txIndex                                                                  int32
+		from, to, creates, logsBloom                                             storable.ByteArray
+		blobGasUsed, blobGasPrice                                                *string
+	)
+	err = a.backend.DB().QueryRow(`select t.tx_hash, b.block_hash, t.included_in_block, t.tx_index, t."from", t."to", t.creates, t.tx_gas_used, t.cumulative_gas_used, coalesce(t.msg_status, ''), t.tx_logs_bloom, t.tx_type, coalesce(t.effective_gas_price, t.tx_gas_price), t.blob_gas_used, t.blob_gas_price from txs t join blocks b on b.number = t.included_in_block where t.tx_hash = $1 limit 1`, hash).Scan(
+		&txHash, &blockHash, &blockNumber, &txIndex, &from, &to, &creates, &gasUsed, &cumulativeGasUsed, &status, &logsBloom, &txType, &effectiveGasPrice, &blobGasUsed, &blobGasPrice,
+	)
+	if err == sql.ErrNoRows {
+		return nil, errNotIndexed
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	receipt := map[string]interface{}{
+		"transactionHash":   hexData(txHash),
+		"transactionIndex":  hexQuantity(int64(txIndex)),
+		"blockHash":         hexData(blockHash),
+		"blockNumber":       hexQuantity(blockNumber),
+		"from":              hexData(from.String()),
+		"to":                hexData(to.String()),
+		"contractAddress":   nil,
+		"logsBloom":         hexData(logsBloom.String()),
+		"gasUsed":           decToHexQuantity(gasUsed),
+		"cumulativeGasUsed": decToHexQuantity(cumulativeGasUsed),
+		"effectiveGasPrice": decToHexQuantity(effectiveGasPrice),
+		"type":              hexQuantity(txType),
+	}
+
+	if status != "" {
+		receipt["status"] = status
+	}
+
+	if blobGasUsed != nil && blobGasPrice != nil {
+		receipt["blobGasUsed"] = decToHexQuantity(*blobGasUsed)
+		receipt["blobGasPrice"] = decToHexQuantity(*blobGasPrice)
+	}
+
+	if creates != "" {
+		receipt["to"] = nil
+		receipt["contractAddress"] = hexData(creates.String())
+	}
+
+	logs, err := a.rpcLogs("l.tx_hash = $1", []interface{}{hash})
+	if err != nil {
+		return nil, err
+	}
+	receipt["logs"] = logs
+
+	return receipt, nil
+}
+
+type rpcLogFilter struct {
+	FromBlock json.RawMessage   `json:"fromBlock"`
+	ToBlock   json.RawMessage   `json:"toBlock"`
+	BlockHash string            `json:"blockHash"`
+	Address   json.RawMessage   `json:"address"`
+	Topics    []json.RawMessage `json:"topics"`
+}
+
+func (a *API) rpcGetLogs(params []json.RawMessage) (interface{}, error) {
+	if len(params) < 1 {
+		return nil, invalidParamsError{"missing filter"}
+	}
+
+	var filter rpcLogFilter
+	err := json.Unmarshal(params[0], &filter)
+	if err != nil {
+		return nil, invalidParamsError{"invalid filter: " + err.Error()}
+	}
+
+	var conditions []string
+	var args []interface{}
+	addCondition := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	addInCondition := func(column string, values []string) {
+		placeholders := make([]string, len(values))
+		for i, v := range values {
+			args = append(args, v)
+			placeholders[i] = fmt.Sprintf("$%d", len(args))
+		}
+		conditions = append(conditions, fmt.Sprintf("%s in (%s)", column, strings.Join(placeholders, ", ")))
+	}
+
+	if filter.BlockHash != "" {
+		var number int64
+		err := a.backend.DB().QueryRow(`select number from blocks where block_hash = $1`, utils.CleanUpHex(filter.BlockHash)).Scan(&number)
+		if err == sql.ErrNoRows {
+			return nil, errNotIndexed
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		addCondition("l.included_in_block = $%d", number)
+	} else {
+		from, err := a.parseRPCBlockNumber(filter.FromBlock)
+		if err != nil {
+			return nil, err
+		}
+
+		to, err := a.parseRPCBlockNumber(filter.ToBlock)
+		if err != nil {
+			return nil, err
+		}
+
+		if to < from {
+			return nil, invalidParamsError{"invalid block range"}
+		}
+
+		if to-from >= MaxLogsBlocksInRange {
+			return nil, invalidParamsError{fmt.Sprintf("block range too wide; at most %d blocks can be queried at once", MaxLogsBlocksInRange)}
+		}
+
+		// blocks can be missing below the highest one (backfill, gaps, failed blocks), and the logs of a range with
+		// holes would look complete; the node answers instead
+		var stored int64
+		err = a.backend.DB().QueryRow(`select count(*) from blocks where number between $1 and $2`, from, to).Scan(&stored)
+		if err != nil {
+			return nil, err
+		}
+
+		if stored != to-from+1 {
+			return nil, errNotIndexed
+		}
+
+		addCondition("l.included_in_block >= $%d", from)
+		addCondition("l.included_in_block <= $%d", to)
+	}
+
+	addresses, err := rpcHexList(filter.Address)
+	if err != nil {
+		return nil, invalidParamsError{"invalid address filter"}
+	}
+	if len(addresses) > 0 {
+		addInCondition("l.logged_by", addresses)
+	}
+
+	if len(filter.Topics) > 4 {
+		return nil, invalidParamsError{"too many topics"}
+	}
+	for i, t := range filter.Topics {
+		topics, err := rpcHexList(t)
+		if err != nil {
+			return nil, invalidParamsError{fmt.Sprintf("invalid topic at position %d", i)}
+		}
+
+		if len(topics) > 0 {
+			addInCondition(fmt.Sprintf("l.topic_%d", i), topics)
+		}
+	}
+
+	return a.rpcLogs(strings.Join(conditions, " and "), args)
+}
+
+// rpcBlock builds the JSON-RPC representation of the block matching the condition, including either the full
+// transactions or only their hashes
+func (a *API) rpcBlock(condition string, arg interface{}, fullTxs bool) (interface{}, error) {
+	var (
+		number, size                                                                  int64
+		hash, parentHash, gasLimit, gasUsed, difficulty, totalDiff                    string
+		creationTime                                                                  storable.DatetimeToJSONUnix
+		extraData, mixHash, nonce, logsBloom, miner, receiptsRoot, txRoot, sha3Uncles storable.ByteArray
+		uncles                                                                        storable.JSONStringArray
+	)
+
+	err := a.backend.DB().QueryRow(fmt.Sprintf(rpcBlockQuery, condition), arg).Scan(
+		&number, &hash, &parentHash, &creationTime, &gasLimit, &gasUsed, &difficulty, &totalDiff, &extraData, &mixHash, &nonce, &size, &logsBloom, &uncles, &miner, &receiptsRoot, &txRoot, &sha3Uncles,
+	)
+	if err == sql.ErrNoRows {
+		return nil, errNotIndexed
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if uncles == nil {
+		uncles = storable.JSONStringArray{}
+	}
+
+	block := map[string]interface{}{
+		"number":           hexQuantity(number),
+		"hash":             hexData(hash),
+		"parentHash":       hexData(parentHash),
+		"timestamp":        hexQuantity(time.Time(creationTime).Unix()),
+		"gasLimit":         decToHexQuantity(gasLimit),
+		"gasUsed":          decToHexQuantity(gasUsed),
+		"difficulty":       decToHexQuantity(difficulty),
+		"totalDifficulty":  decToHexQuantity(totalDiff),
+		"extraData":        hexData(extraData.String()),
+		"mixHash":          hexData(mixHash.String()),
+		"nonce":            hexData(nonce.String()),
+		"size":             hexQuantity(size),
+		"logsBloom":        hexData(logsBloom.String()),
+		"miner":            hexData(miner.String()),
+		"receiptsRoot":     hexData(receiptsRoot.String()),
+		"transactionsRoot": hexData(txRoot.String()),
+		"sha3Uncles":       hexData(sha3Uncles.String()),
+		"uncles":           uncles,
+	}
+
+	txs, err := a.rpcTxs("t.included_in_block = $1", number)
+	if err != nil {
+		return nil, err
+	}
+
+	if fullTxs {
+		block["transactions"] = txs
+	} else {
+		hashes := make([]interface{}, len(txs))
+		for i, tx := range txs {
+			hashes[i] = tx["hash"]
+		}
+		block["transactions"] = hashes
+	}
+
+	return block, nil
+}
+
+// rpcTxs returns the JSON-RPC representation of the transactions matching the condition, ordered by index
+func (a *API) rpcTxs(condition string, arg interface{}) ([]map[string]interface{}, error) {
+	rows, err := a.backend.DB().Query(fmt.Sprintf(rpcTxQuery, condition)+" order by t.tx_index", arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	txs := make([]map[string]interface{}, 0)
+	for rows.Next() {
+		var (
+			hash, blockHash, value, gas, gasPrice                string
+			blockNumber, nonce, txType                           int64
+			txIndex                                              int32
+			from, to, input, creates                             storable.ByteArray
+			maxFeePerGas, maxPriorityFeePerGas, maxFeePerBlobGas *string
+		)
+
+		err := rows.Scan(&hash, &blockNumber, &blockHash, &txIndex, &from, &to, &value, &nonce, &gas, &gasPrice, &input, &creates, &txType, &maxFeePerGas, &maxPriorityFeePerGas, &maxFeePerBlobGas)
+		if err != nil {
+			return nil, err
+		}
+
+		tx := map[string]interface{}{
+			"hash":             hexData(hash),
+			"blockHash":        hexData(blockHash),
+			"blockNumber":      hexQuantity(blockNumber),
+			"transactionIndex": hexQuantity(int64(txIndex)),
+			"from":             hexData(from.String()),
+			"to":               hexData(to.String()),
+			"value":            decToHexQuantity(value),
+			"nonce":            hexQuantity(nonce),
+			"gas":              decToHexQuantity(gas),
+			"gasPrice":         decToHexQuantity(gasPrice),
+			"input":            hexData(input.String()),
+			"type":             hexQuantity(txType),
+		}
+
+		// we store the address of the created contract in the `to` field, but the node returns null
+		if creates != "" {
+			tx["to"] = nil
+		}
+
+		// the fields of the typed transactions are only returned for the types that have them, like the node does
+		if txType > 0 {
+			tx["accessList"] = make([]map[string]interface{}, 0)
+		}
+
+		if maxFeePerGas != nil && maxPriorityFeePerGas != nil {
+			tx["maxFeePerGas"] = decToHexQuantity(*maxFeePerGas)
+			tx["maxPriorityFeePerGas"] = decToHexQuantity(*maxPriorityFeePerGas)
+		}
+
+		if maxFeePerBlobGas != nil {
+			tx["maxFeePerBlobGas"] = decToHexQuantity(*maxFeePerBlobGas)
+			tx["blobVersionedHashes"] = make([]string, 0)
+		}
+
+		txs = append(txs, tx)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	err = a.rpcTxsExtras(condition, arg, txs)
+	if err != nil {
+		return nil, err
+	}
+
+	return txs, nil
+}
+
+// rpcTxsExtras fills in the access lists and blob versioned hashes of the transactions returned by rpcTxs for the
+// same condition; they are read for all the transactions at once rather than one by one
+func (a *API) rpcTxsExtras(condition string, arg interface{}, txs []map[string]interface{}) error {
+	byHash := make(map[string]map[string]interface{}, len(txs))
+	for _, tx := range txs {
+		byHash[tx["hash"].(string)] = tx
+	}
+
+	rows, err := a.backend.DB().Query(fmt.Sprintf(rpcAccessListsQuery, condition), arg)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			txHash, address string
+			storageKeys     storable.JSONStringArray
+		)
+
+		err := rows.Scan(&txHash, &address, &storageKeys)
+		if err != nil {
+			return err
+		}
+
+		tx, ok := byHash[hexData(txHash)]
+		if !ok {
+			continue
+		}
+
+		keys := make([]string, len(storageKeys))
+		for i, key := range storageKeys {
+			keys[i] = hexData(key)
+		}
+
+		entries, _ := tx["accessList"].([]map[string]interface{})
+		tx["accessList"] = append(entries, map[string]interface{}{
+			"address":     hexData(address),
+			"storageKeys": keys,
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = a.backend.DB().Query(fmt.Sprintf(rpcBlobHashesQuery, condition), arg)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var txHash, versionedHash string
+
+		err := rows.Scan(&txHash, &versionedHash)
+		if err != nil {
+			return err
+		}
+
+		tx, ok := byHash[hexData(txHash)]
+		if !ok {
+			continue
+		}
+
+		hashes, _ := tx["blobVersionedHashes"].([]string)
+		tx["blobVersionedHashes"] = append(hashes, hexData(versionedHash))
+	}
+
+	return rows.Err()
+}
+
+// rpcLogs returns the JSON-RPC representation of the log entries matching the condition
+// Only the logs of the canonical chain are stored, so removed is always false; the logs dropped by a reorg are never
+// served again, and the clients following the chain learn about them from the reorg events of the live feed and the
+// removed deliveries of the webhooks
+func (a *API) rpcLogs(condition string, args []interface{}) ([]map[string]interface{}, error) {
+	rows, err := a.backend.DB().Query(fmt.Sprintf(rpcLogsQuery, condition), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	logs := make([]map[string]interface{}, 0)
+	for rows.Next() {
+		var (
+			txHash, loggedBy, blockHash    string
+			topic0, topic1, topic2, topic3 string
+			txLogIndex, txIndex            int32
+			blockNumber, blockLogIndex     int64
+			data                           storable.ByteArray
+		)
+
+		err := rows.Scan(&txHash, &txLogIndex, &data, &loggedBy, &topic0, &topic1, &topic2, &topic3, &blockNumber, &txIndex, &blockHash, &blockLogIndex)
+		if err != nil {
+			return nil, err
+		}
+
+		topics := make([]string, 0)
+		for _, t := range []string{topic0, topic1, topic2, topic3} {
+			if t != "" {
+				topics = append(topics, hexData(t))
+			}
+		}
+
+		logs = append(logs, map[string]interface{}{
+			"address":             hexData(loggedBy),
+			"topics":              topics,
+			"data":                hexData(data.String()),
+			"blockNumber":         hexQuantity(blockNumber),
+			"blockHash":           hexData(blockHash),
+			"transactionHash":     hexData(txHash),
+			"transactionIndex":    hexQuantity(int64(txIndex)),
+			"logIndex":            hexQuantity(blockLogIndex),
+			"transactionLogIndex": hexQuantity(int64(txLogIndex)),
+			"removed":             false,
+		})
+	}
+
+	return logs, rows.Err()
+}
+
+// parseRPCBlockNumber resolves a block number parameter (hex quantity or tag) to a number that can be served
+// from the database; blocks above the highest indexed block (and the "pending" tag) return errNotIndexed
+// The "safe" and "finalized" tags resolve to the highest block stored with (at least) that finality status
+func (a *API) parseRPCBlockNumber(raw json.RawMessage) (int64, error) {
+	tag := "latest"
+	if len(raw) > 0 && string(raw) != "null" {
+		err := json.Unmarshal(raw, &tag)
+		if err != nil {
+			return 0, invalidParamsError{"invalid block number"}
+		}
+	}
+
+	var highest int64
+	err := a.backend.DB().QueryRow("select number from blocks order by number desc limit 1").Scan(&highest)
+	if err == sql.ErrNoRows {
+		return 0, errNotIndexed
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	switch tag {
+	case "latest":
+		return highest, nil
+	case "safe", "finalized":
+		return a.highestWithFinality(tag)
+	case "earliest":
+		return 0, nil
+	case "pending":
+		return 0, errNotIndexed
+	}
+
+	number, err := strconv.ParseInt(tag, 0, 64)
+	if err != nil || !strings.HasPrefix(tag, "0x") {
+		return 0, invalidParamsError{"invalid block number"}
+	}
+
+	if number > highest {
+		return 0, errNotIndexed
+	}
+
+	return number, nil
+}
+
+// highestWithFinality returns the highest indexed block that reached at least the given finality status
+func (a *API) highestWithFinality(tag string) (int64, error) {
+	query := "select number from blocks where finality = 'finalized' order by number desc limit 1"
+	if tag == storable.FinalitySafe {
+		query = "select number from blocks where finality in ('safe', 'finalized') order by number desc limit 1"
+	}
+
+	var number int64
+	err := a.backend.DB().QueryRow(query).Scan(&number)
+	if err == sql.ErrNoRows {
+		return 0, errNotIndexed
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return number, nil
+}
+
+func rpcFullTxsParam(params []json.RawMessage) bool {
+	var full bool
+	if len(params) > 1 {
+		json.Unmarshal(params[1], &full)
+	}
+
+	return full
+}
+
+func rpcHashParam(params []json.RawMessage) (string, error) {
+	if len(params) < 1 {
+		return "", invalidParamsError{"missing hash"}
+	}
+
+	var hash string
+	err := json.Unmarshal(params[0], &hash)
+	if err != nil {
+		return "", invalidParamsError{"invalid hash"}
+	}
+
+	return utils.CleanUpHex(hash), nil
+}
+
+// rpcHexList decodes a filter value that can either be null, a single hex string or a list of hex strings
+func rpcHexList(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return []string{utils.CleanUpHex(single)}, nil
+	}
+
+	var list []string
+	err := json.Unmarshal(raw, &list)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range list {
+		list[i] = utils.CleanUpHex(list[i])
+	}
+
+	return list, nil
+}
+
+func hexQuantity(n int64) string {
+	return "0x" + strconv.FormatInt(n, 16)
+}
+
+// decToHexQuantity transforms a base 10 number (as stored in numeric columns) into a hex quantity
+func decToHexQuantity(s string) string {
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return "0x0"
+	}
+
+	return "0x" + n.Text(16)
+}
+
+func hexData(s string) string {
+	return "0x" + s
+}
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Alethio/memento/data"
+	"github.com/Alethio/memento/eth/extra"
+	"github.com/alethio/web3-go/types"
+)
+
+func rpcCall(t *testing.T, a *API, method string, params ...interface{}) rpcResponse {
+	req := rpcRequest{JSONRPC: "2.0", ID: json.RawMessage("1"), Method: method}
+	for _, p := range params {
+		raw, err := json.Marshal(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Params = append(req.Params, raw)
+	}
+
+	return a.execRPC(req)
+}
+
+func TestGetLogsProxiesRangesWithMissingBlocks(t *testing.T) {
+	backend, cleanup := newTestBackend(t)
+	defer cleanup()
+
+	emitter := "0x" + strings.Repeat("ab", 20)
+	topic := testHash(7)
+
+	// block 3 is missing
+	for _, number := range []int64{1, 2, 4} {
+		var tx types.Transaction
+		tx.Hash = testHash(number)
+		tx.From = "0x" + strings.Repeat("01", 20)
+		tx.To = emitter
+
+		b, receipts := newTestBlock(number, tx)
+
+		var l types.Log
+		l.Address = emitter
+		l.Topics = []string{topic}
+		l.Data = "0x"
+		receipts[0].Logs = []types.Log{l}
+
+		storeTestBlock(t, backend, &data.FullBlock{Block: b, Receipts: receipts})
+	}
+
+	var proxied []string
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req rpcRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		proxied = append(proxied, req.Method)
+		w.Write([]byte(`{"jsonrpc": "2.0", "id": 1, "result": "from the node"}`))
+	}))
+	defer node.Close()
+
+	a := New(NewReplica(backend.DB()), Config{EthClientURL: node.URL})
+
+	resp := rpcCall(t, a, "eth_getLogs", map[string]interface{}{"fromBlock": "0x1", "toBlock": "0x2", "address": emitter})
+	logs, ok := resp.Result.([]map[string]interface{})
+	if resp.Error != nil || !ok || len(logs) != 2 || len(proxied) != 0 {
+		t.Fatalf("expected the logs of blocks 1 and 2 from the index, got %+v", resp)
+	}
+
+	if logs[1]["blockNumber"] != "0x2" || logs[1]["transactionHash"] != testHash(2) || logs[1]["topics"].([]string)[0] != topic {
+		t.Fatalf("unexpected log %+v", logs[1])
+	}
+
+	for _, filter := range []map[string]interface{}{
+		{"fromBlock": "0x1", "toBlock": "0x4"},
+		{"fromBlock": "0x3", "toBlock": "latest"},
+		{"fromBlock": "0x4", "toBlock": "0x5"},
+	} {
+		proxied = nil
+
+		resp = rpcCall(t, a, "eth_getLogs", filter)
+		if resp.Error != nil || len(proxied) != 1 || proxied[0] != "eth_getLogs" {
+			t.Errorf("%v: expected the request to be proxied, got %+v", filter, resp)
+		}
+	}
+
+	proxied = nil
+	resp = rpcCall(t, a, "eth_getLogs", map[string]interface{}{"fromBlock": "0x4", "toBlock": "latest"})
+	logs, ok = resp.Result.([]map[string]interface{})
+	if !ok || len(logs) != 1 || len(proxied) != 0 {
+		t.Fatalf("expected the log of block 4 from the index, got %+v", resp)
+	}
+
+	resp = rpcCall(t, a, "eth_getLogs", map[string]interface{}{"fromBlock": "0x4", "toBlock": "0x1"})
+	if resp.Error == nil || resp.Error.Code != rpcInvalidParams {
+		t.Fatalf("expected an invalid range error, got %+v", resp)
+	}
+}
+
+func TestRPCTypedTransactions(t *testing.T) {
+	backend, cleanup := newTestBackend(t)
+	defer cleanup()
+
+	var legacy, blob types.Transaction
+	legacy.Hash, legacy.From, legacy.To = testHash(1), "0x"+balanceAlice, "0x"+balanceBob
+	blob.Hash, blob.From, blob.To = testHash(2), "0x"+balanceAlice, "0x"+balanceBob
+
+	b, receipts := newTestBlock(1, legacy, blob)
+	fb := &data.FullBlock{Block: b, Receipts: receipts}
+	fb.BlockExtra.BaseFeePerGas = "0x1"
+	fb.BlockExtra.Transactions = []extra.Transaction{
+		{Hash: legacy.Hash},
+		{
+			Hash: blob.Hash, Type: "0x3", MaxFeePerGas: "0x10", MaxPriorityFeePerGas: "0x2", MaxFeePerBlobGas: "0x20",
+			AccessList:          []extra.AccessTuple{{Address: "0x" + balanceBob, StorageKeys: []string{testHash(5)}}},
+			BlobVersionedHashes: []string{testHash(8), testHash(9)},
+		},
+	}
+	fb.ReceiptsExtra = map[string]extra.Receipt{
+		blob.Hash: {TransactionHash: blob.Hash, Type: "0x3", EffectiveGasPrice: "0x3", BlobGasUsed: "0x20000", BlobGasPrice: "0x4"},
+	}
+	storeTestBlock(t, backend, fb)
+
+	a := New(NewReplica(backend.DB()), Config{})
+
+	resp := rpcCall(t, a, "eth_getBlockByNumber", "0x1", true)
+	block, ok := resp.Result.(map[string]interface{})
+	if resp.Error != nil || !ok {
+		t.Fatalf("expected block 1, got %+v", resp)
+	}
+
+	txs := block["transactions"].([]map[string]interface{})
+	if len(txs) != 2 {
+		t.Fatalf("expected 2 transactions, got %+v", txs)
+	}
+
+	if txs[0]["type"] != "0x0" || txs[0]["accessList"] != nil || txs[0]["maxFeePerGas"] != nil || txs[0]["blobVersionedHashes"] != nil {
+		t.Errorf("expected a legacy transaction without the typed fields, got %+v", txs[0])
+	}
+
+	tx := txs[1]
+	if tx["type"] != "0x3" || tx["maxFeePerGas"] != "0x10" || tx["maxPriorityFeePerGas"] != "0x2" || tx["maxFeePerBlobGas"] != "0x20" {
+		t.Errorf("unexpected fee fields %+v", tx)
+	}
+
+	accessList, _ := tx["accessList"].([]map[string]interface{})
+	if len(accessList) != 1 || accessList[0]["address"] != "0x"+balanceBob || accessList[0]["storageKeys"].([]string)[0] != testHash(5) {
+		t.Errorf("unexpected access list %+v", tx["accessList"])
+	}
+
+	hashes, _ := tx["blobVersionedHashes"].([]string)
+	if len(hashes) != 2 || hashes[0] != testHash(8) || hashes[1] != testHash(9) {
+		t.Errorf("unexpected blob versioned hashes %+v", tx["blobVersionedHashes"])
+	}
+
+	resp = rpcCall(t, a, "eth_getTransactionByHash", blob.Hash)
+	if tx, ok := resp.Result.(map[string]interface{}); !ok || len(tx["blobVersionedHashes"].([]string)) != 2 {
+		t.Errorf("expected the blob versioned hashes of the transaction, got %+v", resp)
+	}
+
+	resp = rpcCall(t, a, "eth_getTransactionReceipt", blob.Hash)
+	receipt, ok := resp.Result.(map[string]interface{})
+	if resp.Error != nil || !ok {
+		t.Fatalf("expected the receipt, got %+v", resp)
+	}
+
+	if receipt["type"] != "0x3" || receipt["effectiveGasPrice"] != "0x3" || receipt["blobGasUsed"] != "0x20000" || receipt["blobGasPrice"] != "0x4" {
+		t.Errorf("unexpected receipt %+v", receipt)
+	}
+}
+
+func TestRPCBatchSize(t *testing.T) {
+	db, cleanup := newTestDB(t)
+	defer cleanup()
+
+	a := newTestDBAPI(db, Config{})
+
+	batch := func(size int) string {
+		reqs := make([]string, size)
+		for i := range reqs {
+			reqs[i] = `{"jsonrpc": "2.0", "id": 1, "method": "eth_getBlockByNumber", "params": ["0x1", false]}`
+		}
+
+		return "[" + strings.Join(reqs, ",") + "]"
+	}
+
+	w := request(a, http.MethodPost, "/rpc", "", batch(MaxRPCBatchSize))
+
+	var resps []rpcResponse
+	err := json.Unmarshal(w.Body.Bytes(), &resps)
+	if err != nil || len(resps) != MaxRPCBatchSize {
+		t.Fatalf("expected %d responses, got %s", MaxRPCBatchSize, w.Body.String())
+	}
+
+	w = request(a, http.MethodPost, "/rpc", "", batch(MaxRPCBatchSize+1))
+
+	var resp rpcResponse
+	err = json.Unmarshal(w.Body.Bytes(), &resp)
+	if err != nil || resp.Error == nil || resp.Error.Code != rpcInvalidRequest {
+		t.Errorf("expected a batch above the limit to be refused, got %s", w.Body.String())
+	}
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Alethio/memento/data"
+	"github.com/Alethio/memento/metrics"
+	"github.com/Alethio/memento/storage"
+	"github.com/alethio/web3-go/types"
+	"github.com/gin-gonic/gin"
+)
+
+// newTestBackend returns a migrated sqlite backend along with the function that removes it
+func newTestBackend(t *testing.T) (storage.Backend, func()) {
+	dir, err := ioutil.TempDir("", "memento-api")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backend, err := storage.New(storage.Config{Driver: storage.DriverSQLite, SQLitePath: filepath.Join(dir, "test.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cleanup := func() {
+		backend.Close()
+		os.RemoveAll(dir)
+	}
+
+	err = backend.Migrate()
+	if err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+
+	return backend, cleanup
+}
+
+func newTestDB(t *testing.T) (*sql.DB, func()) {
+	backend, cleanup := newTestBackend(t)
+	return backend.DB(), cleanup
+}
+
+// newTestDBAPI returns an API backed by a Replica of db, with its routes set up
+func newTestDBAPI(db *sql.DB, config Config) *API {
+	gin.SetMode(gin.TestMode)
+
+	a := New(NewReplica(db), config)
+	a.engine = gin.New()
+	a.setRoutes()
+
+	return a
+}
+
+// testHash returns a 32 bytes hex hash made of n
+func testHash(n int64) string {
+	return fmt.Sprintf("0x%064x", n)
+}
+
+// newTestBlock returns a block with the fields required to store it and the given transactions, whose receipts are
+// returned along with it
+func newTestBlock(number int64, txs ...types.Transaction) (types.Block, data.Receipts) {
+	var b types.Block
+	b.Number = fmt.Sprintf("0x%x", number)
+	b.Hash = testHash(1000 + number)
+	b.ParentHash = testHash(1000 + number - 1)
+	b.Miner = "0x" + fmt.Sprintf("%040x", 0xc0ffee)
+	b.Difficulty = "0x0"
+	b.TotalDifficulty = "0x0"
+	b.Timestamp = fmt.Sprintf("0x%x", 1600000000+number*12)
+	b.GasLimit = "0x1c9c380"
+	b.GasUsed = "0x0"
+	b.Nonce = "0x0000000000000000"
+	b.Size = "0x100"
+	b.Sha3Uncles = testHash(0)
+	b.LogsBloom = "0x00"
+	b.TransactionsRoot = testHash(0)
+	b.StateRoot = testHash(0)
+	b.ReceiptsRoot = testHash(0)
+	b.ExtraData = "0x"
+	b.MixHash = testHash(0)
+
+	receipts := make(data.Receipts, len(txs))
+	for i := range txs {
+		txs[i].BlockHash = b.Hash
+		txs[i].BlockNumber = b.Number
+		txs[i].TransactionIndex = fmt.Sprintf("0x%x", i)
+		if txs[i].Nonce == "" {
+			txs[i].Nonce = "0x0"
+		}
+		if txs[i].Gas == "" {
+			txs[i].Gas = "0x5208"
+		}
+		if txs[i].GasPrice == "" {
+			txs[i].GasPrice = "0x1"
+		}
+		if txs[i].Value == "" {
+			txs[i].Value = "0x0"
+		}
+		if txs[i].Input == "" {
+			txs[i].Input = "0x"
+		}
+		txs[i].V, txs[i].R, txs[i].S = "0x1", "0x1", "0x1"
+
+		var r types.Receipt
+		r.TransactionHash = txs[i].Hash
+		r.TransactionIndex = txs[i].TransactionIndex
+		r.BlockHash = b.Hash
+		r.BlockNumber = b.Number
+		r.GasUsed = "0x5208"
+		r.CumulativeGasUsed = fmt.Sprintf("0x%x", 21000*(i+1))
+		r.Status = "0x1"
+		r.LogsBloom = "0x00"
+		receipts[i] = r
+	}
+	b.Transactions = txs
+
+	return b, receipts
+}
+
+// storeTestBlock stores a block the way the indexer does
+func storeTestBlock(t *testing.T, backend storage.Backend, fb *data.FullBlock) {
+	fb.RegisterStorables()
+
+	err := fb.Store(backend, metrics.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,8 @@
+package types
+
+// BalancePoint is the balance of an account after a block that changed it
+type BalancePoint struct {
+	Block   int64  `json:"block"`
+	Change  string `json:"change"`
+	Balance string `json:"balance"`
+}
@@ -0,0 +1,9 @@
+package types
+
+type Blob struct {
+	TxHash          string `json:"txHash"`
+	IncludedInBlock int64  `json:"includedInBlock"`
+	TxIndex         int32  `json:"txIndex"`
+	BlobIndex       int32  `json:"blobIndex"`
+	VersionedHash   string `json:"versionedHash"`
+}
@@ -0,0 +1,20 @@
+package types
+
+import "github.com/Alethio/memento/data/storable"
+
+type Contract struct {
+	Address           string                       `json:"address"`
+	Creator           string                       `json:"creator"`
+	TxHash            string                       `json:"txHash"`
+	IncludedInBlock   int64                        `json:"includedInBlock"`
+	TxIndex           int32                        `json:"txIndex"`
+	TraceAddress      string                       `json:"traceAddress"`
+	CodeHash          *string                      `json:"codeHash"`
+	BlockCreationTime *storable.DatetimeToJSONUnix `json:"blockCreationTime"`
+}
+
+type Account struct {
+	Address    string    `json:"address"`
+	IsContract bool      `json:"isContract"`
+	Creation   *Contract `json:"creation"`
+}
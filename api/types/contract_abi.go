@@ -0,0 +1,19 @@
+package types
+
+import (
+	"encoding/json"
+
+	"github.com/Alethio/memento/data/storable"
+)
+
+type ContractABI struct {
+	Address   string                      `json:"address"`
+	Name      string                      `json:"name"`
+	ABI       json.RawMessage             `json:"abi,omitempty"`
+	CreatedAt storable.DatetimeToJSONUnix `json:"createdAt"`
+}
+
+type FunctionSignature struct {
+	Selector  string `json:"selector"`
+	Signature string `json:"signature"`
+}
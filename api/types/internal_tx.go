@@ -0,0 +1,20 @@
+package types
+
+import "github.com/Alethio/memento/data/storable"
+
+type InternalTx struct {
+	TxHash          string             `json:"txHash"`
+	IncludedInBlock int64              `json:"includedInBlock"`
+	TxIndex         int32              `json:"txIndex"`
+	TraceIndex      int32              `json:"traceIndex"`
+	TraceAddress    string             `json:"traceAddress"`
+	Type            string             `json:"type"`
+	CallType        string             `json:"callType"`
+	From            storable.ByteArray `json:"from"`
+	To              storable.ByteArray `json:"to"`
+	Value           string             `json:"value"`
+	MsgGasLimit     string             `json:"msgGasLimit"`
+	MsgGasUsed      string             `json:"msgGasUsed"`
+	MsgPayload      storable.ByteArray `json:"msgPayload"`
+	MsgError        string             `json:"msgError"`
+}
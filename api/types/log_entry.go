@@ -4,7 +4,11 @@ import "github.com/Alethio/memento/data/storable"
 
 type LogEntry struct {
 	TxHash            string                 `json:"txHash"`
-	LogIndex          int32                  `json:"logIndex"`
+	LogIndex          int64                  `json:"logIndex"`
+	TxLogIndex        int32                  `json:"txLogIndex"`
+	BlockHash         string                 `json:"blockHash"`
+	IncludedInBlock   int64                  `json:"includedInBlock"`
+	TxIndex           int32                  `json:"txIndex"`
 	LogData           storable.ByteArray     `json:"logData"`
 	LoggedBy          string                 `json:"loggedBy"`
 	HasLogTopics      []string               `json:"hasLogTopics"`
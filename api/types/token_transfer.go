@@ -0,0 +1,17 @@
+package types
+
+type TokenTransfer struct {
+	TxHash          string  `json:"txHash"`
+	IncludedInBlock int64   `json:"includedInBlock"`
+	TxIndex         int32   `json:"txIndex"`
+	LogIndex        int64   `json:"logIndex"`
+	TxLogIndex      int32   `json:"txLogIndex"`
+	BatchIndex      int32   `json:"batchIndex"`
+	TokenAddress    string  `json:"tokenAddress"`
+	TokenStandard   string  `json:"tokenStandard"`
+	Operator        string  `json:"operator,omitempty"`
+	From            string  `json:"from"`
+	To              string  `json:"to"`
+	Amount          string  `json:"amount"`
+	TokenID         *string `json:"tokenId,omitempty"`
+}
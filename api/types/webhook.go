@@ -0,0 +1,33 @@
+package types
+
+import (
+	"encoding/json"
+
+	"github.com/Alethio/memento/data/storable"
+)
+
+// Webhook only carries the secret when it's created
+type Webhook struct {
+	ID          string                      `json:"id"`
+	URL         string                      `json:"url"`
+	Secret      string                      `json:"secret,omitempty"`
+	Address     string                      `json:"address,omitempty"`
+	Topic0      string                      `json:"topic0,omitempty"`
+	Description string                      `json:"description"`
+	CreatedAt   storable.DatetimeToJSONUnix `json:"createdAt"`
+}
+
+type WebhookDelivery struct {
+	ID            string                       `json:"id"`
+	Kind          string                       `json:"kind"`
+	BlockNumber   int64                        `json:"blockNumber"`
+	BlockHash     string                       `json:"blockHash"`
+	TxHash        string                       `json:"txHash"`
+	Status        string                       `json:"status"`
+	Attempts      int                          `json:"attempts"`
+	ResponseCode  *int                         `json:"responseCode"`
+	Error         string                       `json:"error,omitempty"`
+	NextAttemptAt *storable.DatetimeToJSONUnix `json:"nextAttemptAt,omitempty"`
+	DeliveredAt   *storable.DatetimeToJSONUnix `json:"deliveredAt,omitempty"`
+	Payload       json.RawMessage              `json:"payload"`
+}
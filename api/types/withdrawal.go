@@ -0,0 +1,12 @@
+package types
+
+import "github.com/Alethio/memento/data/storable"
+
+type Withdrawal struct {
+	IncludedInBlock   int64                       `json:"includedInBlock"`
+	WithdrawalIndex   int64                       `json:"withdrawalIndex"`
+	ValidatorIndex    int64                       `json:"validatorIndex"`
+	Address           string                      `json:"address"`
+	Amount            string                      `json:"amount"`
+	BlockCreationTime storable.DatetimeToJSONUnix `json:"blockCreationTime"`
+}
@@ -0,0 +1,138 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Alethio/memento/abi"
+	"github.com/Alethio/memento/storage"
+	"github.com/Alethio/memento/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var abiCmd = &cobra.Command{
+	Use:   "abi",
+	Short: "Manage the contract ABIs used to decode the log entries",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.HelpFunc()(cmd, args)
+	},
+}
+
+var abiAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Add the ABI of a contract from a JSON file, replacing the existing one",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		bindViperToDBFlags(cmd)
+		viper.BindPFlag("address", cmd.Flag("address"))
+		viper.BindPFlag("file", cmd.Flag("file"))
+		viper.BindPFlag("name", cmd.Flag("name"))
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		address, err := utils.ValidateAccount(viper.GetString("address"))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		data, err := ioutil.ReadFile(viper.GetString("file"))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		backend := abiBackend()
+		defer backend.Close()
+
+		err = abi.Save(backend.DB(), address, viper.GetString("name"), data)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Printf("ABI of 0x%s saved.\n", address)
+	},
+}
+
+var abiListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the contracts that have an ABI",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		bindViperToDBFlags(cmd)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		backend := abiBackend()
+		defer backend.Close()
+
+		contracts, err := abi.List(backend.DB())
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if len(contracts) == 0 {
+			fmt.Println("There are no contract ABIs.")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ADDRESS\tNAME\tADDED AT")
+		for _, c := range contracts {
+			fmt.Fprintf(w, "0x%s\t%s\t%s\n", c.Address, c.Name, c.CreatedAt.Format(time.RFC3339))
+		}
+		w.Flush()
+	},
+}
+
+var abiRemoveCmd = &cobra.Command{
+	Use:   "remove",
+	Short: "Remove the ABI of a contract",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		bindViperToDBFlags(cmd)
+		viper.BindPFlag("address", cmd.Flag("address"))
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		address, err := utils.ValidateAccount(viper.GetString("address"))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		backend := abiBackend()
+		defer backend.Close()
+
+		deleted, err := abi.Delete(backend.DB(), address)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if !deleted {
+			fmt.Printf("There is no ABI for 0x%s.\n", address)
+			return
+		}
+
+		fmt.Printf("ABI of 0x%s removed.\n", address)
+	},
+}
+
+func abiBackend() storage.Backend {
+	backend, err := storage.New(buildStorageConfig())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return backend
+}
+
+func init() {
+	addDBFlags(abiAddCmd)
+	addDBFlags(abiListCmd)
+	addDBFlags(abiRemoveCmd)
+
+	abiAddCmd.Flags().String("address", "", "Address of the contract")
+	abiAddCmd.Flags().String("file", "", "Path of the JSON file holding the ABI")
+	abiAddCmd.Flags().String("name", "", "Optional name of the contract")
+	abiRemoveCmd.Flags().String("address", "", "Address of the contract")
+
+	abiCmd.AddCommand(abiAddCmd)
+	abiCmd.AddCommand(abiListCmd)
+	abiCmd.AddCommand(abiRemoveCmd)
+}
@@ -0,0 +1,46 @@
+package commands
+
+import (
+	"github.com/Alethio/memento/api"
+	"github.com/Alethio/memento/storage"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var apiCmd = &cobra.Command{
+	Use:   "api",
+	Short: "Serve the HTTP API without indexing",
+	Long: `Serve the HTTP API from an already indexed database, which can be a read replica.
+
+Unlike "memento run", this doesn't track the chain, doesn't need redis and doesn't run the migrations, so any number of
+instances can be started behind a load balancer. The live feed is not available, and unless --api.read-only=false is
+given, neither are the endpoints that write to the database (ABI, signature and webhook management). All of them,
+and the webhook endpoints, also require --api.admin-token.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		bindViperToDBFlags(cmd)
+		bindViperToAPIFlags(cmd)
+		viper.BindPFlag("api.read-only", cmd.Flag("api.read-only"))
+		viper.BindPFlag("eth.client.http", cmd.Flag("eth.client.http"))
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		backend, err := storage.New(buildStorageConfig())
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer backend.Close()
+
+		config := buildAPIConfig()
+		config.ReadOnly = viper.GetBool("api.read-only")
+
+		a := api.New(api.NewReplica(backend.DB()), config)
+		a.Run()
+	},
+}
+
+func init() {
+	addDBFlags(apiCmd)
+	addAPIFlags(apiCmd)
+
+	apiCmd.Flags().Bool("api.read-only", true, "Leave out the endpoints that write to the database")
+	apiCmd.Flags().String("eth.client.http", "", "HTTP endpoint of the node the code and balance requests, and the JSON-RPC calls for data that isn't indexed, are proxied to")
+}
@@ -101,7 +101,14 @@ func init() {
 
 	// commands
 	RootCmd.AddCommand(runCmd)
+	RootCmd.AddCommand(apiCmd)
 	RootCmd.AddCommand(migrateCmd)
 	RootCmd.AddCommand(resetCmd)
 	RootCmd.AddCommand(queueCmd)
+	RootCmd.AddCommand(workerCmd)
+	RootCmd.AddCommand(abiCmd)
+	RootCmd.AddCommand(signaturesCmd)
+	RootCmd.AddCommand(webhooksCmd)
+	RootCmd.AddCommand(verifyCmd)
+	RootCmd.AddCommand(reindexCmd)
 }
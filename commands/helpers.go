@@ -3,6 +3,8 @@ package commands
 import (
 	"fmt"
 
+	"github.com/Alethio/memento/api"
+	"github.com/Alethio/memento/storage"
 	"github.com/gin-gonic/gin"
 	formatter "github.com/kwix/logrus-module-formatter"
 	"github.com/sirupsen/logrus"
@@ -50,6 +52,8 @@ func initLogging() {
 }
 
 func addDBFlags(cmd *cobra.Command) {
+	cmd.Flags().String("db.driver", storage.DriverPostgres, "Storage backend: postgres or sqlite (embedded, requires a binary built with cgo)")
+	cmd.Flags().String("db.sqlite-path", "memento.db", "Path of the database file used by the sqlite backend")
 	cmd.Flags().String("db.connection-string", "", "Postgres connection string.")
 	cmd.Flags().String("db.host", "localhost", "Database host")
 	cmd.Flags().String("db.port", "5432", "Database port")
@@ -59,6 +63,8 @@ func addDBFlags(cmd *cobra.Command) {
 }
 
 func bindViperToDBFlags(cmd *cobra.Command) {
+	viper.BindPFlag("db.driver", cmd.Flag("db.driver"))
+	viper.BindPFlag("db.sqlite-path", cmd.Flag("db.sqlite-path"))
 	viper.BindPFlag("db.connection-string", cmd.Flag("db.connection-string"))
 	viper.BindPFlag("db.host", cmd.Flag("db.host"))
 	viper.BindPFlag("db.port", cmd.Flag("db.port"))
@@ -77,6 +83,31 @@ func bindViperToRedisFlags(cmd *cobra.Command) {
 	viper.BindPFlag("redis.list", cmd.Flag("redis.list"))
 }
 
+func addAPIFlags(cmd *cobra.Command) {
+	cmd.Flags().String("api.port", "3001", "HTTP API port")
+	cmd.Flags().Bool("api.dev-cors", false, "Enable development cors for HTTP API")
+	cmd.Flags().String("api.dev-cors-host", "", "Allowed host for HTTP API dev cors")
+	cmd.Flags().String("api.admin-token", "", "Bearer token required by the webhook, ABI and signature management endpoints, which are not served without one")
+}
+
+func bindViperToAPIFlags(cmd *cobra.Command) {
+	viper.BindPFlag("api.port", cmd.Flag("api.port"))
+	viper.BindPFlag("api.dev-cors", cmd.Flag("api.dev-cors"))
+	viper.BindPFlag("api.dev-cors-host", cmd.Flag("api.dev-cors-host"))
+	viper.BindPFlag("api.admin-token", cmd.Flag("api.admin-token"))
+}
+
+// buildAPIConfig returns the api configuration built from the api flags; the node is the one of eth.client.http
+func buildAPIConfig() api.Config {
+	return api.Config{
+		Port:           viper.GetString("api.port"),
+		DevCorsEnabled: viper.GetBool("api.dev-cors"),
+		DevCorsHost:    viper.GetString("api.dev-cors-host"),
+		EthClientURL:   viper.GetString("eth.client.http"),
+		AdminToken:     viper.GetString("api.admin-token"),
+	}
+}
+
 func buildDBConnectionString() {
 	if viper.GetString("db.connection-string") == "" {
 		var user, pass string
@@ -92,3 +123,14 @@ func buildDBConnectionString() {
 		viper.Set("db.connection-string", p)
 	}
 }
+
+// buildStorageConfig returns the configuration of the storage backend selected via the db.* flags
+func buildStorageConfig() storage.Config {
+	buildDBConnectionString()
+
+	return storage.Config{
+		Driver:                   viper.GetString("db.driver"),
+		PostgresConnectionString: viper.GetString("db.connection-string"),
+		SQLitePath:               viper.GetString("db.sqlite-path"),
+	}
+}
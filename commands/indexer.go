@@ -0,0 +1,86 @@
+package commands
+
+import (
+	"time"
+
+	"github.com/Alethio/memento/core"
+	"github.com/Alethio/memento/eth/bestblock"
+	"github.com/Alethio/memento/scraper"
+	"github.com/Alethio/memento/taskmanager"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// addIndexerFlags registers the flags shared by the commands that process blocks (run and worker)
+func addIndexerFlags(cmd *cobra.Command) {
+	// feature flags
+	cmd.Flags().Bool("feature.uncles.enabled", true, "Enable/disable uncles scraping")
+	cmd.Flags().Bool("feature.traces.enabled", false, "Enable/disable internal transactions scraping (requires a node with tracing APIs enabled)")
+	cmd.Flags().String("feature.block-rewards", core.BlockRewardsAuto, "The proof-of-work block reward schedule the balances are computed with: auto (mainnet's on mainnet, none elsewhere), mainnet, none or a list of block:wei pairs (e.g. 0:5000000000000000000,4370000:3000000000000000000)")
+	cmd.Flags().String("feature.traces.source", "geth", "The tracing API used for internal transactions: geth (debug_traceBlockByNumber with callTracer) or parity (trace_block, also used by erigon)")
+
+	// core
+	cmd.Flags().Int("core.workers", 1, "The number of blocks to be processed (scraped, validated and stored) concurrently")
+
+	// queue
+	cmd.Flags().Int64("queue.max-attempts", 5, "The number of times a block is tried before it's moved to the failed queue (0 means retry forever)")
+	cmd.Flags().Duration("queue.retry-backoff", 5*time.Second, "The delay before a failed block is retried; it doubles with each attempt")
+	cmd.Flags().Duration("queue.max-retry-backoff", 10*time.Minute, "The maximum delay between two attempts of a failed block")
+	cmd.Flags().Duration("queue.lease", taskmanager.DefaultLeaseDuration, "How long a block being processed can go without its lease being renewed before it is re-queued (e.g. because its worker died)")
+
+	// eth
+	cmd.Flags().String("eth.client.http", "", "HTTP endpoint of JSON-RPC enabled Ethereum node")
+	cmd.Flags().String("eth.client.ws", "", "WS endpoint of JSON-RPC enabled Ethereum node (provide this only if you want to use websocket subscription for tracking best block)")
+	cmd.Flags().Duration("eth.client.poll-interval", 15*time.Second, "Interval to be used for polling the Ethereum node for best block")
+}
+
+func bindViperToIndexerFlags(cmd *cobra.Command) {
+	viper.BindPFlag("feature.uncles.enabled", cmd.Flag("feature.uncles.enabled"))
+	viper.BindPFlag("feature.traces.enabled", cmd.Flag("feature.traces.enabled"))
+	viper.BindPFlag("feature.traces.source", cmd.Flag("feature.traces.source"))
+	viper.BindPFlag("feature.block-rewards", cmd.Flag("feature.block-rewards"))
+
+	viper.BindPFlag("core.workers", cmd.Flag("core.workers"))
+
+	viper.BindPFlag("queue.max-attempts", cmd.Flag("queue.max-attempts"))
+	viper.BindPFlag("queue.retry-backoff", cmd.Flag("queue.retry-backoff"))
+	viper.BindPFlag("queue.max-retry-backoff", cmd.Flag("queue.max-retry-backoff"))
+	viper.BindPFlag("queue.lease", cmd.Flag("queue.lease"))
+
+	viper.BindPFlag("eth.client.http", cmd.Flag("eth.client.http"))
+	viper.BindPFlag("eth.client.ws", cmd.Flag("eth.client.ws"))
+	viper.BindPFlag("eth.client.poll-interval", cmd.Flag("eth.client.poll-interval"))
+}
+
+// buildCoreConfig returns the core configuration built from the db, redis and indexer flags
+func buildCoreConfig() core.Config {
+	return core.Config{
+		BestBlockTracker: bestblock.Config{
+			NodeURL:      viper.GetString("eth.client.http"),
+			NodeURLWS:    viper.GetString("eth.client.ws"),
+			PollInterval: viper.GetDuration("eth.client.poll-interval"),
+		},
+		TaskManager: taskmanager.Config{
+			RedisServer:     viper.GetString("redis.server"),
+			RedisPassword:   viper.GetString("REDIS_PASSWORD"),
+			TodoList:        viper.GetString("redis.list"),
+			MaxAttempts:     viper.GetInt64("queue.max-attempts"),
+			RetryBackoff:    viper.GetDuration("queue.retry-backoff"),
+			MaxRetryBackoff: viper.GetDuration("queue.max-retry-backoff"),
+			LeaseDuration:   viper.GetDuration("queue.lease"),
+		},
+		Scraper: scraper.Config{
+			NodeURL:      viper.GetString("eth.client.http"),
+			EnableUncles: viper.GetBool("feature.uncles.enabled"),
+			EnableTraces: viper.GetBool("feature.traces.enabled"),
+			TracesSource: viper.GetString("feature.traces.source"),
+		},
+		Storage: buildStorageConfig(),
+		Features: core.Features{
+			Uncles:       viper.GetBool("feature.uncles.enabled"),
+			Traces:       viper.GetBool("feature.traces.enabled"),
+			BlockRewards: viper.GetString("feature.block-rewards"),
+		},
+		Workers: viper.GetInt("core.workers"),
+	}
+}
@@ -1,13 +1,8 @@
 package commands
 
 import (
-	"database/sql"
-
-	_ "github.com/Alethio/memento/migrations"
-	_ "github.com/lib/pq"
-	"github.com/pressly/goose"
+	"github.com/Alethio/memento/storage"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 )
 
 var migrateCmd = &cobra.Command{
@@ -17,14 +12,12 @@ var migrateCmd = &cobra.Command{
 		bindViperToDBFlags(cmd)
 	},
 	Run: func(cmd *cobra.Command, args []string) {
-		buildDBConnectionString()
-
-		db, err := sql.Open("postgres", viper.GetString("db.connection-string"))
+		backend, err := storage.New(buildStorageConfig())
 		if err != nil {
 			log.Fatal(err)
 		}
 
-		err = goose.Up(db, "/tmp")
+		err = backend.Migrate()
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -17,18 +17,7 @@ var queueCmd = &cobra.Command{
 		bindViperToRedisFlags(cmd)
 	},
 	Run: func(cmd *cobra.Command, args []string) {
-		r := redis.NewClient(&redis.Options{
-			Addr:        viper.GetString("redis.server"),
-			Password:    viper.GetString("REDIS_PASSWORD"),
-			DB:          0,
-			ReadTimeout: time.Second * 1,
-		})
-
-		err := r.Ping().Err()
-		if err != nil {
-			log.Fatal(err)
-			return
-		}
+		r := redisClient()
 
 		list := viper.GetString("redis.list")
 
@@ -54,6 +43,23 @@ var queueCmd = &cobra.Command{
 	},
 }
 
+// redisClient connects to the redis server holding the todo queue
+func redisClient() *redis.Client {
+	r := redis.NewClient(&redis.Options{
+		Addr:        viper.GetString("redis.server"),
+		Password:    viper.GetString("REDIS_PASSWORD"),
+		DB:          0,
+		ReadTimeout: time.Second * 1,
+	})
+
+	err := r.Ping().Err()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return r
+}
+
 func addTodo(r *redis.Client, list string, number int64) error {
 	log.WithField("block", number).Info("adding block to todo")
 	return r.ZAdd(list, redis.Z{
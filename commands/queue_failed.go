@@ -0,0 +1,115 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Alethio/memento/taskmanager"
+	"github.com/go-redis/redis"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var queueFailedCmd = &cobra.Command{
+	Use:   "failed",
+	Short: "Inspect and manage the blocks that could not be processed after the maximum number of attempts",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.HelpFunc()(cmd, args)
+	},
+}
+
+var queueFailedListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the failed blocks along with their last error",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		bindViperToRedisFlags(cmd)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		blocks, err := failedQueue().List()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if len(blocks) == 0 {
+			fmt.Println("There are no failed blocks.")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "BLOCK\tATTEMPTS\tFAILED AT\tERROR")
+		for _, b := range blocks {
+			fmt.Fprintf(w, "%d\t%d\t%s\t%s\n", b.Number, b.Attempts, b.FailedAt.Format(time.RFC3339), b.Error)
+		}
+		w.Flush()
+	},
+}
+
+var queueFailedRetryCmd = &cobra.Command{
+	Use:   "retry",
+	Short: "Move failed blocks back to the todo queue (all of them, unless --block is specified)",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		bindViperToRedisFlags(cmd)
+		viper.BindPFlag("block", cmd.Flag("block"))
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		var blocks []int64
+		if block := viper.GetInt64("block"); block >= 0 {
+			blocks = append(blocks, block)
+		}
+
+		count, err := failedQueue().Retry(blocks...)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Printf("%d block(s) moved back to the todo queue.\n", count)
+	},
+}
+
+var queueFailedPurgeCmd = &cobra.Command{
+	Use:   "purge",
+	Short: "Remove all the blocks from the failed queue without retrying them",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		bindViperToRedisFlags(cmd)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		err := failedQueue().Purge()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Println("The failed queue was purged.")
+	},
+}
+
+func failedQueue() *taskmanager.FailedQueue {
+	r := redis.NewClient(&redis.Options{
+		Addr:        viper.GetString("redis.server"),
+		Password:    viper.GetString("REDIS_PASSWORD"),
+		DB:          0,
+		ReadTimeout: time.Second * 1,
+	})
+
+	err := r.Ping().Err()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return taskmanager.NewFailedQueue(r, viper.GetString("redis.list"))
+}
+
+func init() {
+	addRedisFlags(queueFailedListCmd)
+	addRedisFlags(queueFailedRetryCmd)
+	addRedisFlags(queueFailedPurgeCmd)
+
+	queueFailedRetryCmd.Flags().Int64("block", -1, "Only retry the provided block")
+
+	queueFailedCmd.AddCommand(queueFailedListCmd)
+	queueFailedCmd.AddCommand(queueFailedRetryCmd)
+	queueFailedCmd.AddCommand(queueFailedPurgeCmd)
+
+	queueCmd.AddCommand(queueFailedCmd)
+}
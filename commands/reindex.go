@@ -0,0 +1,204 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Alethio/memento/data"
+	"github.com/Alethio/memento/scraper"
+	"github.com/Alethio/memento/storage"
+	"github.com/alethio/web3-go/validator"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// reindexProgressInterval is the number of reindexed blocks between two progress messages
+const reindexProgressInterval = 1000
+
+var reindexCmd = &cobra.Command{
+	Use:   "reindex",
+	Short: "Rebuild the rows of a single table for a range of stored blocks",
+	Long: `Rebuild the rows --storable derived from the blocks between --from and --to (the highest stored block by
+default), without touching the other tables. The rows of each block are deleted and produced again by the current
+version of the storable from the data scraped from the node.
+
+Every stored block records the version of each storable its rows were produced with. With --stale, only the blocks
+whose rows were produced by an older version are reindexed; combined with --dry-run, the command lists them.
+
+The storables are: ` + strings.Join(data.StorableNames(), ", ") + `.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		bindViperToDBFlags(cmd)
+		viper.BindPFlag("storable", cmd.Flag("storable"))
+		viper.BindPFlag("from", cmd.Flag("from"))
+		viper.BindPFlag("to", cmd.Flag("to"))
+		viper.BindPFlag("stale", cmd.Flag("stale"))
+		viper.BindPFlag("dry-run", cmd.Flag("dry-run"))
+		viper.BindPFlag("core.workers", cmd.Flag("core.workers"))
+		viper.BindPFlag("feature.uncles.enabled", cmd.Flag("feature.uncles.enabled"))
+		viper.BindPFlag("feature.traces.enabled", cmd.Flag("feature.traces.enabled"))
+		viper.BindPFlag("feature.traces.source", cmd.Flag("feature.traces.source"))
+		viper.BindPFlag("eth.client.http", cmd.Flag("eth.client.http"))
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		name := viper.GetString("storable")
+		version, err := data.StorableVersion(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		backend := abiBackend()
+		defer backend.Close()
+
+		from := viper.GetInt64("from")
+		to := viper.GetInt64("to")
+		if to < 0 {
+			highest, err := backend.HighestBlock()
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			to = highest
+		}
+
+		var blocks []int64
+		if viper.GetBool("stale") {
+			blocks, err = data.StaleBlocks(backend.DB(), name, from, to)
+			if err != nil {
+				log.Fatal(err)
+			}
+		} else {
+			for number := from; number <= to; number++ {
+				blocks = append(blocks, number)
+			}
+		}
+
+		if viper.GetBool("dry-run") {
+			for _, r := range blockRanges(blocks) {
+				fmt.Printf("%d-%d\n", r.From, r.To)
+			}
+
+			fmt.Printf("%d blocks between %d and %d would be reindexed with version %d of %s.\n", len(blocks), from, to, version, name)
+			return
+		}
+
+		if viper.GetString("eth.client.http") == "" {
+			log.Fatal("a node is required to reindex the blocks; use --eth.client.http")
+		}
+
+		s, err := scraper.New(buildCoreConfig().Scraper)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		var (
+			mu            sync.Mutex
+			wg            sync.WaitGroup
+			done, skipped int
+			firstErr      error
+			numbers       = make(chan int64)
+			stop          = make(chan struct{})
+			stopOnce      sync.Once
+		)
+
+		for i := 0; i < viper.GetInt("core.workers"); i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				for number := range numbers {
+					err := reindexBlock(s, backend, name, number)
+
+					mu.Lock()
+					switch err {
+					case nil:
+						done++
+						if done%reindexProgressInterval == 0 {
+							log.WithField("block", number).Infof("reindexed %d blocks", done)
+						}
+					case data.ErrBlockNotStored, data.ErrBlockMismatch:
+						skipped++
+						log.WithField("block", number).Warn(err)
+					default:
+						if firstErr == nil {
+							firstErr = fmt.Errorf("could not reindex block %d: %s", number, err)
+						}
+						stopOnce.Do(func() { close(stop) })
+					}
+					mu.Unlock()
+				}
+			}()
+		}
+
+	feed:
+		for _, number := range blocks {
+			select {
+			case numbers <- number:
+			case <-stop:
+				break feed
+			}
+		}
+		close(numbers)
+		wg.Wait()
+
+		fmt.Printf("Reindexed %s for %d blocks with version %d; %d skipped.\n", name, done, version, skipped)
+
+		if firstErr != nil {
+			log.Fatal(firstErr)
+		}
+	},
+}
+
+// reindexBlock scrapes and validates a block the same way the indexer does, then rebuilds the rows of a storable
+func reindexBlock(s *scraper.Scraper, backend storage.Backend, name string, number int64) error {
+	blk, err := s.Exec(number)
+	if err != nil {
+		return err
+	}
+
+	v := validator.New()
+	v.LoadBlock(blk.Block)
+	v.LoadUncles(blk.Uncles)
+	v.LoadReceipts(blk.Receipts)
+	if len(blk.Traces) > 0 {
+		v.LoadTraces(blk.Traces)
+	}
+
+	_, err = v.Run()
+	if err != nil {
+		return err
+	}
+
+	return blk.Reindex(backend, name)
+}
+
+// blockRanges groups ascending block numbers into ranges of consecutive blocks
+func blockRanges(blocks []int64) []data.Gap {
+	var ranges []data.Gap
+	for _, number := range blocks {
+		if len(ranges) > 0 && ranges[len(ranges)-1].To == number-1 {
+			ranges[len(ranges)-1].To = number
+			continue
+		}
+
+		ranges = append(ranges, data.Gap{From: number, To: number})
+	}
+
+	return ranges
+}
+
+func init() {
+	addDBFlags(reindexCmd)
+
+	reindexCmd.Flags().String("storable", "", "The storable to reindex, named after the table it writes to")
+	reindexCmd.Flags().Int64("from", 0, "First block to reindex")
+	reindexCmd.Flags().Int64("to", -1, "Last block to reindex, inclusive (defaults to the highest stored block)")
+	reindexCmd.Flags().Bool("stale", false, "Only reindex the blocks whose rows were produced by an older version of the storable")
+	reindexCmd.Flags().Bool("dry-run", false, "Print the ranges of blocks that would be reindexed and exit")
+	reindexCmd.Flags().Int("core.workers", 1, "The number of blocks reindexed concurrently")
+
+	reindexCmd.Flags().Bool("feature.uncles.enabled", true, "Enable/disable uncles scraping")
+	reindexCmd.Flags().Bool("feature.traces.enabled", false, "Enable/disable internal transactions scraping (requires a node with tracing APIs enabled)")
+	reindexCmd.Flags().String("feature.traces.source", "geth", "The tracing API used for internal transactions: geth or parity")
+	reindexCmd.Flags().String("eth.client.http", "", "HTTP endpoint of JSON-RPC enabled Ethereum node")
+}
@@ -2,15 +2,13 @@ package commands
 
 import (
 	"bufio"
-	"database/sql"
 	"fmt"
 	"os"
 	"strings"
 	"time"
 
-	_ "github.com/Alethio/memento/migrations"
+	"github.com/Alethio/memento/storage"
 	"github.com/go-redis/redis"
-	_ "github.com/lib/pq"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -55,30 +53,12 @@ var resetCmd = &cobra.Command{
 
 		fmt.Print("Truncating database ... ")
 
-		buildDBConnectionString()
-
-		db, err := sql.Open("postgres", viper.GetString("db.connection-string"))
-		if err != nil {
-			log.Fatal(err)
-		}
-
-		tx, err := db.Begin()
-		if err != nil {
-			log.Fatal(err)
-		}
-
-		_, err = tx.Exec(`
-		truncate table blocks restart identity;
-		truncate table uncles restart identity;
-		truncate table txs restart identity;
-		truncate table log_entries restart identity;
-		truncate table account_txs restart identity;
-		`)
+		backend, err := storage.New(buildStorageConfig())
 		if err != nil {
 			log.Fatal(err)
 		}
 
-		err = tx.Commit()
+		err = backend.Truncate()
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -10,15 +10,12 @@ import (
 
 	"github.com/Alethio/memento/api"
 
-	"github.com/Alethio/memento/scraper"
-
-	"github.com/Alethio/memento/taskmanager"
-
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
 	"github.com/Alethio/memento/core"
 	"github.com/Alethio/memento/eth/bestblock"
+	"github.com/Alethio/memento/webhook"
 )
 
 var runCmd = &cobra.Command{
@@ -27,49 +24,41 @@ var runCmd = &cobra.Command{
 	PreRun: func(cmd *cobra.Command, args []string) {
 		bindViperToDBFlags(cmd)
 		bindViperToRedisFlags(cmd)
+		bindViperToIndexerFlags(cmd)
+		bindViperToAPIFlags(cmd)
 	},
 	Run: func(cmd *cobra.Command, args []string) {
-		buildDBConnectionString()
-
 		stopChan := make(chan os.Signal, 1)
 		signal.Notify(stopChan, syscall.SIGINT)
 		signal.Notify(stopChan, syscall.SIGTERM)
 
-		c := core.New(core.Config{
-			BestBlockTracker: bestblock.Config{
-				NodeURL:      viper.GetString("eth.client.http"),
-				NodeURLWS:    viper.GetString("eth.client.ws"),
-				PollInterval: viper.GetDuration("eth.client.poll-interval"),
-			},
-			TaskManager: taskmanager.Config{
-				RedisServer:     viper.GetString("redis.server"),
-				RedisPassword:   viper.GetString("REDIS_PASSWORD"),
-				TodoList:        viper.GetString("redis.list"),
-				BackfillEnabled: viper.GetBool("feature.backfill.enabled"),
-			},
-			Scraper: scraper.Config{
-				NodeURL:      viper.GetString("eth.client.http"),
-				EnableUncles: viper.GetBool("feature.uncles.enabled"),
-			},
-			PostgresConnectionString: viper.GetString("db.connection-string"),
-			Features: core.Features{
-				Backfill: viper.GetBool("feature.backfill.enabled"),
-				Lag: core.FeatureLag{
-					Enabled: viper.GetBool("feature.lag.enabled"),
-					Value:   viper.GetInt64("feature.lag.value"),
-				},
-				Automigrate: viper.GetBool("feature.automigrate.enabled"),
-				Uncles:      viper.GetBool("feature.uncles.enabled"),
-			},
-		})
+		config := buildCoreConfig()
+		config.TaskManager.BackfillEnabled = viper.GetBool("feature.backfill.enabled")
+		config.Features.Backfill = viper.GetBool("feature.backfill.enabled")
+		config.Features.Lag = core.FeatureLag{
+			Enabled: viper.GetBool("feature.lag.enabled"),
+			Value:   viper.GetInt64("feature.lag.value"),
+		}
+		config.Features.Finality = core.FeatureFinality{
+			Wait: viper.GetString("feature.finality.wait"),
+			Mark: viper.GetBool("feature.finality.mark"),
+		}
+		config.Features.Automigrate = viper.GetBool("feature.automigrate.enabled")
+		config.Features.GapScan = core.FeatureGapScan{
+			Enabled:  viper.GetBool("feature.gap-scan.enabled"),
+			Interval: viper.GetDuration("feature.gap-scan.interval"),
+		}
+
+		switch config.Features.Finality.Wait {
+		case "", bestblock.TagSafe, bestblock.TagFinalized:
+		default:
+			log.Fatalf("invalid feature.finality.wait %q; must be one of: safe, finalized", config.Features.Finality.Wait)
+		}
+
+		c := core.New(config)
 		c.Run()
 
-		a := api.New(c, api.Config{
-			Port:           viper.GetString("api.port"),
-			DevCorsEnabled: viper.GetBool("api.dev-cors"),
-			DevCorsHost:    viper.GetString("api.dev-cors-host"),
-			EthClientURL:   viper.GetString("eth.client.http"),
-		})
+		a := api.New(c, buildAPIConfig())
 		go a.Run()
 
 		d := dashboard.New(c, dashboard.Config{
@@ -78,9 +67,25 @@ var runCmd = &cobra.Command{
 		})
 		go d.Run()
 
+		var deliverer *webhook.Deliverer
+		if viper.GetBool("webhooks.enabled") {
+			deliverer = webhook.NewDeliverer(c.DB(), webhook.Config{
+				Interval:        viper.GetDuration("webhooks.interval"),
+				Timeout:         viper.GetDuration("webhooks.timeout"),
+				MaxAttempts:     viper.GetInt("webhooks.max-attempts"),
+				RetryBackoff:    viper.GetDuration("webhooks.retry-backoff"),
+				MaxRetryBackoff: viper.GetDuration("webhooks.max-retry-backoff"),
+				BatchSize:       100,
+			})
+			go deliverer.Run()
+		}
+
 		select {
 		case <-stopChan:
 			log.Info("Got stop signal. Finishing work.")
+			if deliverer != nil {
+				deliverer.Close()
+			}
 			err := c.Close()
 			if err != nil {
 				log.Fatal(err)
@@ -93,6 +98,8 @@ var runCmd = &cobra.Command{
 func init() {
 	addDBFlags(runCmd)
 	addRedisFlags(runCmd)
+	addIndexerFlags(runCmd)
+	addAPIFlags(runCmd)
 
 	// feature flags
 	runCmd.Flags().Bool("feature.backfill.enabled", true, "Enable/disable the automatic backfilling of data")
@@ -104,31 +111,39 @@ func init() {
 	runCmd.Flags().Int64("feature.lag.value", 10, "The amount of blocks to lag behind the tip of the chain")
 	viper.BindPFlag("feature.lag.value", runCmd.Flag("feature.lag.value"))
 
+	runCmd.Flags().String("feature.finality.wait", "", "Queue new blocks only up to the safe or finalized head instead of the tip of the chain (one of: safe, finalized); the lag feature is ignored if set")
+	viper.BindPFlag("feature.finality.wait", runCmd.Flag("feature.finality.wait"))
+
+	runCmd.Flags().Bool("feature.finality.mark", false, "Update the finality status of the indexed blocks as the safe and finalized heads advance (use it to index the tip eagerly)")
+	viper.BindPFlag("feature.finality.mark", runCmd.Flag("feature.finality.mark"))
+
 	runCmd.Flags().Bool("feature.automigrate.enabled", true, "Enable/disable the automatic migrations feature")
 	viper.BindPFlag("feature.automigrate.enabled", runCmd.Flag("feature.automigrate.enabled"))
 
-	runCmd.Flags().Bool("feature.uncles.enabled", true, "Enable/disable uncles scraping")
-	viper.BindPFlag("feature.uncles.enabled", runCmd.Flag("feature.uncles.enabled"))
+	runCmd.Flags().Bool("feature.gap-scan.enabled", true, "Enable/disable the periodic scan for missing blocks, which are queued again")
+	viper.BindPFlag("feature.gap-scan.enabled", runCmd.Flag("feature.gap-scan.enabled"))
+
+	runCmd.Flags().Duration("feature.gap-scan.interval", 10*time.Minute, "The time between two scans for missing blocks")
+	viper.BindPFlag("feature.gap-scan.interval", runCmd.Flag("feature.gap-scan.interval"))
 
-	// eth
-	runCmd.Flags().String("eth.client.http", "", "HTTP endpoint of JSON-RPC enabled Ethereum node")
-	viper.BindPFlag("eth.client.http", runCmd.Flag("eth.client.http"))
+	// webhooks
+	runCmd.Flags().Bool("webhooks.enabled", true, "Enable/disable sending the queued webhook deliveries from this instance")
+	viper.BindPFlag("webhooks.enabled", runCmd.Flag("webhooks.enabled"))
 
-	runCmd.Flags().String("eth.client.ws", "", "WS endpoint of JSON-RPC enabled Ethereum node (provide this only if you want to use websocket subscription for tracking best block)")
-	viper.BindPFlag("eth.client.ws", runCmd.Flag("eth.client.ws"))
+	runCmd.Flags().Duration("webhooks.interval", time.Second, "How often the webhook delivery queue is checked")
+	viper.BindPFlag("webhooks.interval", runCmd.Flag("webhooks.interval"))
 
-	runCmd.Flags().Duration("eth.client.poll-interval", 15*time.Second, "Interval to be used for polling the Ethereum node for best block")
-	viper.BindPFlag("eth.client.poll-interval", runCmd.Flag("eth.client.poll-interval"))
+	runCmd.Flags().Duration("webhooks.timeout", 10*time.Second, "Timeout of the webhook delivery requests")
+	viper.BindPFlag("webhooks.timeout", runCmd.Flag("webhooks.timeout"))
 
-	// api
-	runCmd.Flags().String("api.port", "3001", "HTTP API port")
-	viper.BindPFlag("api.port", runCmd.Flag("api.port"))
+	runCmd.Flags().Int("webhooks.max-attempts", 8, "The number of times a webhook delivery is tried before it's marked as failed")
+	viper.BindPFlag("webhooks.max-attempts", runCmd.Flag("webhooks.max-attempts"))
 
-	runCmd.Flags().Bool("api.dev-cors", false, "Enable development cors for HTTP API")
-	viper.BindPFlag("api.dev-cors", runCmd.Flag("api.dev-cors"))
+	runCmd.Flags().Duration("webhooks.retry-backoff", 10*time.Second, "The delay before a failed webhook delivery is retried; it doubles with each attempt")
+	viper.BindPFlag("webhooks.retry-backoff", runCmd.Flag("webhooks.retry-backoff"))
 
-	runCmd.Flags().String("api.dev-cors-host", "", "Allowed host for HTTP API dev cors")
-	viper.BindPFlag("api.dev-cors-host", runCmd.Flag("api.dev-cors-host"))
+	runCmd.Flags().Duration("webhooks.max-retry-backoff", time.Hour, "The maximum delay between two attempts of a webhook delivery")
+	viper.BindPFlag("webhooks.max-retry-backoff", runCmd.Flag("webhooks.max-retry-backoff"))
 
 	// dashboard
 	runCmd.Flags().String("dashboard.port", "3000", "Memento Dashboard port")
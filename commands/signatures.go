@@ -0,0 +1,96 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Alethio/memento/abi"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var signaturesCmd = &cobra.Command{
+	Use:   "signatures",
+	Short: "Manage the function signatures used to decode the transaction payloads",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.HelpFunc()(cmd, args)
+	},
+}
+
+var signaturesImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import function signatures from a 4byte-style dump (JSON export or one signature per line)",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		bindViperToDBFlags(cmd)
+		viper.BindPFlag("file", cmd.Flag("file"))
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		f, err := os.Open(viper.GetString("file"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+
+		signatures, err := abi.ReadSignatures(f)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// the dumps contain signatures the parser doesn't accept (e.g. with unsupported types); they are skipped
+		var valid []string
+		for _, signature := range signatures {
+			if _, err := abi.ParseMethodSignature(signature); err != nil {
+				log.WithField("signature", signature).Debug("skipping invalid signature")
+				continue
+			}
+
+			valid = append(valid, signature)
+		}
+
+		backend := abiBackend()
+		defer backend.Close()
+
+		added, err := abi.SaveSignatures(backend.DB(), valid)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Printf("%d signature(s) read, %d skipped, %d added.\n", len(signatures), len(signatures)-len(valid), added)
+	},
+}
+
+var signaturesAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Add function signatures, e.g. --signature 'transfer(address,uint256)'",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		bindViperToDBFlags(cmd)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		// read straight from the flag, since signatures contain commas that viper would split on
+		signatures, err := cmd.Flags().GetStringArray("signature")
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		backend := abiBackend()
+		defer backend.Close()
+
+		added, err := abi.SaveSignatures(backend.DB(), signatures)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Printf("%d signature(s) added.\n", added)
+	},
+}
+
+func init() {
+	addDBFlags(signaturesImportCmd)
+	addDBFlags(signaturesAddCmd)
+
+	signaturesImportCmd.Flags().String("file", "", "Path of the dump file")
+	signaturesAddCmd.Flags().StringArray("signature", nil, "Function signature; can be repeated")
+
+	signaturesCmd.AddCommand(signaturesImportCmd)
+	signaturesCmd.AddCommand(signaturesAddCmd)
+}
@@ -0,0 +1,134 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Alethio/memento/scraper"
+	"github.com/Alethio/memento/storage"
+	"github.com/Alethio/memento/verify"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check the indexed blocks for gaps, reorged blocks and missing rows",
+	Long: `Check the blocks between --from and --to (the highest stored block by default) and print a report.
+
+A block is reported if it's missing, stored more than once or, when a node is configured, if its hash doesn't match the
+node's canonical block. A stored block is also reported if its transactions, log entries, uncles (unless
+--feature.uncles.enabled=false) or account transactions don't add up to the counts it was stored with.
+
+With --queue, the reported blocks are deleted and added to the todo queue so the indexer stores them again from
+scratch. The command exits with status 1 if any block is reported.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		bindViperToDBFlags(cmd)
+		bindViperToRedisFlags(cmd)
+		viper.BindPFlag("from", cmd.Flag("from"))
+		viper.BindPFlag("to", cmd.Flag("to"))
+		viper.BindPFlag("queue", cmd.Flag("queue"))
+		viper.BindPFlag("feature.uncles.enabled", cmd.Flag("feature.uncles.enabled"))
+		viper.BindPFlag("eth.client.http", cmd.Flag("eth.client.http"))
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		backend := abiBackend()
+		defer backend.Close()
+
+		from := viper.GetInt64("from")
+		to := viper.GetInt64("to")
+		if to < 0 {
+			highest, err := backend.HighestBlock()
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			to = highest
+		}
+
+		config := verify.Config{
+			Uncles: viper.GetBool("feature.uncles.enabled"),
+		}
+
+		if url := viper.GetString("eth.client.http"); url != "" {
+			s, err := scraper.New(scraper.Config{NodeURL: url})
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			config.Node = s
+		} else {
+			log.Warn("no node configured; the block hashes won't be checked")
+		}
+
+		report, err := verify.New(backend.DB(), config).Run(from, to)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		for _, p := range report.Problems {
+			fmt.Println(p)
+		}
+
+		blocks := report.Blocks()
+		fmt.Printf("Checked blocks %d to %d: %d stored, %d with problems.\n", report.From, report.To, report.Stored, len(blocks))
+
+		if len(blocks) == 0 {
+			return
+		}
+
+		if viper.GetBool("queue") {
+			r := redisClient()
+			list := viper.GetString("redis.list")
+
+			for _, number := range blocks {
+				// the indexer skips the blocks that are already stored
+				err := deleteBlock(backend, number)
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				err = addTodo(r, list, number)
+				if err != nil {
+					log.Fatal(err)
+				}
+			}
+
+			fmt.Printf("Queued %d blocks for reprocessing.\n", len(blocks))
+		}
+
+		os.Exit(1)
+	},
+}
+
+func deleteBlock(backend storage.Backend, number int64) error {
+	tx, err := backend.Begin()
+	if err != nil {
+		return err
+	}
+
+	err = backend.LockBlock(tx, number)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = backend.DeleteBlock(tx, number)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func init() {
+	addDBFlags(verifyCmd)
+	addRedisFlags(verifyCmd)
+
+	verifyCmd.Flags().Int64("from", 0, "First block to check")
+	verifyCmd.Flags().Int64("to", -1, "Last block to check, inclusive (defaults to the highest stored block)")
+	verifyCmd.Flags().Bool("queue", false, "Add the blocks with problems to the todo queue")
+	verifyCmd.Flags().Bool("feature.uncles.enabled", true, "Check the uncles (disable it if the uncles are not scraped)")
+	verifyCmd.Flags().String("eth.client.http", "", "HTTP endpoint of the node the block hashes are checked against (optional)")
+}
@@ -0,0 +1,174 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Alethio/memento/webhook"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var webhooksCmd = &cobra.Command{
+	Use:   "webhooks",
+	Short: "Manage the webhooks notified of the activity of addresses and events",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.HelpFunc()(cmd, args)
+	},
+}
+
+var webhooksAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Add a webhook for the transactions of an address or the log entries with a given topic 0",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		bindViperToDBFlags(cmd)
+		viper.BindPFlag("url", cmd.Flag("url"))
+		viper.BindPFlag("address", cmd.Flag("address"))
+		viper.BindPFlag("topic0", cmd.Flag("topic0"))
+		viper.BindPFlag("description", cmd.Flag("description"))
+		viper.BindPFlag("secret", cmd.Flag("secret"))
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		w := &webhook.Webhook{
+			URL:         viper.GetString("url"),
+			Address:     viper.GetString("address"),
+			Topic0:      viper.GetString("topic0"),
+			Description: viper.GetString("description"),
+			Secret:      viper.GetString("secret"),
+		}
+
+		backend := abiBackend()
+		defer backend.Close()
+
+		err := webhook.Create(backend.DB(), w)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Printf("Webhook %s added.\nSecret: %s\n", w.ID, w.Secret)
+	},
+}
+
+var webhooksListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the webhooks",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		bindViperToDBFlags(cmd)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		backend := abiBackend()
+		defer backend.Close()
+
+		webhooks, err := webhook.List(backend.DB())
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if len(webhooks) == 0 {
+			fmt.Println("There are no webhooks.")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tURL\tADDRESS\tTOPIC0\tDESCRIPTION\tADDED AT")
+		for _, hook := range webhooks {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", hook.ID, hook.URL, prefixed(hook.Address), prefixed(hook.Topic0), hook.Description, hook.CreatedAt.Format(time.RFC3339))
+		}
+		w.Flush()
+	},
+}
+
+var webhooksRemoveCmd = &cobra.Command{
+	Use:   "remove",
+	Short: "Remove a webhook and its delivery log",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		bindViperToDBFlags(cmd)
+		viper.BindPFlag("id", cmd.Flag("id"))
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		backend := abiBackend()
+		defer backend.Close()
+
+		id := viper.GetString("id")
+		deleted, err := webhook.Delete(backend.DB(), id)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if !deleted {
+			fmt.Printf("There is no webhook %s.\n", id)
+			return
+		}
+
+		fmt.Printf("Webhook %s removed.\n", id)
+	},
+}
+
+var webhooksDeliveriesCmd = &cobra.Command{
+	Use:   "deliveries",
+	Short: "Show the latest deliveries of a webhook",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		bindViperToDBFlags(cmd)
+		viper.BindPFlag("id", cmd.Flag("id"))
+		viper.BindPFlag("status", cmd.Flag("status"))
+		viper.BindPFlag("limit", cmd.Flag("limit"))
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		backend := abiBackend()
+		defer backend.Close()
+
+		deliveries, err := webhook.Deliveries(backend.DB(), viper.GetString("id"), viper.GetString("status"), "", viper.GetInt("limit"))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if len(deliveries) == 0 {
+			fmt.Println("There are no deliveries.")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tKIND\tBLOCK\tTX\tSTATUS\tATTEMPTS\tCODE\tERROR")
+		for _, d := range deliveries {
+			code := "-"
+			if d.ResponseCode != nil {
+				code = fmt.Sprint(*d.ResponseCode)
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%d\t0x%s\t%s\t%d\t%s\t%s\n", d.ID, d.Kind, d.BlockNumber, d.TxHash, d.Status, d.Attempts, code, d.Error)
+		}
+		w.Flush()
+	},
+}
+
+func prefixed(hex string) string {
+	if hex == "" {
+		return "-"
+	}
+
+	return "0x" + hex
+}
+
+func init() {
+	addDBFlags(webhooksAddCmd)
+	addDBFlags(webhooksListCmd)
+	addDBFlags(webhooksRemoveCmd)
+	addDBFlags(webhooksDeliveriesCmd)
+
+	webhooksAddCmd.Flags().String("url", "", "URL the notifications are POSTed to")
+	webhooksAddCmd.Flags().String("address", "", "Address whose transactions are watched, or which emits the watched event if topic0 is set")
+	webhooksAddCmd.Flags().String("topic0", "", "Topic 0 of the watched log entries")
+	webhooksAddCmd.Flags().String("description", "", "Optional description of the webhook")
+	webhooksAddCmd.Flags().String("secret", "", "Secret the notifications are signed with (generated if empty)")
+	webhooksRemoveCmd.Flags().String("id", "", "ID of the webhook")
+	webhooksDeliveriesCmd.Flags().String("id", "", "ID of the webhook")
+	webhooksDeliveriesCmd.Flags().String("status", "", "Only show the deliveries with this status (pending, delivered or failed)")
+	webhooksDeliveriesCmd.Flags().Int("limit", 20, "Number of deliveries to show")
+
+	webhooksCmd.AddCommand(webhooksAddCmd)
+	webhooksCmd.AddCommand(webhooksListCmd)
+	webhooksCmd.AddCommand(webhooksRemoveCmd)
+	webhooksCmd.AddCommand(webhooksDeliveriesCmd)
+}
@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Alethio/memento/core"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var workerCmd = &cobra.Command{
+	Use:   "worker",
+	Short: "Process blocks from the shared todo queue without producing tasks",
+	Long: `Only process blocks from the shared todo queue.
+
+A worker doesn't add blocks to the queue, doesn't backfill and doesn't run migrations, the api or the dashboard; all of
+these are the job of the leader, which is elected among the instances started with "memento run". Any number of workers
+can be started against the same redis and database to spread the processing of blocks. Blocks being processed are leased,
+so a block held by a worker that dies is re-queued once its lease expires. The events of the blocks a worker stores are
+relayed through redis to the live feed of the instances started with "memento run". The metrics of the worker are
+served in the Prometheus format on /metrics at metrics.port.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		bindViperToDBFlags(cmd)
+		bindViperToRedisFlags(cmd)
+		bindViperToIndexerFlags(cmd)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		stopChan := make(chan os.Signal, 1)
+		signal.Notify(stopChan, syscall.SIGINT)
+		signal.Notify(stopChan, syscall.SIGTERM)
+
+		config := buildCoreConfig()
+		config.TaskManager.ConsumerOnly = true
+
+		c := core.New(config)
+		c.Run()
+
+		go func() {
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", c.Metrics())
+
+			err := http.ListenAndServe(":"+viper.GetString("metrics.port"), mux)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}()
+
+		<-stopChan
+		log.Info("Got stop signal. Finishing work.")
+		err := c.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Info("Work done. Goodbye!")
+	},
+}
+
+func init() {
+	addDBFlags(workerCmd)
+	addRedisFlags(workerCmd)
+	addIndexerFlags(workerCmd)
+
+	workerCmd.Flags().String("metrics.port", "3002", "Port on which the metrics of the worker are served to Prometheus")
+	viper.BindPFlag("metrics.port", workerCmd.Flag("metrics.port"))
+}
@@ -28,25 +28,7 @@ func (c *Core) Reset() error {
 		return err
 	}
 
-	tx, err := c.db.Begin()
-	if err != nil {
-		log.Error(err)
-		return err
-	}
-
-	_, err = tx.Exec(`
-		truncate table blocks restart identity;
-		truncate table uncles restart identity;
-		truncate table txs restart identity;
-		truncate table log_entries restart identity;
-		truncate table account_txs restart identity;
-		`)
-	if err != nil {
-		log.Error(err)
-		return err
-	}
-
-	err = tx.Commit()
+	err = c.storage.Truncate()
 	if err != nil {
 		log.Error(err)
 		return err
@@ -1,13 +1,15 @@
 package core
 
 import (
-	"database/sql"
 	"sync"
 	"time"
 
+	"github.com/Alethio/memento/data"
+	"github.com/Alethio/memento/data/storable"
+	"github.com/Alethio/memento/feed"
 	"github.com/Alethio/memento/metrics"
-
-	"github.com/pressly/goose"
+	"github.com/Alethio/memento/storage"
+	"github.com/Alethio/memento/webhook"
 
 	"github.com/alethio/web3-go/validator"
 
@@ -15,11 +17,16 @@ import (
 	"github.com/Alethio/memento/taskmanager"
 
 	"github.com/Alethio/memento/eth/bestblock"
+	"github.com/go-redis/redis"
 	"github.com/sirupsen/logrus"
 )
 
 var log = logrus.WithField("module", "core")
 
+// indexedHeadInterval is the time between two reads of the highest stored block, which keep the indexed head and lag
+// metrics right when the blocks are stored or rolled back by other processes
+const indexedHeadInterval = 15 * time.Second
+
 type Core struct {
 	config Config
 
@@ -27,9 +34,13 @@ type Core struct {
 	bbtracker   *bestblock.Tracker
 	taskmanager *taskmanager.Manager
 	scraper     *scraper.Scraper
-	db          *sql.DB
+	storage     storage.Backend
+	feed        *feed.Hub
+	relay       *feed.Relay
+	rewards     []storable.BlockReward
 
-	stopMu sync.Mutex
+	stopMu sync.RWMutex
+	closed bool
 }
 
 func New(config Config) *Core {
@@ -60,6 +71,12 @@ func New(config Config) *Core {
 		lag = config.Features.Lag.Value
 	}
 
+	if config.Features.Finality.Wait != "" && !bbtracker.SupportsFinality() {
+		log.Fatalf("cannot wait for %s blocks: the node doesn't support the finality block tags", config.Features.Finality.Wait)
+	}
+
+	config.TaskManager.Finality = config.Features.Finality.Wait
+
 	tm, err := taskmanager.New(bbtracker, lag, m, config.TaskManager)
 	if err != nil {
 		log.Fatal("could not start task manager")
@@ -70,27 +87,35 @@ func New(config Config) *Core {
 		log.Fatal("could not start scraper")
 	}
 
-	log.Info("connecting to postgres")
-	db, err := sql.Open("postgres", config.PostgresConnectionString)
+	rewards, err := blockRewards(config.Features.BlockRewards, s)
 	if err != nil {
-		log.Fatal(err)
+		log.Fatal("could not set up the block rewards: ", err)
 	}
 
-	err = db.Ping()
+	backend, err := storage.New(config.Storage)
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	if config.Features.Automigrate {
 		log.Info("attempting automatic execution of migrations")
-		err = goose.Up(db, "/")
-		if err != nil && err != goose.ErrNoNextVersion {
+		err = backend.Migrate()
+		if err != nil {
 			log.Fatal(err)
 		}
 		log.Info("database version is up to date")
 	}
 
-	log.Info("connected to postgres successfuly")
+	// the events go through redis, which all the instances already share, so the live feed of the instance serving the
+	// API also covers the blocks processed by the workers
+	hub := feed.NewHub()
+	relay, err := feed.NewRelay(hub, redis.NewClient(&redis.Options{
+		Addr:     config.TaskManager.RedisServer,
+		Password: config.TaskManager.RedisPassword,
+	}), config.TaskManager.TodoList+":feed")
+	if err != nil {
+		log.Fatal("could not start the feed relay: ", err)
+	}
 
 	return &Core{
 		config:      config,
@@ -98,7 +123,10 @@ func New(config Config) *Core {
 		bbtracker:   bbtracker,
 		taskmanager: tm,
 		scraper:     s,
-		db:          db,
+		storage:     backend,
+		feed:        hub,
+		relay:       relay,
+		rewards:     rewards,
 	}
 }
 
@@ -112,24 +140,25 @@ func (c *Core) Run() {
 	}()
 
 	go func() {
-		max, err := c.getHighestBlock()
+		max, err := c.storage.HighestBlock()
 		if err != nil {
 			log.Fatal("could not get highest block from db:", err)
 		}
 
 		log.WithField("block", max).Info("got highest block from db")
+		c.metrics.RecordIndexedBlock(max)
 
 		best := c.bbtracker.BestBlock()
 
 		log.WithField("block", best).Info("got highest block from network")
 
-		if c.config.Features.Backfill {
-			var lag int64
-			if c.config.Features.Lag.Enabled {
-				lag = c.config.Features.Lag.Value
-			}
+		if !c.taskmanager.IsLeader() {
+			log.Info("skipping backfilling since this instance is not the leader")
+			return
+		}
 
-			backfillTarget := best - lag
+		if c.config.Features.Backfill {
+			backfillTarget := c.taskmanager.Target(best)
 
 			if max+1 < backfillTarget {
 				log.Infof("adding tasks for %d blocks to be backfilled", backfillTarget-max+1)
@@ -145,77 +174,217 @@ func (c *Core) Run() {
 		}
 	}()
 
+	go c.refreshIndexedHead()
+
+	if c.config.Features.Finality.Mark {
+		go c.markFinality()
+	}
+
+	if c.config.Features.GapScan.Enabled {
+		go c.scanGaps()
+	}
+
 	go c.taskmanager.FeedToChan(blockChan)
 
-	go func() {
-		for b := range blockChan {
-			c.stopMu.Lock()
-			log := log.WithField("block", b)
-			log.Info("processing block")
+	workers := c.config.Workers
+	if workers < 1 {
+		workers = 1
+	}
 
-			start := time.Now()
-			blk, err := c.scraper.Exec(b)
-			if err != nil {
-				c.stopMu.Unlock()
-				err1 := c.taskmanager.Todo(b)
-				if err1 != nil {
-					log.Fatal(err1)
-				}
-				time.Sleep(2 * time.Second)
-				continue
-			}
+	log.Infof("starting %d block processing workers", workers)
+	for i := 0; i < workers; i++ {
+		go c.work(blockChan)
+	}
+}
 
-			c.metrics.RecordScrapingTime(time.Since(start))
+// refreshIndexedHead periodically sets the indexed head to the highest block in the database; the blocks stored by
+// this process move it right away, but the ones stored by the workers and the leader only show up this way
+func (c *Core) refreshIndexedHead() {
+	ticker := time.NewTicker(indexedHeadInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		c.stopMu.RLock()
+		if c.closed {
+			c.stopMu.RUnlock()
+			return
+		}
 
-			log.Debug("validating block")
-			v := validator.New()
-			v.LoadBlock(blk.Block)
-			v.LoadUncles(blk.Uncles)
-			v.LoadReceipts(blk.Receipts)
+		highest, err := c.storage.HighestBlock()
+		if err != nil {
+			log.Error("could not get highest block from db: ", err)
+		} else {
+			c.metrics.SetIndexedHead(highest)
+		}
+		c.stopMu.RUnlock()
+	}
+}
 
-			_, err = v.Run()
+// work consumes blocks from the provided channel and runs each of them through the scrape -> validate -> store pipeline
+// Multiple workers can run at the same time; the stopMu read lock is held while a block is in-flight so Close can
+// wait for all of them to finish their current block
+func (c *Core) work(blockChan chan int64) {
+	for b := range blockChan {
+		c.stopMu.RLock()
+		if c.closed {
+			c.stopMu.RUnlock()
+
+			// give the block back instead of waiting for its lease to expire
+			err := c.taskmanager.Release(b)
 			if err != nil {
-				c.stopMu.Unlock()
-				c.metrics.RecordInvalidBlock()
-				log.Error("error validating block: ", err)
-				err1 := c.taskmanager.Todo(b)
-				if err1 != nil {
-					log.Fatal(err1)
-				}
-				continue
+				log.Error(err)
 			}
-			log.Debug("block is valid")
+			return
+		}
 
-			log.Debug("storing block into the database")
+		stopLease := c.taskmanager.KeepLease(b)
+		err := c.process(b)
+		stopLease()
 
-			indexingStart := time.Now()
-			blk.RegisterStorables()
-			err = blk.Store(c.db, c.metrics)
+		if err != nil {
+			c.fail(b, err)
+		} else {
+			err = c.taskmanager.Done(b)
 			if err != nil {
-				c.stopMu.Unlock()
-				log.Error("error storing block: ", err)
-				err1 := c.taskmanager.Todo(b)
-				if err1 != nil {
-					log.Fatal(err1)
-				}
-				continue
+				log.Error(err)
 			}
-			c.metrics.RecordIndexingTime(time.Since(indexingStart))
-			c.metrics.RecordProcessingTime(time.Since(start))
-			log.WithField("duration", time.Since(start)).Info("done processing block")
-			c.stopMu.Unlock()
 		}
-	}()
+
+		c.stopMu.RUnlock()
+	}
+}
+
+// process scrapes, validates and stores a single block
+func (c *Core) process(b int64) error {
+	log := log.WithField("block", b)
+	log.Info("processing block")
+
+	start := time.Now()
+	blk, err := c.scraper.Exec(b)
+	if err != nil {
+		return err
+	}
+
+	c.metrics.RecordScrapingTime(time.Since(start))
+
+	log.Debug("validating block")
+	v := validator.New()
+	v.LoadBlock(blk.Block)
+	v.LoadUncles(blk.Uncles)
+	v.LoadReceipts(blk.Receipts)
+	if len(blk.Traces) > 0 {
+		v.LoadTraces(blk.Traces)
+	}
+
+	_, err = v.Run()
+	if err != nil {
+		c.metrics.RecordInvalidBlock()
+		log.Error("error validating block: ", err)
+		return err
+	}
+	log.Debug("block is valid")
+
+	log.Debug("storing block into the database")
+
+	indexingStart := time.Now()
+	blk.Finality = c.finality(b)
+	blk.BlockRewards = c.rewards
+	blk.RegisterStorables()
+
+	// the webhook deliveries are queued along with the block, and the events relayed to the feed once it's committed
+	var events []feed.Event
+	blk.OnStore = func(tx storage.Tx) error {
+		var err error
+		events, err = storedBlockEvents(blk)
+		if err != nil {
+			return err
+		}
+
+		return webhook.Enqueue(tx, events)
+	}
+
+	err = blk.Store(c.storage, c.metrics)
+	if reorgErr, ok := err.(*data.ReorgError); ok {
+		log.Warn(reorgErr)
+		return c.rollback(b, blk.Block.Hash)
+	}
+	if childErr, ok := err.(*data.StaleChildError); ok {
+		log.Warn(childErr)
+		return c.rollbackStaleChildren(b, blk.Block.Hash)
+	}
+	if err != nil {
+		log.Error("error storing block: ", err)
+		return err
+	}
+
+	c.metrics.RecordIndexingTime(time.Since(indexingStart))
+	c.metrics.RecordProcessingTime(time.Since(start))
+	log.WithField("duration", time.Since(start)).Info("done processing block")
+
+	// nothing was stored, and no events produced, for a block that was already there
+	c.relay.Publish(events...)
+
+	return nil
+}
+
+// storedBlockEvents returns the events describing a block being stored, preceded by the reorg of the block it replaced
+func storedBlockEvents(blk *data.FullBlock) ([]feed.Event, error) {
+	var events []feed.Event
+	if reorg := blk.Replaced(); reorg != nil {
+		events = append(events, feed.ReorgEvent(*reorg))
+	}
+
+	blockEvents, err := feed.BlockEvents(blk)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(events, blockEvents...), nil
+}
+
+// blockRewards resolves the block reward schedule of the chain from its setting
+func blockRewards(setting string, s *scraper.Scraper) ([]storable.BlockReward, error) {
+	if setting != BlockRewardsAuto {
+		return storable.ParseBlockRewards(setting)
+	}
+
+	chainID, err := s.ChainID()
+	if err != nil {
+		return nil, err
+	}
+
+	if chainID == 1 {
+		return storable.MainnetBlockRewards, nil
+	}
+
+	log.WithField("chain", chainID).Warn("the block reward schedule of the chain is unknown; the balances of its miners won't include the rewards")
+	return nil, nil
+}
+
+// fail hands a block that could not be processed back to the task manager, which decides when to retry it
+func (c *Core) fail(b int64, cause error) {
+	err := c.taskmanager.Fail(b, cause)
+	if err != nil {
+		log.Fatal(err)
+	}
 }
 
 func (c *Core) Close() error {
 	c.stopMu.Lock()
 	defer c.stopMu.Unlock()
 
+	c.closed = true
+
 	c.bbtracker.Close()
 	log.Info("closed best block tracker")
 
-	err := c.db.Close()
+	err := c.relay.Close()
+	if err != nil {
+		log.Error(err)
+	}
+	log.Info("closed feed relay")
+
+	err = c.storage.Close()
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,65 @@
+package core
+
+import (
+	"time"
+
+	"github.com/Alethio/memento/data"
+	"github.com/Alethio/memento/data/storable"
+)
+
+// finality returns the status a block is stored with, based on the safe and finalized heads currently known
+func (c *Core) finality(b int64) string {
+	if !c.bbtracker.SupportsFinality() {
+		return storable.FinalityLatest
+	}
+
+	switch {
+	case b <= c.bbtracker.FinalizedBlock():
+		return storable.FinalityFinalized
+	case b <= c.bbtracker.SafeBlock():
+		return storable.FinalitySafe
+	}
+
+	return storable.FinalityLatest
+}
+
+// markFinality updates the status of the blocks stored ahead of the finalized head as the safe and finalized heads
+// advance; only the leader does it, since it's the same update for all the instances
+func (c *Core) markFinality() {
+	ticker := time.NewTicker(c.config.BestBlockTracker.PollInterval)
+	defer ticker.Stop()
+
+	var lastSafe, lastFinalized int64
+	for range ticker.C {
+		// the read lock is held during the update, like for a block in-flight, so Close doesn't close the db under it
+		c.stopMu.RLock()
+		if c.closed {
+			c.stopMu.RUnlock()
+			return
+		}
+
+		if c.taskmanager.IsLeader() && c.bbtracker.SupportsFinality() {
+			lastSafe, lastFinalized = c.updateFinality(lastSafe, lastFinalized)
+		}
+		c.stopMu.RUnlock()
+	}
+}
+
+// updateFinality marks the stored blocks with the current safe and finalized heads if they moved since the last
+// update and returns the heads the blocks are marked with
+func (c *Core) updateFinality(lastSafe, lastFinalized int64) (int64, int64) {
+	safe := c.bbtracker.SafeBlock()
+	finalized := c.bbtracker.FinalizedBlock()
+	if safe == lastSafe && finalized == lastFinalized {
+		return lastSafe, lastFinalized
+	}
+
+	marked, err := data.MarkFinality(c.storage, safe, finalized)
+	if err != nil {
+		log.Error("could not mark blocks as final: ", err)
+		return lastSafe, lastFinalized
+	}
+
+	log.WithField("safe", safe).WithField("finalized", finalized).Debugf("updated the finality status of %d blocks", marked)
+	return safe, finalized
+}
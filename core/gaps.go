@@ -0,0 +1,83 @@
+package core
+
+import (
+	"time"
+
+	"github.com/Alethio/memento/data"
+)
+
+// maxGapBlocksPerScan bounds the number of missing blocks queued by a single scan, so a large hole (e.g. a database
+// restored from an old backup) is filled progressively instead of flooding the queue
+const maxGapBlocksPerScan = 10000
+
+// scanGaps periodically looks for the blocks missing below the highest stored block and queues the ones the task
+// manager lost track of, e.g. because they were popped right before a crash
+// Without backfilling the chain is only indexed from the first stored block, so nothing below it counts as missing
+func (c *Core) scanGaps() {
+	ticker := time.NewTicker(c.config.Features.GapScan.Interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		// the read lock is held during the scan, like for a block in-flight, so Close doesn't close the db under it
+		c.stopMu.RLock()
+		if c.closed {
+			c.stopMu.RUnlock()
+			return
+		}
+
+		if c.taskmanager.IsLeader() {
+			err := c.healGaps()
+			if err != nil {
+				log.Error("could not scan for gaps: ", err)
+			}
+		}
+		c.stopMu.RUnlock()
+	}
+}
+
+func (c *Core) healGaps() error {
+	start := time.Now()
+
+	var from int64
+	if !c.config.Features.Backfill {
+		from = -1
+	}
+
+	gaps, err := data.Gaps(c.storage.DB(), from)
+	if err != nil {
+		return err
+	}
+
+	var missing []int64
+	var count int64
+	for _, gap := range gaps {
+		count += gap.Size()
+
+		for number := gap.From; number <= gap.To && len(missing) < maxGapBlocksPerScan; number++ {
+			missing = append(missing, number)
+		}
+	}
+
+	untracked, err := c.taskmanager.Untracked(missing)
+	if err != nil {
+		return err
+	}
+
+	for _, number := range untracked {
+		err = c.taskmanager.Todo(number)
+		if err != nil {
+			return err
+		}
+	}
+
+	c.metrics.RecordGaps(int64(len(gaps)), count, int64(len(untracked)))
+
+	log := log.WithField("gaps", len(gaps)).WithField("missing", count).WithField("duration", time.Since(start))
+	if len(untracked) > 0 {
+		log.WithField("queued", len(untracked)).Warn("queued missing blocks")
+	} else {
+		log.Debug("done scanning for gaps")
+	}
+
+	return nil
+}
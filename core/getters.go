@@ -3,13 +3,33 @@ package core
 import (
 	"database/sql"
 
+	"github.com/Alethio/memento/feed"
 	"github.com/Alethio/memento/metrics"
+	"github.com/Alethio/memento/storage"
+	"github.com/Alethio/memento/taskmanager"
 )
 
 func (c *Core) DB() *sql.DB {
-	return c.db
+	return c.storage.DB()
+}
+
+func (c *Core) Storage() storage.Backend {
+	return c.storage
 }
 
 func (c *Core) Metrics() *metrics.Provider {
 	return c.metrics
 }
+
+func (c *Core) FailedQueue() *taskmanager.FailedQueue {
+	return c.taskmanager.Failed()
+}
+
+func (c *Core) Feed() *feed.Hub {
+	return c.feed
+}
+
+// LatestBlock returns the best block of the network, as last seen by the best block tracker
+func (c *Core) LatestBlock() int64 {
+	return c.metrics.GetLatestBLock()
+}
@@ -0,0 +1,115 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/Alethio/memento/data"
+	"github.com/Alethio/memento/data/storable"
+	"github.com/Alethio/memento/feed"
+	"github.com/Alethio/memento/storage"
+	"github.com/Alethio/memento/webhook"
+)
+
+// maxReorgDepth is how far back the stored chain is compared with the node's before giving up on finding the fork point
+const maxReorgDepth = 1000
+
+// rollback handles a block that doesn't link to the stored chain
+// It walks back from the parent of the block until the stored hashes match the ones of the node's canonical chain,
+// removes everything above that point and queues the removed blocks (and the current one) to be processed again
+func (c *Core) rollback(b int64, newHash string) error {
+	log := log.WithField("block", b)
+	log.Warn("parent hash mismatch; looking for the fork point")
+
+	fork := int64(-1)
+	for number := b - 1; number >= 0 && number >= b-maxReorgDepth; number-- {
+		stored, exists, err := data.StoredBlockHash(c.storage.DB(), number)
+		if err != nil {
+			return err
+		}
+
+		// nothing stored at this height, so there's nothing left to roll back
+		if !exists {
+			fork = number
+			break
+		}
+
+		canonical, err := c.scraper.BlockHash(number)
+		if err != nil {
+			return err
+		}
+
+		if storable.Trim0x(canonical) == stored {
+			fork = number
+			break
+		}
+	}
+
+	if fork < 0 {
+		return fmt.Errorf("could not find the fork point within %d blocks", maxReorgDepth)
+	}
+
+	return c.rollbackTo(b, fork, newHash)
+}
+
+// rollbackStaleChildren handles a block whose stored child doesn't link to it
+// The block's parent already matched, so everything stored from the block up comes from the old chain
+func (c *Core) rollbackStaleChildren(b int64, newHash string) error {
+	log.WithField("block", b).Warn("stored child doesn't link to the block; rolling back the blocks above its parent")
+
+	return c.rollbackTo(b, b-1, newHash)
+}
+
+// rollbackTo removes everything stored above the fork point and queues the removed blocks (and the current one) to
+// be processed again
+func (c *Core) rollbackTo(b, fork int64, newHash string) error {
+	log := log.WithField("block", b)
+
+	reorg := &data.Reorg{
+		ForkBlock:    fork,
+		NewBlockHash: storable.Trim0x(newHash),
+	}
+
+	// the removed deliveries are queued along with the reorg record, so they can't be lost once the blocks are gone
+	err := data.Rollback(c.storage, reorg, func(tx storage.Tx, r data.Reorg) error {
+		return webhook.Enqueue(tx, []feed.Event{feed.ReorgEvent(r)})
+	})
+	if err != nil {
+		return err
+	}
+
+	if reorg.Depth() == 0 {
+		// the stored chain already agrees with the node (e.g. another worker rolled it back in the meantime); let the
+		// block go through the regular retry mechanism
+		return fmt.Errorf("stored chain below block %d changed while rolling back", b)
+	}
+
+	c.metrics.RecordReorg(reorg.Depth())
+	for i := int64(0); i < reorg.Depth(); i++ {
+		c.metrics.RecordReorgedBlock()
+	}
+
+	// the indexed head (and the lag computed from it) follows the stored chain back down
+	highest, err := c.storage.HighestBlock()
+	if err != nil {
+		log.Error("could not get highest block from db: ", err)
+	} else {
+		c.metrics.SetIndexedHead(highest)
+	}
+
+	log.WithField("fork", reorg.ForkBlock).WithField("depth", reorg.Depth()).Warn("rolled back reorged blocks")
+	c.relay.Publish(feed.ReorgEvent(*reorg))
+
+	head := reorg.HeadBlock
+	if head < b {
+		head = b
+	}
+
+	for number := fork + 1; number <= head; number++ {
+		err = c.taskmanager.Todo(number)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
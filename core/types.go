@@ -1,27 +1,59 @@
 package core
 
 import (
+	"time"
+
 	"github.com/Alethio/memento/eth/bestblock"
 	"github.com/Alethio/memento/scraper"
+	"github.com/Alethio/memento/storage"
 	"github.com/Alethio/memento/taskmanager"
 )
 
 type Features struct {
 	Backfill    bool
 	Lag         FeatureLag
+	Finality    FeatureFinality
 	Automigrate bool
 	Uncles      bool
+	Traces      bool
+	GapScan     FeatureGapScan
+
+	// BlockRewards is the block reward schedule the balance changes are computed with: BlockRewardsAuto, or anything
+	// accepted by storable.ParseBlockRewards
+	BlockRewards string
 }
 
+// BlockRewardsAuto uses the mainnet block reward schedule on mainnet and none on the other chains
+const BlockRewardsAuto = "auto"
+
 type FeatureLag struct {
 	Enabled bool
 	Value   int64
 }
 
+type FeatureGapScan struct {
+	Enabled bool
+
+	// Interval is the time between two scans of the blocks table
+	Interval time.Duration
+}
+
+type FeatureFinality struct {
+	// Wait is the head new blocks are queued up to: bestblock.TagSafe, bestblock.TagFinalized or empty to follow the
+	// tip of the chain
+	Wait string
+
+	// Mark enables updating the finality status of the stored blocks as the safe and finalized heads advance
+	Mark bool
+}
+
 type Config struct {
-	BestBlockTracker         bestblock.Config
-	TaskManager              taskmanager.Config
-	Scraper                  scraper.Config
-	PostgresConnectionString string
-	Features                 Features
+	BestBlockTracker bestblock.Config
+	TaskManager      taskmanager.Config
+	Scraper          scraper.Config
+	Storage          storage.Config
+	Features         Features
+
+	// Workers is the number of blocks that are scraped, validated and stored concurrently
+	Workers int
 }
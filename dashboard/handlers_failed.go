@@ -0,0 +1,71 @@
+package dashboard
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (d *Dashboard) FailedHandler(c *gin.Context) {
+	var errors []string
+
+	blocks, err := d.core.FailedQueue().List()
+	if err != nil {
+		errors = append(errors, fmt.Sprintf("Could not get failed blocks: %s", err))
+	}
+
+	d.sendResponse(c, "failed", gin.H{
+		"errors": errors,
+		"blocks": blocks,
+	})
+}
+
+func (d *Dashboard) FailedPostHandler(c *gin.Context) {
+	var errors []string
+	var success []string
+
+	defer func() {
+		blocks, err := d.core.FailedQueue().List()
+		if err != nil {
+			errors = append(errors, fmt.Sprintf("Could not get failed blocks: %s", err))
+		}
+
+		d.sendResponse(c, "failed", gin.H{
+			"errors":  errors,
+			"success": success,
+			"blocks":  blocks,
+		})
+	}()
+
+	switch c.PostForm("action") {
+	case "retry":
+		var blocks []int64
+		if block := c.PostForm("block"); block != "" {
+			blockInt, err := strconv.ParseInt(block, 10, 64)
+			if err != nil {
+				errors = append(errors, "Block number must be numeric!")
+				return
+			}
+			blocks = append(blocks, blockInt)
+		}
+
+		count, err := d.core.FailedQueue().Retry(blocks...)
+		if err != nil {
+			errors = append(errors, fmt.Sprintf("Could not retry blocks: %s", err))
+			return
+		}
+
+		success = append(success, fmt.Sprintf("%d block(s) moved back to the todo queue!", count))
+	case "purge":
+		err := d.core.FailedQueue().Purge()
+		if err != nil {
+			errors = append(errors, fmt.Sprintf("Could not purge failed blocks: %s", err))
+			return
+		}
+
+		success = append(success, "Failed blocks successfully purged!")
+	default:
+		errors = append(errors, "Unknown action!")
+	}
+}
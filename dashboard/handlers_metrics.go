@@ -0,0 +1,10 @@
+package dashboard
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// MetricsHandler exposes the metrics tracked by memento in the Prometheus text format
+func (d *Dashboard) MetricsHandler(c *gin.Context) {
+	d.core.Metrics().ServeHTTP(c.Writer, c.Request)
+}
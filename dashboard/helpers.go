@@ -15,10 +15,10 @@ func (d *Dashboard) getDBEntries() (types.DBEntries, error) {
 
 	err := d.core.DB().QueryRow(`
 	select
-	       (select count(*) from blocks)::text as blocks,
-	       (select count(*) from txs)::text as txs,
-	       (select count(*) from uncles)::text as uncles,
-	       (select count(*) from log_entries)::text as log_entries
+	       cast((select count(*) from blocks) as text) as blocks,
+	       cast((select count(*) from txs) as text) as txs,
+	       cast((select count(*) from uncles) as text) as uncles,
+	       cast((select count(*) from log_entries) as text) as log_entries
 	`).Scan(&dbEntries.Blocks, &dbEntries.Txs, &dbEntries.Uncles, &dbEntries.LogEntries)
 	if err != nil {
 		log.Error(err)
@@ -31,27 +31,20 @@ func (d *Dashboard) getDBEntries() (types.DBEntries, error) {
 func (d *Dashboard) getDBStats() (types.DBStats, error) {
 	var dbStats types.DBStats
 
-	err := d.core.DB().QueryRow(`
-		select pg_size_pretty(sum(table_size))   as table_size,
-		       sum(table_size) 					 as raw_table_size,
-			   pg_size_pretty(sum(indexes_size)) as indexes_size,
-		       sum(indexes_size) 				 as raw_indexes_size,
-			   pg_size_pretty(sum(total_size))   as total_size,
-			   (select version_id from goose_db_version order by id desc limit 1) as migration_version,
-		       coalesce((select number from blocks order by number desc limit 1)::text, 'null') as max_block
-		from (
-				 select table_name,
-						pg_table_size(table_name)          as table_size,
-						pg_indexes_size(table_name)        as indexes_size,
-						pg_total_relation_size(table_name) as total_size
-				 from (
-						  select table_name::text
-						  from information_schema.tables
-						  where table_schema = 'public'
-					  ) as all_tables
-				 order by total_size desc
-			 ) as pretty_sizes
-     	`).Scan(&dbStats.DataSize, &dbStats.RawDataSize, &dbStats.IndexesSize, &dbStats.RawIndexesSize, &dbStats.TotalSize, &dbStats.MigrationsVersion, &dbStats.MaxBlock)
+	stats, err := d.core.Storage().Stats()
+	if err != nil {
+		log.Error(err)
+		return dbStats, err
+	}
+
+	dbStats.DataSize = stats.DataSize
+	dbStats.RawDataSize = stats.RawDataSize
+	dbStats.IndexesSize = stats.IndexesSize
+	dbStats.RawIndexesSize = stats.RawIndexesSize
+	dbStats.TotalSize = stats.TotalSize
+	dbStats.MigrationsVersion = strconv.FormatInt(stats.MigrationsVersion, 10)
+
+	err = d.core.DB().QueryRow(`select coalesce((select cast(number as text) from blocks order by number desc limit 1), 'null')`).Scan(&dbStats.MaxBlock)
 	if err != nil {
 		log.Error(err)
 		return dbStats, err
@@ -70,6 +63,7 @@ func (d *Dashboard) getProcStats() types.ProcStats {
 	procStats.TodoLength = strconv.FormatInt(d.core.Metrics().GetTodoLength(), 10)
 	procStats.ReorgedBlocks = strconv.FormatInt(d.core.Metrics().GetReorgedBlocks(), 10)
 	procStats.InvalidBlocks = strconv.FormatInt(d.core.Metrics().GetInvalidBlocks(), 10)
+	procStats.MissingBlocks = strconv.FormatInt(d.core.Metrics().GetGapBlocks(), 10)
 
 	procStats.PercentageDone = fmt.Sprintf("%f", 1-float64(d.core.Metrics().GetTodoLength())/float64(d.core.Metrics().GetLatestBLock()))
 
@@ -19,12 +19,15 @@ func (d *Dashboard) setRoutes() {
 	d.engine.GET("/", d.IndexHandler)
 	d.engine.GET("/queue", d.QueueHandler)
 	d.engine.POST("/queue", d.QueuePostHandler)
+	d.engine.GET("/queue/failed", d.FailedHandler)
+	d.engine.POST("/queue/failed", d.FailedPostHandler)
 	d.engine.GET("/pause", d.PauseHandler)
 	d.engine.POST("/pause", d.PausePostHandler)
 	d.engine.GET("/config", d.ConfigHandler)
 	d.engine.POST("/config", d.ConfigPostHandler)
 	d.engine.GET("/reset", d.ResetHandler)
 	d.engine.POST("/reset", d.ResetPostHandler)
+	d.engine.GET("/metrics", d.MetricsHandler)
 }
 
 func dict(values ...interface{}) (map[string]interface{}, error) {
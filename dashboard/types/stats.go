@@ -11,7 +11,7 @@ type DBStats struct {
 }
 
 type ProcStats struct {
-	ReorgedBlocks, InvalidBlocks, MemoryUsage, TodoLength, Version, PercentageDone string
+	ReorgedBlocks, InvalidBlocks, MissingBlocks, MemoryUsage, TodoLength, Version, PercentageDone string
 }
 
 type TimingStats struct {
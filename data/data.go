@@ -1,12 +1,13 @@
 package data
 
 import (
-	"database/sql"
 	"strconv"
 
 	"github.com/Alethio/memento/metrics"
+	"github.com/Alethio/memento/storage"
 
 	"github.com/Alethio/memento/data/storable"
+	"github.com/Alethio/memento/eth/extra"
 	"github.com/sirupsen/logrus"
 
 	"github.com/alethio/web3-go/types"
@@ -18,8 +19,35 @@ type FullBlock struct {
 	Block    types.Block
 	Receipts Receipts
 	Uncles   []types.Block
+	Traces   []types.Trace
+
+	// BlockExtra and ReceiptsExtra (keyed by transaction hash) hold the fields the web3-go types don't know about
+	BlockExtra    extra.Block
+	ReceiptsExtra map[string]extra.Receipt
+
+	// Codes holds the deployed code of the contracts created by the transactions of the block, keyed by address
+	// It's only scraped when traces are disabled, since the traces include the code
+	Codes map[string]string
+
+	// Finality is the status the block is stored with; one of the storable.Finality* values
+	Finality string
+
+	// Traced is set when the traces of the block were scraped, so its internal transactions are known (Traces can be
+	// empty either way)
+	Traced bool
+
+	// BlockRewards is the block reward schedule of the chain the miners' balance changes are computed with
+	BlockRewards []storable.BlockReward
+
+	// OnStore, if set, is run by Store in the transaction the block is written in, after its rows; what it writes
+	// (e.g. the webhook deliveries produced by the block) is committed, or rolled back, along with the block
+	OnStore func(tx storage.Tx) error
 
 	storables []Storable
+
+	// skipped and replaced describe the outcome of Store
+	skipped  bool
+	replaced *Reorg
 }
 
 type Receipts []types.Receipt
@@ -38,55 +66,119 @@ func (a Receipts) Less(i, j int) bool {
 // input: raw Ethereum data + a database transaction
 // output: processed/derived/enhanced data stored directly to the db
 type Storable interface {
-	ToDB(tx *sql.Tx) error
+	ToDB(tx storage.Tx) error
+}
+
+// RowsCounter is implemented by the storables that can report how many rows they inserted, for metrics purposes
+type RowsCounter interface {
+	InsertedRows() (table string, count int)
 }
 
 // RegisterStorables instantiates all the storables defined via code with the requested raw data
 // Only the storables that are registered will be executed when the Store function is called
 func (fb *FullBlock) RegisterStorables() {
-	fb.storables = append(fb.storables, storable.NewStorableBlock(fb.Block))
-	fb.storables = append(fb.storables, storable.NewStorableTxs(fb.Block, fb.Receipts))
-	fb.storables = append(fb.storables, storable.NewStorableUncles(fb.Block, fb.Uncles))
-	fb.storables = append(fb.storables, storable.NewStorableLogEntries(fb.Block, fb.Receipts))
-	fb.storables = append(fb.storables, storable.NewStorableAccountTxs(fb.Block))
+	fb.storables = append(fb.storables, storable.NewStorableBlock(fb.Block, fb.Receipts, fb.BlockExtra, fb.ReceiptsExtra, fb.Finality, fb.Traced, currentVersions().String()))
+	for _, def := range storableDefs {
+		fb.storables = append(fb.storables, def.New(fb))
+	}
+}
+
+// Skipped returns true if Store found the block already stored and left it untouched
+func (fb *FullBlock) Skipped() bool {
+	return fb.skipped
+}
+
+// Replaced returns the reorg Store handled by replacing a stored block with the same number, if any
+func (fb *FullBlock) Replaced() *Reorg {
+	return fb.replaced
 }
 
 // Store will open a database transaction and execute all the registered Storables in the said transaction
-func (fb *FullBlock) Store(db *sql.DB, m *metrics.Provider) error {
-	exists, err := fb.checkBlockExists(db)
+// The block number is locked for the duration of the transaction, so concurrent workers that happen to process the
+// same block number (e.g. during a reorg) are serialized
+// If the block doesn't link to the stored block at number-1, nothing is stored and a *ReorgError is returned; if the
+// stored block at number+1 doesn't link to it, nothing is stored either and a *StaleChildError is returned
+func (fb *FullBlock) Store(backend storage.Backend, m *metrics.Provider) error {
+	number, err := fb.extractBlockNumber()
+	if err != nil {
+		return err
+	}
+
+	tx, err := backend.Begin()
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	err = backend.LockBlock(tx, number)
+	if err != nil {
+		log.Error(err)
+		tx.Rollback()
+		return err
+	}
+
+	exists, err := fb.checkBlockExists(tx)
 	if err != nil {
+		tx.Rollback()
 		return err
 	}
 
 	if exists {
 		log.Info("block already exists in the database; skipping")
-		return nil
+		fb.skipped = true
+		return tx.Rollback()
+	}
+
+	reorged, err := fb.checkBlockReorged(tx)
+	if err != nil {
+		tx.Rollback()
+		return err
 	}
 
-	reorged, err := fb.checkBlockReorged(db)
+	err = fb.checkParent(tx)
 	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = fb.checkChild(tx)
+	if err != nil {
+		tx.Rollback()
 		return err
 	}
 
 	if reorged {
 		m.RecordReorgedBlock()
-		number, err := fb.extractBlockNumber()
+		m.RecordReorg(1)
+		log.WithField("block", number).Warn("detected reorged block")
+
+		oldHash, err := fb.storedHash(tx, number)
 		if err != nil {
+			tx.Rollback()
 			return err
 		}
-		log.WithField("block", number).Warn("detected reorged block")
-		_, err = db.Exec("select delete_block($1)", number)
+
+		err = backend.DeleteBlock(tx, number)
 		if err != nil {
 			log.Error(err)
+			tx.Rollback()
 			return err
 		}
-		log.WithField("block", number).Info("removed old version from the db; will be replaced with new version")
-	}
 
-	tx, err := db.Begin()
-	if err != nil {
-		log.Error(err)
-		return err
+		reorg := Reorg{
+			ForkBlock:    number - 1,
+			HeadBlock:    number,
+			OldHeadHash:  oldHash,
+			NewBlockHash: fb.extractBlockHash(),
+		}
+
+		err = insertReorg(tx, reorg)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		fb.replaced = &reorg
+		log.WithField("block", number).Info("removed old version from the db; will be replaced with new version")
 	}
 
 	for _, s := range fb.storables {
@@ -97,11 +189,27 @@ func (fb *FullBlock) Store(db *sql.DB, m *metrics.Provider) error {
 		}
 	}
 
+	if fb.OnStore != nil {
+		err = fb.OnStore(tx)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		log.Error(err)
 		return err
 	}
 
+	m.RecordIndexedBlock(number)
+	for _, s := range fb.storables {
+		if rc, ok := s.(RowsCounter); ok {
+			table, count := rc.InsertedRows()
+			m.RecordInsertedRows(table, int64(count))
+		}
+	}
+
 	return nil
 }
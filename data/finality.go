@@ -0,0 +1,67 @@
+package data
+
+import (
+	"github.com/Alethio/memento/storage"
+)
+
+// finalityBatchSize is the number of blocks MarkFinality updates per transaction, which bounds the size of the
+// transactions of the first runs over a large index
+const finalityBatchSize = 1000
+
+// MarkFinality updates the finality status of the stored blocks that crossed the given safe and finalized heads and
+// returns the number of blocks that were updated
+// Statuses only move forward, so blocks already marked as finalized are never touched again; neither are the blocks
+// stored before the statuses were recorded, whose status is null (unknown)
+func MarkFinality(backend storage.Backend, safe, finalized int64) (int64, error) {
+	return markFinality(backend, safe, finalized, finalityBatchSize)
+}
+
+func markFinality(backend storage.Backend, safe, finalized int64, batchSize int) (int64, error) {
+	// the statuses are inlined rather than bound, so the planners can match the partial index on unfinalized blocks
+	marked, err := markBatches(backend, `update blocks set finality = 'finalized' where number in (select number from blocks where number <= $1 and finality in ('latest', 'safe') limit $2)`, finalized, batchSize)
+	if err != nil {
+		return marked, err
+	}
+
+	safeMarked, err := markBatches(backend, `update blocks set finality = 'safe' where number in (select number from blocks where number <= $1 and finality = 'latest' limit $2)`, safe, batchSize)
+
+	return marked + safeMarked, err
+}
+
+// markBatches runs update, which marks up to batchSize blocks up to head, a transaction at a time until it runs out of
+// blocks and returns the number of blocks it marked
+func markBatches(backend storage.Backend, update string, head int64, batchSize int) (int64, error) {
+	var marked int64
+	for {
+		tx, err := backend.Begin()
+		if err != nil {
+			log.Error(err)
+			return marked, err
+		}
+
+		res, err := tx.Exec(update, head, batchSize)
+		if err != nil {
+			log.Error(err)
+			tx.Rollback()
+			return marked, err
+		}
+
+		count, err := res.RowsAffected()
+		if err != nil {
+			log.Error(err)
+			tx.Rollback()
+			return marked, err
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			log.Error(err)
+			return marked, err
+		}
+
+		marked += count
+		if count < int64(batchSize) {
+			return marked, nil
+		}
+	}
+}
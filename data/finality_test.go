@@ -0,0 +1,72 @@
+package data
+
+import (
+	"testing"
+)
+
+func TestMarkFinality(t *testing.T) {
+	backend, cleanup := newTestBackend(t)
+	defer cleanup()
+
+	storeChain(t, backend, 1, 10)
+
+	// the blocks stored before the statuses were recorded have none
+	_, err := backend.DB().Exec(`update blocks set finality = null where number <= 2`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a batch size smaller than the number of blocks takes several transactions
+	marked, err := markFinality(backend, 8, 6, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if marked != 6 {
+		t.Errorf("expected the blocks 3..8 to be marked, got %d", marked)
+	}
+
+	expected := map[int64]*string{}
+	for number := int64(1); number <= 10; number++ {
+		status := "latest"
+		switch {
+		case number <= 2:
+			expected[number] = nil
+			continue
+		case number <= 6:
+			status = "finalized"
+		case number <= 8:
+			status = "safe"
+		}
+		expected[number] = &status
+	}
+
+	rows, err := backend.DB().Query(`select number, finality from blocks order by number`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var number int64
+		var finality *string
+		err := rows.Scan(&number, &finality)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		e := expected[number]
+		if (e == nil) != (finality == nil) || e != nil && *e != *finality {
+			t.Errorf("block %d: expected %v, got %v", number, e, finality)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the statuses only move forward, and nothing is left to mark for the same heads
+	marked, err = markFinality(backend, 8, 6, 3)
+	if err != nil || marked != 0 {
+		t.Errorf("expected nothing to be marked again, got %d, %v", marked, err)
+	}
+}
@@ -0,0 +1,58 @@
+package data
+
+import (
+	"database/sql"
+)
+
+// Gap is a range of consecutive blocks, From to To inclusive, that are missing from the database
+type Gap struct {
+	From, To int64
+}
+
+// Size returns the number of blocks missing in the gap
+func (g Gap) Size() int64 {
+	return g.To - g.From + 1
+}
+
+// Gaps returns the ranges of blocks missing between from and the highest stored block, in ascending order
+// If from is negative, the blocks are only looked for from the lowest stored block
+func Gaps(db *sql.DB, from int64) ([]Gap, error) {
+	var lowest sql.NullInt64
+	err := db.QueryRow(`select min(number) from blocks where number >= $1`, from).Scan(&lowest)
+	if err != nil {
+		return nil, err
+	}
+
+	if !lowest.Valid {
+		return nil, nil
+	}
+
+	var gaps []Gap
+	if from >= 0 && lowest.Int64 > from {
+		gaps = append(gaps, Gap{From: from, To: lowest.Int64 - 1})
+	}
+
+	rows, err := db.Query(`
+		select number, next from (
+			select number, lead(number) over (order by number) as next from blocks where number >= $1
+		) t
+		where next > number + 1
+		order by number
+	`, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var number, next int64
+		err := rows.Scan(&number, &next)
+		if err != nil {
+			return nil, err
+		}
+
+		gaps = append(gaps, Gap{From: number + 1, To: next - 1})
+	}
+
+	return gaps, rows.Err()
+}
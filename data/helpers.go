@@ -5,6 +5,7 @@ import (
 	"strconv"
 
 	"github.com/Alethio/memento/data/storable"
+	"github.com/Alethio/memento/storage"
 )
 
 // extractBlockNumber returns the block number as int64 by extracting it from the raw data
@@ -24,11 +25,11 @@ func (fb *FullBlock) extractBlockHash() string {
 }
 
 // checkBlockExists verifies if the current block matches any other block in the database by hash
-func (fb *FullBlock) checkBlockExists(db *sql.DB) (bool, error) {
+func (fb *FullBlock) checkBlockExists(tx storage.Tx) (bool, error) {
 	hash := fb.extractBlockHash()
 
 	var count int
-	err := db.QueryRow(`select count(*) from blocks where block_hash = $1`, hash).Scan(&count)
+	err := tx.QueryRow(`select count(*) from blocks where block_hash = $1`, hash).Scan(&count)
 	if err != nil {
 		log.Error(err)
 		return false, err
@@ -44,14 +45,14 @@ func (fb *FullBlock) checkBlockExists(db *sql.DB) (bool, error) {
 // checkBlockReorged verifies if the current block matches any block in the database on number
 // this is meant to be used in order to detect if the database contains a blocks with the same number
 // but different hash if the checkBlockExists function returns false
-func (fb *FullBlock) checkBlockReorged(db *sql.DB) (bool, error) {
+func (fb *FullBlock) checkBlockReorged(tx storage.Tx) (bool, error) {
 	number, err := fb.extractBlockNumber()
 	if err != nil {
 		return false, err
 	}
 
 	var count int
-	err = db.QueryRow(`select count(*) from blocks where number = $1`, number).Scan(&count)
+	err = tx.QueryRow(`select count(*) from blocks where number = $1`, number).Scan(&count)
 	if err != nil {
 		log.Error(err)
 		return false, err
@@ -63,3 +64,75 @@ func (fb *FullBlock) checkBlockReorged(db *sql.DB) (bool, error) {
 
 	return false, nil
 }
+
+// checkParent verifies that the block links to the block stored at number-1 (if any) and returns a *ReorgError if the
+// parent hash doesn't match
+func (fb *FullBlock) checkParent(tx storage.Tx) error {
+	number, err := fb.extractBlockNumber()
+	if err != nil {
+		return err
+	}
+
+	if number == 0 {
+		return nil
+	}
+
+	stored, err := fb.storedHash(tx, number-1)
+	if err != nil {
+		return err
+	}
+
+	parent := storable.Trim0x(fb.Block.ParentHash)
+	if stored != "" && stored != parent {
+		return &ReorgError{
+			Number:           number,
+			ParentHash:       parent,
+			StoredParentHash: stored,
+		}
+	}
+
+	return nil
+}
+
+// checkChild verifies that the block stored at number+1 (if any) links to the block and returns a *StaleChildError if
+// its parent hash doesn't match
+func (fb *FullBlock) checkChild(tx storage.Tx) error {
+	number, err := fb.extractBlockNumber()
+	if err != nil {
+		return err
+	}
+
+	var parent string
+	err = tx.QueryRow(`select parent_block_hash from blocks where number = $1`, number+1).Scan(&parent)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	hash := fb.extractBlockHash()
+	if parent != hash {
+		return &StaleChildError{
+			Number:          number,
+			Hash:            hash,
+			ChildParentHash: parent,
+		}
+	}
+
+	return nil
+}
+
+// storedHash returns the hash of the block stored with the given number or an empty string if there's none
+func (fb *FullBlock) storedHash(tx storage.Tx, number int64) (string, error) {
+	var hash string
+
+	err := tx.QueryRow(`select block_hash from blocks where number = $1`, number).Scan(&hash)
+	if err != nil && err != sql.ErrNoRows {
+		log.Error(err)
+		return "", err
+	}
+
+	return hash, nil
+}
@@ -0,0 +1,122 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Alethio/memento/storage"
+)
+
+var (
+	// ErrBlockNotStored is returned by Reindex when there's no block with the same number in the database
+	ErrBlockNotStored = errors.New("the block is not stored")
+
+	// ErrBlockMismatch is returned by Reindex when the stored block with the same number has a different hash, e.g.
+	// because it was reorged since
+	ErrBlockMismatch = errors.New("the stored block has a different hash")
+)
+
+// Reindex replaces the rows the named storable derived from the block with the ones produced by its current version
+// and records the new version on the block; the other tables are left untouched
+func (fb *FullBlock) Reindex(backend storage.Backend, name string) error {
+	def, err := findStorable(name)
+	if err != nil {
+		return err
+	}
+
+	number, err := fb.extractBlockNumber()
+	if err != nil {
+		return err
+	}
+
+	tx, err := backend.Begin()
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	err = backend.LockBlock(tx, number)
+	if err != nil {
+		log.Error(err)
+		tx.Rollback()
+		return err
+	}
+
+	var (
+		hash    string
+		current *string
+	)
+	err = tx.QueryRow(`select block_hash, storable_versions from blocks where number = $1`, number).Scan(&hash, &current)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		return ErrBlockNotStored
+	}
+	if err != nil {
+		log.Error(err)
+		tx.Rollback()
+		return err
+	}
+
+	if hash != fb.extractBlockHash() {
+		tx.Rollback()
+		return ErrBlockMismatch
+	}
+
+	_, err = tx.Exec(fmt.Sprintf(`delete from %s where included_in_block = $1`, def.Name), number)
+	if err != nil {
+		log.Error(err)
+		tx.Rollback()
+		return err
+	}
+
+	err = def.New(fb).ToDB(tx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	versions := parseVersions(current)
+	versions[def.Name] = def.Version
+
+	_, err = tx.Exec(`update blocks set storable_versions = $1 where number = $2`, versions.String(), number)
+	if err != nil {
+		log.Error(err)
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// StaleBlocks returns the numbers of the blocks between from and to, inclusive, whose rows of the named storable were
+// produced by a version other than the current one
+func StaleBlocks(db *sql.DB, name string, from, to int64) ([]int64, error) {
+	def, err := findStorable(name)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`
+		select number from blocks
+		where number between $1 and $2 and coalesce(storable_versions, $3) not like $4
+		order by number
+	`, from, to, versionEntry(def.Name, 1), "%"+versionEntry(def.Name, def.Version)+"%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var blocks []int64
+	for rows.Next() {
+		var number int64
+		err := rows.Scan(&number)
+		if err != nil {
+			return nil, err
+		}
+
+		blocks = append(blocks, number)
+	}
+
+	return blocks, rows.Err()
+}
@@ -0,0 +1,163 @@
+package data
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/Alethio/memento/storage"
+)
+
+// ReorgError is returned by Store when the parent hash of the block doesn't match the hash of the block stored at
+// number-1, meaning that the stored chain was reorganized and needs to be rolled back
+type ReorgError struct {
+	Number           int64
+	ParentHash       string
+	StoredParentHash string
+}
+
+func (e *ReorgError) Error() string {
+	return fmt.Sprintf("parent hash of block %d (%s) doesn't match the stored block %d (%s)", e.Number, e.ParentHash, e.Number-1, e.StoredParentHash)
+}
+
+// StaleChildError is returned by Store when the block stored at number+1 doesn't link to the block, meaning that it
+// (and whatever is stored above it) comes from a chain that is no longer canonical; this happens when the blocks are
+// not stored in ascending order, e.g. with several workers or during a descending backfill
+type StaleChildError struct {
+	Number          int64
+	Hash            string
+	ChildParentHash string
+}
+
+func (e *StaleChildError) Error() string {
+	return fmt.Sprintf("stored block %d links to %s instead of block %d (%s)", e.Number+1, e.ChildParentHash, e.Number, e.Hash)
+}
+
+// Reorg describes a chain reorganization: all the stored blocks above ForkBlock, up to HeadBlock, belong to a chain
+// that is no longer canonical
+type Reorg struct {
+	ForkBlock    int64
+	HeadBlock    int64
+	OldHeadHash  string
+	NewBlockHash string
+}
+
+// Depth returns the number of blocks replaced by the reorg
+func (r Reorg) Depth() int64 {
+	return r.HeadBlock - r.ForkBlock
+}
+
+// StoredBlockHash returns the hash of the block stored with the given number, if any
+func StoredBlockHash(db *sql.DB, number int64) (string, bool, error) {
+	var hash string
+
+	err := db.QueryRow(`select block_hash from blocks where number = $1`, number).Scan(&hash)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	return hash, true, nil
+}
+
+// rollbackBatchSize is the number of blocks Rollback deletes per transaction, which bounds the size of the
+// transactions of deep reorgs
+const rollbackBatchSize = 100
+
+// Rollback deletes all the blocks stored above r.ForkBlock and records the reorg in the audit table
+// HeadBlock and OldHeadHash are filled in with the highest block that was removed
+// The blocks are deleted from the top down, a batch per transaction, so an interrupted rollback leaves the stored chain
+// without holes and the blocks it didn't get to are found by the next one
+// onReorg, if set, is run in the transaction the reorg is recorded in, so what it writes is committed along with it
+func Rollback(backend storage.Backend, r *Reorg, onReorg func(tx storage.Tx, r Reorg) error) error {
+	err := backend.DB().QueryRow(`select number, block_hash from blocks order by number desc limit 1`).Scan(&r.HeadBlock, &r.OldHeadHash)
+	if err == sql.ErrNoRows || (err == nil && r.HeadBlock <= r.ForkBlock) {
+		r.HeadBlock = r.ForkBlock
+		return nil
+	}
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	for top := r.HeadBlock; top > r.ForkBlock; top -= rollbackBatchSize {
+		bottom := top - rollbackBatchSize + 1
+		if bottom <= r.ForkBlock {
+			bottom = r.ForkBlock + 1
+		}
+
+		// the reorg is recorded along with the first batch, when the blocks start disappearing
+		var record *Reorg
+		if top == r.HeadBlock {
+			record = r
+		}
+
+		err = deleteBlocks(backend, bottom, top, record, onReorg)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteBlocks deletes the blocks from bottom to top, in a single transaction in which the reorg is recorded (and
+// onReorg run) if given
+func deleteBlocks(backend storage.Backend, bottom, top int64, r *Reorg, onReorg func(tx storage.Tx, r Reorg) error) error {
+	tx, err := backend.Begin()
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	for number := top; number >= bottom; number-- {
+		err = backend.LockBlock(tx, number)
+		if err != nil {
+			log.Error(err)
+			tx.Rollback()
+			return err
+		}
+
+		err = backend.DeleteBlock(tx, number)
+		if err != nil {
+			log.Error(err)
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if r != nil {
+		err = insertReorg(tx, *r)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		if onReorg != nil {
+			err = onReorg(tx, *r)
+			if err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func insertReorg(tx storage.Tx, r Reorg) error {
+	_, err := tx.Exec(`insert into reorgs (fork_block, head_block, depth, old_head_hash, new_block_hash) values ($1, $2, $3, $4, $5)`, r.ForkBlock, r.HeadBlock, r.Depth(), r.OldHeadHash, r.NewBlockHash)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	return nil
+}
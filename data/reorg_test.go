@@ -0,0 +1,284 @@
+package data
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Alethio/memento/metrics"
+	"github.com/Alethio/memento/storage"
+	"github.com/alethio/web3-go/types"
+)
+
+// newTestBackend returns a migrated sqlite backend along with the function that removes it
+func newTestBackend(t *testing.T) (storage.Backend, func()) {
+	dir, err := ioutil.TempDir("", "memento-data")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backend, err := storage.New(storage.Config{Driver: storage.DriverSQLite, SQLitePath: filepath.Join(dir, "test.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cleanup := func() {
+		backend.Close()
+		os.RemoveAll(dir)
+	}
+
+	err = backend.Migrate()
+	if err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+
+	return backend, cleanup
+}
+
+// testHash returns the hash of block number on the given branch
+func testHash(branch, number int64) string {
+	return fmt.Sprintf("0x%032x%032x", branch, number)
+}
+
+// testBlock returns an empty block of the given branch whose parent is on parentBranch
+func testBlock(number, branch, parentBranch int64) *FullBlock {
+	var b types.Block
+	b.Number = fmt.Sprintf("0x%x", number)
+	b.Hash = testHash(branch, number)
+	b.ParentHash = testHash(parentBranch, number-1)
+	b.Miner = fmt.Sprintf("0x%040x", 0xc0ffee)
+	b.Difficulty = "0x0"
+	b.TotalDifficulty = "0x0"
+	b.Timestamp = fmt.Sprintf("0x%x", 1600000000+number*12)
+	b.GasLimit = "0x1c9c380"
+	b.GasUsed = "0x0"
+	b.Nonce = "0x0000000000000000"
+	b.Size = "0x100"
+	b.Sha3Uncles = testHash(0, 0)
+	b.LogsBloom = "0x00"
+	b.TransactionsRoot = testHash(0, 0)
+	b.StateRoot = testHash(0, 0)
+	b.ReceiptsRoot = testHash(0, 0)
+	b.ExtraData = "0x"
+	b.MixHash = testHash(0, 0)
+
+	fb := &FullBlock{Block: b}
+	fb.RegisterStorables()
+
+	return fb
+}
+
+// storeChain stores the blocks from..to of branch 0
+func storeChain(t *testing.T, backend storage.Backend, from, to int64) {
+	for number := from; number <= to; number++ {
+		err := testBlock(number, 0, 0).Store(backend, metrics.New())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func storedBlocks(t *testing.T, backend storage.Backend) int64 {
+	var count int64
+	err := backend.DB().QueryRow(`select count(*) from blocks`).Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return count
+}
+
+func TestStoreDetectsParentMismatch(t *testing.T) {
+	backend, cleanup := newTestBackend(t)
+	defer cleanup()
+
+	storeChain(t, backend, 1, 3)
+
+	err := testBlock(4, 1, 1).Store(backend, metrics.New())
+	reorgErr, ok := err.(*ReorgError)
+	if !ok {
+		t.Fatalf("expected a *ReorgError, got %v", err)
+	}
+	if reorgErr.Number != 4 {
+		t.Errorf("expected the error to be about block 4, got %d", reorgErr.Number)
+	}
+	if n := storedBlocks(t, backend); n != 3 {
+		t.Errorf("expected nothing to be stored, got %d blocks", n)
+	}
+}
+
+func TestStoreDetectsStaleChildren(t *testing.T) {
+	backend, cleanup := newTestBackend(t)
+	defer cleanup()
+
+	storeChain(t, backend, 1, 5)
+
+	// block 3 of a new branch still links to block 2, but the stored block 4 links to the old block 3
+	err := testBlock(3, 1, 0).Store(backend, metrics.New())
+	childErr, ok := err.(*StaleChildError)
+	if !ok {
+		t.Fatalf("expected a *StaleChildError, got %v", err)
+	}
+	if childErr.Number != 3 || childErr.ChildParentHash != testHash(0, 3)[2:] {
+		t.Errorf("unexpected error: %+v", childErr)
+	}
+
+	hash, _, err := StoredBlockHash(backend.DB(), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != testHash(0, 3)[2:] {
+		t.Errorf("expected the old block 3 to stay stored, got %s", hash)
+	}
+
+	// once the stale blocks are rolled back, the new one goes in
+	err = Rollback(backend, &Reorg{ForkBlock: 2}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = testBlock(3, 1, 0).Store(backend, metrics.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStoreAcceptsLinkedChildren(t *testing.T) {
+	backend, cleanup := newTestBackend(t)
+	defer cleanup()
+
+	storeChain(t, backend, 2, 3)
+
+	err := testBlock(1, 0, 0).Store(backend, metrics.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRollbackInBatches(t *testing.T) {
+	backend, cleanup := newTestBackend(t)
+	defer cleanup()
+
+	const head = 2*rollbackBatchSize + 10
+	storeChain(t, backend, 0, head)
+
+	r := &Reorg{ForkBlock: 4, NewBlockHash: testHash(1, 5)[2:]}
+	err := Rollback(backend, r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.HeadBlock != head || r.OldHeadHash != testHash(0, head)[2:] {
+		t.Errorf("expected the old head to be %d (%s), got %d (%s)", head, testHash(0, head)[2:], r.HeadBlock, r.OldHeadHash)
+	}
+	if n := storedBlocks(t, backend); n != 5 {
+		t.Errorf("expected the blocks 0..4 to be left, got %d blocks", n)
+	}
+
+	var reorgs, depth int64
+	err = backend.DB().QueryRow(`select count(*), max(depth) from reorgs`).Scan(&reorgs, &depth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reorgs != 1 || depth != head-4 {
+		t.Errorf("expected a single reorg of depth %d to be recorded, got %d of depth %d", head-4, reorgs, depth)
+	}
+
+	// nothing above the fork point is a no-op
+	r = &Reorg{ForkBlock: 4}
+	err = Rollback(backend, r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Depth() != 0 {
+		t.Errorf("expected an empty rollback, got depth %d", r.Depth())
+	}
+}
+
+func TestStoreRunsOnStoreInItsTransaction(t *testing.T) {
+	backend, cleanup := newTestBackend(t)
+	defer cleanup()
+
+	storeChain(t, backend, 1, 2)
+
+	recorded := func(t *testing.T) int64 {
+		var count int64
+		err := backend.DB().QueryRow(`select count(*) from reorgs`).Scan(&count)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return count
+	}
+
+	// a failing hook leaves neither the block nor what the hook wrote
+	fb := testBlock(3, 0, 0)
+	fb.OnStore = func(tx storage.Tx) error {
+		err := insertReorg(tx, Reorg{ForkBlock: 2, HeadBlock: 3})
+		if err != nil {
+			return err
+		}
+
+		return fmt.Errorf("hook failed")
+	}
+
+	err := fb.Store(backend, metrics.New())
+	if err == nil || err.Error() != "hook failed" {
+		t.Fatalf("expected the error of the hook, got %v", err)
+	}
+	if n := storedBlocks(t, backend); n != 2 || recorded(t) != 0 {
+		t.Fatalf("expected nothing to be stored, got %d blocks and %d reorgs", n, recorded(t))
+	}
+
+	fb = testBlock(3, 0, 0)
+	fb.OnStore = func(tx storage.Tx) error {
+		return insertReorg(tx, Reorg{ForkBlock: 2, HeadBlock: 3})
+	}
+
+	err = fb.Store(backend, metrics.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := storedBlocks(t, backend); n != 3 || recorded(t) != 1 {
+		t.Errorf("expected the block and the row of the hook, got %d blocks and %d reorgs", n, recorded(t))
+	}
+}
+
+func TestRollbackRunsOnReorgInItsTransaction(t *testing.T) {
+	backend, cleanup := newTestBackend(t)
+	defer cleanup()
+
+	storeChain(t, backend, 0, 5)
+
+	var calls []Reorg
+	failing := func(tx storage.Tx, r Reorg) error {
+		calls = append(calls, r)
+		return fmt.Errorf("hook failed")
+	}
+
+	err := Rollback(backend, &Reorg{ForkBlock: 2}, failing)
+	if err == nil {
+		t.Fatal("expected the error of the hook")
+	}
+	if n := storedBlocks(t, backend); n != 6 {
+		t.Errorf("expected the rollback to be undone, got %d blocks", n)
+	}
+
+	calls = nil
+	err = Rollback(backend, &Reorg{ForkBlock: 2}, func(tx storage.Tx, r Reorg) error {
+		calls = append(calls, r)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 1 || calls[0].ForkBlock != 2 || calls[0].HeadBlock != 5 {
+		t.Errorf("expected the hook to be run once with the reorg, got %+v", calls)
+	}
+	if n := storedBlocks(t, backend); n != 3 {
+		t.Errorf("expected the blocks 0..2 to be left, got %d blocks", n)
+	}
+}
@@ -0,0 +1,111 @@
+package storable
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/Alethio/memento/eth/extra"
+	"github.com/Alethio/memento/storage"
+
+	"github.com/alethio/web3-go/types"
+)
+
+type AccessListsGroup struct {
+	RawBlock      types.Block
+	RawBlockExtra extra.Block
+
+	blockNumber int64
+
+	entries []*AccessListEntry
+}
+
+// AccessListEntry is an address (and the storage keys of it) declared in the EIP-2930 access list of a transaction
+type AccessListEntry struct {
+	TxHash          string
+	IncludedInBlock int64
+	TxIndex         int32
+	EntryIndex      int32
+	Address         string
+	StorageKeys     JSONStringArray
+}
+
+func NewStorableAccessLists(block types.Block, blockExtra extra.Block) *AccessListsGroup {
+	return &AccessListsGroup{RawBlock: block, RawBlockExtra: blockExtra}
+}
+
+func (alg *AccessListsGroup) ToDB(tx storage.Tx) error {
+	err := alg.enhance()
+	if err != nil {
+		return err
+	}
+
+	if len(alg.entries) == 0 {
+		return nil
+	}
+
+	log.Trace("storing access lists")
+	start := time.Now()
+	defer func() {
+		log.WithField("duration", time.Since(start)).WithField("count", len(alg.entries)).Debug("done storing access lists")
+	}()
+
+	stmt, err := tx.BulkInsert("tx_access_lists", "tx_hash", "included_in_block", "tx_index", "entry_index", "address", "storage_keys")
+	if err != nil {
+		return err
+	}
+
+	for _, e := range alg.entries {
+		_, err = stmt.Exec(e.TxHash, e.IncludedInBlock, e.TxIndex, e.EntryIndex, e.Address, e.StorageKeys)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = stmt.Exec()
+	if err != nil {
+		return err
+	}
+
+	err = stmt.Close()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (alg *AccessListsGroup) InsertedRows() (string, int) {
+	return "tx_access_lists", len(alg.entries)
+}
+
+// enhance flattens the access lists of all the transactions in the block into one entry per address
+func (alg *AccessListsGroup) enhance() error {
+	number, err := strconv.ParseInt(alg.RawBlock.Number, 0, 64)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	alg.blockNumber = number
+
+	for index, tx := range alg.RawBlock.Transactions {
+		txExtra := alg.RawBlockExtra.Tx(index)
+
+		for entryIndex, tuple := range txExtra.AccessList {
+			storageKeys := make(JSONStringArray, len(tuple.StorageKeys))
+			for i, key := range tuple.StorageKeys {
+				storageKeys[i] = Trim0x(key)
+			}
+
+			alg.entries = append(alg.entries, &AccessListEntry{
+				TxHash:          Trim0x(tx.Hash),
+				IncludedInBlock: alg.blockNumber,
+				TxIndex:         int32(index),
+				EntryIndex:      int32(entryIndex),
+				Address:         Trim0x(tuple.Address),
+				StorageKeys:     storageKeys,
+			})
+		}
+	}
+
+	return nil
+}
@@ -1,17 +1,20 @@
 package storable
 
 import (
-	"database/sql"
+	"fmt"
 	"strconv"
 	"time"
 
-	"github.com/lib/pq"
+	"github.com/Alethio/memento/eth/extra"
+	"github.com/Alethio/memento/storage"
 
 	"github.com/alethio/web3-go/types"
 )
 
 type AccountTxsGroup struct {
-	RawBlock types.Block
+	RawBlock      types.Block
+	RawTraces     []types.Trace
+	RawBlockExtra extra.Block
 
 	blockNumber int64
 
@@ -21,6 +24,10 @@ type AccountTxsGroup struct {
 // AccountTx is a type of entity that represents a transaction between two accounts
 // For each transaction, 2 AccountTx entities will be created, one for each direction of the transaction
 // This helps with querying an account's transactions history in a specific order (e.g. chronological) and paginated
+// Internal transactions that move value are also recorded (with Internal set), so that the history of an account
+// includes the transactions in which it sent or received value through a contract call
+// Withdrawals are recorded for their recipient, with WithdrawalIndex set and no TxHash; since they are processed after
+// all the transactions of the block, their TxIndex continues after the last transaction
 type AccountTx struct {
 	Address         string
 	Counterparty    string
@@ -28,14 +35,16 @@ type AccountTx struct {
 	Out             bool
 	IncludedInBlock int64
 	TxIndex         int64
+	Internal        bool
+	WithdrawalIndex *int64
 }
 
-func NewStorableAccountTxs(block types.Block) *AccountTxsGroup {
-	return &AccountTxsGroup{RawBlock: block}
+func NewStorableAccountTxs(block types.Block, traces []types.Trace, blockExtra extra.Block) *AccountTxsGroup {
+	return &AccountTxsGroup{RawBlock: block, RawTraces: traces, RawBlockExtra: blockExtra}
 }
 
-func (atg *AccountTxsGroup) ToDB(tx *sql.Tx) error {
-	if len(atg.RawBlock.Transactions) == 0 {
+func (atg *AccountTxsGroup) ToDB(tx storage.Tx) error {
+	if len(atg.RawBlock.Transactions) == 0 && len(atg.RawBlockExtra.Withdrawals) == 0 {
 		return nil
 	}
 
@@ -50,13 +59,13 @@ func (atg *AccountTxsGroup) ToDB(tx *sql.Tx) error {
 		return err
 	}
 
-	stmt, err := tx.Prepare(pq.CopyIn("account_txs", "address", "counterparty", "tx_hash", "out", "included_in_block", "tx_index"))
+	stmt, err := tx.BulkInsert("account_txs", "address", "counterparty", "tx_hash", "out", "included_in_block", "tx_index", "internal", "withdrawal_index")
 	if err != nil {
 		return err
 	}
 
 	for _, at := range atg.accountTxs {
-		_, err = stmt.Exec(at.Address, at.Counterparty, at.TxHash, at.Out, at.IncludedInBlock, at.TxIndex)
+		_, err = stmt.Exec(at.Address, at.Counterparty, at.TxHash, at.Out, at.IncludedInBlock, at.TxIndex, at.Internal, at.WithdrawalIndex)
 		if err != nil {
 			return err
 		}
@@ -75,6 +84,10 @@ func (atg *AccountTxsGroup) ToDB(tx *sql.Tx) error {
 	return nil
 }
 
+func (atg *AccountTxsGroup) InsertedRows() (string, int) {
+	return "account_txs", len(atg.accountTxs)
+}
+
 // enhance processes all the transactions in the raw block and generates a list containing the AccountTx entities
 // corresponding to each of the block's transactions
 func (atg *AccountTxsGroup) enhance() error {
@@ -105,9 +118,98 @@ func (atg *AccountTxsGroup) enhance() error {
 		atg.accountTxs = append(atg.accountTxs, storableAccountTxIn)
 	}
 
+	err = atg.enhanceInternal()
+	if err != nil {
+		return err
+	}
+
+	return atg.enhanceWithdrawals()
+}
+
+// enhanceWithdrawals generates an incoming AccountTx entity for the recipient of each withdrawal in the block
+func (atg *AccountTxsGroup) enhanceWithdrawals() error {
+	for position, w := range atg.RawBlockExtra.Withdrawals {
+		index, err := strconv.ParseInt(w.Index, 0, 64)
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+
+		atg.accountTxs = append(atg.accountTxs, &AccountTx{
+			Address:         Trim0x(w.Address),
+			IncludedInBlock: atg.blockNumber,
+			TxIndex:         int64(len(atg.RawBlock.Transactions) + position),
+			WithdrawalIndex: &index,
+		})
+	}
+
+	return nil
+}
+
+// enhanceInternal generates AccountTx entities for the internal transactions that moved value
+// The ones that failed or were reverted along with a call they were made from (or with the whole transaction) moved
+// nothing, and delegate calls only repeat the value of their caller, so they are all skipped, like for the balances
+// An account is linked at most once per transaction and direction, so transactions that already show up in the
+// account's history are not duplicated
+func (atg *AccountTxsGroup) enhanceInternal() error {
+	seen := make(map[string]bool)
+	for _, at := range atg.accountTxs {
+		seen[accountTxKey(at)] = true
+	}
+
+	failed := newFailedTraces(atg.RawTraces)
+	for _, trace := range atg.RawTraces {
+		if !IsInternalTrace(trace) || failed.reverted(trace) || IsDelegateCall(trace) {
+			continue
+		}
+
+		from, to, value := TraceParticipants(trace)
+		if from == "" || to == "" {
+			continue
+		}
+
+		v, err := optionalHexStrToBigIntStr(value)
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+		if v == "0" {
+			continue
+		}
+
+		for _, out := range []bool{true, false} {
+			at := &AccountTx{
+				IncludedInBlock: atg.blockNumber,
+				TxIndex:         int64(*trace.TransactionPosition),
+				TxHash:          Trim0x(*trace.TransactionHash),
+				Out:             out,
+				Internal:        true,
+			}
+
+			if out {
+				at.Address = Trim0x(from)
+				at.Counterparty = Trim0x(to)
+			} else {
+				at.Address = Trim0x(to)
+				at.Counterparty = Trim0x(from)
+			}
+
+			if seen[accountTxKey(at)] {
+				continue
+			}
+			seen[accountTxKey(at)] = true
+
+			atg.accountTxs = append(atg.accountTxs, at)
+		}
+	}
+
 	return nil
 }
 
+func accountTxKey(at *AccountTx) string {
+	return fmt.Sprintf("%s-%s-%t", at.Address, at.TxHash, at.Out)
+}
+
 func (atg *AccountTxsGroup) buildStorableAccountTx(tx types.Transaction, txIndex int64, out bool) (*AccountTx, error) {
 	at := &AccountTx{}
 	at.IncludedInBlock = atg.blockNumber
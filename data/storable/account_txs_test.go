@@ -0,0 +1,41 @@
+package storable
+
+import (
+	"testing"
+)
+
+func TestAccountTxsSkipRevertedInternalTransfers(t *testing.T) {
+	block, _, blockExtra, _ := decodeTestBlock(t, powBlock, powReceipts)
+	_, traces := decodeTestTraces(t, powUncles, powTraces)
+
+	atg := NewStorableAccountTxs(block, traces, blockExtra)
+	err := atg.enhance()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var internal []*AccountTx
+	for _, at := range atg.accountTxs {
+		if at.Internal {
+			internal = append(internal, at)
+		}
+	}
+
+	// only the call from cc to dd moved value; the failed call, the call it made, the delegate call and the call made
+	// by the failed transfer didn't
+	if len(internal) != 2 {
+		t.Fatalf("expected the 2 sides of a single internal transfer, got %d entries", len(internal))
+	}
+
+	out, in := internal[0], internal[1]
+	if !out.Out || out.Address != "cc" || out.Counterparty != "dd" || out.TxHash != "03" || out.TxIndex != 2 {
+		t.Errorf("unexpected outgoing entry %+v", out)
+	}
+	if in.Out || in.Address != "dd" || in.Counterparty != "cc" || in.TxHash != "03" || in.TxIndex != 2 {
+		t.Errorf("unexpected incoming entry %+v", in)
+	}
+
+	if len(atg.accountTxs) != 2*len(block.Transactions)+2 {
+		t.Errorf("expected 2 entries per transaction plus the internal ones, got %d", len(atg.accountTxs))
+	}
+}
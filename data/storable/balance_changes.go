@@ -0,0 +1,387 @@
+package storable
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Alethio/memento/eth/extra"
+	"github.com/Alethio/memento/storage"
+
+	"github.com/alethio/web3-go/types"
+)
+
+// BlockReward is the reward of the miner of a block from Block onwards, in wei
+type BlockReward struct {
+	Block  int64
+	Reward *big.Int
+}
+
+// MainnetBlockRewards is the mainnet schedule of the proof-of-work block rewards: 5 ETH at launch, 3 ETH from
+// Byzantium and 2 ETH from Constantinople; the blocks without difficulty (proof-of-stake) have no reward
+var MainnetBlockRewards = []BlockReward{
+	{0, new(big.Int).Mul(big.NewInt(5), big.NewInt(1e18))},
+	{4370000, new(big.Int).Mul(big.NewInt(3), big.NewInt(1e18))},
+	{7280000, new(big.Int).Mul(big.NewInt(2), big.NewInt(1e18))},
+}
+
+// Block reward schedules that can be given by name to ParseBlockRewards
+const (
+	BlockRewardsMainnet = "mainnet"
+	BlockRewardsNone    = "none"
+)
+
+// ParseBlockRewards returns the block reward schedule described by value, which is either the name of a schedule or
+// a comma separated list of block:reward pairs, with the rewards in wei and the blocks in ascending order (e.g.
+// "0:5000000000000000000,4370000:3000000000000000000"); the "none" schedule is empty
+func ParseBlockRewards(value string) ([]BlockReward, error) {
+	switch value {
+	case BlockRewardsMainnet:
+		return MainnetBlockRewards, nil
+	case BlockRewardsNone:
+		return nil, nil
+	}
+
+	var rewards []BlockReward
+	for _, pair := range strings.Split(value, ",") {
+		parts := strings.Split(strings.TrimSpace(pair), ":")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid block reward %q: expected block:reward", pair)
+		}
+
+		block, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil || block < 0 {
+			return nil, fmt.Errorf("invalid block reward %q: bad block number", pair)
+		}
+
+		reward, ok := new(big.Int).SetString(parts[1], 10)
+		if !ok || reward.Sign() < 0 {
+			return nil, fmt.Errorf("invalid block reward %q: bad reward", pair)
+		}
+
+		if len(rewards) > 0 && block <= rewards[len(rewards)-1].Block {
+			return nil, fmt.Errorf("invalid block rewards %q: the blocks must be in ascending order", value)
+		}
+
+		rewards = append(rewards, BlockReward{block, reward})
+	}
+
+	return rewards, nil
+}
+
+type BalanceChangesGroup struct {
+	RawBlock         types.Block
+	RawBlockExtra    extra.Block
+	RawReceipts      []types.Receipt
+	RawReceiptsExtra map[string]extra.Receipt
+	RawUncles        []types.Block
+	RawTraces        []types.Trace
+
+	// Rewards is the block reward schedule of the chain; without one, the miners are only credited with the fees
+	Rewards []BlockReward
+
+	blockNumber int64
+	deltas      map[string]*big.Int
+
+	failedTraces failedTraces
+
+	balanceChanges []*BalanceChange
+}
+
+// BalanceChange is the net change of the native balance of an account caused by a block
+// It adds up the values moved by the transactions (and, when traces are scraped, by the internal transactions), the
+// fees paid by the senders and earned by the miner, the block and uncle rewards and the withdrawals; the accounts
+// whose balance didn't change are left out
+// An account's balance at a block is the sum of its changes up to that block, which is only exact if all the blocks
+// before it are stored with traces and with the chain's block reward schedule, and doesn't include the genesis
+// allocations
+type BalanceChange struct {
+	Address         string
+	IncludedInBlock int64
+	Delta           string
+}
+
+func NewStorableBalanceChanges(block types.Block, receipts []types.Receipt, blockExtra extra.Block, receiptsExtra map[string]extra.Receipt, uncles []types.Block, traces []types.Trace, rewards []BlockReward) *BalanceChangesGroup {
+	return &BalanceChangesGroup{
+		Rewards:          rewards,
+		RawBlock:         block,
+		RawBlockExtra:    blockExtra,
+		RawReceipts:      receipts,
+		RawReceiptsExtra: receiptsExtra,
+		RawUncles:        uncles,
+		RawTraces:        traces,
+	}
+}
+
+func (bcg *BalanceChangesGroup) ToDB(tx storage.Tx) error {
+	log.Trace("storing balance changes")
+	start := time.Now()
+	defer func() {
+		log.WithField("duration", time.Since(start)).WithField("count", len(bcg.balanceChanges)).Debug("done storing balance changes")
+	}()
+
+	err := bcg.enhance()
+	if err != nil {
+		return err
+	}
+
+	if len(bcg.balanceChanges) == 0 {
+		return nil
+	}
+
+	stmt, err := tx.BulkInsert("balance_changes", "address", "included_in_block", "delta")
+	if err != nil {
+		return err
+	}
+
+	for _, bc := range bcg.balanceChanges {
+		_, err = stmt.Exec(bc.Address, bc.IncludedInBlock, bc.Delta)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = stmt.Exec()
+	if err != nil {
+		return err
+	}
+
+	err = stmt.Close()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (bcg *BalanceChangesGroup) InsertedRows() (string, int) {
+	return "balance_changes", len(bcg.balanceChanges)
+}
+
+// enhance adds up the balance changes of every account touched by the block
+func (bcg *BalanceChangesGroup) enhance() error {
+	number, err := strconv.ParseInt(bcg.RawBlock.Number, 0, 64)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	bcg.blockNumber = number
+	bcg.deltas = make(map[string]*big.Int)
+
+	bcg.failedTraces = newFailedTraces(bcg.RawTraces)
+
+	err = bcg.enhanceTxs()
+	if err != nil {
+		return err
+	}
+
+	err = bcg.enhanceInternal()
+	if err != nil {
+		return err
+	}
+
+	err = bcg.enhanceRewards()
+	if err != nil {
+		return err
+	}
+
+	for _, w := range bcg.RawBlockExtra.Withdrawals {
+		amount, err := WithdrawalAmount(w)
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+
+		bcg.add(w.Address, amount)
+	}
+
+	addresses := make([]string, 0, len(bcg.deltas))
+	for address, delta := range bcg.deltas {
+		if delta.Sign() != 0 {
+			addresses = append(addresses, address)
+		}
+	}
+	sort.Strings(addresses)
+
+	for _, address := range addresses {
+		bcg.balanceChanges = append(bcg.balanceChanges, &BalanceChange{
+			Address:         address,
+			IncludedInBlock: bcg.blockNumber,
+			Delta:           bcg.deltas[address].String(),
+		})
+	}
+
+	return nil
+}
+
+// enhanceTxs accounts for the value moved by each successful transaction and for the fees of all of them: the sender
+// pays the gas (and blob gas) at the effective price, of which the miner earns what's above the base fee
+// The receipts before Byzantium have no status, so the failed transactions can only be told apart by their traces
+func (bcg *BalanceChangesGroup) enhanceTxs() error {
+	baseFee, err := optionalHexStrToBigInt(bcg.RawBlockExtra.BaseFeePerGas)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	miner := bcg.RawBlock.Miner
+	if miner == "" {
+		miner = bcg.RawBlock.Author
+	}
+
+	for index, tx := range bcg.RawBlock.Transactions {
+		if index >= len(bcg.RawReceipts) {
+			break
+		}
+		receipt := bcg.RawReceipts[index]
+		receiptExtra := bcg.RawReceiptsExtra[tx.Hash]
+
+		gasUsed, err := HexStrToBigInt(receipt.GasUsed)
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+
+		price, err := HexStrToBigInt(effectiveGasPrice(tx, receiptExtra))
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+
+		blobGasUsed, err := optionalHexStrToBigInt(receiptExtra.BlobGasUsed)
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+
+		blobGasPrice, err := optionalHexStrToBigInt(receiptExtra.BlobGasPrice)
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+
+		fee := new(big.Int).Mul(gasUsed, price)
+		fee.Add(fee, blobGasUsed.Mul(blobGasUsed, blobGasPrice))
+		bcg.add(tx.From, fee.Neg(fee))
+
+		tip := new(big.Int).Sub(price, baseFee)
+		bcg.add(miner, tip.Mul(tip, gasUsed))
+
+		if receipt.Status == "0x0" || bcg.failedTraces.txFailed(tx.Hash) {
+			continue
+		}
+
+		value, err := optionalHexStrToBigInt(tx.Value)
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+
+		to := tx.To
+		if to == "" {
+			if contractAddress, ok := receipt.ContractAddress.(string); ok {
+				to = contractAddress
+			}
+		}
+
+		bcg.add(to, value)
+		bcg.add(tx.From, new(big.Int).Neg(value))
+	}
+
+	return nil
+}
+
+// enhanceInternal accounts for the value moved by the internal transactions; the ones that failed or have a failed
+// ancestor were reverted, so they're skipped
+func (bcg *BalanceChangesGroup) enhanceInternal() error {
+	for _, trace := range bcg.RawTraces {
+		if !IsInternalTrace(trace) || bcg.failedTraces.reverted(trace) || IsDelegateCall(trace) {
+			continue
+		}
+
+		from, to, value := TraceParticipants(trace)
+		if from == "" || to == "" {
+			continue
+		}
+
+		v, err := optionalHexStrToBigInt(value)
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+
+		bcg.add(to, v)
+		bcg.add(from, new(big.Int).Neg(v))
+	}
+
+	return nil
+}
+
+// enhanceRewards credits the miner with the block reward, plus 1/32 of it for each uncle, and the miner of each uncle
+// with 8-d eighths of the block reward, d being how many blocks the uncle is older than the block
+// The uncles' miners are only known if the uncles are scraped
+func (bcg *BalanceChangesGroup) enhanceRewards() error {
+	difficulty, err := optionalHexStrToBigInt(bcg.RawBlock.Difficulty)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	if bcg.blockNumber == 0 || difficulty.Sign() == 0 {
+		return nil
+	}
+
+	var reward *big.Int
+	for _, r := range bcg.Rewards {
+		if bcg.blockNumber >= r.Block {
+			reward = r.Reward
+		}
+	}
+
+	if reward == nil {
+		return nil
+	}
+
+	miner := bcg.RawBlock.Miner
+	if miner == "" {
+		miner = bcg.RawBlock.Author
+	}
+
+	minerReward := new(big.Int).Div(reward, big.NewInt(32))
+	minerReward.Mul(minerReward, big.NewInt(int64(len(bcg.RawBlock.Uncles))))
+	bcg.add(miner, minerReward.Add(minerReward, reward))
+
+	for _, uncle := range bcg.RawUncles {
+		uncleNumber, err := strconv.ParseInt(uncle.Number, 0, 64)
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+
+		uncleMiner := uncle.Miner
+		if uncleMiner == "" {
+			uncleMiner = uncle.Author
+		}
+
+		uncleReward := new(big.Int).Mul(reward, big.NewInt(8+uncleNumber-bcg.blockNumber))
+		bcg.add(uncleMiner, uncleReward.Div(uncleReward, big.NewInt(8)))
+	}
+
+	return nil
+}
+
+func (bcg *BalanceChangesGroup) add(address string, delta *big.Int) {
+	address = strings.ToLower(Trim0x(address))
+	if address == "" {
+		return
+	}
+
+	if _, ok := bcg.deltas[address]; !ok {
+		bcg.deltas[address] = new(big.Int)
+	}
+
+	bcg.deltas[address].Add(bcg.deltas[address], delta)
+}
@@ -0,0 +1,218 @@
+package storable
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/alethio/web3-go/types"
+)
+
+// powBlock is a Constantinople block (2 ETH of reward) with an uncle and a successful transfer, a transfer whose
+// receipt has a failed status, a contract call with internal transactions and a pre-Byzantium style transfer whose
+// failure is only known from its trace
+const powBlock = `{
+	"number": "0x6f6540",
+	"timestamp": "0x5c6d7b00",
+	"miner": "0x0000000000000000000000000000000000000011",
+	"difficulty": "0x1",
+	"uncles": ["0x00000000000000000000000000000000000000000000000000000000000000f1"],
+	"transactions": [
+		{"hash": "0x01", "transactionIndex": "0x0", "from": "0xaa", "to": "0xbb", "value": "0x3e8", "gasPrice": "0xa"},
+		{"hash": "0x02", "transactionIndex": "0x1", "from": "0xaa", "to": "0xcc", "value": "0x1f4", "gasPrice": "0xa"},
+		{"hash": "0x03", "transactionIndex": "0x2", "from": "0xaa", "to": "0xcc", "value": "0x0", "gasPrice": "0xa"},
+		{"hash": "0x04", "transactionIndex": "0x3", "from": "0xaa", "to": "0x12", "value": "0x384", "gasPrice": "0xa"}
+	]
+}`
+
+const powReceipts = `[
+	{"transactionHash": "0x01", "gasUsed": "0x5208", "status": "0x1"},
+	{"transactionHash": "0x02", "gasUsed": "0x7530", "status": "0x0"},
+	{"transactionHash": "0x03", "gasUsed": "0xc350", "status": "0x1"},
+	{"transactionHash": "0x04", "gasUsed": "0x5208"}
+]`
+
+const powUncles = `[{"number": "0x6f653f", "miner": "0x0000000000000000000000000000000000000099"}]`
+
+// powTraces are the traces of the contract call and of the transfer that failed; the second internal call of the
+// contract failed, which reverts the call it made, the delegate call doesn't move anything of its own and the call
+// made by the failed transfer was reverted along with it
+const powTraces = `[
+	{"transactionHash": "0x03", "transactionPosition": 2, "traceAddress": [], "type": "call", "action": {"callType": "call", "from": "0xaa", "to": "0xcc", "value": "0x0"}},
+	{"transactionHash": "0x03", "transactionPosition": 2, "traceAddress": [0], "type": "call", "action": {"callType": "call", "from": "0xcc", "to": "0xdd", "value": "0x64"}},
+	{"transactionHash": "0x03", "transactionPosition": 2, "traceAddress": [1], "type": "call", "action": {"callType": "call", "from": "0xcc", "to": "0xee", "value": "0xc8"}, "error": "Reverted"},
+	{"transactionHash": "0x03", "transactionPosition": 2, "traceAddress": [1, 0], "type": "call", "action": {"callType": "call", "from": "0xee", "to": "0xff", "value": "0x32"}},
+	{"transactionHash": "0x03", "transactionPosition": 2, "traceAddress": [2], "type": "call", "action": {"callType": "delegatecall", "from": "0xcc", "to": "0xab", "value": "0x7"}},
+	{"transactionHash": "0x04", "transactionPosition": 3, "traceAddress": [], "type": "call", "action": {"callType": "call", "from": "0xaa", "to": "0x12", "value": "0x384"}, "error": "Out of gas"},
+	{"transactionHash": "0x04", "transactionPosition": 3, "traceAddress": [0], "type": "call", "action": {"callType": "call", "from": "0x12", "to": "0x13", "value": "0x5"}}
+]`
+
+// decodeTestTraces decodes the uncles and traces of a block
+func decodeTestTraces(t *testing.T, rawUncles, rawTraces string) ([]types.Block, []types.Trace) {
+	var uncles []types.Block
+	err := json.Unmarshal([]byte(rawUncles), &uncles)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var traces []types.Trace
+	err = json.Unmarshal([]byte(rawTraces), &traces)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return uncles, traces
+}
+
+// balanceChanges returns the deltas computed for a block, by address
+func balanceChanges(t *testing.T, bcg *BalanceChangesGroup) map[string]string {
+	err := bcg.enhance()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := make(map[string]string, len(bcg.balanceChanges))
+	for i, bc := range bcg.balanceChanges {
+		if i > 0 && bcg.balanceChanges[i-1].Address >= bc.Address {
+			t.Errorf("expected the changes to be sorted by address, got %s after %s", bc.Address, bcg.balanceChanges[i-1].Address)
+		}
+		if bc.IncludedInBlock != bcg.blockNumber {
+			t.Errorf("expected the changes to be included in block %d, got %d", bcg.blockNumber, bc.IncludedInBlock)
+		}
+
+		changes[bc.Address] = bc.Delta
+	}
+
+	return changes
+}
+
+func checkBalanceChanges(t *testing.T, changes, expected map[string]string) {
+	for address, delta := range expected {
+		if changes[address] != delta {
+			t.Errorf("expected %s to change by %s, got %q", address, delta, changes[address])
+		}
+	}
+
+	for address, delta := range changes {
+		if _, ok := expected[address]; !ok {
+			t.Errorf("unexpected change of %s for %s", delta, address)
+		}
+	}
+}
+
+func TestBalanceChangesProofOfWork(t *testing.T) {
+	block, receipts, blockExtra, receiptsExtra := decodeTestBlock(t, powBlock, powReceipts)
+	uncles, traces := decodeTestTraces(t, powUncles, powTraces)
+
+	changes := balanceChanges(t, NewStorableBalanceChanges(block, receipts, blockExtra, receiptsExtra, uncles, traces, MainnetBlockRewards))
+
+	checkBalanceChanges(t, changes, map[string]string{
+		// 1000 sent and 21000 + 30000 + 50000 + 21000 gas paid at 10
+		"aa": "-1221000",
+		"bb": "1000",
+		"cc": "-100",
+		"dd": "100",
+		// the fees, the block reward and 1/32 of it for the uncle
+		"0000000000000000000000000000000000000011": "2062500000001220000",
+		// 7/8 of the block reward for an uncle one block older
+		"0000000000000000000000000000000000000099": "1750000000000000000",
+	})
+}
+
+func TestBalanceChangesWithoutBlockRewards(t *testing.T) {
+	block, receipts, blockExtra, receiptsExtra := decodeTestBlock(t, powBlock, powReceipts)
+	uncles, traces := decodeTestTraces(t, powUncles, powTraces)
+
+	changes := balanceChanges(t, NewStorableBalanceChanges(block, receipts, blockExtra, receiptsExtra, uncles, traces, nil))
+
+	checkBalanceChanges(t, changes, map[string]string{
+		"aa": "-1221000",
+		"bb": "1000",
+		"cc": "-100",
+		"dd": "100",
+		// only the fees, and nothing for the uncle
+		"0000000000000000000000000000000000000011": "1220000",
+	})
+}
+
+func TestParseBlockRewards(t *testing.T) {
+	rewards, err := ParseBlockRewards(BlockRewardsMainnet)
+	if err != nil || len(rewards) != len(MainnetBlockRewards) {
+		t.Errorf("expected the mainnet schedule, got %v, %v", rewards, err)
+	}
+
+	rewards, err = ParseBlockRewards(BlockRewardsNone)
+	if err != nil || rewards != nil {
+		t.Errorf("expected no rewards, got %v, %v", rewards, err)
+	}
+
+	rewards, err = ParseBlockRewards("0:5000, 100:3000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rewards) != 2 || rewards[0].Block != 0 || rewards[0].Reward.String() != "5000" ||
+		rewards[1].Block != 100 || rewards[1].Reward.String() != "3000" {
+		t.Errorf("unexpected rewards %v", rewards)
+	}
+
+	for _, value := range []string{"", "classic", "100", "x:1", "-1:1", "0:x", "0:-1", "100:1,50:1", "0:1,0:2"} {
+		_, err := ParseBlockRewards(value)
+		if err == nil {
+			t.Errorf("expected %q to be rejected", value)
+		}
+	}
+}
+
+// posBlock is a Cancun block with a base fee, a blob transaction, a contract creation and withdrawals
+const posBlock = `{
+	"number": "0x12a05f2",
+	"timestamp": "0x65f1b057",
+	"miner": "0x0000000000000000000000000000000000000011",
+	"difficulty": "0x0",
+	"baseFeePerGas": "0x64",
+	"withdrawals": [
+		{"index": "0x1", "validatorIndex": "0x1", "address": "0xbb", "amount": "0x1"},
+		{"index": "0x2", "validatorIndex": "0x2", "address": "0xcc", "amount": "0x2"}
+	],
+	"transactions": [
+		{"hash": "0x01", "from": "0xaa", "to": "0xbb", "value": "0x1", "gasPrice": "0x6e", "type": "0x3"},
+		{"hash": "0x02", "from": "0xaa", "to": null, "value": "0x5", "gasPrice": "0x6e", "type": "0x2"}
+	]
+}`
+
+const posReceipts = `[
+	{"transactionHash": "0x01", "gasUsed": "0x5208", "status": "0x1", "effectiveGasPrice": "0x6e", "blobGasUsed": "0x20000", "blobGasPrice": "0x2"},
+	{"transactionHash": "0x02", "gasUsed": "0x7530", "status": "0x1", "effectiveGasPrice": "0x6e", "contractAddress": "0xee"}
+]`
+
+func TestBalanceChangesProofOfStake(t *testing.T) {
+	block, receipts, blockExtra, receiptsExtra := decodeTestBlock(t, posBlock, posReceipts)
+
+	changes := balanceChanges(t, NewStorableBalanceChanges(block, receipts, blockExtra, receiptsExtra, nil, nil, MainnetBlockRewards))
+
+	checkBalanceChanges(t, changes, map[string]string{
+		// 6 sent, 21000 + 30000 gas at 110 and 131072 blob gas at 2
+		"aa": "-5872150",
+		// the value and a withdrawal of 1 gwei
+		"bb": "1000000001",
+		"cc": "2000000000",
+		"ee": "5",
+		// only the tips, 10 per gas; there's no block reward
+		"0000000000000000000000000000000000000011": "510000",
+	})
+}
+
+func TestBalanceChangesLeaveOutUnchangedAccounts(t *testing.T) {
+	block, receipts, blockExtra, _ := decodeTestBlock(t, posBlock, posReceipts)
+
+	// a transfer to itself at no cost (and without blobs) doesn't change anything
+	block.Transactions = block.Transactions[:1]
+	block.Transactions[0].To = block.Transactions[0].From
+	receipts = receipts[:1]
+	receipts[0].GasUsed = "0x0"
+	blockExtra.Withdrawals = nil
+
+	changes := balanceChanges(t, NewStorableBalanceChanges(block, receipts, blockExtra, nil, nil, nil, MainnetBlockRewards))
+	if len(changes) != 0 {
+		t.Errorf("expected no changes, got %v", changes)
+	}
+}
@@ -0,0 +1,103 @@
+package storable
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/Alethio/memento/eth/extra"
+	"github.com/Alethio/memento/storage"
+
+	"github.com/alethio/web3-go/types"
+)
+
+type BlobHashesGroup struct {
+	RawBlock      types.Block
+	RawBlockExtra extra.Block
+
+	blockNumber int64
+
+	blobHashes []*BlobHash
+}
+
+// BlobHash is the versioned hash of a blob carried by an EIP-4844 transaction
+// The blobs themselves are not part of the execution layer and are pruned by the consensus clients
+type BlobHash struct {
+	TxHash          string
+	IncludedInBlock int64
+	TxIndex         int32
+	BlobIndex       int32
+	VersionedHash   string
+}
+
+func NewStorableBlobHashes(block types.Block, blockExtra extra.Block) *BlobHashesGroup {
+	return &BlobHashesGroup{RawBlock: block, RawBlockExtra: blockExtra}
+}
+
+func (bg *BlobHashesGroup) ToDB(tx storage.Tx) error {
+	err := bg.enhance()
+	if err != nil {
+		return err
+	}
+
+	if len(bg.blobHashes) == 0 {
+		return nil
+	}
+
+	log.Trace("storing blob hashes")
+	start := time.Now()
+	defer func() {
+		log.WithField("duration", time.Since(start)).WithField("count", len(bg.blobHashes)).Debug("done storing blob hashes")
+	}()
+
+	stmt, err := tx.BulkInsert("blob_hashes", "tx_hash", "included_in_block", "tx_index", "blob_index", "versioned_hash")
+	if err != nil {
+		return err
+	}
+
+	for _, b := range bg.blobHashes {
+		_, err = stmt.Exec(b.TxHash, b.IncludedInBlock, b.TxIndex, b.BlobIndex, b.VersionedHash)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = stmt.Exec()
+	if err != nil {
+		return err
+	}
+
+	err = stmt.Close()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (bg *BlobHashesGroup) InsertedRows() (string, int) {
+	return "blob_hashes", len(bg.blobHashes)
+}
+
+// enhance collects the versioned hashes of all the blob transactions in the block
+func (bg *BlobHashesGroup) enhance() error {
+	number, err := strconv.ParseInt(bg.RawBlock.Number, 0, 64)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	bg.blockNumber = number
+
+	for index, tx := range bg.RawBlock.Transactions {
+		for blobIndex, hash := range bg.RawBlockExtra.Tx(index).BlobVersionedHashes {
+			bg.blobHashes = append(bg.blobHashes, &BlobHash{
+				TxHash:          Trim0x(tx.Hash),
+				IncludedInBlock: bg.blockNumber,
+				TxIndex:         int32(index),
+				BlobIndex:       int32(blobIndex),
+				VersionedHash:   Trim0x(hash),
+			})
+		}
+	}
+
+	return nil
+}
@@ -1,11 +1,12 @@
 package storable
 
 import (
-	"database/sql"
+	"math/big"
 	"strconv"
 	"time"
 
-	"github.com/lib/pq"
+	"github.com/Alethio/memento/eth/extra"
+	"github.com/Alethio/memento/storage"
 
 	"github.com/sirupsen/logrus"
 
@@ -14,8 +15,19 @@ import (
 
 var log = logrus.WithField("module", "data")
 
+// Finality statuses of a stored block, named after the block tags of the JSON-RPC API
+const (
+	FinalityLatest    = "latest"
+	FinalitySafe      = "safe"
+	FinalityFinalized = "finalized"
+)
+
 type Block struct {
-	RawBlock             types.Block
+	RawBlock         types.Block
+	RawBlockExtra    extra.Block
+	RawReceipts      []types.Receipt
+	RawReceiptsExtra map[string]extra.Receipt
+
 	Number               int64
 	BlockHash            string
 	ParentBlockHash      string
@@ -36,13 +48,44 @@ type Block struct {
 	Sha3Uncles           ByteArray
 	NumberOfUncles       int32
 	NumberOfTxs          int32
+	Finality             string
+
+	// BaseFeePerGas is only set for blocks after London
+	BaseFeePerGas *string
+
+	// BurntFees is the part of the transaction fees that was burnt (base fee * gas used) and PriorityFees the part
+	// that went to the miner
+	BurntFees    string
+	PriorityFees string
+
+	// Shanghai and Cancun fields; not set for the blocks before the respective forks
+	WithdrawalsRoot       ByteArray
+	NumberOfWithdrawals   int32
+	BlobGasUsed           *string
+	ExcessBlobGas         *string
+	ParentBeaconBlockRoot ByteArray
+
+	// HasTraces is set if the traces of the block were scraped, without which the internal transactions (and the
+	// balance changes they cause) are missing
+	HasTraces bool
+
+	// StorableVersions records the version of each storable the rows derived from the block were produced with
+	StorableVersions string
 }
 
-func NewStorableBlock(block types.Block) *Block {
-	return &Block{RawBlock: block}
+func NewStorableBlock(block types.Block, receipts []types.Receipt, blockExtra extra.Block, receiptsExtra map[string]extra.Receipt, finality string, hasTraces bool, storableVersions string) *Block {
+	return &Block{
+		RawBlock:         block,
+		RawBlockExtra:    blockExtra,
+		RawReceipts:      receipts,
+		RawReceiptsExtra: receiptsExtra,
+		Finality:         finality,
+		HasTraces:        hasTraces,
+		StorableVersions: storableVersions,
+	}
 }
 
-func (sb *Block) ToDB(tx *sql.Tx) error {
+func (sb *Block) ToDB(tx storage.Tx) error {
 	log.Trace("storing block")
 	start := time.Now()
 	defer func() { log.WithField("duration", time.Since(start)).Debug("done storing block") }()
@@ -52,12 +95,12 @@ func (sb *Block) ToDB(tx *sql.Tx) error {
 		return err
 	}
 
-	stmt, err := tx.Prepare(pq.CopyIn("blocks", "number", "block_hash", "parent_block_hash", "block_creation_time", "block_gas_limit", "block_gas_used", "block_difficulty", "total_block_difficulty", "block_extra_data", "block_mix_hash", "block_nonce", "block_size", "block_logs_bloom", "includes_uncle", "has_beneficiary", "has_receipts_trie", "has_tx_trie", "sha3_uncles", "number_of_uncles", "number_of_txs"))
+	stmt, err := tx.BulkInsert("blocks", "number", "block_hash", "parent_block_hash", "block_creation_time", "block_gas_limit", "block_gas_used", "block_difficulty", "total_block_difficulty", "block_extra_data", "block_mix_hash", "block_nonce", "block_size", "block_logs_bloom", "includes_uncle", "has_beneficiary", "has_receipts_trie", "has_tx_trie", "sha3_uncles", "number_of_uncles", "number_of_txs", "finality", "base_fee_per_gas", "burnt_fees", "priority_fees", "withdrawals_root", "number_of_withdrawals", "blob_gas_used", "excess_blob_gas", "parent_beacon_block_root", "has_traces", "storable_versions")
 	if err != nil {
 		return err
 	}
 
-	_, err = stmt.Exec(sb.Number, sb.BlockHash, sb.ParentBlockHash, sb.BlockCreationTime, sb.BlockGasLimit, sb.BlockGasUsed, sb.BlockDifficulty, sb.TotalBlockDifficulty, sb.BlockExtraData, sb.BlockMixHash, sb.BlockNonce, sb.BlockSize, sb.BlockLogsBloom, sb.IncludesUncle, sb.HasBeneficiary, sb.HasReceiptsTrie, sb.HasTxTrie, sb.Sha3Uncles, sb.NumberOfUncles, sb.NumberOfTxs)
+	_, err = stmt.Exec(sb.Number, sb.BlockHash, sb.ParentBlockHash, sb.BlockCreationTime, sb.BlockGasLimit, sb.BlockGasUsed, sb.BlockDifficulty, sb.TotalBlockDifficulty, sb.BlockExtraData, sb.BlockMixHash, sb.BlockNonce, sb.BlockSize, sb.BlockLogsBloom, sb.IncludesUncle, sb.HasBeneficiary, sb.HasReceiptsTrie, sb.HasTxTrie, sb.Sha3Uncles, sb.NumberOfUncles, sb.NumberOfTxs, sb.Finality, sb.BaseFeePerGas, sb.BurntFees, sb.PriorityFees, sb.WithdrawalsRoot, sb.NumberOfWithdrawals, sb.BlobGasUsed, sb.ExcessBlobGas, sb.ParentBeaconBlockRoot, sb.HasTraces, sb.StorableVersions)
 	if err != nil {
 		return err
 	}
@@ -75,6 +118,10 @@ func (sb *Block) ToDB(tx *sql.Tx) error {
 	return nil
 }
 
+func (sb *Block) InsertedRows() (string, int) {
+	return "blocks", 1
+}
+
 // enhance processes all the raw data of a block and does the necessary transformations,
 // resulting in an object that's ready for inserting into the database
 func (sb *Block) enhance() error {
@@ -95,6 +142,12 @@ func (sb *Block) enhance() error {
 	sb.HasReceiptsTrie = ByteArray(Trim0x(b.ReceiptsRoot))
 	sb.HasTxTrie = ByteArray(Trim0x(b.TransactionsRoot))
 	sb.Sha3Uncles = ByteArray(Trim0x(b.Sha3Uncles))
+	sb.WithdrawalsRoot = ByteArray(Trim0x(sb.RawBlockExtra.WithdrawalsRoot))
+	sb.ParentBeaconBlockRoot = ByteArray(Trim0x(sb.RawBlockExtra.ParentBeaconBlockRoot))
+
+	if sb.Finality == "" {
+		sb.Finality = FinalityLatest
+	}
 
 	// -- ints
 	number, err := strconv.ParseInt(b.Number, 0, 64)
@@ -148,9 +201,72 @@ func (sb *Block) enhance() error {
 	}
 	sb.BlockCreationTime = DatetimeToJSONUnix(time.Unix(timestamp, 0))
 
+	sb.BaseFeePerGas, err = nullableHexStrToBigIntStr(sb.RawBlockExtra.BaseFeePerGas)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	sb.BlobGasUsed, err = nullableHexStrToBigIntStr(sb.RawBlockExtra.BlobGasUsed)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	sb.ExcessBlobGas, err = nullableHexStrToBigIntStr(sb.RawBlockExtra.ExcessBlobGas)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
 	// -- computed
 	sb.NumberOfTxs = int32(len(b.Transactions))
 	sb.NumberOfUncles = int32(len(b.Uncles))
+	sb.NumberOfWithdrawals = int32(len(sb.RawBlockExtra.Withdrawals))
+
+	err = sb.computeFees()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// computeFees splits the fees paid by the transactions in the block into the burnt base fee and the priority fee
+// Before London there's no base fee, so all the fees count as priority fees
+func (sb *Block) computeFees() error {
+	baseFee := new(big.Int)
+	if sb.BaseFeePerGas != nil {
+		baseFee.SetString(*sb.BaseFeePerGas, 10)
+	}
+
+	burnt := new(big.Int)
+	priority := new(big.Int)
+	for index, tx := range sb.RawBlock.Transactions {
+		if index >= len(sb.RawReceipts) {
+			break
+		}
+
+		gasUsed, err := HexStrToBigInt(sb.RawReceipts[index].GasUsed)
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+
+		price, err := HexStrToBigInt(effectiveGasPrice(tx, sb.RawReceiptsExtra[tx.Hash]))
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+
+		burnt.Add(burnt, new(big.Int).Mul(baseFee, gasUsed))
+
+		tip := new(big.Int).Sub(price, baseFee)
+		priority.Add(priority, tip.Mul(tip, gasUsed))
+	}
+
+	sb.BurntFees = burnt.String()
+	sb.PriorityFees = priority.String()
 
 	return nil
 }
@@ -0,0 +1,212 @@
+package storable
+
+import (
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"github.com/Alethio/memento/storage"
+	"golang.org/x/crypto/sha3"
+
+	"github.com/alethio/web3-go/types"
+)
+
+type ContractsGroup struct {
+	RawBlock    types.Block
+	RawReceipts []types.Receipt
+	RawTraces   []types.Trace
+	RawCodes    map[string]string
+
+	blockNumber       int64
+	blockCreationTime DatetimeToJSONUnix
+
+	contracts []*Contract
+}
+
+// Contract is an account created by a contract creation transaction or, when traces are scraped, by another contract
+// (e.g. a factory); TraceAddress is empty for the former
+// Creations that failed or were reverted are not recorded
+type Contract struct {
+	Address         string
+	Creator         string
+	TxHash          string
+	IncludedInBlock int64
+	TxIndex         int32
+	TraceAddress    string
+	CodeHash        *string
+}
+
+// NewStorableContracts builds the contracts created in a block from the traces if there are any, or from the receipts
+// otherwise; codes holds the deployed code of the contracts keyed by address, which is only needed without traces
+func NewStorableContracts(block types.Block, receipts []types.Receipt, traces []types.Trace, codes map[string]string) *ContractsGroup {
+	return &ContractsGroup{
+		RawBlock:    block,
+		RawReceipts: receipts,
+		RawTraces:   traces,
+		RawCodes:    codes,
+	}
+}
+
+func (cg *ContractsGroup) ToDB(tx storage.Tx) error {
+	err := cg.enhance()
+	if err != nil {
+		return err
+	}
+
+	if len(cg.contracts) == 0 {
+		return nil
+	}
+
+	log.Trace("storing contracts")
+	start := time.Now()
+	defer func() {
+		log.WithField("duration", time.Since(start)).WithField("count", len(cg.contracts)).Debug("done storing contracts")
+	}()
+
+	stmt, err := tx.BulkInsert("contracts", "address", "creator", "tx_hash", "included_in_block", "tx_index", "trace_address", "code_hash", "block_creation_time")
+	if err != nil {
+		return err
+	}
+
+	for _, c := range cg.contracts {
+		_, err = stmt.Exec(c.Address, c.Creator, c.TxHash, c.IncludedInBlock, c.TxIndex, c.TraceAddress, c.CodeHash, cg.blockCreationTime)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = stmt.Exec()
+	if err != nil {
+		return err
+	}
+
+	err = stmt.Close()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (cg *ContractsGroup) InsertedRows() (string, int) {
+	return "contracts", len(cg.contracts)
+}
+
+func (cg *ContractsGroup) enhance() error {
+	number, err := strconv.ParseInt(cg.RawBlock.Number, 0, 64)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	cg.blockNumber = number
+
+	timestamp, err := strconv.ParseInt(cg.RawBlock.Timestamp, 0, 64)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	cg.blockCreationTime = DatetimeToJSONUnix(time.Unix(timestamp, 0))
+
+	if len(cg.RawTraces) > 0 {
+		return cg.enhanceFromTraces()
+	}
+
+	return cg.enhanceFromReceipts()
+}
+
+func (cg *ContractsGroup) enhanceFromReceipts() error {
+	for index, tx := range cg.RawBlock.Transactions {
+		receipt := cg.RawReceipts[index]
+
+		address, ok := receipt.ContractAddress.(string)
+		if !ok || address == "" || receipt.Status == "0x0" {
+			continue
+		}
+
+		c := &Contract{
+			Address:         Trim0x(address),
+			Creator:         Trim0x(tx.From),
+			TxHash:          Trim0x(tx.Hash),
+			IncludedInBlock: cg.blockNumber,
+			TxIndex:         int32(index),
+		}
+
+		if code, ok := cg.RawCodes[address]; ok {
+			codeHash, err := CodeHash(code)
+			if err != nil {
+				log.Error(err)
+				return err
+			}
+			c.CodeHash = &codeHash
+		}
+
+		cg.contracts = append(cg.contracts, c)
+	}
+
+	return nil
+}
+
+func (cg *ContractsGroup) enhanceFromTraces() error {
+	// the calls made by a reverted call are reverted too, even though their own trace has no error
+	reverted := make(map[string]bool)
+
+	for _, trace := range cg.RawTraces {
+		if trace.TransactionHash == nil || trace.TransactionPosition == nil {
+			continue
+		}
+
+		key := *trace.TransactionHash + "-" + FormatTraceAddress(trace.TraceAddress)
+		parentKey := *trace.TransactionHash + "-" + FormatTraceAddress(parentTraceAddress(trace.TraceAddress))
+		if trace.Error != nil || len(trace.TraceAddress) > 0 && reverted[parentKey] {
+			reverted[key] = true
+			continue
+		}
+
+		if trace.Type != "create" || trace.Result == nil || trace.Result.Address == nil || trace.Action.From == nil {
+			continue
+		}
+
+		c := &Contract{
+			Address:         Trim0x(*trace.Result.Address),
+			Creator:         Trim0x(*trace.Action.From),
+			TxHash:          Trim0x(*trace.TransactionHash),
+			IncludedInBlock: cg.blockNumber,
+			TxIndex:         int32(*trace.TransactionPosition),
+			TraceAddress:    FormatTraceAddress(trace.TraceAddress),
+		}
+
+		if trace.Result.Code != nil {
+			codeHash, err := CodeHash(*trace.Result.Code)
+			if err != nil {
+				log.Error(err)
+				return err
+			}
+			c.CodeHash = &codeHash
+		}
+
+		cg.contracts = append(cg.contracts, c)
+	}
+
+	return nil
+}
+
+func parentTraceAddress(traceAddress []int) []int {
+	if len(traceAddress) == 0 {
+		return nil
+	}
+
+	return traceAddress[:len(traceAddress)-1]
+}
+
+// CodeHash returns the Keccak-256 hash of the hex encoded code of a contract, as a hex string without 0x
+func CodeHash(code string) (string, error) {
+	raw, err := hex.DecodeString(Trim0x(code))
+	if err != nil {
+		return "", err
+	}
+
+	h := sha3.NewLegacyKeccak256()
+	h.Write(raw)
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,269 @@
+package storable
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Alethio/memento/storage"
+
+	"github.com/alethio/web3-go/types"
+)
+
+type InternalTxsGroup struct {
+	RawBlock  types.Block
+	RawTraces []types.Trace
+
+	blockNumber int64
+
+	internalTxs []*InternalTx
+}
+
+// InternalTx is a message call, contract creation or self-destruct executed by a contract as a result of a transaction
+// The top-level call of each transaction is not stored since it is the transaction itself
+type InternalTx struct {
+	TxHash          string
+	IncludedInBlock int64
+	TxIndex         int32
+	TraceIndex      int32
+	TraceAddress    string
+	Type            string
+	CallType        string
+	From            ByteArray
+	To              ByteArray
+	Value           string
+	MsgGasLimit     string
+	MsgGasUsed      string
+	MsgPayload      ByteArray
+	MsgError        string
+}
+
+func NewStorableInternalTxs(block types.Block, traces []types.Trace) *InternalTxsGroup {
+	return &InternalTxsGroup{RawBlock: block, RawTraces: traces}
+}
+
+func (itg *InternalTxsGroup) ToDB(tx storage.Tx) error {
+	if len(itg.RawTraces) == 0 {
+		return nil
+	}
+
+	log.Trace("storing internal txs")
+	start := time.Now()
+	defer func() {
+		log.WithField("duration", time.Since(start)).WithField("count", len(itg.internalTxs)).Debug("done storing internal txs")
+	}()
+
+	err := itg.enhance()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.BulkInsert("internal_txs", "tx_hash", "included_in_block", "tx_index", "trace_index", "trace_address", "type", "call_type", "from", "to", "value", "msg_gas_limit", "msg_gas_used", "msg_payload", "msg_error")
+	if err != nil {
+		return err
+	}
+
+	for _, it := range itg.internalTxs {
+		_, err = stmt.Exec(it.TxHash, it.IncludedInBlock, it.TxIndex, it.TraceIndex, it.TraceAddress, it.Type, it.CallType, it.From, it.To, it.Value, it.MsgGasLimit, it.MsgGasUsed, it.MsgPayload, it.MsgError)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = stmt.Exec()
+	if err != nil {
+		return err
+	}
+
+	err = stmt.Close()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (itg *InternalTxsGroup) InsertedRows() (string, int) {
+	return "internal_txs", len(itg.internalTxs)
+}
+
+// enhance processes the raw traces and generates a list of InternalTx entities for all the internal calls in the block
+func (itg *InternalTxsGroup) enhance() error {
+	number, err := strconv.ParseInt(itg.RawBlock.Number, 0, 64)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	itg.blockNumber = number
+
+	var traceIndex int32
+	for _, trace := range itg.RawTraces {
+		if !IsInternalTrace(trace) {
+			continue
+		}
+
+		it, err := itg.buildStorableInternalTx(trace, traceIndex)
+		if err != nil {
+			return err
+		}
+
+		itg.internalTxs = append(itg.internalTxs, it)
+		traceIndex++
+	}
+
+	return nil
+}
+
+func (itg *InternalTxsGroup) buildStorableInternalTx(trace types.Trace, traceIndex int32) (*InternalTx, error) {
+	it := &InternalTx{}
+	it.IncludedInBlock = itg.blockNumber
+	it.TxHash = Trim0x(*trace.TransactionHash)
+	it.TxIndex = int32(*trace.TransactionPosition)
+	it.TraceIndex = traceIndex
+	it.TraceAddress = FormatTraceAddress(trace.TraceAddress)
+	it.Type = trace.Type
+
+	from, to, value := TraceParticipants(trace)
+	it.From = ByteArray(Trim0x(from))
+	it.To = ByteArray(Trim0x(to))
+
+	if trace.Action.CallType != nil {
+		it.CallType = *trace.Action.CallType
+	}
+
+	if trace.Action.Input != nil {
+		it.MsgPayload = ByteArray(Trim0x(*trace.Action.Input))
+	} else if trace.Action.Init != nil {
+		it.MsgPayload = ByteArray(Trim0x(*trace.Action.Init))
+	}
+
+	if trace.Error != nil {
+		it.MsgError = *trace.Error
+	}
+
+	var err error
+	it.Value, err = optionalHexStrToBigIntStr(value)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
+	var gas, gasUsed string
+	if trace.Action.Gas != nil {
+		gas = *trace.Action.Gas
+	}
+	if trace.Result != nil && trace.Result.GasUsed != nil {
+		gasUsed = *trace.Result.GasUsed
+	}
+
+	it.MsgGasLimit, err = optionalHexStrToBigIntStr(gas)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
+	it.MsgGasUsed, err = optionalHexStrToBigIntStr(gasUsed)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
+	return it, nil
+}
+
+// IsInternalTrace returns true if the trace describes a call triggered by a contract, as opposed to the top-level
+// call of a transaction or a block/uncle reward
+func IsInternalTrace(trace types.Trace) bool {
+	return len(trace.TraceAddress) > 0 && trace.TransactionHash != nil && trace.TransactionPosition != nil && trace.Type != "reward"
+}
+
+// IsDelegateCall returns true if the trace is a delegate call, which runs in the context of the caller, so the value
+// it carries only repeats the caller's and isn't moved by it
+func IsDelegateCall(trace types.Trace) bool {
+	return trace.Action.CallType != nil && *trace.Action.CallType == "delegatecall"
+}
+
+// failedTraces holds the failed traces of a block, keyed by transaction hash and trace address
+type failedTraces map[string]bool
+
+func newFailedTraces(traces []types.Trace) failedTraces {
+	failed := make(failedTraces)
+	for _, trace := range traces {
+		if trace.Error != nil && trace.TransactionHash != nil {
+			failed[traceKey(*trace.TransactionHash, trace.TraceAddress)] = true
+		}
+	}
+
+	return failed
+}
+
+// txFailed returns true if the top-level call of the transaction failed
+func (f failedTraces) txFailed(txHash string) bool {
+	return f[traceKey(txHash, nil)]
+}
+
+// reverted returns true if the trace or one of the traces it was called from failed
+func (f failedTraces) reverted(trace types.Trace) bool {
+	for i := 0; i <= len(trace.TraceAddress); i++ {
+		if f[traceKey(*trace.TransactionHash, trace.TraceAddress[:i])] {
+			return true
+		}
+	}
+
+	return false
+}
+
+func traceKey(txHash string, traceAddress []int) string {
+	return strings.ToLower(Trim0x(txHash)) + ":" + FormatTraceAddress(traceAddress)
+}
+
+// TraceParticipants returns the sender, the recipient and the value (as hex) moved by the given trace, regardless
+// of its type
+func TraceParticipants(trace types.Trace) (from, to, value string) {
+	a := trace.Action
+
+	switch trace.Type {
+	case "create":
+		if a.From != nil {
+			from = *a.From
+		}
+		if trace.Result != nil && trace.Result.Address != nil {
+			to = *trace.Result.Address
+		}
+		if a.Value != nil {
+			value = *a.Value
+		}
+	case "suicide":
+		if a.Address != nil {
+			from = *a.Address
+		}
+		if a.RefundAddress != nil {
+			to = *a.RefundAddress
+		}
+		if a.Balance != nil {
+			value = *a.Balance
+		}
+	default:
+		if a.From != nil {
+			from = *a.From
+		}
+		if a.To != nil {
+			to = *a.To
+		}
+		if a.Value != nil {
+			value = *a.Value
+		}
+	}
+
+	return
+}
+
+// FormatTraceAddress transforms a trace address like [0 2 1] into its string representation "0_2_1"
+func FormatTraceAddress(traceAddress []int) string {
+	parts := make([]string, len(traceAddress))
+	for i, v := range traceAddress {
+		parts[i] = strconv.Itoa(v)
+	}
+
+	return strings.Join(parts, "_")
+}
@@ -1,11 +1,11 @@
 package storable
 
 import (
-	"database/sql"
+	"fmt"
 	"strconv"
 	"time"
 
-	"github.com/lib/pq"
+	"github.com/Alethio/memento/storage"
 
 	"github.com/alethio/web3-go/types"
 )
@@ -20,8 +20,14 @@ type LogEntriesGroup struct {
 }
 
 type LogEntry struct {
-	TxHash          string
-	LogIndex        int32
+	TxHash string
+
+	// LogIndex is the position of the log entry within its transaction's receipt and BlockLogIndex its position within
+	// the block, which is what the nodes call logIndex
+	LogIndex      int32
+	BlockLogIndex int32
+	BlockHash     string
+
 	LogData         ByteArray
 	LoggedBy        string
 	Topic0          string
@@ -35,7 +41,7 @@ func NewStorableLogEntries(block types.Block, receipts []types.Receipt) *LogEntr
 	return &LogEntriesGroup{RawBlock: block, RawReceipts: receipts}
 }
 
-func (leg *LogEntriesGroup) ToDB(tx *sql.Tx) error {
+func (leg *LogEntriesGroup) ToDB(tx storage.Tx) error {
 	if len(leg.RawReceipts) == 0 {
 		return nil
 	}
@@ -51,13 +57,13 @@ func (leg *LogEntriesGroup) ToDB(tx *sql.Tx) error {
 		return err
 	}
 
-	stmt, err := tx.Prepare(pq.CopyIn("log_entries", "tx_hash", "log_index", "log_data", "logged_by", "topic_0", "topic_1", "topic_2", "topic_3", "included_in_block"))
+	stmt, err := tx.BulkInsert("log_entries", "tx_hash", "log_index", "log_data", "logged_by", "topic_0", "topic_1", "topic_2", "topic_3", "included_in_block", "block_log_index", "block_hash")
 	if err != nil {
 		return err
 	}
 
 	for _, log := range leg.logEntries {
-		_, err = stmt.Exec(log.TxHash, log.LogIndex, log.LogData, log.LoggedBy, log.Topic0, log.Topic1, log.Topic2, log.Topic3, log.IncludedInBlock)
+		_, err = stmt.Exec(log.TxHash, log.LogIndex, log.LogData, log.LoggedBy, log.Topic0, log.Topic1, log.Topic2, log.Topic3, log.IncludedInBlock, log.BlockLogIndex, log.BlockHash)
 		if err != nil {
 			return err
 		}
@@ -76,8 +82,14 @@ func (leg *LogEntriesGroup) ToDB(tx *sql.Tx) error {
 	return nil
 }
 
+func (leg *LogEntriesGroup) InsertedRows() (string, int) {
+	return "log_entries", len(leg.logEntries)
+}
+
 // enhance processes the raw receipts data and generates a combined list of LogEntry entities for all the
 // transactions included in the block
+// The block log indexes are the ones given by the node; they must match the count of the logs across the receipts,
+// which fails for receipts that are missing logs or out of the order of the transactions
 func (leg *LogEntriesGroup) enhance() error {
 	number, err := strconv.ParseInt(leg.RawBlock.Number, 0, 64)
 	if err != nil {
@@ -86,6 +98,7 @@ func (leg *LogEntriesGroup) enhance() error {
 	}
 	leg.blockNumber = number
 
+	var blockIndex int32
 	for _, receipt := range leg.RawReceipts {
 		for index, log := range receipt.Logs {
 			le, err := leg.buildStorableLogEntry(log, receipt.TransactionHash, int32(index))
@@ -93,6 +106,21 @@ func (leg *LogEntriesGroup) enhance() error {
 				return err
 			}
 
+			// nodes that leave out the log index get it from the count
+			if log.LogIndex != "" {
+				nodeIndex, err := strconv.ParseInt(log.LogIndex, 0, 32)
+				if err != nil {
+					return fmt.Errorf("invalid log index %q in the receipt of %s", log.LogIndex, receipt.TransactionHash)
+				}
+
+				if int32(nodeIndex) != blockIndex {
+					return fmt.Errorf("log %d of %s has log index %d instead of %d; the receipts are incomplete or out of order", index, receipt.TransactionHash, nodeIndex, blockIndex)
+				}
+			}
+
+			le.BlockLogIndex = blockIndex
+			blockIndex++
+
 			leg.logEntries = append(leg.logEntries, le)
 		}
 	}
@@ -105,6 +133,7 @@ func (leg *LogEntriesGroup) buildStorableLogEntry(log types.Log, txHash string,
 	l.IncludedInBlock = leg.blockNumber
 	l.TxHash = Trim0x(txHash)
 	l.LogIndex = index // = transaction log index
+	l.BlockHash = Trim0x(leg.RawBlock.Hash)
 
 	l.LogData = ByteArray(Trim0x(log.Data))
 	l.LoggedBy = Trim0x(log.Address)
@@ -0,0 +1,88 @@
+package storable
+
+import (
+	"strings"
+	"testing"
+)
+
+// logsBlock has two transactions with two logs each, whose receipts come with the log indexes of the block
+const logsBlock = `{
+	"number": "0x10",
+	"hash": "0x00000000000000000000000000000000000000000000000000000000000000b2",
+	"transactions": [
+		{"hash": "0x01", "transactionIndex": "0x0", "from": "0xaa", "to": "0xbb", "value": "0x0"},
+		{"hash": "0x02", "transactionIndex": "0x1", "from": "0xaa", "to": "0xcc", "value": "0x0"}
+	]
+}`
+
+const logsReceipts = `[
+	{"transactionHash": "0x01", "transactionIndex": "0x0", "logs": [
+		{"address": "0xbb", "topics": ["0x01"], "data": "0x", "logIndex": "0x0"},
+		{"address": "0xbb", "topics": ["0x02", "0x03"], "data": "0xff", "logIndex": "0x1"}
+	]},
+	{"transactionHash": "0x02", "transactionIndex": "0x1", "logs": [
+		{"address": "0xcc", "topics": [], "data": "0x", "logIndex": "0x2"},
+		{"address": "0xcc", "topics": ["0x04"], "data": "0x", "logIndex": "0x3"}
+	]}
+]`
+
+func TestLogEntriesIndexes(t *testing.T) {
+	block, receipts, _, _ := decodeTestBlock(t, logsBlock, logsReceipts)
+
+	leg := NewStorableLogEntries(block, receipts)
+	err := leg.enhance()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct {
+		txHash        string
+		logIndex      int32
+		blockLogIndex int32
+	}{
+		{"01", 0, 0},
+		{"01", 1, 1},
+		{"02", 0, 2},
+		{"02", 1, 3},
+	}
+
+	if len(leg.logEntries) != len(expected) {
+		t.Fatalf("expected %d log entries, got %d", len(expected), len(leg.logEntries))
+	}
+
+	for i, e := range expected {
+		le := leg.logEntries[i]
+		if le.TxHash != e.txHash || le.LogIndex != e.logIndex || le.BlockLogIndex != e.blockLogIndex || le.BlockHash != strings.Repeat("0", 62)+"b2" {
+			t.Errorf("log %d: expected %+v, got %+v", i, e, le)
+		}
+	}
+}
+
+func TestLogEntriesRejectMismatchedIndexes(t *testing.T) {
+	block, receipts, _, _ := decodeTestBlock(t, logsBlock, logsReceipts)
+
+	// the receipts in the wrong order disagree with the indexes given by the node
+	receipts[0], receipts[1] = receipts[1], receipts[0]
+
+	err := NewStorableLogEntries(block, receipts).enhance()
+	if err == nil {
+		t.Errorf("expected the receipts out of order to be rejected")
+	}
+
+	// without the indexes of the node, the logs are counted
+	block, receipts, _, _ = decodeTestBlock(t, logsBlock, logsReceipts)
+	for i := range receipts {
+		for j := range receipts[i].Logs {
+			receipts[i].Logs[j].LogIndex = ""
+		}
+	}
+
+	leg := NewStorableLogEntries(block, receipts)
+	err = leg.enhance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last := leg.logEntries[len(leg.logEntries)-1]; last.BlockLogIndex != 3 {
+		t.Errorf("expected the last log to be counted at 3, got %d", last.BlockLogIndex)
+	}
+}
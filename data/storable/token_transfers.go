@@ -0,0 +1,295 @@
+package storable
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/Alethio/memento/storage"
+
+	"github.com/alethio/web3-go/types"
+)
+
+const (
+	TokenStandardERC20   = "erc20"
+	TokenStandardERC721  = "erc721"
+	TokenStandardERC1155 = "erc1155"
+
+	// Transfer(address,address,uint256) - shared by ERC-20 and ERC-721
+	TransferEventTopic = "ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+
+	// TransferSingle(address,address,address,uint256,uint256)
+	TransferSingleEventTopic = "c3d58168c5ae7397731d063d5bbf3d657854427343f4c083240f7aacaa2d0f62"
+
+	// TransferBatch(address,address,address,uint256[],uint256[])
+	TransferBatchEventTopic = "4a39dc06d4c0dbc64b70af90fd698a233a518aa5d07e595d983b8c0526c8f7fb"
+)
+
+type TokenTransfersGroup struct {
+	RawBlock    types.Block
+	RawReceipts []types.Receipt
+
+	blockNumber int64
+
+	tokenTransfers []*TokenTransfer
+}
+
+// TokenTransfer is a movement of fungible or non-fungible tokens decoded from a Transfer, TransferSingle or
+// TransferBatch event
+// A TransferBatch event generates one TokenTransfer for each of the token IDs it contains, identified by BatchIndex
+type TokenTransfer struct {
+	TxHash          string
+	IncludedInBlock int64
+	TxIndex         int32
+	LogIndex        int32
+	BatchIndex      int32
+	TokenAddress    string
+	TokenStandard   string
+	Operator        string
+	From            string
+	To              string
+	Amount          string
+	TokenID         *string
+}
+
+func NewStorableTokenTransfers(block types.Block, receipts []types.Receipt) *TokenTransfersGroup {
+	return &TokenTransfersGroup{RawBlock: block, RawReceipts: receipts}
+}
+
+func (ttg *TokenTransfersGroup) ToDB(tx storage.Tx) error {
+	if len(ttg.RawReceipts) == 0 {
+		return nil
+	}
+
+	log.Trace("storing token transfers")
+	start := time.Now()
+	defer func() {
+		log.WithField("duration", time.Since(start)).WithField("count", len(ttg.tokenTransfers)).Debug("done storing token transfers")
+	}()
+
+	err := ttg.enhance()
+	if err != nil {
+		return err
+	}
+
+	if len(ttg.tokenTransfers) == 0 {
+		return nil
+	}
+
+	stmt, err := tx.BulkInsert("token_transfers", "tx_hash", "included_in_block", "tx_index", "log_index", "batch_index", "token_address", "token_standard", "operator", "from", "to", "amount", "token_id")
+	if err != nil {
+		return err
+	}
+
+	for _, tt := range ttg.tokenTransfers {
+		_, err = stmt.Exec(tt.TxHash, tt.IncludedInBlock, tt.TxIndex, tt.LogIndex, tt.BatchIndex, tt.TokenAddress, tt.TokenStandard, tt.Operator, tt.From, tt.To, tt.Amount, tt.TokenID)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = stmt.Exec()
+	if err != nil {
+		return err
+	}
+
+	err = stmt.Close()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (ttg *TokenTransfersGroup) InsertedRows() (string, int) {
+	return "token_transfers", len(ttg.tokenTransfers)
+}
+
+// enhance goes through all the logs in the block's receipts and decodes the ones that represent token transfers
+// Logs that match a transfer event signature but can't be decoded (non-standard implementations) are skipped
+func (ttg *TokenTransfersGroup) enhance() error {
+	number, err := strconv.ParseInt(ttg.RawBlock.Number, 0, 64)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	ttg.blockNumber = number
+
+	for _, receipt := range ttg.RawReceipts {
+		txIndex, err := strconv.ParseInt(receipt.TransactionIndex, 0, 32)
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+
+		for index, l := range receipt.Logs {
+			transfers, err := ttg.decodeTransfers(l)
+			if err != nil {
+				log.WithField("tx", receipt.TransactionHash).WithField("logIndex", index).Debug("could not decode token transfer: ", err)
+				continue
+			}
+
+			for _, tt := range transfers {
+				tt.TxHash = Trim0x(receipt.TransactionHash)
+				tt.IncludedInBlock = ttg.blockNumber
+				tt.TxIndex = int32(txIndex)
+				tt.LogIndex = int32(index)
+				tt.TokenAddress = Trim0x(l.Address)
+			}
+
+			ttg.tokenTransfers = append(ttg.tokenTransfers, transfers...)
+		}
+	}
+
+	return nil
+}
+
+// decodeTransfers returns the token transfers described by a log entry or nil if the log is not a transfer event
+func (ttg *TokenTransfersGroup) decodeTransfers(l types.Log) ([]*TokenTransfer, error) {
+	if len(l.Topics) == 0 {
+		return nil, nil
+	}
+
+	data, err := hex.DecodeString(Trim0x(l.Data))
+	if err != nil {
+		return nil, err
+	}
+
+	topics := make([][]byte, len(l.Topics))
+	for i, t := range l.Topics {
+		topics[i], err = hex.DecodeString(Trim0x(t))
+		if err != nil {
+			return nil, err
+		}
+
+		if len(topics[i]) != 32 {
+			return nil, fmt.Errorf("topic %d is not a 32 bytes word", i)
+		}
+	}
+
+	switch Trim0x(l.Topics[0]) {
+	case TransferEventTopic:
+		switch {
+		case len(topics) == 3 && len(data) == 32:
+			// ERC-20: Transfer(address indexed from, address indexed to, uint256 value)
+			return []*TokenTransfer{{
+				TokenStandard: TokenStandardERC20,
+				From:          wordToAddress(topics[1]),
+				To:            wordToAddress(topics[2]),
+				Amount:        wordToBigIntStr(data),
+			}}, nil
+		case len(topics) == 4 && len(data) == 0:
+			// ERC-721: Transfer(address indexed from, address indexed to, uint256 indexed tokenId)
+			tokenID := wordToBigIntStr(topics[3])
+			return []*TokenTransfer{{
+				TokenStandard: TokenStandardERC721,
+				From:          wordToAddress(topics[1]),
+				To:            wordToAddress(topics[2]),
+				Amount:        "1",
+				TokenID:       &tokenID,
+			}}, nil
+		case len(topics) == 1 && len(data) == 96:
+			// pre-standard ERC-721 implementations (e.g. CryptoKitties) don't index any of the parameters
+			tokenID := wordToBigIntStr(data[64:96])
+			return []*TokenTransfer{{
+				TokenStandard: TokenStandardERC721,
+				From:          wordToAddress(data[0:32]),
+				To:            wordToAddress(data[32:64]),
+				Amount:        "1",
+				TokenID:       &tokenID,
+			}}, nil
+		}
+
+		return nil, fmt.Errorf("unexpected Transfer event layout (%d topics, %d bytes of data)", len(topics), len(data))
+	case TransferSingleEventTopic:
+		if len(topics) != 4 || len(data) != 64 {
+			return nil, fmt.Errorf("unexpected TransferSingle event layout (%d topics, %d bytes of data)", len(topics), len(data))
+		}
+
+		tokenID := wordToBigIntStr(data[0:32])
+		return []*TokenTransfer{{
+			TokenStandard: TokenStandardERC1155,
+			Operator:      wordToAddress(topics[1]),
+			From:          wordToAddress(topics[2]),
+			To:            wordToAddress(topics[3]),
+			Amount:        wordToBigIntStr(data[32:64]),
+			TokenID:       &tokenID,
+		}}, nil
+	case TransferBatchEventTopic:
+		if len(topics) != 4 {
+			return nil, fmt.Errorf("unexpected TransferBatch event layout (%d topics)", len(topics))
+		}
+
+		ids, err := decodeUint256Array(data, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		values, err := decodeUint256Array(data, 1)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(ids) != len(values) {
+			return nil, fmt.Errorf("TransferBatch ids and values have different lengths")
+		}
+
+		var transfers []*TokenTransfer
+		for i := range ids {
+			tokenID := ids[i]
+			transfers = append(transfers, &TokenTransfer{
+				TokenStandard: TokenStandardERC1155,
+				BatchIndex:    int32(i),
+				Operator:      wordToAddress(topics[1]),
+				From:          wordToAddress(topics[2]),
+				To:            wordToAddress(topics[3]),
+				Amount:        values[i],
+				TokenID:       &tokenID,
+			})
+		}
+
+		return transfers, nil
+	}
+
+	return nil, nil
+}
+
+// wordToAddress extracts an address from a 32 bytes ABI-encoded word
+func wordToAddress(word []byte) string {
+	return hex.EncodeToString(word[len(word)-20:])
+}
+
+// wordToBigIntStr decodes a 32 bytes ABI-encoded uint256 into its base 10 representation
+func wordToBigIntStr(word []byte) string {
+	return new(big.Int).SetBytes(word).String()
+}
+
+// decodeUint256Array decodes the dynamic uint256[] found at the given argument position of the ABI-encoded data
+func decodeUint256Array(data []byte, position int) ([]string, error) {
+	headStart := position * 32
+	if len(data) < headStart+32 {
+		return nil, fmt.Errorf("data too short for argument %d", position)
+	}
+
+	// the offset and length come from the log, so they're bounded by the data before doing any arithmetic with them
+	offset := new(big.Int).SetBytes(data[headStart : headStart+32])
+	if !offset.IsInt64() || offset.Int64() > int64(len(data)-32) {
+		return nil, fmt.Errorf("invalid offset for argument %d", position)
+	}
+	start := int(offset.Int64())
+
+	length := new(big.Int).SetBytes(data[start : start+32])
+	if !length.IsInt64() || length.Int64() > int64((len(data)-start-32)/32) {
+		return nil, fmt.Errorf("invalid length for argument %d", position)
+	}
+
+	values := make([]string, length.Int64())
+	for i := range values {
+		wordStart := start + 32 + i*32
+		values[i] = wordToBigIntStr(data[wordStart : wordStart+32])
+	}
+
+	return values, nil
+}
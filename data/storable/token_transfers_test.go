@@ -0,0 +1,132 @@
+package storable
+
+import (
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/alethio/web3-go/types"
+)
+
+func word(n *big.Int) string {
+	b := n.Bytes()
+	return strings.Repeat("00", 32-len(b)) + hex.EncodeToString(b)
+}
+
+func wordInt(n int64) string {
+	return word(big.NewInt(n))
+}
+
+func topicAddress(address string) string {
+	return "0x" + strings.Repeat("0", 24) + address
+}
+
+const (
+	testOperator = "1111111111111111111111111111111111111111"
+	testFrom     = "2222222222222222222222222222222222222222"
+	testTo       = "3333333333333333333333333333333333333333"
+)
+
+func batchLog(data string) types.Log {
+	return types.Log{
+		Topics: []string{"0x" + TransferBatchEventTopic, topicAddress(testOperator), topicAddress(testFrom), topicAddress(testTo)},
+		Data:   "0x" + data,
+	}
+}
+
+func TestDecodeTransfers(t *testing.T) {
+	ttg := &TokenTransfersGroup{}
+
+	t.Run("erc20", func(t *testing.T) {
+		l := types.Log{
+			Topics: []string{"0x" + TransferEventTopic, topicAddress(testFrom), topicAddress(testTo)},
+			Data:   "0x" + wordInt(1000),
+		}
+
+		transfers, err := ttg.decodeTransfers(l)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(transfers) != 1 || transfers[0].TokenStandard != TokenStandardERC20 || transfers[0].From != testFrom || transfers[0].To != testTo || transfers[0].Amount != "1000" || transfers[0].TokenID != nil {
+			t.Fatalf("unexpected transfers %+v", transfers)
+		}
+	})
+
+	t.Run("erc721", func(t *testing.T) {
+		l := types.Log{
+			Topics: []string{"0x" + TransferEventTopic, topicAddress(testFrom), topicAddress(testTo), "0x" + wordInt(7)},
+			Data:   "0x",
+		}
+
+		transfers, err := ttg.decodeTransfers(l)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(transfers) != 1 || transfers[0].TokenStandard != TokenStandardERC721 || transfers[0].Amount != "1" || *transfers[0].TokenID != "7" {
+			t.Fatalf("unexpected transfers %+v", transfers)
+		}
+	})
+
+	t.Run("erc1155 batch", func(t *testing.T) {
+		// ids [1, 2] at offset 64, values [10, 20] at offset 160
+		data := wordInt(64) + wordInt(160) + wordInt(2) + wordInt(1) + wordInt(2) + wordInt(2) + wordInt(10) + wordInt(20)
+
+		transfers, err := ttg.decodeTransfers(batchLog(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(transfers) != 2 {
+			t.Fatalf("expected 2 transfers, got %d", len(transfers))
+		}
+
+		for i, expected := range []struct{ id, amount string }{{"1", "10"}, {"2", "20"}} {
+			tt := transfers[i]
+			if tt.BatchIndex != int32(i) || *tt.TokenID != expected.id || tt.Amount != expected.amount || tt.Operator != testOperator {
+				t.Fatalf("unexpected transfer %d: %+v", i, tt)
+			}
+		}
+	})
+
+	t.Run("unrelated event", func(t *testing.T) {
+		transfers, err := ttg.decodeTransfers(types.Log{Topics: []string{"0x" + wordInt(1)}, Data: "0x"})
+		if err != nil || transfers != nil {
+			t.Fatalf("expected no transfers and no error, got %+v, %v", transfers, err)
+		}
+	})
+}
+
+func TestDecodeUint256ArrayRejectsOversizedData(t *testing.T) {
+	huge := new(big.Int).Lsh(big.NewInt(1), 59) // times 32 wraps around int64
+	maxInt64 := big.NewInt(1<<63 - 1)
+
+	cases := map[string]string{
+		"length wrapping around":       wordInt(64) + wordInt(96) + word(huge) + wordInt(0),
+		"length larger than the data":  wordInt(64) + wordInt(96) + wordInt(3) + wordInt(1),
+		"offset wrapping around":       word(maxInt64) + wordInt(64),
+		"offset past the data":         wordInt(64) + wordInt(64),
+		"data shorter than the offset": wordInt(64),
+	}
+
+	for name, data := range cases {
+		t.Run(name, func(t *testing.T) {
+			raw, err := hex.DecodeString(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = decodeUint256Array(raw, 0)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+
+			_, err = (&TokenTransfersGroup{}).decodeTransfers(batchLog(data))
+			if err == nil {
+				t.Fatal("expected the batch to be rejected")
+			}
+		})
+	}
+}
@@ -1,18 +1,21 @@
 package storable
 
 import (
-	"database/sql"
 	"strconv"
+	"strings"
 	"time"
 
-	"github.com/lib/pq"
+	"github.com/Alethio/memento/eth/extra"
+	"github.com/Alethio/memento/storage"
 
 	"github.com/alethio/web3-go/types"
 )
 
 type TxsGroup struct {
-	RawBlock    types.Block
-	RawReceipts []types.Receipt
+	RawBlock         types.Block
+	RawReceipts      []types.Receipt
+	RawBlockExtra    extra.Block
+	RawReceiptsExtra map[string]extra.Receipt
 
 	blockNumber       int64
 	blockCreationTime DatetimeToJSONUnix
@@ -37,13 +40,38 @@ type Tx struct {
 	TxLogsBloom         ByteArray
 	BlockCreationTime   DatetimeToJSONUnix
 	LogEntriesTriggered int32
+
+	// TxType is the EIP-2718 transaction type; 0 for legacy transactions
+	TxType int32
+
+	// MaxFeePerGas and MaxPriorityFeePerGas are only set for EIP-1559 transactions
+	MaxFeePerGas         *string
+	MaxPriorityFeePerGas *string
+
+	// EffectiveGasPrice is the price per gas actually paid by the sender; it's the same as TxGasPrice for the
+	// transactions that were not priced with EIP-1559
+	EffectiveGasPrice string
+
+	// MaxFeePerBlobGas, BlobGasUsed and BlobGasPrice are only set for EIP-4844 blob transactions
+	MaxFeePerBlobGas *string
+	BlobGasUsed      *string
+	BlobGasPrice     *string
+
+	// MethodSelector is the first 4 bytes of the payload of calls to contracts; it's not set for contract creations
+	// and for payloads that are too short
+	MethodSelector *string
 }
 
-func NewStorableTxs(block types.Block, receipts []types.Receipt) *TxsGroup {
-	return &TxsGroup{RawBlock: block, RawReceipts: receipts}
+func NewStorableTxs(block types.Block, receipts []types.Receipt, blockExtra extra.Block, receiptsExtra map[string]extra.Receipt) *TxsGroup {
+	return &TxsGroup{
+		RawBlock:         block,
+		RawReceipts:      receipts,
+		RawBlockExtra:    blockExtra,
+		RawReceiptsExtra: receiptsExtra,
+	}
 }
 
-func (t *TxsGroup) ToDB(dbTx *sql.Tx) error {
+func (t *TxsGroup) ToDB(dbTx storage.Tx) error {
 	if len(t.RawBlock.Transactions) == 0 {
 		return nil
 	}
@@ -59,13 +87,13 @@ func (t *TxsGroup) ToDB(dbTx *sql.Tx) error {
 		return err
 	}
 
-	stmt, err := dbTx.Prepare(pq.CopyIn("txs", "tx_hash", "included_in_block", "tx_index", "from", "to", "value", "tx_nonce", "msg_gas_limit", "tx_gas_used", "tx_gas_price", "cumulative_gas_used", "msg_payload", "msg_status", "creates", "tx_logs_bloom", "block_creation_time", "log_entries_triggered"))
+	stmt, err := dbTx.BulkInsert("txs", "tx_hash", "included_in_block", "tx_index", "from", "to", "value", "tx_nonce", "msg_gas_limit", "tx_gas_used", "tx_gas_price", "cumulative_gas_used", "msg_payload", "msg_status", "creates", "tx_logs_bloom", "block_creation_time", "log_entries_triggered", "tx_type", "max_fee_per_gas", "max_priority_fee_per_gas", "effective_gas_price", "max_fee_per_blob_gas", "blob_gas_used", "blob_gas_price", "method_selector")
 	if err != nil {
 		return err
 	}
 
 	for _, tx := range t.txs {
-		_, err = stmt.Exec(tx.TxHash, tx.IncludedInBlock, tx.TxIndex, tx.From, tx.To, tx.Value, tx.TxNonce, tx.MsgGasLimit, tx.TxGasUsed, tx.TxGasPrice, tx.CumulativeGasUsed, tx.MsgPayload, tx.MsgStatus, tx.Creates, tx.TxLogsBloom, tx.BlockCreationTime, tx.LogEntriesTriggered)
+		_, err = stmt.Exec(tx.TxHash, tx.IncludedInBlock, tx.TxIndex, tx.From, tx.To, tx.Value, tx.TxNonce, tx.MsgGasLimit, tx.TxGasUsed, tx.TxGasPrice, tx.CumulativeGasUsed, tx.MsgPayload, tx.MsgStatus, tx.Creates, tx.TxLogsBloom, tx.BlockCreationTime, tx.LogEntriesTriggered, tx.TxType, tx.MaxFeePerGas, tx.MaxPriorityFeePerGas, tx.EffectiveGasPrice, tx.MaxFeePerBlobGas, tx.BlobGasUsed, tx.BlobGasPrice, tx.MethodSelector)
 		if err != nil {
 			return err
 		}
@@ -84,6 +112,10 @@ func (t *TxsGroup) ToDB(dbTx *sql.Tx) error {
 	return nil
 }
 
+func (t *TxsGroup) InsertedRows() (string, int) {
+	return "txs", len(t.txs)
+}
+
 // enhance processes the raw block and raw receipts data generating a list containing all the txs in the block
 // in a format that's ready for insertion into the database
 func (t *TxsGroup) enhance() error {
@@ -102,7 +134,7 @@ func (t *TxsGroup) enhance() error {
 	t.blockCreationTime = DatetimeToJSONUnix(time.Unix(timestamp, 0))
 
 	for index, tx := range t.RawBlock.Transactions {
-		storableTx, err := t.buildStorableTx(tx, t.RawReceipts[index])
+		storableTx, err := t.buildStorableTx(tx, t.RawReceipts[index], t.RawBlockExtra.Tx(index), t.RawReceiptsExtra[tx.Hash])
 		if err != nil {
 			return err
 		}
@@ -113,7 +145,7 @@ func (t *TxsGroup) enhance() error {
 	return nil
 }
 
-func (t *TxsGroup) buildStorableTx(tx types.Transaction, receipt types.Receipt) (*Tx, error) {
+func (t *TxsGroup) buildStorableTx(tx types.Transaction, receipt types.Receipt, txExtra extra.Transaction, receiptExtra extra.Receipt) (*Tx, error) {
 	sTx := &Tx{}
 	sTx.IncludedInBlock = t.blockNumber
 	sTx.BlockCreationTime = t.blockCreationTime
@@ -151,6 +183,12 @@ func (t *TxsGroup) buildStorableTx(tx types.Transaction, receipt types.Receipt)
 	}
 	sTx.TxNonce = txNonce
 
+	sTx.TxType, err = txType(txExtra)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
 	// -- bigint
 	gasLimit, err := HexStrToBigIntStr(tx.Gas)
 	if err != nil {
@@ -187,8 +225,80 @@ func (t *TxsGroup) buildStorableTx(tx types.Transaction, receipt types.Receipt)
 	}
 	sTx.CumulativeGasUsed = cumulativeGasUsed
 
+	sTx.MaxFeePerGas, err = nullableHexStrToBigIntStr(txExtra.MaxFeePerGas)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
+	sTx.MaxPriorityFeePerGas, err = nullableHexStrToBigIntStr(txExtra.MaxPriorityFeePerGas)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
+	effectiveGasPrice, err := HexStrToBigIntStr(effectiveGasPrice(tx, receiptExtra))
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	sTx.EffectiveGasPrice = effectiveGasPrice
+
+	sTx.MaxFeePerBlobGas, err = nullableHexStrToBigIntStr(txExtra.MaxFeePerBlobGas)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
+	sTx.BlobGasUsed, err = nullableHexStrToBigIntStr(receiptExtra.BlobGasUsed)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
+	sTx.BlobGasPrice, err = nullableHexStrToBigIntStr(receiptExtra.BlobGasPrice)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
 	// -- computed
 	sTx.LogEntriesTriggered = int32(len(receipt.Logs))
+	sTx.MethodSelector = methodSelector(sTx)
 
 	return sTx, nil
 }
+
+// txType returns the EIP-2718 type of a transaction; transactions without a type are legacy ones
+func txType(tx extra.Transaction) (int32, error) {
+	if Trim0x(tx.Type) == "" {
+		return 0, nil
+	}
+
+	value, err := strconv.ParseInt(tx.Type, 0, 32)
+	if err != nil {
+		return 0, err
+	}
+
+	return int32(value), nil
+}
+
+// methodSelector returns the selector of the function called by a transaction, as a hex string without 0x
+func methodSelector(tx *Tx) *string {
+	if tx.Creates != "" || len(tx.MsgPayload) < 8 {
+		return nil
+	}
+
+	selector := strings.ToLower(string(tx.MsgPayload[:8]))
+	return &selector
+}
+
+// effectiveGasPrice returns the price per gas paid by the sender of a transaction as a hex string
+// Receipts from before London don't have the field, in which case the gas price of the transaction was paid
+func effectiveGasPrice(tx types.Transaction, receipt extra.Receipt) string {
+	if Trim0x(receipt.EffectiveGasPrice) != "" {
+		return receipt.EffectiveGasPrice
+	}
+
+	return tx.GasPrice
+}
@@ -0,0 +1,203 @@
+package storable
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Alethio/memento/eth/extra"
+	"github.com/alethio/web3-go/types"
+)
+
+// londonBlock is a block with a legacy transaction, an EIP-2930 one with an access list, an EIP-1559 contract call and
+// an EIP-1559 contract creation, as returned by eth_getBlockByNumber
+const londonBlock = `{
+	"number": "0xc5d488",
+	"hash": "0x00000000000000000000000000000000000000000000000000000000000000b1",
+	"timestamp": "0x610bdaa8",
+	"baseFeePerGas": "0x64",
+	"transactions": [
+		{
+			"hash": "0x01", "transactionIndex": "0x0", "nonce": "0x1", "from": "0xaa", "to": "0xbb", "value": "0xa",
+			"gas": "0x5208", "gasPrice": "0x96", "input": "0x"
+		},
+		{
+			"hash": "0x02", "transactionIndex": "0x1", "nonce": "0x2", "from": "0xaa", "to": "0xcc", "value": "0x0",
+			"gas": "0x10000", "gasPrice": "0x96", "input": "0xa9059cbb0000", "type": "0x1",
+			"accessList": [
+				{"address": "0xcc", "storageKeys": ["0x01", "0x02"]},
+				{"address": "0xdd", "storageKeys": []}
+			]
+		},
+		{
+			"hash": "0x03", "transactionIndex": "0x2", "nonce": "0x3", "from": "0xaa", "to": "0xcc", "value": "0x0",
+			"gas": "0x10000", "gasPrice": "0x6e", "input": "0x095EA7B30000", "type": "0x2",
+			"maxFeePerGas": "0xc8", "maxPriorityFeePerGas": "0xa", "accessList": []
+		},
+		{
+			"hash": "0x04", "transactionIndex": "0x3", "nonce": "0x4", "from": "0xaa", "to": null, "value": "0x0",
+			"gas": "0x20000", "gasPrice": "0x6e", "input": "0x60806040", "type": "0x2",
+			"maxFeePerGas": "0xc8", "maxPriorityFeePerGas": "0xa", "accessList": []
+		}
+	]
+}`
+
+// londonReceipts are the receipts of londonBlock; the one of the legacy transaction predates the effectiveGasPrice field
+const londonReceipts = `[
+	{"transactionHash": "0x01", "gasUsed": "0x5208", "cumulativeGasUsed": "0x5208", "status": "0x1", "logs": []},
+	{"transactionHash": "0x02", "gasUsed": "0x6000", "cumulativeGasUsed": "0xb208", "status": "0x1", "logs": [], "type": "0x1", "effectiveGasPrice": "0x96"},
+	{"transactionHash": "0x03", "gasUsed": "0x7000", "cumulativeGasUsed": "0x12208", "status": "0x0", "logs": [], "type": "0x2", "effectiveGasPrice": "0x6e"},
+	{"transactionHash": "0x04", "gasUsed": "0x8000", "cumulativeGasUsed": "0x1a208", "status": "0x1", "logs": [], "type": "0x2", "effectiveGasPrice": "0x6e", "contractAddress": "0xee"}
+]`
+
+// decodeTestBlock decodes a block and its receipts the way the scraper does, into the web3-go types and their extras
+func decodeTestBlock(t *testing.T, rawBlock, rawReceipts string) (types.Block, []types.Receipt, extra.Block, map[string]extra.Receipt) {
+	var block types.Block
+	var blockExtra extra.Block
+	for _, v := range []interface{}{&block, &blockExtra} {
+		err := json.Unmarshal([]byte(rawBlock), v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var receipts []types.Receipt
+	var receiptsExtra []extra.Receipt
+	for _, v := range []interface{}{&receipts, &receiptsExtra} {
+		err := json.Unmarshal([]byte(rawReceipts), v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	extras := make(map[string]extra.Receipt, len(receiptsExtra))
+	for _, r := range receiptsExtra {
+		extras[r.TransactionHash] = r
+	}
+
+	return block, receipts, blockExtra, extras
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return "<nil>"
+	}
+
+	return *s
+}
+
+func TestTypedTxs(t *testing.T) {
+	txs := NewStorableTxs(decodeTestBlock(t, londonBlock, londonReceipts))
+	err := txs.enhance()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(txs.txs) != 4 {
+		t.Fatalf("expected 4 txs, got %d", len(txs.txs))
+	}
+
+	testCases := []struct {
+		txType               int32
+		maxFeePerGas         string
+		maxPriorityFeePerGas string
+		effectiveGasPrice    string
+		methodSelector       string
+	}{
+		{0, "<nil>", "<nil>", "150", "<nil>"},
+		{1, "<nil>", "<nil>", "150", "a9059cbb"},
+		{2, "200", "10", "110", "095ea7b3"},
+		{2, "200", "10", "110", "<nil>"},
+	}
+
+	for i, tc := range testCases {
+		tx := txs.txs[i]
+		if tx.TxType != tc.txType {
+			t.Errorf("tx %d: expected type %d, got %d", i, tc.txType, tx.TxType)
+		}
+		if stringValue(tx.MaxFeePerGas) != tc.maxFeePerGas || stringValue(tx.MaxPriorityFeePerGas) != tc.maxPriorityFeePerGas {
+			t.Errorf("tx %d: expected max fees %s/%s, got %s/%s", i, tc.maxFeePerGas, tc.maxPriorityFeePerGas, stringValue(tx.MaxFeePerGas), stringValue(tx.MaxPriorityFeePerGas))
+		}
+		if tx.EffectiveGasPrice != tc.effectiveGasPrice {
+			t.Errorf("tx %d: expected effective gas price %s, got %s", i, tc.effectiveGasPrice, tx.EffectiveGasPrice)
+		}
+		if stringValue(tx.MethodSelector) != tc.methodSelector {
+			t.Errorf("tx %d: expected method selector %s, got %s", i, tc.methodSelector, stringValue(tx.MethodSelector))
+		}
+		if tx.MaxFeePerBlobGas != nil || tx.BlobGasUsed != nil || tx.BlobGasPrice != nil {
+			t.Errorf("tx %d: expected no blob fields", i)
+		}
+	}
+
+	if txs.txs[3].Creates != "ee" || txs.txs[3].To != "ee" {
+		t.Errorf("expected the last tx to create ee, got creates %q and to %q", txs.txs[3].Creates, txs.txs[3].To)
+	}
+}
+
+func TestTxTypeRejectsMalformedTypes(t *testing.T) {
+	_, err := txType(extra.Transaction{Type: "0xzz"})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestAccessLists(t *testing.T) {
+	block, _, blockExtra, _ := decodeTestBlock(t, londonBlock, londonReceipts)
+
+	alg := NewStorableAccessLists(block, blockExtra)
+	err := alg.enhance()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(alg.entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(alg.entries))
+	}
+
+	first, second := alg.entries[0], alg.entries[1]
+	if first.TxHash != "02" || first.IncludedInBlock != 0xc5d488 || first.TxIndex != 1 || first.EntryIndex != 0 || first.Address != "cc" {
+		t.Errorf("unexpected first entry %+v", first)
+	}
+	if len(first.StorageKeys) != 2 || first.StorageKeys[0] != "01" || first.StorageKeys[1] != "02" {
+		t.Errorf("unexpected storage keys %v", first.StorageKeys)
+	}
+	if second.EntryIndex != 1 || second.Address != "dd" || len(second.StorageKeys) != 0 {
+		t.Errorf("unexpected second entry %+v", second)
+	}
+}
+
+func TestBlockFees(t *testing.T) {
+	block, receipts, blockExtra, receiptsExtra := decodeTestBlock(t, londonBlock, londonReceipts)
+	block.Size, block.GasLimit, block.GasUsed, block.Difficulty, block.TotalDifficulty = "0x100", "0x1c9c380", "0x1a208", "0x0", "0x0"
+
+	sb := NewStorableBlock(block, receipts, blockExtra, receiptsExtra, "", false, "")
+	err := sb.enhance()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stringValue(sb.BaseFeePerGas) != "100" {
+		t.Errorf("expected a base fee of 100, got %s", stringValue(sb.BaseFeePerGas))
+	}
+
+	// gas used: 0x5208 + 0x6000 + 0x7000 + 0x8000 = 107016, all of it burnt at 100 per gas
+	if sb.BurntFees != "10701600" {
+		t.Errorf("expected 10701600 burnt, got %s", sb.BurntFees)
+	}
+
+	// tips: 50 per gas for the first two, 10 per gas for the last two
+	if sb.PriorityFees != "2893200" {
+		t.Errorf("expected 2893200 in priority fees, got %s", sb.PriorityFees)
+	}
+
+	// before London there's no base fee, so everything paid is a priority fee
+	blockExtra.BaseFeePerGas = ""
+	sb = NewStorableBlock(block, receipts, blockExtra, receiptsExtra, "", false, "")
+	err = sb.enhance()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sb.BaseFeePerGas != nil || sb.BurntFees != "0" || sb.PriorityFees != "13594800" {
+		t.Errorf("expected no base fee and 13594800 in priority fees, got %s, %s and %s", stringValue(sb.BaseFeePerGas), sb.BurntFees, sb.PriorityFees)
+	}
+}
@@ -11,6 +11,11 @@ import (
 type ByteArray string
 
 func (val *ByteArray) Scan(value interface{}) error {
+	if value == nil {
+		*val = ""
+		return nil
+	}
+
 	encoded := hex.EncodeToString(value.([]byte))
 	*val = ByteArray(encoded)
 
@@ -29,7 +34,7 @@ func (val ByteArray) String() string {
 type JSONStringArray []string
 
 func (j *JSONStringArray) Scan(value interface{}) error {
-	err := json.Unmarshal(value.([]byte), j)
+	err := json.Unmarshal(jsonBytes(value), j)
 
 	return err
 }
@@ -42,7 +47,7 @@ func (j JSONStringArray) Value() (driver.Value, error) {
 type JSONObject map[string]interface{}
 
 func (obj *JSONObject) Scan(value interface{}) error {
-	err := json.Unmarshal(value.([]byte), obj)
+	err := json.Unmarshal(jsonBytes(value), obj)
 
 	return err
 }
@@ -51,6 +56,15 @@ func (obj JSONObject) Value() (driver.Value, error) {
 	return json.Marshal(obj)
 }
 
+// jsonBytes returns the raw JSON of a json column, which sqlite returns as a string instead of []byte
+func jsonBytes(value interface{}) []byte {
+	if s, ok := value.(string); ok {
+		return []byte(s)
+	}
+
+	return value.([]byte)
+}
+
 // DatetimeToJSONUnix binds a time.Time to a `timestamp` database field
 // when marshaled to JSON, outputs a unix timestamp
 type DatetimeToJSONUnix time.Time
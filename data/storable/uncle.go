@@ -1,11 +1,10 @@
 package storable
 
 import (
-	"database/sql"
 	"strconv"
 	"time"
 
-	"github.com/lib/pq"
+	"github.com/Alethio/memento/storage"
 
 	"github.com/alethio/web3-go/types"
 )
@@ -39,7 +38,7 @@ func NewStorableUncles(block types.Block, uncles []types.Block) *UnclesGroup {
 	return &UnclesGroup{RawBlock: block, RawUncles: uncles}
 }
 
-func (ug *UnclesGroup) ToDB(tx *sql.Tx) error {
+func (ug *UnclesGroup) ToDB(tx storage.Tx) error {
 	if len(ug.RawUncles) == 0 {
 		return nil
 	}
@@ -55,7 +54,7 @@ func (ug *UnclesGroup) ToDB(tx *sql.Tx) error {
 		return err
 	}
 
-	stmt, err := tx.Prepare(pq.CopyIn("uncles", "block_hash", "included_in_block", "number", "block_creation_time", "uncle_index", "block_gas_limit", "block_gas_used", "has_beneficiary", "block_difficulty", "block_extra_data", "block_mix_hash", "block_nonce", "sha3_uncles"))
+	stmt, err := tx.BulkInsert("uncles", "block_hash", "included_in_block", "number", "block_creation_time", "uncle_index", "block_gas_limit", "block_gas_used", "has_beneficiary", "block_difficulty", "block_extra_data", "block_mix_hash", "block_nonce", "sha3_uncles")
 	if err != nil {
 		return err
 	}
@@ -80,6 +79,10 @@ func (ug *UnclesGroup) ToDB(tx *sql.Tx) error {
 	return nil
 }
 
+func (ug *UnclesGroup) InsertedRows() (string, int) {
+	return "uncles", len(ug.uncles)
+}
+
 func (ug *UnclesGroup) enhance() error {
 	number, err := strconv.ParseInt(ug.RawBlock.Number, 0, 64)
 	if err != nil {
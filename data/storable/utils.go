@@ -12,6 +12,38 @@ func HexStrToBigIntStr(hexString string) (string, error) {
 	return value.String(), err
 }
 
+// optionalHexStrToBigIntStr works like HexStrToBigIntStr but treats missing values (empty string or "0x") as zero
+func optionalHexStrToBigIntStr(hexString string) (string, error) {
+	if Trim0x(hexString) == "" {
+		return "0", nil
+	}
+
+	return HexStrToBigIntStr(hexString)
+}
+
+// optionalHexStrToBigInt works like HexStrToBigInt but returns 0 for missing values
+func optionalHexStrToBigInt(hexString string) (*big.Int, error) {
+	if Trim0x(hexString) == "" {
+		return new(big.Int), nil
+	}
+
+	return HexStrToBigInt(hexString)
+}
+
+// nullableHexStrToBigIntStr works like HexStrToBigIntStr but returns nil for missing values, so they are stored as NULL
+func nullableHexStrToBigIntStr(hexString string) (*string, error) {
+	if Trim0x(hexString) == "" {
+		return nil, nil
+	}
+
+	value, err := HexStrToBigIntStr(hexString)
+	if err != nil {
+		return nil, err
+	}
+
+	return &value, nil
+}
+
 // HexStrToBigInt transforms a hex sting like "0xff" to a big.Int. Arbitrary length values are possible.
 func HexStrToBigInt(hexString string) (*big.Int, error) {
 	value := new(big.Int)
@@ -0,0 +1,153 @@
+package storable
+
+import (
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/Alethio/memento/eth/extra"
+	"github.com/Alethio/memento/storage"
+
+	"github.com/alethio/web3-go/types"
+)
+
+// gwei is the unit withdrawal amounts are expressed in by the beacon chain
+var gwei = big.NewInt(1000000000)
+
+type WithdrawalsGroup struct {
+	RawBlock      types.Block
+	RawBlockExtra extra.Block
+
+	blockNumber       int64
+	blockCreationTime DatetimeToJSONUnix
+
+	withdrawals []*Withdrawal
+}
+
+// Withdrawal is a transfer from the beacon chain to an execution layer account (EIP-4895)
+// Amount is converted from Gwei to wei, so it can be summed up with the values of the transactions
+type Withdrawal struct {
+	IncludedInBlock   int64
+	WithdrawalIndex   int64
+	ValidatorIndex    int64
+	Address           string
+	Amount            string
+	BlockCreationTime DatetimeToJSONUnix
+}
+
+func NewStorableWithdrawals(block types.Block, blockExtra extra.Block) *WithdrawalsGroup {
+	return &WithdrawalsGroup{RawBlock: block, RawBlockExtra: blockExtra}
+}
+
+func (wg *WithdrawalsGroup) ToDB(tx storage.Tx) error {
+	if len(wg.RawBlockExtra.Withdrawals) == 0 {
+		return nil
+	}
+
+	log.Trace("storing withdrawals")
+	start := time.Now()
+	defer func() {
+		log.WithField("duration", time.Since(start)).WithField("count", len(wg.withdrawals)).Debug("done storing withdrawals")
+	}()
+
+	err := wg.enhance()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.BulkInsert("withdrawals", "included_in_block", "withdrawal_index", "validator_index", "address", "amount", "block_creation_time")
+	if err != nil {
+		return err
+	}
+
+	for _, w := range wg.withdrawals {
+		_, err = stmt.Exec(w.IncludedInBlock, w.WithdrawalIndex, w.ValidatorIndex, w.Address, w.Amount, w.BlockCreationTime)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = stmt.Exec()
+	if err != nil {
+		return err
+	}
+
+	err = stmt.Close()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (wg *WithdrawalsGroup) InsertedRows() (string, int) {
+	return "withdrawals", len(wg.withdrawals)
+}
+
+// enhance processes the withdrawals of the raw block generating a list that's ready for insertion into the database
+func (wg *WithdrawalsGroup) enhance() error {
+	number, err := strconv.ParseInt(wg.RawBlock.Number, 0, 64)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	wg.blockNumber = number
+
+	timestamp, err := strconv.ParseInt(wg.RawBlock.Timestamp, 0, 64)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	wg.blockCreationTime = DatetimeToJSONUnix(time.Unix(timestamp, 0))
+
+	for _, raw := range wg.RawBlockExtra.Withdrawals {
+		w, err := wg.buildStorableWithdrawal(raw)
+		if err != nil {
+			return err
+		}
+
+		wg.withdrawals = append(wg.withdrawals, w)
+	}
+
+	return nil
+}
+
+func (wg *WithdrawalsGroup) buildStorableWithdrawal(raw extra.Withdrawal) (*Withdrawal, error) {
+	w := &Withdrawal{
+		IncludedInBlock:   wg.blockNumber,
+		Address:           Trim0x(raw.Address),
+		BlockCreationTime: wg.blockCreationTime,
+	}
+
+	var err error
+	w.WithdrawalIndex, err = strconv.ParseInt(raw.Index, 0, 64)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
+	w.ValidatorIndex, err = strconv.ParseInt(raw.ValidatorIndex, 0, 64)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
+	amount, err := WithdrawalAmount(raw)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	w.Amount = amount.String()
+
+	return w, nil
+}
+
+// WithdrawalAmount returns the amount of a withdrawal in wei
+func WithdrawalAmount(w extra.Withdrawal) (*big.Int, error) {
+	amount, err := HexStrToBigInt(w.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	return amount.Mul(amount, gwei), nil
+}
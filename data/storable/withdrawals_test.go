@@ -0,0 +1,151 @@
+package storable
+
+import (
+	"testing"
+
+	"github.com/Alethio/memento/eth/extra"
+)
+
+// cancunBlock is a block with a blob transaction, a regular one and two withdrawals, as returned by
+// eth_getBlockByNumber
+const cancunBlock = `{
+	"number": "0x12a05f2",
+	"hash": "0x00000000000000000000000000000000000000000000000000000000000000c1",
+	"timestamp": "0x65f1b057",
+	"size": "0x200", "gasLimit": "0x1c9c380", "gasUsed": "0xa410", "difficulty": "0x0", "totalDifficulty": "0x0",
+	"baseFeePerGas": "0x64",
+	"withdrawalsRoot": "0x00000000000000000000000000000000000000000000000000000000000000d1",
+	"blobGasUsed": "0x40000",
+	"excessBlobGas": "0x80000",
+	"parentBeaconBlockRoot": "0x00000000000000000000000000000000000000000000000000000000000000e1",
+	"withdrawals": [
+		{"index": "0x2a", "validatorIndex": "0x3e8", "address": "0xaa", "amount": "0x3b9aca00"},
+		{"index": "0x2b", "validatorIndex": "0x3e9", "address": "0xbb", "amount": "0x1"}
+	],
+	"transactions": [
+		{
+			"hash": "0x01", "transactionIndex": "0x0", "nonce": "0x1", "from": "0xaa", "to": "0xbb", "value": "0x0",
+			"gas": "0x5208", "gasPrice": "0x6e", "input": "0x", "type": "0x3",
+			"maxFeePerGas": "0xc8", "maxPriorityFeePerGas": "0xa", "accessList": [],
+			"maxFeePerBlobGas": "0x3", "blobVersionedHashes": ["0x0101", "0x0102"]
+		},
+		{
+			"hash": "0x02", "transactionIndex": "0x1", "nonce": "0x2", "from": "0xaa", "to": "0xbb", "value": "0x1",
+			"gas": "0x5208", "gasPrice": "0x6e", "input": "0x", "type": "0x2",
+			"maxFeePerGas": "0xc8", "maxPriorityFeePerGas": "0xa", "accessList": []
+		}
+	]
+}`
+
+const cancunReceipts = `[
+	{"transactionHash": "0x01", "gasUsed": "0x5208", "cumulativeGasUsed": "0x5208", "status": "0x1", "logs": [], "type": "0x3", "effectiveGasPrice": "0x6e", "blobGasUsed": "0x40000", "blobGasPrice": "0x2"},
+	{"transactionHash": "0x02", "gasUsed": "0x5208", "cumulativeGasUsed": "0xa410", "status": "0x1", "logs": [], "type": "0x2", "effectiveGasPrice": "0x6e"}
+]`
+
+func TestWithdrawals(t *testing.T) {
+	block, _, blockExtra, _ := decodeTestBlock(t, cancunBlock, cancunReceipts)
+
+	wg := NewStorableWithdrawals(block, blockExtra)
+	err := wg.enhance()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(wg.withdrawals) != 2 {
+		t.Fatalf("expected 2 withdrawals, got %d", len(wg.withdrawals))
+	}
+
+	first, second := wg.withdrawals[0], wg.withdrawals[1]
+	if first.IncludedInBlock != 0x12a05f2 || first.WithdrawalIndex != 42 || first.ValidatorIndex != 1000 || first.Address != "aa" {
+		t.Errorf("unexpected first withdrawal %+v", first)
+	}
+
+	// the amounts are in gwei on the beacon chain
+	if first.Amount != "1000000000000000000" || second.Amount != "1000000000" {
+		t.Errorf("expected the amounts in wei, got %s and %s", first.Amount, second.Amount)
+	}
+}
+
+func TestWithdrawalsRejectMalformedFields(t *testing.T) {
+	block, _, _, _ := decodeTestBlock(t, cancunBlock, cancunReceipts)
+
+	for _, w := range []extra.Withdrawal{
+		{Index: "0xzz", ValidatorIndex: "0x1", Amount: "0x1"},
+		{Index: "0x1", ValidatorIndex: "", Amount: "0x1"},
+		{Index: "0x1", ValidatorIndex: "0x1", Amount: "0xzz"},
+	} {
+		wg := NewStorableWithdrawals(block, extra.Block{Withdrawals: []extra.Withdrawal{w}})
+		err := wg.enhance()
+		if err == nil {
+			t.Errorf("expected an error for %+v", w)
+		}
+	}
+}
+
+func TestBlobHashes(t *testing.T) {
+	block, _, blockExtra, _ := decodeTestBlock(t, cancunBlock, cancunReceipts)
+
+	bg := NewStorableBlobHashes(block, blockExtra)
+	err := bg.enhance()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(bg.blobHashes) != 2 {
+		t.Fatalf("expected 2 blob hashes, got %d", len(bg.blobHashes))
+	}
+
+	for i, h := range bg.blobHashes {
+		if h.TxHash != "01" || h.IncludedInBlock != 0x12a05f2 || h.TxIndex != 0 || h.BlobIndex != int32(i) {
+			t.Errorf("unexpected blob hash %+v", h)
+		}
+	}
+	if bg.blobHashes[0].VersionedHash != "0101" || bg.blobHashes[1].VersionedHash != "0102" {
+		t.Errorf("unexpected versioned hashes %s and %s", bg.blobHashes[0].VersionedHash, bg.blobHashes[1].VersionedHash)
+	}
+}
+
+func TestBlobFields(t *testing.T) {
+	block, receipts, blockExtra, receiptsExtra := decodeTestBlock(t, cancunBlock, cancunReceipts)
+
+	sb := NewStorableBlock(block, receipts, blockExtra, receiptsExtra, "", false, "")
+	err := sb.enhance()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stringValue(sb.BlobGasUsed) != "262144" || stringValue(sb.ExcessBlobGas) != "524288" {
+		t.Errorf("unexpected blob gas %s/%s", stringValue(sb.BlobGasUsed), stringValue(sb.ExcessBlobGas))
+	}
+	if sb.NumberOfWithdrawals != 2 || string(sb.WithdrawalsRoot) != "00000000000000000000000000000000000000000000000000000000000000d1" {
+		t.Errorf("unexpected withdrawals fields %d and %s", sb.NumberOfWithdrawals, sb.WithdrawalsRoot)
+	}
+	if string(sb.ParentBeaconBlockRoot) != "00000000000000000000000000000000000000000000000000000000000000e1" {
+		t.Errorf("unexpected parent beacon block root %s", sb.ParentBeaconBlockRoot)
+	}
+
+	txs := NewStorableTxs(block, receipts, blockExtra, receiptsExtra)
+	err = txs.enhance()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blob, regular := txs.txs[0], txs.txs[1]
+	if blob.TxType != 3 || stringValue(blob.MaxFeePerBlobGas) != "3" || stringValue(blob.BlobGasUsed) != "262144" || stringValue(blob.BlobGasPrice) != "2" {
+		t.Errorf("unexpected blob tx fields %d, %s, %s and %s", blob.TxType, stringValue(blob.MaxFeePerBlobGas), stringValue(blob.BlobGasUsed), stringValue(blob.BlobGasPrice))
+	}
+	if regular.MaxFeePerBlobGas != nil || regular.BlobGasUsed != nil || regular.BlobGasPrice != nil {
+		t.Errorf("expected no blob fields on a regular tx")
+	}
+
+	// blocks from before Cancun have none of the blob fields
+	sb = NewStorableBlock(block, receipts, extra.Block{}, receiptsExtra, "", false, "")
+	err = sb.enhance()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sb.BlobGasUsed != nil || sb.ExcessBlobGas != nil || sb.NumberOfWithdrawals != 0 {
+		t.Errorf("expected no blob or withdrawal fields, got %s, %s and %d", stringValue(sb.BlobGasUsed), stringValue(sb.ExcessBlobGas), sb.NumberOfWithdrawals)
+	}
+}
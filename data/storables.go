@@ -0,0 +1,134 @@
+package data
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Alethio/memento/data/storable"
+)
+
+// storableDef describes one of the storables that derive rows from a block
+type storableDef struct {
+	// Name is the table the storable writes to
+	Name string
+
+	// Version must be increased whenever a change to the storable alters the rows it produces, so that the blocks
+	// stored with the previous version can be found and reindexed
+	Version int
+
+	New func(fb *FullBlock) Storable
+}
+
+// storableDefs lists the storables executed after the block itself, in order; every one of them writes to a single
+// table keyed by included_in_block
+var storableDefs = []storableDef{
+	{"txs", 1, func(fb *FullBlock) Storable {
+		return storable.NewStorableTxs(fb.Block, fb.Receipts, fb.BlockExtra, fb.ReceiptsExtra)
+	}},
+	{"tx_access_lists", 1, func(fb *FullBlock) Storable { return storable.NewStorableAccessLists(fb.Block, fb.BlockExtra) }},
+	{"uncles", 1, func(fb *FullBlock) Storable { return storable.NewStorableUncles(fb.Block, fb.Uncles) }},
+	// log_entries 2: block_log_index and block_hash
+	{"log_entries", 2, func(fb *FullBlock) Storable { return storable.NewStorableLogEntries(fb.Block, fb.Receipts) }},
+	{"internal_txs", 1, func(fb *FullBlock) Storable { return storable.NewStorableInternalTxs(fb.Block, fb.Traces) }},
+	{"token_transfers", 1, func(fb *FullBlock) Storable { return storable.NewStorableTokenTransfers(fb.Block, fb.Receipts) }},
+	{"account_txs", 1, func(fb *FullBlock) Storable {
+		return storable.NewStorableAccountTxs(fb.Block, fb.Traces, fb.BlockExtra)
+	}},
+	{"withdrawals", 1, func(fb *FullBlock) Storable { return storable.NewStorableWithdrawals(fb.Block, fb.BlockExtra) }},
+	{"blob_hashes", 1, func(fb *FullBlock) Storable { return storable.NewStorableBlobHashes(fb.Block, fb.BlockExtra) }},
+	{"contracts", 1, func(fb *FullBlock) Storable {
+		return storable.NewStorableContracts(fb.Block, fb.Receipts, fb.Traces, fb.Codes)
+	}},
+	{"balance_changes", 1, func(fb *FullBlock) Storable {
+		return storable.NewStorableBalanceChanges(fb.Block, fb.Receipts, fb.BlockExtra, fb.ReceiptsExtra, fb.Uncles, fb.Traces, fb.BlockRewards)
+	}},
+}
+
+// StorableNames returns the names of the storables that can be reindexed
+func StorableNames() []string {
+	var names []string
+	for _, def := range storableDefs {
+		names = append(names, def.Name)
+	}
+
+	return names
+}
+
+// StorableVersion returns the current version of the named storable
+func StorableVersion(name string) (int, error) {
+	def, err := findStorable(name)
+	if err != nil {
+		return 0, err
+	}
+
+	return def.Version, nil
+}
+
+func findStorable(name string) (storableDef, error) {
+	for _, def := range storableDefs {
+		if def.Name == name {
+			return def, nil
+		}
+	}
+
+	return storableDef{}, fmt.Errorf("unknown storable %q; must be one of %s", name, strings.Join(StorableNames(), ", "))
+}
+
+// storableVersions maps the name of each storable to the version a block's rows were produced with
+type storableVersions map[string]int
+
+// currentVersions returns the versions the storables produce rows with now
+func currentVersions() storableVersions {
+	v := make(storableVersions)
+	for _, def := range storableDefs {
+		v[def.Name] = def.Version
+	}
+
+	return v
+}
+
+// parseVersions reads the storable_versions column of a block; the blocks stored before the versions were recorded
+// have a null column and were produced by version 1 of every storable
+func parseVersions(column *string) storableVersions {
+	v := make(storableVersions)
+	for _, def := range storableDefs {
+		v[def.Name] = 1
+	}
+
+	if column == nil {
+		return v
+	}
+
+	for _, entry := range strings.Split(*column, ";") {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		version, err := strconv.Atoi(parts[1])
+		if err != nil {
+			continue
+		}
+
+		v[parts[0]] = version
+	}
+
+	return v
+}
+
+// String formats the versions the way they're stored: name=version entries in the order of storableDefs, each of
+// them enclosed in semicolons so a single entry can be matched with like
+func (v storableVersions) String() string {
+	var b strings.Builder
+	b.WriteString(";")
+	for _, def := range storableDefs {
+		fmt.Fprintf(&b, "%s=%d;", def.Name, v[def.Name])
+	}
+
+	return b.String()
+}
+
+func versionEntry(name string, version int) string {
+	return fmt.Sprintf(";%s=%d;", name, version)
+}
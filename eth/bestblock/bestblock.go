@@ -20,9 +20,15 @@ type Tracker struct {
 	config          Config
 	bestBlockNumber int64
 	mu              sync.Mutex
-	errChan         chan error
-	stopChan        chan bool
-	conn            *ethrpc.ETH
+
+	safeBlockNumber      int64
+	finalizedBlockNumber int64
+	finalitySupported    bool
+
+	errChan  chan error
+	stopChan chan bool
+	done     chan struct{}
+	conn     *ethrpc.ETH
 
 	started    bool
 	stopped    bool
@@ -39,6 +45,7 @@ func NewTracker(config Config) (*Tracker, error) {
 		config:      config,
 		errChan:     make(chan error),
 		stopChan:    make(chan bool),
+		done:        make(chan struct{}),
 		subscribers: make(map[chan int64]bool),
 	}, nil
 }
@@ -49,6 +56,8 @@ func NewTracker(config Config) (*Tracker, error) {
 func (b *Tracker) Run() {
 	log.Info("starting best block tracker")
 
+	b.startFinality()
+
 	for {
 		var conn *ethrpc.ETH
 		var err error
@@ -90,6 +99,28 @@ func (b *Tracker) Run() {
 	}
 }
 
+// startFinality gets the safe and finalized heads once, so they are known by the time the tracker reports it started,
+// and keeps polling them in the background
+func (b *Tracker) startFinality() {
+	url := b.config.NodeURL
+	if url == "" {
+		url = b.config.NodeURLWS
+	}
+
+	conn, err := ethrpc.NewWithDefaults(url)
+	if err != nil {
+		log.Error("could not set up finality tracking: ", err)
+		return
+	}
+
+	b.getFinality(conn)
+	if !b.SupportsFinality() {
+		log.Warn("node doesn't support the safe and finalized block tags; blocks will not be marked as final")
+	}
+
+	go b.runFinality(conn)
+}
+
 // BestBlock returns the current best block known to the tracker
 func (b *Tracker) BestBlock() int64 {
 	b.mu.Lock()
@@ -135,6 +166,8 @@ func (b *Tracker) Err() chan error {
 
 // Close stops the tracker
 func (b *Tracker) Close() {
+	close(b.done)
+
 	if b.subscribed {
 		b.stopChan <- true
 	}
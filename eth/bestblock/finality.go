@@ -0,0 +1,104 @@
+package bestblock
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/alethio/web3-go/ethrpc"
+)
+
+const (
+	TagSafe      = "safe"
+	TagFinalized = "finalized"
+)
+
+// SafeBlock returns the number of the latest block the node considers safe or 0 if it's not known
+func (b *Tracker) SafeBlock() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.safeBlockNumber
+}
+
+// FinalizedBlock returns the number of the latest finalized block or 0 if it's not known
+func (b *Tracker) FinalizedBlock() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.finalizedBlockNumber
+}
+
+// SupportsFinality returns true if the node answered to the safe and finalized block tags
+// Nodes of chains that have no notion of finality (e.g. pre-merge) return an error for them
+func (b *Tracker) SupportsFinality() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.finalitySupported
+}
+
+// runFinality polls the node for the safe and finalized heads every [config.PollInterval]
+// The heads are polled over http even if the best block is tracked via websockets, since there's no subscription for them
+func (b *Tracker) runFinality(conn *ethrpc.ETH) {
+	ticker := time.NewTicker(b.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			b.getFinality(conn)
+		case <-b.done:
+			return
+		}
+	}
+}
+
+// getFinality gets the safe and finalized heads and saves them on the Tracker struct
+// They can't move backwards, so lower values returned by a lagging node are ignored
+func (b *Tracker) getFinality(conn *ethrpc.ETH) {
+	safe, err := getTaggedBlockNumber(conn, TagSafe)
+	if err != nil {
+		b.finalityError(err)
+		return
+	}
+
+	finalized, err := getTaggedBlockNumber(conn, TagFinalized)
+	if err != nil {
+		b.finalityError(err)
+		return
+	}
+
+	log.WithField("safe", safe).WithField("finalized", finalized).Trace("got finality heads")
+
+	b.mu.Lock()
+	b.finalitySupported = true
+	if safe > b.safeBlockNumber {
+		b.safeBlockNumber = safe
+	}
+	if finalized > b.finalizedBlockNumber {
+		b.finalizedBlockNumber = finalized
+	}
+	b.mu.Unlock()
+}
+
+func (b *Tracker) finalityError(err error) {
+	if !b.SupportsFinality() {
+		log.Trace("node doesn't support the finality block tags: ", err)
+		return
+	}
+
+	log.Error("could not get finality heads: ", err)
+}
+
+func getTaggedBlockNumber(conn *ethrpc.ETH, tag string) (int64, error) {
+	var res struct {
+		Number string `json:"number"`
+	}
+
+	err := conn.MakeRequest(&res, "eth_getBlockByNumber", tag, false)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseInt(res.Number, 0, 64)
+}
@@ -0,0 +1,65 @@
+// Package extra holds the JSON-RPC fields introduced by the forks that came after the web3-go types were written
+// (typed transactions, EIP-1559 fees, access lists, ...)
+// They are decoded from the same responses as the web3-go types and travel next to them through the pipeline
+package extra
+
+// Block holds the fields of an eth_getBlockByNumber response (with full transactions) missing from types.Block
+type Block struct {
+	Hash          string        `json:"hash"`
+	BaseFeePerGas string        `json:"baseFeePerGas"`
+	Transactions  []Transaction `json:"transactions"`
+
+	// Shanghai
+	WithdrawalsRoot string       `json:"withdrawalsRoot"`
+	Withdrawals     []Withdrawal `json:"withdrawals"`
+
+	// Cancun
+	BlobGasUsed           string `json:"blobGasUsed"`
+	ExcessBlobGas         string `json:"excessBlobGas"`
+	ParentBeaconBlockRoot string `json:"parentBeaconBlockRoot"`
+}
+
+// Withdrawal is an EIP-4895 withdrawal from the beacon chain; the amount is in Gwei
+type Withdrawal struct {
+	Index          string `json:"index"`
+	ValidatorIndex string `json:"validatorIndex"`
+	Address        string `json:"address"`
+	Amount         string `json:"amount"`
+}
+
+// Transaction holds the fields of a transaction missing from types.Transaction
+type Transaction struct {
+	Hash                 string        `json:"hash"`
+	Type                 string        `json:"type"`
+	MaxFeePerGas         string        `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas string        `json:"maxPriorityFeePerGas"`
+	AccessList           []AccessTuple `json:"accessList"`
+
+	// EIP-4844 blob transactions
+	MaxFeePerBlobGas    string   `json:"maxFeePerBlobGas"`
+	BlobVersionedHashes []string `json:"blobVersionedHashes"`
+}
+
+// AccessTuple is an entry of an EIP-2930 access list
+type AccessTuple struct {
+	Address     string   `json:"address"`
+	StorageKeys []string `json:"storageKeys"`
+}
+
+// Receipt holds the fields of an eth_getTransactionReceipt response missing from types.Receipt
+type Receipt struct {
+	TransactionHash   string `json:"transactionHash"`
+	Type              string `json:"type"`
+	EffectiveGasPrice string `json:"effectiveGasPrice"`
+	BlobGasUsed       string `json:"blobGasUsed"`
+	BlobGasPrice      string `json:"blobGasPrice"`
+}
+
+// Tx returns the extra fields of the transaction with the given index, or an empty Transaction if there's none
+func (b Block) Tx(index int) Transaction {
+	if index < 0 || index >= len(b.Transactions) {
+		return Transaction{}
+	}
+
+	return b.Transactions[index]
+}
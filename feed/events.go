@@ -0,0 +1,97 @@
+package feed
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/Alethio/memento/data"
+	"github.com/Alethio/memento/data/storable"
+)
+
+// BlockEvents returns the event of a stored block followed by the events of its transactions
+func BlockEvents(fb *data.FullBlock) ([]Event, error) {
+	number, err := strconv.ParseInt(fb.Block.Number, 0, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp, err := strconv.ParseInt(fb.Block.Timestamp, 0, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	events := []Event{{
+		Type: EventBlock,
+		Block: &Block{
+			Number:            number,
+			Hash:              storable.Trim0x(fb.Block.Hash),
+			ParentHash:        storable.Trim0x(fb.Block.ParentHash),
+			BlockCreationTime: timestamp,
+			NumberOfTxs:       len(fb.Block.Transactions),
+			Finality:          fb.Finality,
+		},
+	}}
+
+	if len(fb.Receipts) != len(fb.Block.Transactions) {
+		return nil, fmt.Errorf("block %d has %d transactions but %d receipts", number, len(fb.Block.Transactions), len(fb.Receipts))
+	}
+
+	for index, tx := range fb.Block.Transactions {
+		receipt := fb.Receipts[index]
+
+		value, err := storable.HexStrToBigIntStr(tx.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		t := &Tx{
+			TxHash:          storable.Trim0x(tx.Hash),
+			IncludedInBlock: number,
+			TxIndex:         int64(index),
+			From:            storable.Trim0x(tx.From),
+			To:              storable.Trim0x(tx.To),
+			Value:           value,
+			MsgStatus:       receipt.Status,
+		}
+
+		if contractAddress, ok := receipt.ContractAddress.(string); ok && tx.To == "" {
+			t.Creates = storable.Trim0x(contractAddress)
+		}
+
+		for _, l := range receipt.Logs {
+			logIndex, err := strconv.ParseInt(l.LogIndex, 0, 64)
+			if err != nil {
+				return nil, err
+			}
+
+			var topics []string
+			for _, topic := range l.Topics {
+				topics = append(topics, storable.Trim0x(topic))
+			}
+
+			t.Logs = append(t.Logs, Log{
+				LogIndex: logIndex,
+				LoggedBy: storable.Trim0x(l.Address),
+				Topics:   topics,
+				Data:     storable.Trim0x(l.Data),
+			})
+		}
+
+		events = append(events, Event{Type: EventTx, Tx: t})
+	}
+
+	return events, nil
+}
+
+func ReorgEvent(r data.Reorg) Event {
+	return Event{
+		Type: EventReorg,
+		Reorg: &Reorg{
+			ForkBlock:    r.ForkBlock,
+			HeadBlock:    r.HeadBlock,
+			Depth:        r.Depth(),
+			OldHeadHash:  r.OldHeadHash,
+			NewBlockHash: r.NewBlockHash,
+		},
+	}
+}
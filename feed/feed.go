@@ -0,0 +1,151 @@
+// Package feed broadcasts the blocks, transactions and reorgs processed by the indexer to the live subscribers of
+// the API
+// The events of the blocks processed by the workers reach the API's subscribers through a Relay
+package feed
+
+import (
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+var log = logrus.WithField("module", "feed")
+
+// Event types
+const (
+	EventBlock = "block"
+	EventTx    = "tx"
+	EventReorg = "reorg"
+)
+
+// subscriptionBuffer is the number of events a subscriber can lag behind before it's dropped; since a block is
+// published along with all its transactions at once, it must hold a few full blocks
+const subscriptionBuffer = 4096
+
+type Event struct {
+	Type  string `json:"type"`
+	Block *Block `json:"block,omitempty"`
+	Tx    *Tx    `json:"tx,omitempty"`
+	Reorg *Reorg `json:"reorg,omitempty"`
+}
+
+type Block struct {
+	Number            int64  `json:"number"`
+	Hash              string `json:"hash"`
+	ParentHash        string `json:"parentHash"`
+	BlockCreationTime int64  `json:"blockCreationTime"`
+	NumberOfTxs       int    `json:"numberOfTxs"`
+	Finality          string `json:"finality"`
+}
+
+type Tx struct {
+	TxHash          string `json:"txHash"`
+	IncludedInBlock int64  `json:"includedInBlock"`
+	TxIndex         int64  `json:"txIndex"`
+	From            string `json:"from"`
+	To              string `json:"to"`
+	Creates         string `json:"creates,omitempty"`
+	Value           string `json:"value"`
+	MsgStatus       string `json:"msgStatus"`
+	Logs            []Log  `json:"logs,omitempty"`
+}
+
+type Log struct {
+	LogIndex int64    `json:"logIndex"`
+	LoggedBy string   `json:"loggedBy"`
+	Topics   []string `json:"topics"`
+	Data     string   `json:"data"`
+}
+
+// Reorg is sent when the blocks above ForkBlock, up to HeadBlock, were removed because they're no longer canonical
+// The new blocks follow as regular block events once they're processed
+type Reorg struct {
+	ForkBlock    int64  `json:"forkBlock"`
+	HeadBlock    int64  `json:"headBlock"`
+	Depth        int64  `json:"depth"`
+	OldHeadHash  string `json:"oldHeadHash"`
+	NewBlockHash string `json:"newBlockHash"`
+}
+
+// Hub fans the published events out to the subscribers
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[*Subscription]bool
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		subscribers: make(map[*Subscription]bool),
+	}
+}
+
+// Subscription receives the events matching its filter on C
+// C is closed when the subscription is closed, either by the subscriber or by the hub because the subscriber didn't
+// keep up with the events
+type Subscription struct {
+	C chan Event
+
+	hub    *Hub
+	filter Filter
+	once   sync.Once
+}
+
+func (h *Hub) Subscribe(filter Filter) *Subscription {
+	s := &Subscription{
+		C:      make(chan Event, subscriptionBuffer),
+		hub:    h,
+		filter: filter,
+	}
+
+	h.mu.Lock()
+	h.subscribers[s] = true
+	h.mu.Unlock()
+
+	log.Trace("new client subscribed")
+
+	return s
+}
+
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	s.close()
+}
+
+// close must be called with the hub's lock held
+func (s *Subscription) close() {
+	s.once.Do(func() {
+		delete(s.hub.subscribers, s)
+		close(s.C)
+	})
+}
+
+// Publish sends the events to the matching subscribers without blocking; subscribers whose buffer is full are dropped
+func (h *Hub) Publish(events ...Event) {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for s := range h.subscribers {
+		for _, e := range events {
+			if !s.filter.Match(e) {
+				continue
+			}
+
+			select {
+			case s.C <- e:
+			default:
+				log.Warn("dropping a subscriber that can't keep up with the events")
+				s.close()
+			}
+
+			if _, ok := h.subscribers[s]; !ok {
+				break
+			}
+		}
+	}
+}
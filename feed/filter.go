@@ -0,0 +1,70 @@
+package feed
+
+import "strings"
+
+// Filter selects the events a subscriber receives
+// Events restricts the types of events (all of them if empty). Addresses and Topics only apply to transactions: a
+// transaction matches if it was sent from or to one of the addresses, or if one of its logs was emitted by one of the
+// addresses, has one of the topics or has one of the addresses as a topic (e.g. the recipient of a token transfer);
+// all the transactions match if both are empty
+type Filter struct {
+	Events    map[string]bool
+	Addresses map[string]bool
+	Topics    map[string]bool
+}
+
+// NewFilter builds a filter from lists of event types, addresses and topics, which can be 0x-prefixed
+func NewFilter(events, addresses, topics []string) Filter {
+	f := Filter{
+		Events:    make(map[string]bool),
+		Addresses: make(map[string]bool),
+		Topics:    make(map[string]bool),
+	}
+
+	for _, e := range events {
+		f.Events[e] = true
+	}
+
+	for _, a := range addresses {
+		f.Addresses[clean(a)] = true
+	}
+
+	for _, t := range topics {
+		f.Topics[clean(t)] = true
+	}
+
+	return f
+}
+
+func (f Filter) Match(e Event) bool {
+	if len(f.Events) > 0 && !f.Events[e.Type] {
+		return false
+	}
+
+	if e.Type != EventTx || len(f.Addresses) == 0 && len(f.Topics) == 0 {
+		return true
+	}
+
+	tx := e.Tx
+	if f.Addresses[tx.From] || f.Addresses[tx.To] || f.Addresses[tx.Creates] {
+		return true
+	}
+
+	for _, l := range tx.Logs {
+		if f.Addresses[l.LoggedBy] {
+			return true
+		}
+
+		for _, topic := range l.Topics {
+			if f.Topics[topic] || len(topic) == 64 && f.Addresses[topic[24:]] && strings.Trim(topic[:24], "0") == "" {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func clean(s string) string {
+	return strings.ToLower(strings.TrimPrefix(s, "0x"))
+}
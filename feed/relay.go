@@ -0,0 +1,117 @@
+package feed
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// Relay shares the events between the processes indexing the same chain through a redis channel, so the subscribers
+// of the instance serving the API also get the blocks processed by the workers
+// Every process publishes the events of the blocks it stores to its own hub and to the channel, and forwards the ones
+// published by the others to its hub
+type Relay struct {
+	hub     *Hub
+	redis   *redis.Client
+	pubsub  *redis.PubSub
+	channel string
+
+	// origin identifies the process in the messages, so it doesn't publish its own events twice
+	origin string
+}
+
+type relayMessage struct {
+	Origin string  `json:"origin"`
+	Events []Event `json:"events"`
+}
+
+// NewRelay subscribes to the channel and starts forwarding the events published on it by the other processes to hub
+func NewRelay(hub *Hub, client *redis.Client, channel string) (*Relay, error) {
+	r := &Relay{
+		hub:     hub,
+		redis:   client,
+		channel: channel,
+		origin:  relayOrigin(),
+	}
+
+	r.pubsub = client.Subscribe(channel)
+
+	// wait for the subscription to be confirmed, so the events published from now on are not missed
+	_, err := r.pubsub.Receive()
+	if err != nil {
+		r.pubsub.Close()
+		return nil, err
+	}
+
+	go r.forward(r.pubsub.Channel())
+
+	return r, nil
+}
+
+// Publish sends the events to the subscribers of this process and to the other processes
+// Failing to relay them is only logged, like a subscriber that can't keep up, since the indexing must go on
+func (r *Relay) Publish(events ...Event) {
+	if r == nil || len(events) == 0 {
+		return
+	}
+
+	r.hub.Publish(events...)
+
+	payload, err := json.Marshal(relayMessage{Origin: r.origin, Events: events})
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	err = r.redis.Publish(r.channel, payload).Err()
+	if err != nil {
+		log.Error("could not relay events: ", err)
+	}
+}
+
+func (r *Relay) Close() error {
+	if r == nil {
+		return nil
+	}
+
+	err := r.pubsub.Close()
+	if err != nil {
+		return err
+	}
+
+	return r.redis.Close()
+}
+
+// forward publishes the events received from the other processes to the hub until the subscription is closed
+func (r *Relay) forward(messages <-chan *redis.Message) {
+	for msg := range messages {
+		r.receive(msg.Payload)
+	}
+}
+
+func (r *Relay) receive(payload string) {
+	var msg relayMessage
+	err := json.Unmarshal([]byte(payload), &msg)
+	if err != nil {
+		log.Error("could not decode relayed events: ", err)
+		return
+	}
+
+	if msg.Origin == r.origin {
+		return
+	}
+
+	r.hub.Publish(msg.Events...)
+}
+
+func relayOrigin() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	return fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), time.Now().UnixNano())
+}
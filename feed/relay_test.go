@@ -0,0 +1,52 @@
+package feed
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func relayPayload(t *testing.T, origin string, events ...Event) string {
+	payload, err := json.Marshal(relayMessage{Origin: origin, Events: events})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(payload)
+}
+
+func TestRelayForwardsEventsOfOtherProcesses(t *testing.T) {
+	hub := NewHub()
+	s := hub.Subscribe(Filter{})
+	defer s.Close()
+
+	r := &Relay{hub: hub, origin: "memento-1"}
+
+	block := Event{Type: EventBlock, Block: &Block{Number: 7, Hash: "0x07"}}
+	r.receive(relayPayload(t, "memento-1", block))
+	r.receive("not json")
+	r.receive(relayPayload(t, "memento-2", block, Event{Type: EventReorg, Reorg: &Reorg{ForkBlock: 5, HeadBlock: 7, Depth: 2}}))
+
+	if len(s.C) != 2 {
+		t.Fatalf("expected the 2 events of the other process, got %d", len(s.C))
+	}
+
+	e := <-s.C
+	if e.Type != EventBlock || e.Block.Number != 7 || e.Block.Hash != "0x07" {
+		t.Errorf("unexpected block event: %+v", e)
+	}
+
+	e = <-s.C
+	if e.Type != EventReorg || e.Reorg.Depth != 2 {
+		t.Errorf("unexpected reorg event: %+v", e)
+	}
+}
+
+func TestNilRelay(t *testing.T) {
+	var r *Relay
+	r.Publish(Event{Type: EventBlock, Block: &Block{}})
+
+	err := r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -49,6 +49,13 @@ func (p *Provider) GetLatestBLock() int64 {
 	return p.latestBlock
 }
 
+func (p *Provider) GetIndexedHead() int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.indexedHead
+}
+
 func (p *Provider) GetTodoLength() int64 {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -69,3 +76,17 @@ func (p *Provider) GetInvalidBlocks() int64 {
 
 	return p.invalidBlocks
 }
+
+func (p *Provider) GetGaps() int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.gaps
+}
+
+func (p *Provider) GetGapBlocks() int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.gapBlocks
+}
@@ -0,0 +1,48 @@
+package metrics
+
+import "time"
+
+// DefaultDurationBuckets are the upper bounds (in seconds) used for the duration histograms
+var DefaultDurationBuckets = []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// ReorgDepthBuckets are the upper bounds (in blocks) used for the reorg depth histogram
+var ReorgDepthBuckets = []float64{1, 2, 3, 5, 10, 25, 50, 100}
+
+// Histogram counts observations into cumulative buckets, following the Prometheus histogram semantics
+type Histogram struct {
+	Buckets []float64
+	Counts  []uint64
+	Sum     float64
+	Count   uint64
+}
+
+func NewHistogram(buckets []float64) Histogram {
+	return Histogram{
+		Buckets: buckets,
+		Counts:  make([]uint64, len(buckets)),
+	}
+}
+
+func (h *Histogram) Observe(value float64) {
+	for i, upperBound := range h.Buckets {
+		if value <= upperBound {
+			h.Counts[i]++
+		}
+	}
+
+	h.Sum += value
+	h.Count++
+}
+
+func (h *Histogram) ObserveDuration(duration time.Duration) {
+	h.Observe(duration.Seconds())
+}
+
+func (h *Histogram) Reset() {
+	for i := range h.Counts {
+		h.Counts[i] = 0
+	}
+
+	h.Sum = 0
+	h.Count = 0
+}
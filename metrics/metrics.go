@@ -12,25 +12,59 @@ type Provider struct {
 	scrapingTime   AverageDuration
 	indexingTime   AverageDuration
 
+	processingHist Histogram
+	scrapingHist   Histogram
+	indexingHist   Histogram
+	reorgDepthHist Histogram
+
 	latestBlock   int64
+	indexedHead   int64
 	todoLength    int64
 	reorgedBlocks int64
 	invalidBlocks int64
+
+	// gaps and gapBlocks describe the blocks found missing by the last gap scan; queuedGapBlocks counts the ones the
+	// scans put back into the todo list
+	gaps            int64
+	gapBlocks       int64
+	queuedGapBlocks int64
+
+	insertedRows map[string]int64
 }
 
 func New() *Provider {
-	return &Provider{}
+	return &Provider{
+		processingHist: NewHistogram(DefaultDurationBuckets),
+		scrapingHist:   NewHistogram(DefaultDurationBuckets),
+		indexingHist:   NewHistogram(DefaultDurationBuckets),
+		reorgDepthHist: NewHistogram(ReorgDepthBuckets),
+		insertedRows:   make(map[string]int64),
+	}
 }
 
 func (p *Provider) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	p.processingTime.Reset()
 	p.scrapingTime.Reset()
 	p.indexingTime.Reset()
 
+	p.processingHist.Reset()
+	p.scrapingHist.Reset()
+	p.indexingHist.Reset()
+	p.reorgDepthHist.Reset()
+
 	p.latestBlock = 0
+	p.indexedHead = 0
 	p.todoLength = 0
 	p.reorgedBlocks = 0
 	p.invalidBlocks = 0
+	p.gaps = 0
+	p.gapBlocks = 0
+	p.queuedGapBlocks = 0
+
+	p.insertedRows = make(map[string]int64)
 }
 
 func (p *Provider) RecordProcessingTime(duration time.Duration) {
@@ -38,6 +72,7 @@ func (p *Provider) RecordProcessingTime(duration time.Duration) {
 	defer p.mu.Unlock()
 
 	p.processingTime.Add(duration)
+	p.processingHist.ObserveDuration(duration)
 }
 
 func (p *Provider) RecordScrapingTime(duration time.Duration) {
@@ -45,6 +80,7 @@ func (p *Provider) RecordScrapingTime(duration time.Duration) {
 	defer p.mu.Unlock()
 
 	p.scrapingTime.Add(duration)
+	p.scrapingHist.ObserveDuration(duration)
 }
 
 func (p *Provider) RecordIndexingTime(duration time.Duration) {
@@ -52,6 +88,7 @@ func (p *Provider) RecordIndexingTime(duration time.Duration) {
 	defer p.mu.Unlock()
 
 	p.indexingTime.Add(duration)
+	p.indexingHist.ObserveDuration(duration)
 }
 
 func (p *Provider) RecordLatestBlock(block int64) {
@@ -61,6 +98,31 @@ func (p *Provider) RecordLatestBlock(block int64) {
 	p.latestBlock = block
 }
 
+// RecordIndexedBlock keeps track of the highest block stored in the database
+func (p *Provider) RecordIndexedBlock(block int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if block > p.indexedHead {
+		p.indexedHead = block
+	}
+}
+
+// SetIndexedHead replaces the highest block stored in the database, which goes down when blocks are rolled back
+func (p *Provider) SetIndexedHead(block int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.indexedHead = block
+}
+
+func (p *Provider) RecordInsertedRows(table string, count int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.insertedRows[table] += count
+}
+
 func (p *Provider) RecordTodoLength(len int64) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -75,9 +137,28 @@ func (p *Provider) RecordReorgedBlock() {
 	p.reorgedBlocks++
 }
 
+// RecordReorg tracks a chain reorganization that replaced the given number of blocks
+func (p *Provider) RecordReorg(depth int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.reorgDepthHist.Observe(float64(depth))
+}
+
 func (p *Provider) RecordInvalidBlock() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
 	p.invalidBlocks++
 }
+
+// RecordGaps tracks the result of a gap scan: the number of ranges of missing blocks, the number of blocks they hold
+// and how many of them were queued again
+func (p *Provider) RecordGaps(gaps, blocks, queued int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.gaps = gaps
+	p.gapBlocks = blocks
+	p.queuedGapBlocks += queued
+}
@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// ServeHTTP makes the provider an http.Handler serving the metrics to Prometheus scrapers
+func (p *Provider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	p.WritePrometheus(w)
+}
+
+// WritePrometheus writes all the metrics tracked by the provider to w using the Prometheus text exposition format
+func (p *Provider) WritePrometheus(w io.Writer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	writeGauge(w, "memento_latest_block", "Best block known to the node", p.latestBlock)
+	writeGauge(w, "memento_indexed_head", "Highest block stored in the database", p.indexedHead)
+
+	var lag int64
+	if p.latestBlock > p.indexedHead {
+		lag = p.latestBlock - p.indexedHead
+	}
+	writeGauge(w, "memento_lag_blocks", "Number of blocks the indexed head is behind the node's best block", lag)
+
+	writeGauge(w, "memento_todo_length", "Number of blocks waiting in the todo queue", p.todoLength)
+	writeCounter(w, "memento_reorged_blocks_total", "Number of reorged blocks that were replaced", p.reorgedBlocks)
+	writeCounter(w, "memento_invalid_blocks_total", "Number of scraped blocks that failed validation", p.invalidBlocks)
+	writeGauge(w, "memento_gaps", "Number of ranges of missing blocks found below the indexed head by the last gap scan", p.gaps)
+	writeGauge(w, "memento_gap_blocks", "Number of blocks missing below the indexed head according to the last gap scan", p.gapBlocks)
+	writeCounter(w, "memento_gap_blocks_queued_total", "Number of missing blocks put back into the todo queue by the gap scans", p.queuedGapBlocks)
+
+	writeHistogram(w, "memento_processing_duration_seconds", "Time spent processing (scraping, validating and storing) a block", p.processingHist)
+	writeHistogram(w, "memento_scraping_duration_seconds", "Time spent scraping a block from the node", p.scrapingHist)
+	writeHistogram(w, "memento_indexing_duration_seconds", "Time spent storing a block into the database", p.indexingHist)
+	writeHistogram(w, "memento_reorg_depth_blocks", "Number of blocks replaced by each detected chain reorganization", p.reorgDepthHist)
+
+	fmt.Fprintf(w, "# HELP memento_inserted_rows_total Number of rows inserted into each table\n")
+	fmt.Fprintf(w, "# TYPE memento_inserted_rows_total counter\n")
+
+	tables := make([]string, 0, len(p.insertedRows))
+	for table := range p.insertedRows {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	for _, table := range tables {
+		fmt.Fprintf(w, "memento_inserted_rows_total{table=%q} %d\n", table, p.insertedRows[table])
+	}
+}
+
+func writeGauge(w io.Writer, name, help string, value int64) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", name)
+	fmt.Fprintf(w, "%s %d\n", name, value)
+}
+
+func writeCounter(w io.Writer, name, help string, value int64) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s counter\n", name)
+	fmt.Fprintf(w, "%s %d\n", name, value)
+}
+
+func writeHistogram(w io.Writer, name, help string, h Histogram) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s histogram\n", name)
+
+	for i, upperBound := range h.Buckets {
+		fmt.Fprintf(w, "%s_bucket{le=\"%s\"} %d\n", name, strconv.FormatFloat(upperBound, 'f', -1, 64), h.Counts[i])
+	}
+
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", name, h.Count)
+	fmt.Fprintf(w, "%s_sum %s\n", name, strconv.FormatFloat(h.Sum, 'f', -1, 64))
+	fmt.Fprintf(w, "%s_count %d\n", name, h.Count)
+}
@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIndexedHeadFollowsRollbacks(t *testing.T) {
+	p := New()
+	p.RecordLatestBlock(110)
+	p.RecordIndexedBlock(100)
+	p.RecordIndexedBlock(90)
+
+	if head := p.GetIndexedHead(); head != 100 {
+		t.Fatalf("expected the indexed head to stay at 100, got %d", head)
+	}
+
+	p.SetIndexedHead(80)
+
+	var buf bytes.Buffer
+	p.WritePrometheus(&buf)
+
+	for _, line := range []string{"memento_indexed_head 80\n", "memento_lag_blocks 30\n"} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("expected %q in:\n%s", line, buf.String())
+		}
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	p := New()
+	p.RecordIndexedBlock(100)
+
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") {
+		t.Fatalf("unexpected response %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(w.Body.String(), "memento_indexed_head 100\n") {
+		t.Errorf("expected the indexed head in:\n%s", w.Body.String())
+	}
+}
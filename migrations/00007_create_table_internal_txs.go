@@ -0,0 +1,94 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upCreateTableInternalTxs, downCreateTableInternalTxs)
+}
+
+func upCreateTableInternalTxs(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	create table internal_txs
+	(
+		tx_hash                    text        not null,
+		included_in_block          bigint      not null,
+		tx_index                   integer     not null,
+		trace_index                integer     not null,
+		trace_address              text        not null,
+		type                       text        not null,
+		call_type                  text,
+		"from"                     bytea,
+		"to"                       bytea,
+		value                      numeric(78) not null,
+		msg_gas_limit              numeric(78),
+		msg_gas_used               numeric(78),
+		msg_payload                bytea,
+		msg_error                  text,
+		created_at                 timestamp default now()
+	);
+
+	create index on internal_txs (tx_hash, trace_index);
+	create index on internal_txs (included_in_block desc);
+
+	alter table account_txs add column internal bool not null default false;
+
+	create or replace function delete_block(in block_number bigint) returns void as
+	$body$
+	declare
+		tables varchar[];
+		tbl    varchar;
+	begin
+		tables := array [
+			'uncles',
+			'txs',
+			'log_entries',
+			'account_txs',
+			'internal_txs'
+			];
+
+		foreach tbl in array tables
+			loop
+				perform __delete_entity(tbl, block_number);
+			end loop;
+
+		delete from blocks where number = block_number;
+	end;
+	$body$ language 'plpgsql';
+	`)
+	return err
+}
+
+func downCreateTableInternalTxs(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	drop table internal_txs;
+
+	alter table account_txs drop column internal;
+
+	create or replace function delete_block(in block_number bigint) returns void as
+	$body$
+	declare
+		tables varchar[];
+		tbl    varchar;
+	begin
+		tables := array [
+			'uncles',
+			'txs',
+			'log_entries',
+			'account_txs'
+			];
+
+		foreach tbl in array tables
+			loop
+				perform __delete_entity(tbl, block_number);
+			end loop;
+
+		delete from blocks where number = block_number;
+	end;
+	$body$ language 'plpgsql';
+	`)
+	return err
+}
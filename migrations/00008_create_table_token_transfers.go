@@ -0,0 +1,92 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upCreateTableTokenTransfers, downCreateTableTokenTransfers)
+}
+
+func upCreateTableTokenTransfers(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	create table token_transfers
+	(
+		tx_hash                    text        not null,
+		included_in_block          bigint      not null,
+		tx_index                   integer     not null,
+		log_index                  integer     not null,
+		batch_index                integer     not null default 0,
+		token_address              text        not null,
+		token_standard             text        not null,
+		operator                   text,
+		"from"                     text        not null,
+		"to"                       text        not null,
+		amount                     numeric(78) not null,
+		token_id                   numeric(78),
+		created_at                 timestamp default now()
+	);
+
+	create index on token_transfers ("from", included_in_block desc, tx_index desc, log_index desc, batch_index desc);
+	create index on token_transfers ("to", included_in_block desc, tx_index desc, log_index desc, batch_index desc);
+	create index on token_transfers (token_address, included_in_block desc, tx_index desc, log_index desc, batch_index desc);
+	create index on token_transfers (included_in_block desc);
+
+	create or replace function delete_block(in block_number bigint) returns void as
+	$body$
+	declare
+		tables varchar[];
+		tbl    varchar;
+	begin
+		tables := array [
+			'uncles',
+			'txs',
+			'log_entries',
+			'account_txs',
+			'internal_txs',
+			'token_transfers'
+			];
+
+		foreach tbl in array tables
+			loop
+				perform __delete_entity(tbl, block_number);
+			end loop;
+
+		delete from blocks where number = block_number;
+	end;
+	$body$ language 'plpgsql';
+	`)
+	return err
+}
+
+func downCreateTableTokenTransfers(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	drop table token_transfers;
+
+	create or replace function delete_block(in block_number bigint) returns void as
+	$body$
+	declare
+		tables varchar[];
+		tbl    varchar;
+	begin
+		tables := array [
+			'uncles',
+			'txs',
+			'log_entries',
+			'account_txs',
+			'internal_txs'
+			];
+
+		foreach tbl in array tables
+			loop
+				perform __delete_entity(tbl, block_number);
+			end loop;
+
+		delete from blocks where number = block_number;
+	end;
+	$body$ language 'plpgsql';
+	`)
+	return err
+}
@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upCreateTableReorgs, downCreateTableReorgs)
+}
+
+func upCreateTableReorgs(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	create table reorgs
+	(
+		id                         serial primary key,
+		fork_block                 bigint      not null,
+		head_block                 bigint      not null,
+		depth                      integer     not null,
+		old_head_hash              text        not null,
+		new_block_hash             text        not null,
+		detected_at                timestamp with time zone default now()
+	);
+
+	create index on reorgs (detected_at desc);
+	`)
+	return err
+}
+
+func downCreateTableReorgs(tx *sql.Tx) error {
+	_, err := tx.Exec("drop table reorgs;")
+	return err
+}
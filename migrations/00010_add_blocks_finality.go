@@ -0,0 +1,29 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upAddBlocksFinality, downAddBlocksFinality)
+}
+
+func upAddBlocksFinality(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	alter table blocks add column finality text;
+
+	create index blocks_unfinalized_idx on blocks (number) where finality in ('latest', 'safe');
+	`)
+	return err
+}
+
+func downAddBlocksFinality(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	drop index if exists blocks_unfinalized_idx;
+
+	alter table blocks drop column finality;
+	`)
+	return err
+}
@@ -0,0 +1,109 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upAddTypedTxFields, downAddTypedTxFields)
+}
+
+func upAddTypedTxFields(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	alter table txs
+		add column tx_type                  smallint    not null default 0,
+		add column max_fee_per_gas          numeric(78),
+		add column max_priority_fee_per_gas numeric(78),
+		add column effective_gas_price      numeric(78);
+
+	alter table blocks
+		add column base_fee_per_gas         numeric(78),
+		add column burnt_fees               numeric(78),
+		add column priority_fees            numeric(78);
+
+	create table tx_access_lists
+	(
+		tx_hash                    text        not null,
+		included_in_block          bigint      not null,
+		tx_index                   integer     not null,
+		entry_index                integer     not null,
+		address                    text        not null,
+		storage_keys               jsonb       not null,
+		created_at                 timestamp default now()
+	);
+
+	create index on tx_access_lists (tx_hash, entry_index);
+	create index on tx_access_lists (address);
+	create index on tx_access_lists (included_in_block desc);
+
+	create or replace function delete_block(in block_number bigint) returns void as
+	$body$
+	declare
+		tables varchar[];
+		tbl    varchar;
+	begin
+		tables := array [
+			'uncles',
+			'txs',
+			'log_entries',
+			'account_txs',
+			'internal_txs',
+			'token_transfers',
+			'tx_access_lists'
+			];
+
+		foreach tbl in array tables
+			loop
+				perform __delete_entity(tbl, block_number);
+			end loop;
+
+		delete from blocks where number = block_number;
+	end;
+	$body$ language 'plpgsql';
+	`)
+	return err
+}
+
+func downAddTypedTxFields(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	drop table tx_access_lists;
+
+	alter table blocks
+		drop column base_fee_per_gas,
+		drop column burnt_fees,
+		drop column priority_fees;
+
+	alter table txs
+		drop column tx_type,
+		drop column max_fee_per_gas,
+		drop column max_priority_fee_per_gas,
+		drop column effective_gas_price;
+
+	create or replace function delete_block(in block_number bigint) returns void as
+	$body$
+	declare
+		tables varchar[];
+		tbl    varchar;
+	begin
+		tables := array [
+			'uncles',
+			'txs',
+			'log_entries',
+			'account_txs',
+			'internal_txs',
+			'token_transfers'
+			];
+
+		foreach tbl in array tables
+			loop
+				perform __delete_entity(tbl, block_number);
+			end loop;
+
+		delete from blocks where number = block_number;
+	end;
+	$body$ language 'plpgsql';
+	`)
+	return err
+}
@@ -0,0 +1,135 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upAddWithdrawalsAndBlobs, downAddWithdrawalsAndBlobs)
+}
+
+func upAddWithdrawalsAndBlobs(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	alter table blocks
+		add column withdrawals_root         bytea,
+		add column number_of_withdrawals    integer     default 0,
+		add column blob_gas_used            numeric(78),
+		add column excess_blob_gas          numeric(78),
+		add column parent_beacon_block_root bytea;
+
+	alter table txs
+		add column max_fee_per_blob_gas     numeric(78),
+		add column blob_gas_used            numeric(78),
+		add column blob_gas_price           numeric(78);
+
+	alter table account_txs
+		add column withdrawal_index         bigint;
+
+	create table withdrawals
+	(
+		included_in_block          bigint      not null,
+		withdrawal_index           bigint      not null,
+		validator_index            bigint      not null,
+		address                    text        not null,
+		amount                     numeric(78) not null,
+		block_creation_time        timestamp with time zone,
+		created_at                 timestamp default now()
+	);
+
+	create index on withdrawals (included_in_block desc, withdrawal_index);
+	create index on withdrawals (address, included_in_block desc);
+	create index on withdrawals (withdrawal_index);
+
+	create table blob_hashes
+	(
+		tx_hash                    text        not null,
+		included_in_block          bigint      not null,
+		tx_index                   integer     not null,
+		blob_index                 integer     not null,
+		versioned_hash             text        not null,
+		created_at                 timestamp default now()
+	);
+
+	create index on blob_hashes (tx_hash, blob_index);
+	create index on blob_hashes (versioned_hash);
+	create index on blob_hashes (included_in_block desc);
+
+	create or replace function delete_block(in block_number bigint) returns void as
+	$body$
+	declare
+		tables varchar[];
+		tbl    varchar;
+	begin
+		tables := array [
+			'uncles',
+			'txs',
+			'log_entries',
+			'account_txs',
+			'internal_txs',
+			'token_transfers',
+			'tx_access_lists',
+			'withdrawals',
+			'blob_hashes'
+			];
+
+		foreach tbl in array tables
+			loop
+				perform __delete_entity(tbl, block_number);
+			end loop;
+
+		delete from blocks where number = block_number;
+	end;
+	$body$ language 'plpgsql';
+	`)
+	return err
+}
+
+func downAddWithdrawalsAndBlobs(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	drop table blob_hashes;
+	drop table withdrawals;
+
+	alter table account_txs
+		drop column withdrawal_index;
+
+	alter table txs
+		drop column max_fee_per_blob_gas,
+		drop column blob_gas_used,
+		drop column blob_gas_price;
+
+	alter table blocks
+		drop column withdrawals_root,
+		drop column number_of_withdrawals,
+		drop column blob_gas_used,
+		drop column excess_blob_gas,
+		drop column parent_beacon_block_root;
+
+	create or replace function delete_block(in block_number bigint) returns void as
+	$body$
+	declare
+		tables varchar[];
+		tbl    varchar;
+	begin
+		tables := array [
+			'uncles',
+			'txs',
+			'log_entries',
+			'account_txs',
+			'internal_txs',
+			'token_transfers',
+			'tx_access_lists'
+			];
+
+		foreach tbl in array tables
+			loop
+				perform __delete_entity(tbl, block_number);
+			end loop;
+
+		delete from blocks where number = block_number;
+	end;
+	$body$ language 'plpgsql';
+	`)
+	return err
+}
@@ -0,0 +1,29 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upCreateTableContractAbis, downCreateTableContractAbis)
+}
+
+func upCreateTableContractAbis(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	create table contract_abis
+	(
+		address                    text        primary key,
+		name                       text        not null default '',
+		abi                        text        not null,
+		created_at                 timestamp with time zone default now()
+	);
+	`)
+	return err
+}
+
+func downCreateTableContractAbis(tx *sql.Tx) error {
+	_, err := tx.Exec("drop table contract_abis;")
+	return err
+}
@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upAddTxsMethodSelector, downAddTxsMethodSelector)
+}
+
+func upAddTxsMethodSelector(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	alter table txs add column method_selector text;
+
+	update txs set method_selector = substr(encode(msg_payload, 'hex'), 1, 8) where length(msg_payload) >= 4 and coalesce(length(creates), 0) = 0;
+
+	create index on txs ("to", method_selector, included_in_block desc, tx_index desc);
+
+	create table function_signatures
+	(
+		selector                   text        not null,
+		signature                  text        not null,
+		created_at                 timestamp with time zone default now(),
+		primary key (selector, signature)
+	);
+	`)
+	return err
+}
+
+func downAddTxsMethodSelector(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	drop table function_signatures;
+
+	alter table txs drop column method_selector;
+	`)
+	return err
+}
@@ -0,0 +1,102 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upCreateTableContracts, downCreateTableContracts)
+}
+
+func upCreateTableContracts(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	create table contracts
+	(
+		address                    text        not null,
+		creator                    text        not null,
+		tx_hash                    text        not null,
+		included_in_block          bigint      not null,
+		tx_index                   integer     not null,
+		trace_address              text        not null default '',
+		code_hash                  text,
+		block_creation_time        timestamp,
+		created_at                 timestamp default now()
+	);
+
+	create index on contracts (address);
+	create index on contracts (creator, included_in_block desc);
+	create index on contracts (included_in_block desc, tx_index desc, trace_address desc);
+
+	-- the contracts created by transactions are known from the txs table; the ones created by other contracts are
+	-- only recorded for the blocks processed from now on
+	insert into contracts (address, creator, tx_hash, included_in_block, tx_index, block_creation_time)
+	select encode(creates, 'hex'), encode("from", 'hex'), tx_hash, included_in_block, tx_index, block_creation_time
+	from txs
+	where length(creates) > 0 and msg_status <> '0x0';
+
+	create or replace function delete_block(in block_number bigint) returns void as
+	$body$
+	declare
+		tables varchar[];
+		tbl    varchar;
+	begin
+		tables := array [
+			'uncles',
+			'txs',
+			'log_entries',
+			'account_txs',
+			'internal_txs',
+			'token_transfers',
+			'tx_access_lists',
+			'withdrawals',
+			'blob_hashes',
+			'contracts'
+			];
+
+		foreach tbl in array tables
+			loop
+				perform __delete_entity(tbl, block_number);
+			end loop;
+
+		delete from blocks where number = block_number;
+	end;
+	$body$ language 'plpgsql';
+	`)
+	return err
+}
+
+func downCreateTableContracts(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	drop table contracts;
+
+	create or replace function delete_block(in block_number bigint) returns void as
+	$body$
+	declare
+		tables varchar[];
+		tbl    varchar;
+	begin
+		tables := array [
+			'uncles',
+			'txs',
+			'log_entries',
+			'account_txs',
+			'internal_txs',
+			'token_transfers',
+			'tx_access_lists',
+			'withdrawals',
+			'blob_hashes'
+			];
+
+		foreach tbl in array tables
+			loop
+				perform __delete_entity(tbl, block_number);
+			end loop;
+
+		delete from blocks where number = block_number;
+	end;
+	$body$ language 'plpgsql';
+	`)
+	return err
+}
@@ -0,0 +1,55 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upCreateTableWebhooks, downCreateTableWebhooks)
+}
+
+func upCreateTableWebhooks(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	create table webhooks
+	(
+		id                         text        primary key,
+		url                        text        not null,
+		secret                     text        not null,
+		address                    text        not null default '',
+		topic0                     text        not null default '',
+		description                text        not null default '',
+		created_at                 timestamp with time zone default now()
+	);
+
+	create table webhook_deliveries
+	(
+		id                         text        primary key,
+		webhook_id                 text        not null,
+		kind                       text        not null,
+		block_number               bigint      not null,
+		block_hash                 text        not null,
+		tx_hash                    text        not null,
+		payload                    text        not null,
+		status                     text        not null default 'pending',
+		attempts                   integer     not null default 0,
+		response_code              integer,
+		error                      text        not null default '',
+		next_attempt_at            timestamp with time zone not null,
+		delivered_at               timestamp with time zone,
+		created_at                 timestamp with time zone default now()
+	);
+
+	create unique index on webhook_deliveries (webhook_id, kind, block_hash, tx_hash);
+	create index on webhook_deliveries (status, next_attempt_at);
+	create index on webhook_deliveries (webhook_id, id desc);
+	create index on webhook_deliveries (block_number);
+	`)
+	return err
+}
+
+func downCreateTableWebhooks(tx *sql.Tx) error {
+	_, err := tx.Exec("drop table webhook_deliveries; drop table webhooks;")
+	return err
+}
@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upAddIncludedInBlockIndexes, downAddIncludedInBlockIndexes)
+}
+
+// The log entries and uncles are looked up by block when a block is deleted and when the index is verified; the sqlite
+// schema has had these indexes from the start
+func upAddIncludedInBlockIndexes(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	create index log_entries_included_in_block_idx on log_entries (included_in_block desc);
+	create index uncles_included_in_block_idx on uncles (included_in_block desc);
+	`)
+	return err
+}
+
+func downAddIncludedInBlockIndexes(tx *sql.Tx) error {
+	_, err := tx.Exec("drop index log_entries_included_in_block_idx; drop index uncles_included_in_block_idx;")
+	return err
+}
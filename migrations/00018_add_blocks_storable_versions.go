@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upAddBlocksStorableVersions, downAddBlocksStorableVersions)
+}
+
+// The blocks stored before this migration have no versions recorded, which is read as version 1 of every storable
+func upAddBlocksStorableVersions(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	alter table blocks add column storable_versions text;
+	`)
+	return err
+}
+
+func downAddBlocksStorableVersions(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	alter table blocks drop column storable_versions;
+	`)
+	return err
+}
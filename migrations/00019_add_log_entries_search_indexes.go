@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upAddLogEntriesSearchIndexes, downAddLogEntriesSearchIndexes)
+}
+
+// The log search filters by emitting contract and topics within a block range; on a large database, building these
+// indexes takes a while
+func upAddLogEntriesSearchIndexes(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	create index log_entries_logged_by_idx on log_entries (logged_by, included_in_block desc);
+	create index log_entries_topic_0_idx on log_entries (topic_0, included_in_block desc);
+	create index log_entries_topic_1_idx on log_entries (topic_1, included_in_block desc);
+	create index log_entries_topic_2_idx on log_entries (topic_2, included_in_block desc);
+	create index log_entries_topic_3_idx on log_entries (topic_3, included_in_block desc);
+	`)
+	return err
+}
+
+func downAddLogEntriesSearchIndexes(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	drop index if exists log_entries_logged_by_idx;
+	drop index if exists log_entries_topic_0_idx;
+	drop index if exists log_entries_topic_1_idx;
+	drop index if exists log_entries_topic_2_idx;
+	drop index if exists log_entries_topic_3_idx;
+	`)
+	return err
+}
@@ -0,0 +1,29 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upAddLogEntriesBlockIndex, downAddLogEntriesBlockIndex)
+}
+
+// log_index keeps the position of a log entry within its receipt; the entries stored before this migration have no
+// block_log_index nor block_hash until they're reindexed (they're derived from the txs and blocks in the meantime)
+func upAddLogEntriesBlockIndex(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	alter table log_entries add column block_log_index integer;
+	alter table log_entries add column block_hash text;
+	`)
+	return err
+}
+
+func downAddLogEntriesBlockIndex(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	alter table log_entries drop column block_log_index;
+	alter table log_entries drop column block_hash;
+	`)
+	return err
+}
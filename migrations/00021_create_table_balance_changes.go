@@ -0,0 +1,91 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upCreateTableBalanceChanges, downCreateTableBalanceChanges)
+}
+
+func upCreateTableBalanceChanges(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	create table balance_changes
+	(
+		address                    text        not null,
+		included_in_block          bigint      not null,
+		delta                      numeric(78) not null,
+		created_at                 timestamp default now()
+	);
+
+	create unique index on balance_changes (address, included_in_block desc);
+	create index on balance_changes (included_in_block desc);
+
+	create or replace function delete_block(in block_number bigint) returns void as
+	$body$
+	declare
+		tables varchar[];
+		tbl    varchar;
+	begin
+		tables := array [
+			'uncles',
+			'txs',
+			'log_entries',
+			'account_txs',
+			'internal_txs',
+			'token_transfers',
+			'tx_access_lists',
+			'withdrawals',
+			'blob_hashes',
+			'contracts',
+			'balance_changes'
+			];
+
+		foreach tbl in array tables
+			loop
+				perform __delete_entity(tbl, block_number);
+			end loop;
+
+		delete from blocks where number = block_number;
+	end;
+	$body$ language 'plpgsql';
+	`)
+	return err
+}
+
+func downCreateTableBalanceChanges(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	drop table balance_changes;
+
+	create or replace function delete_block(in block_number bigint) returns void as
+	$body$
+	declare
+		tables varchar[];
+		tbl    varchar;
+	begin
+		tables := array [
+			'uncles',
+			'txs',
+			'log_entries',
+			'account_txs',
+			'internal_txs',
+			'token_transfers',
+			'tx_access_lists',
+			'withdrawals',
+			'blob_hashes',
+			'contracts'
+			];
+
+		foreach tbl in array tables
+			loop
+				perform __delete_entity(tbl, block_number);
+			end loop;
+
+		delete from blocks where number = block_number;
+	end;
+	$body$ language 'plpgsql';
+	`)
+	return err
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upAddBlocksHasTraces, downAddBlocksHasTraces)
+}
+
+// The blocks stored before this migration are not known to have been stored with their traces, so the balances that
+// depend on them are reported as incomplete until they are reindexed
+// The partial index keeps finding the untraced blocks below a block cheap when (as it should be) there are few of them
+func upAddBlocksHasTraces(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	alter table blocks add column has_traces boolean not null default false;
+
+	create index blocks_untraced_idx on blocks (number) where not has_traces;
+	`)
+	return err
+}
+
+func downAddBlocksHasTraces(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	drop index blocks_untraced_idx;
+
+	alter table blocks drop column has_traces;
+	`)
+	return err
+}
@@ -1,8 +1,9 @@
 package scraper
 
 import (
-	"sort"
+	"encoding/json"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -10,6 +11,8 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/Alethio/memento/data"
+	"github.com/Alethio/memento/eth/extra"
+	"github.com/alethio/web3-go/types"
 
 	"github.com/alethio/web3-go/ethrpc"
 	"github.com/sirupsen/logrus"
@@ -20,6 +23,10 @@ var log = logrus.WithField("module", "scraper")
 type Config struct {
 	NodeURL      string
 	EnableUncles bool
+	EnableTraces bool
+
+	// TracesSource selects the tracing API used to scrape internal transactions (TracesSourceGeth or TracesSourceParity)
+	TracesSource string
 }
 
 type Scraper struct {
@@ -55,47 +62,73 @@ func New(config Config) (*Scraper, error) {
 // - scrapes the block using eth_getBlockByNumber
 // - for each transaction in the block, scrapes the receipts using eth_getTransactionReceipt
 // - for each uncle in the block, scrapes the data using eth_getUncleByBlockHashAndIndex
+// - if enabled, scrapes the call traces using debug_traceBlockByNumber (geth) or trace_block (parity/erigon)
+// - otherwise, scrapes the code of the contracts created in the block using eth_getCode
 func (s *Scraper) Exec(block int64) (*data.FullBlock, error) {
-	log = log.WithField("block", block)
+	log := log.WithField("block", block)
 
 	b := &data.FullBlock{}
 
 	log.Debug("getting block")
 	start := time.Now()
-	dataBlock, err := s.conn.GetBlockByNumber("0x" + strconv.FormatInt(block, 16))
+	var rawBlock json.RawMessage
+	err := s.conn.MakeRequest(&rawBlock, "eth_getBlockByNumber", "0x"+strconv.FormatInt(block, 16), true)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
+	err = decode(rawBlock, &b.Block, &b.BlockExtra)
 	if err != nil {
 		log.Error(err)
 		return nil, err
 	}
-	b.Block = dataBlock
+	dataBlock := b.Block
 	log.WithField("duration", time.Since(start)).Debug("got block")
 
 	log.Debug("getting receipts")
 	start = time.Now()
 
+	// the receipts are requested concurrently, so each one is put at the position of its transaction rather than
+	// appended as it arrives; the block-wide log indexes depend on this order
 	var wg sync.WaitGroup
 	var errs []error
 	var mu sync.Mutex
-	for _, tx := range dataBlock.Transactions {
+	b.Receipts = make(data.Receipts, len(dataBlock.Transactions))
+	b.ReceiptsExtra = make(map[string]extra.Receipt, len(dataBlock.Transactions))
+	for i, tx := range dataBlock.Transactions {
 		wg.Add(1)
+		position := i
 		txCopy := tx
 
 		go func() {
 			defer wg.Done()
 
-			dataReceipt, err := s.conn.GetTransactionReceipt(txCopy.Hash)
+			var rawReceipt json.RawMessage
+			var dataReceipt types.Receipt
+			var receiptExtra extra.Receipt
+
+			err := s.conn.MakeRequest(&rawReceipt, "eth_getTransactionReceipt", txCopy.Hash)
+			if err == nil {
+				err = decode(rawReceipt, &dataReceipt, &receiptExtra)
+			}
+			if err == nil && !strings.EqualFold(dataReceipt.TransactionHash, txCopy.Hash) {
+				err = errors.Errorf("got the receipt of %s instead of %s", dataReceipt.TransactionHash, txCopy.Hash)
+			}
 			if err != nil {
+				mu.Lock()
 				errs = append(errs, err)
+				mu.Unlock()
 				return
 			}
 
 			mu.Lock()
-			b.Receipts = append(b.Receipts, dataReceipt)
+			b.Receipts[position] = dataReceipt
+			b.ReceiptsExtra[txCopy.Hash] = receiptExtra
 			mu.Unlock()
 		}()
 	}
 	wg.Wait()
-	sort.Sort(b.Receipts)
 
 	log.WithField("duration", time.Since(start)).Debugf("got %d receipts", len(b.Receipts))
 	if len(errs) > 0 {
@@ -117,7 +150,88 @@ func (s *Scraper) Exec(block int64) (*data.FullBlock, error) {
 		log.WithField("duration", time.Since(start)).Debugf("got %d uncles", len(b.Uncles))
 	}
 
+	if !s.config.EnableTraces {
+		b.Codes = s.getCodes(block, b.Receipts)
+	}
+
+	if s.config.EnableTraces && len(dataBlock.Transactions) > 0 {
+		log.Debug("getting traces")
+		start = time.Now()
+		traces, err := s.getTraces(dataBlock)
+		if err != nil {
+			log.Error(err)
+			return nil, err
+		}
+
+		b.Traces = traces
+		log.WithField("duration", time.Since(start)).Debugf("got %d traces", len(b.Traces))
+	}
+	b.Traced = s.config.EnableTraces
+
 	log.Debug("done scraping block")
 
 	return b, nil
 }
+
+// getCodes returns the code of the contracts created by the transactions of the block, keyed by address
+// Nodes that don't keep the state of old blocks can't return the code, in which case the contract is stored without
+// its code hash rather than failing the block
+func (s *Scraper) getCodes(block int64, receipts []types.Receipt) map[string]string {
+	codes := make(map[string]string)
+	for _, receipt := range receipts {
+		address, ok := receipt.ContractAddress.(string)
+		if !ok || address == "" || receipt.Status == "0x0" {
+			continue
+		}
+
+		var code string
+		err := s.conn.MakeRequest(&code, "eth_getCode", address, "0x"+strconv.FormatInt(block, 16))
+		if err != nil {
+			log.WithField("block", block).WithField("address", address).Warn("could not get contract code: ", err)
+			continue
+		}
+
+		codes[address] = code
+	}
+
+	return codes
+}
+
+// decode unmarshals the same JSON-RPC result into the web3-go type and its extra fields
+func decode(raw json.RawMessage, value, extraValue interface{}) error {
+	err := json.Unmarshal(raw, value)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(raw, extraValue)
+}
+
+// BlockHash returns the hash of the canonical block with the given number, as currently seen by the node
+func (s *Scraper) BlockHash(block int64) (string, error) {
+	var res struct {
+		Hash string `json:"hash"`
+	}
+
+	err := s.conn.MakeRequest(&res, "eth_getBlockByNumber", "0x"+strconv.FormatInt(block, 16), false)
+	if err != nil {
+		return "", err
+	}
+
+	if res.Hash == "" {
+		return "", errors.Errorf("block %d not found", block)
+	}
+
+	return res.Hash, nil
+}
+
+// ChainID returns the id of the chain the node follows (EIP-155)
+func (s *Scraper) ChainID() (int64, error) {
+	var res string
+	err := s.conn.MakeRequest(&res, "eth_chainId")
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseInt(res, 0, 64)
+}
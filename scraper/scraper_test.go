@@ -0,0 +1,48 @@
+package scraper
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Alethio/memento/eth/extra"
+	"github.com/alethio/web3-go/types"
+)
+
+func TestDecodeKeepsExtraFields(t *testing.T) {
+	raw := json.RawMessage(`{
+		"hash": "0x01",
+		"number": "0x10",
+		"baseFeePerGas": "0x7",
+		"transactions": [{
+			"hash": "0x02", "gasPrice": "0x9", "type": "0x2", "maxFeePerGas": "0xa", "maxPriorityFeePerGas": "0x2",
+			"accessList": [{"address": "0x03", "storageKeys": ["0x04"]}]
+		}]
+	}`)
+
+	var block types.Block
+	var blockExtra extra.Block
+	err := decode(raw, &block, &blockExtra)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if block.Number != "0x10" || len(block.Transactions) != 1 || block.Transactions[0].GasPrice != "0x9" {
+		t.Errorf("unexpected block %+v", block)
+	}
+
+	tx := blockExtra.Tx(0)
+	if blockExtra.BaseFeePerGas != "0x7" || tx.Type != "0x2" || tx.MaxFeePerGas != "0xa" || tx.MaxPriorityFeePerGas != "0x2" {
+		t.Errorf("unexpected extra fields %+v", blockExtra)
+	}
+	if len(tx.AccessList) != 1 || tx.AccessList[0].Address != "0x03" || len(tx.AccessList[0].StorageKeys) != 1 {
+		t.Errorf("unexpected access list %+v", tx.AccessList)
+	}
+	if blockExtra.Tx(1).Hash != "" {
+		t.Errorf("expected an empty transaction past the end of the block")
+	}
+
+	err = decode(json.RawMessage(`{"number": 16}`), &block, &blockExtra)
+	if err == nil {
+		t.Error("expected an error for a malformed block")
+	}
+}
@@ -0,0 +1,126 @@
+package scraper
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/alethio/web3-go/types"
+)
+
+const (
+	TracesSourceGeth   = "geth"
+	TracesSourceParity = "parity"
+)
+
+// gethCallFrame is the structure returned by geth's built-in callTracer for each call
+type gethCallFrame struct {
+	Type    string          `json:"type"`
+	From    string          `json:"from"`
+	To      string          `json:"to"`
+	Value   string          `json:"value"`
+	Gas     string          `json:"gas"`
+	GasUsed string          `json:"gasUsed"`
+	Input   string          `json:"input"`
+	Output  string          `json:"output"`
+	Error   string          `json:"error"`
+	Calls   []gethCallFrame `json:"calls"`
+}
+
+type gethTxTrace struct {
+	TxHash string        `json:"txHash"`
+	Result gethCallFrame `json:"result"`
+	Error  string        `json:"error"`
+}
+
+// getTraces scrapes the call traces of all the transactions in the block using the configured source
+// The geth callTracer output is converted into the flat, parity-style format, so the rest of the pipeline only
+// has to deal with one representation
+func (s *Scraper) getTraces(block types.Block) ([]types.Trace, error) {
+	number := block.Number
+
+	switch s.config.TracesSource {
+	case TracesSourceParity:
+		return s.conn.TraceBlock(number)
+	case TracesSourceGeth:
+		var res []gethTxTrace
+		err := s.conn.MakeRequest(&res, "debug_traceBlockByNumber", number, map[string]interface{}{
+			"tracer": "callTracer",
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if len(res) != len(block.Transactions) {
+			return nil, fmt.Errorf("got %d traces for %d transactions", len(res), len(block.Transactions))
+		}
+
+		blockNumber, err := strconv.ParseInt(number, 0, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		var traces []types.Trace
+		for index, txTrace := range res {
+			if txTrace.Error != "" {
+				return nil, fmt.Errorf("could not trace tx at index %d: %s", index, txTrace.Error)
+			}
+
+			tx := block.Transactions[index]
+			traces = flattenCallFrame(traces, txTrace.Result, []int{}, tx.Hash, block.Hash, int(blockNumber), index)
+		}
+
+		return traces, nil
+	default:
+		return nil, fmt.Errorf("unknown traces source: %s", s.config.TracesSource)
+	}
+}
+
+// flattenCallFrame walks the call frame tree depth-first and appends a parity-style trace for every frame
+func flattenCallFrame(traces []types.Trace, frame gethCallFrame, traceAddress []int, txHash, blockHash string, blockNumber, txPosition int) []types.Trace {
+	t := types.Trace{
+		BlockHash:           &blockHash,
+		BlockNumber:         &blockNumber,
+		Subtraces:           len(frame.Calls),
+		TraceAddress:        traceAddress,
+		TransactionHash:     &txHash,
+		TransactionPosition: &txPosition,
+	}
+
+	from, to, value, gas, gasUsed, input, output := frame.From, frame.To, frame.Value, frame.Gas, frame.GasUsed, frame.Input, frame.Output
+	if value == "" {
+		value = "0x0"
+	}
+
+	switch callType := strings.ToLower(frame.Type); callType {
+	case "create", "create2":
+		t.Type = "create"
+		t.Action = types.TraceAction{From: &from, Gas: &gas, Value: &value, Init: &input}
+		t.Result = &types.TraceResult{GasUsed: &gasUsed, Address: &to, Code: &output}
+	case "selfdestruct":
+		t.Type = "suicide"
+		t.Action = types.TraceAction{Address: &from, RefundAddress: &to, Balance: &value}
+	default:
+		t.Type = "call"
+		t.Action = types.TraceAction{CallType: &callType, From: &from, To: &to, Gas: &gas, Value: &value, Input: &input}
+		t.Result = &types.TraceResult{GasUsed: &gasUsed, Output: &output}
+	}
+
+	if frame.Error != "" {
+		e := frame.Error
+		t.Error = &e
+		t.Result = nil
+	}
+
+	traces = append(traces, t)
+
+	for index, call := range frame.Calls {
+		childAddress := make([]int, len(traceAddress), len(traceAddress)+1)
+		copy(childAddress, traceAddress)
+		childAddress = append(childAddress, index)
+
+		traces = flattenCallFrame(traces, call, childAddress, txHash, blockHash, blockNumber, txPosition)
+	}
+
+	return traces
+}
@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	_ "github.com/Alethio/memento/migrations"
+	"github.com/lib/pq"
+	"github.com/pressly/goose"
+)
+
+// Postgres is the default backend; the schema is managed by goose via the migrations package
+type Postgres struct {
+	db *sql.DB
+}
+
+type postgresTx struct {
+	*sql.Tx
+}
+
+func newPostgres(connectionString string) (*Postgres, error) {
+	log.Info("connecting to postgres")
+	db, err := sql.Open("postgres", connectionString)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Ping()
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info("connected to postgres successfuly")
+
+	return &Postgres{db: db}, nil
+}
+
+func (p *Postgres) Driver() string {
+	return DriverPostgres
+}
+
+func (p *Postgres) DB() *sql.DB {
+	return p.db
+}
+
+func (p *Postgres) Migrate() error {
+	err := goose.Up(p.db, "/")
+	if err != nil && err != goose.ErrNoNextVersion {
+		return err
+	}
+
+	return nil
+}
+
+func (p *Postgres) Begin() (Tx, error) {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	return postgresTx{tx}, nil
+}
+
+// LockBlock takes a transaction-level advisory lock on the block number, so workers that happen to process the same
+// block number (e.g. during a reorg) are serialized, while neighbouring blocks can still be stored in parallel
+func (p *Postgres) LockBlock(tx Tx, number int64) error {
+	_, err := tx.Exec("select pg_advisory_xact_lock($1)", number)
+	return err
+}
+
+// DeleteBlock calls the delete_block function defined by the migrations
+func (p *Postgres) DeleteBlock(tx Tx, number int64) error {
+	_, err := tx.Exec("select delete_block($1)", number)
+	return err
+}
+
+func (p *Postgres) HighestBlock() (int64, error) {
+	return highestBlock(p.db)
+}
+
+func (p *Postgres) Truncate() error {
+	var statements []string
+	for _, table := range truncatedTables() {
+		statements = append(statements, fmt.Sprintf("truncate table %s restart identity;", table))
+	}
+
+	tx, err := p.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(strings.Join(statements, "\n"))
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (p *Postgres) Stats() (Stats, error) {
+	var stats Stats
+
+	err := p.db.QueryRow(`
+		select pg_size_pretty(sum(table_size))   as table_size,
+		       sum(table_size) 					 as raw_table_size,
+			   pg_size_pretty(sum(indexes_size)) as indexes_size,
+		       sum(indexes_size) 				 as raw_indexes_size,
+			   pg_size_pretty(sum(total_size))   as total_size,
+			   (select version_id from goose_db_version order by id desc limit 1) as migration_version
+		from (
+				 select table_name,
+						pg_table_size(table_name)          as table_size,
+						pg_indexes_size(table_name)        as indexes_size,
+						pg_total_relation_size(table_name) as total_size
+				 from (
+						  select table_name::text
+						  from information_schema.tables
+						  where table_schema = 'public'
+					  ) as all_tables
+				 order by total_size desc
+			 ) as pretty_sizes
+     	`).Scan(&stats.DataSize, &stats.RawDataSize, &stats.IndexesSize, &stats.RawIndexesSize, &stats.TotalSize, &stats.MigrationsVersion)
+	if err != nil {
+		return stats, err
+	}
+
+	return stats, nil
+}
+
+func (p *Postgres) Close() error {
+	return p.db.Close()
+}
+
+func (t postgresTx) BulkInsert(table string, columns ...string) (BulkInserter, error) {
+	stmt, err := t.Prepare(pq.CopyIn(table, columns...))
+	if err != nil {
+		return nil, err
+	}
+
+	return stmt, nil
+}
+
+func highestBlock(db *sql.DB) (int64, error) {
+	var block int64
+
+	err := db.QueryRow("select number from blocks order by number desc limit 1").Scan(&block)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	return block, nil
+}
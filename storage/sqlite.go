@@ -0,0 +1,328 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// sqliteDriverName is the name under which the placeholder-rewriting wrapper of go-sqlite3 is registered
+const sqliteDriverName = "memento-sqlite3"
+
+// SQLiteMemory is the SQLitePath of a database that only lives in memory
+const SQLiteMemory = ":memory:"
+
+func init() {
+	sql.Register(sqliteDriverName, sqliteDriver{&sqlite3.SQLiteDriver{ConnectHook: registerFunctions}})
+}
+
+// registerFunctions replaces sum with an exact one, so the sums of the numeric(78) columns, which are stored as text,
+// are the same as with postgres; sqlite's turns to floating point as soon as it meets a value that isn't an integer
+// (as text ones are) and overflows past 64 bits
+func registerFunctions(conn *sqlite3.SQLiteConn) error {
+	return conn.RegisterAggregator("sum", newExactSum, true)
+}
+
+// exactSum adds up integers of any size; unlike sqlite's sum (and like postgres'), it's returned as text, and the
+// sum of no rows is 0 instead of null, which makes no difference to the queries since they all coalesce it to 0
+type exactSum struct {
+	total *big.Int
+}
+
+func newExactSum() *exactSum {
+	return &exactSum{total: new(big.Int)}
+}
+
+func (s *exactSum) Step(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case int64:
+		s.total.Add(s.total, big.NewInt(v))
+		return nil
+	case string:
+		return s.add(v)
+	case []byte:
+		return s.add(string(v))
+	}
+
+	return fmt.Errorf("sum: %v is not an integer", value)
+}
+
+func (s *exactSum) add(value string) error {
+	v, ok := new(big.Int).SetString(value, 10)
+	if !ok {
+		return fmt.Errorf("sum: %q is not an integer", value)
+	}
+
+	s.total.Add(s.total, v)
+	return nil
+}
+
+func (s *exactSum) Done() string {
+	return s.total.String()
+}
+
+// SQLite is an embedded backend that stores everything in a single file, meant for small deployments and CI where
+// running a postgres server is not worth it
+// It requires a binary built with cgo
+type SQLite struct {
+	db *sql.DB
+}
+
+type sqliteTx struct {
+	*sql.Tx
+}
+
+// sqliteBulkInserter inserts rows one by one using a prepared statement; the flushing Exec call is a no-op
+type sqliteBulkInserter struct {
+	stmt *sql.Stmt
+}
+
+func newSQLite(path string) (*SQLite, error) {
+	if path == "" {
+		return nil, fmt.Errorf("the sqlite backend requires a database path")
+	}
+
+	log.WithField("path", path).Info("opening sqlite database")
+
+	// immediate transactions take the write lock when they begin, which serializes the block writers
+	db, err := sql.Open(sqliteDriverName, fmt.Sprintf("file:%s?_txlock=immediate&_busy_timeout=30000&_journal_mode=WAL", path))
+	if err != nil {
+		return nil, err
+	}
+
+	// every connection to :memory: opens a new empty database, so the pool is kept to the one the schema is applied on
+	if path == SQLiteMemory {
+		db.SetMaxOpenConns(1)
+	}
+
+	err = db.Ping()
+	if err != nil {
+		return nil, err
+	}
+
+	return &SQLite{db: db}, nil
+}
+
+func (s *SQLite) Driver() string {
+	return DriverSQLite
+}
+
+func (s *SQLite) DB() *sql.DB {
+	return s.db
+}
+
+// Migrate applies the schema steps newer than the version recorded in the database's user_version pragma
+func (s *SQLite) Migrate() error {
+	var version int
+	err := s.db.QueryRow("pragma user_version").Scan(&version)
+	if err != nil {
+		return err
+	}
+
+	for i := version; i < len(sqliteMigrations); i++ {
+		log.WithField("version", i+1).Info("applying sqlite migration")
+
+		tx, err := s.db.Begin()
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(sqliteMigrations[i])
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		_, err = tx.Exec(fmt.Sprintf("pragma user_version = %d", i+1))
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *SQLite) Begin() (Tx, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	return sqliteTx{tx}, nil
+}
+
+// LockBlock is a no-op: write transactions are opened in immediate mode so they already exclude each other
+func (s *SQLite) LockBlock(tx Tx, number int64) error {
+	return nil
+}
+
+func (s *SQLite) DeleteBlock(tx Tx, number int64) error {
+	for _, table := range BlockTables {
+		_, err := tx.Exec(fmt.Sprintf("delete from %s where included_in_block = $1", table), number)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := tx.Exec("delete from blocks where number = $1", number)
+	return err
+}
+
+func (s *SQLite) HighestBlock() (int64, error) {
+	return highestBlock(s.db)
+}
+
+func (s *SQLite) Truncate() error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	for _, table := range truncatedTables() {
+		_, err = tx.Exec(fmt.Sprintf("delete from %s", table))
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Stats reports the size of the database file; sqlite doesn't expose the size of the indexes separately
+func (s *SQLite) Stats() (Stats, error) {
+	var stats Stats
+	var pageCount, pageSize int64
+
+	err := s.db.QueryRow("pragma page_count").Scan(&pageCount)
+	if err != nil {
+		return stats, err
+	}
+
+	err = s.db.QueryRow("pragma page_size").Scan(&pageSize)
+	if err != nil {
+		return stats, err
+	}
+
+	err = s.db.QueryRow("pragma user_version").Scan(&stats.MigrationsVersion)
+	if err != nil {
+		return stats, err
+	}
+
+	stats.RawDataSize = pageCount * pageSize
+	stats.DataSize = prettySize(stats.RawDataSize)
+	stats.TotalSize = stats.DataSize
+	stats.IndexesSize = "n/a"
+
+	return stats, nil
+}
+
+func (s *SQLite) Close() error {
+	return s.db.Close()
+}
+
+func (t sqliteTx) BulkInsert(table string, columns ...string) (BulkInserter, error) {
+	placeholders := make([]string, len(columns))
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		quoted[i] = fmt.Sprintf("%q", c)
+	}
+
+	stmt, err := t.Prepare(fmt.Sprintf("insert into %s (%s) values (%s)", table, strings.Join(quoted, ", "), strings.Join(placeholders, ", ")))
+	if err != nil {
+		return nil, err
+	}
+
+	return sqliteBulkInserter{stmt}, nil
+}
+
+func (b sqliteBulkInserter) Exec(args ...interface{}) (sql.Result, error) {
+	if len(args) == 0 {
+		return driver.RowsAffected(0), nil
+	}
+
+	return b.stmt.Exec(args...)
+}
+
+func (b sqliteBulkInserter) Close() error {
+	return b.stmt.Close()
+}
+
+// placeholderRegexp matches the postgres-style positional placeholders
+var placeholderRegexp = regexp.MustCompile(`\$(\d+)`)
+
+// rebind turns $n placeholders into ?n, which sqlite binds by position
+// sqlite accepts $n too, but treats it as a named parameter numbered in order of appearance, which breaks queries that
+// don't use the placeholders in ascending order
+func rebind(query string) string {
+	return placeholderRegexp.ReplaceAllString(query, "?$1")
+}
+
+// sqliteDriver wraps the go-sqlite3 driver so the queries written for postgres can run unchanged
+type sqliteDriver struct {
+	driver.Driver
+}
+
+func (d sqliteDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return sqliteConn{conn}, nil
+}
+
+type sqliteConn struct {
+	driver.Conn
+}
+
+func (c sqliteConn) Prepare(query string) (driver.Stmt, error) {
+	return c.Conn.Prepare(rebind(query))
+}
+
+func (c sqliteConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return p.PrepareContext(ctx, rebind(query))
+	}
+
+	return c.Prepare(query)
+}
+
+func (c sqliteConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+
+	return c.Conn.Begin()
+}
+
+func (c sqliteConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if e, ok := c.Conn.(driver.ExecerContext); ok {
+		return e.ExecContext(ctx, rebind(query), args)
+	}
+
+	return nil, driver.ErrSkip
+}
+
+func (c sqliteConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if q, ok := c.Conn.(driver.QueryerContext); ok {
+		return q.QueryContext(ctx, rebind(query), args)
+	}
+
+	return nil, driver.ErrSkip
+}
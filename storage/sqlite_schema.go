@@ -0,0 +1,366 @@
+package storage
+
+// sqliteMigrations holds the schema of the sqlite backend as a list of steps; the number of steps applied is kept in
+// the user_version pragma, so new steps must only ever be appended
+// The tables mirror the postgres migrations, with numeric(78) stored as text, bytea as blob and no plpgsql functions
+var sqliteMigrations = []string{
+	// 1: schema equivalent to the postgres migrations 00001 to 00008
+	`
+	create table blocks (
+		number                   integer   not null,
+		block_hash               text      not null,
+		parent_block_hash        text      not null,
+		block_creation_time      timestamp,
+		block_gas_limit          text      not null,
+		block_gas_used           text      not null,
+		block_difficulty         text      not null,
+		total_block_difficulty   text      not null,
+		block_extra_data         blob,
+		block_mix_hash           blob      not null,
+		block_nonce              blob      not null,
+		block_size               integer   not null,
+		block_logs_bloom         blob      not null,
+		includes_uncle           text,
+		has_beneficiary          blob,
+		has_receipts_trie        blob,
+		has_tx_trie              blob,
+		sha3_uncles              blob,
+		number_of_uncles         integer   default 0,
+		number_of_txs            integer   default 0,
+		created_at               timestamp default current_timestamp
+	);
+
+	create index blocks_block_hash_idx on blocks (block_hash);
+	create index blocks_number_idx on blocks (number);
+
+	create table uncles (
+		block_hash               text      not null,
+		included_in_block        integer   not null,
+		number                   integer   not null,
+		block_creation_time      timestamp not null,
+		uncle_index              integer   not null,
+		block_gas_limit          text      not null,
+		block_gas_used           text      not null,
+		has_beneficiary          blob      not null,
+		block_difficulty         text      not null,
+		block_extra_data         blob      not null,
+		block_mix_hash           blob      not null,
+		block_nonce              blob      not null,
+		sha3_uncles              blob      not null,
+		created_at               timestamp default current_timestamp
+	);
+
+	create index uncles_block_hash_idx on uncles (block_hash);
+	create index uncles_included_in_block_idx on uncles (included_in_block);
+
+	create table txs (
+		tx_hash                  text      not null,
+		included_in_block        integer   not null,
+		tx_index                 integer   not null,
+		"from"                   blob      not null,
+		"to"                     blob      not null,
+		value                    text      not null,
+		tx_nonce                 integer   not null,
+		msg_gas_limit            text      not null,
+		tx_gas_used              text,
+		tx_gas_price             text      not null,
+		cumulative_gas_used      text      not null,
+		msg_payload              blob,
+		msg_status               text,
+		creates                  blob,
+		tx_logs_bloom            blob,
+		block_creation_time      timestamp,
+		log_entries_triggered    integer   default 0,
+		created_at               timestamp default current_timestamp
+	);
+
+	create index txs_tx_hash_idx on txs (tx_hash);
+	create index txs_included_in_block_idx on txs (included_in_block desc, tx_index desc);
+
+	create table log_entries (
+		tx_hash                  text      not null,
+		log_index                integer   not null,
+		log_data                 blob,
+		logged_by                text      not null,
+		topic_0                  text,
+		topic_1                  text,
+		topic_2                  text,
+		topic_3                  text,
+		included_in_block        integer   not null,
+		created_at               timestamp default current_timestamp
+	);
+
+	create index log_entries_tx_hash_idx on log_entries (tx_hash, log_index);
+	create index log_entries_included_in_block_idx on log_entries (included_in_block);
+
+	create table account_txs (
+		address                  text      not null,
+		counterparty             text      not null,
+		tx_hash                  text      not null,
+		out                      boolean,
+		included_in_block        integer   not null,
+		tx_index                 integer   not null,
+		internal                 boolean   not null default false
+	);
+
+	create index account_txs_address_idx on account_txs (address, included_in_block desc, tx_index desc);
+	create index account_txs_included_in_block_idx on account_txs (included_in_block desc);
+
+	create table internal_txs (
+		tx_hash                  text      not null,
+		included_in_block        integer   not null,
+		tx_index                 integer   not null,
+		trace_index              integer   not null,
+		trace_address            text      not null,
+		type                     text      not null,
+		call_type                text,
+		"from"                   blob,
+		"to"                     blob,
+		value                    text      not null,
+		msg_gas_limit            text,
+		msg_gas_used             text,
+		msg_payload              blob,
+		msg_error                text,
+		created_at               timestamp default current_timestamp
+	);
+
+	create index internal_txs_tx_hash_idx on internal_txs (tx_hash, trace_index);
+	create index internal_txs_included_in_block_idx on internal_txs (included_in_block desc);
+
+	create table token_transfers (
+		tx_hash                  text      not null,
+		included_in_block        integer   not null,
+		tx_index                 integer   not null,
+		log_index                integer   not null,
+		batch_index              integer   not null default 0,
+		token_address            text      not null,
+		token_standard           text      not null,
+		operator                 text,
+		"from"                   text      not null,
+		"to"                     text      not null,
+		amount                   text      not null,
+		token_id                 text,
+		created_at               timestamp default current_timestamp
+	);
+
+	create index token_transfers_from_idx on token_transfers ("from", included_in_block desc, tx_index desc, log_index desc, batch_index desc);
+	create index token_transfers_to_idx on token_transfers ("to", included_in_block desc, tx_index desc, log_index desc, batch_index desc);
+	create index token_transfers_token_address_idx on token_transfers (token_address, included_in_block desc, tx_index desc, log_index desc, batch_index desc);
+	create index token_transfers_included_in_block_idx on token_transfers (included_in_block desc);
+	`,
+
+	// 2: postgres migration 00009
+	`
+	create table reorgs (
+		id                       integer   primary key,
+		fork_block               integer   not null,
+		head_block               integer   not null,
+		depth                    integer   not null,
+		old_head_hash            text      not null,
+		new_block_hash           text      not null,
+		detected_at              timestamp default current_timestamp
+	);
+
+	create index reorgs_detected_at_idx on reorgs (detected_at desc);
+	`,
+
+	// 3: postgres migration 00010
+	`
+	alter table blocks add column finality text;
+
+	create index blocks_unfinalized_idx on blocks (number) where finality in ('latest', 'safe');
+	`,
+
+	// 4: postgres migration 00011
+	`
+	alter table txs add column tx_type integer not null default 0;
+	alter table txs add column max_fee_per_gas text;
+	alter table txs add column max_priority_fee_per_gas text;
+	alter table txs add column effective_gas_price text;
+
+	alter table blocks add column base_fee_per_gas text;
+	alter table blocks add column burnt_fees text;
+	alter table blocks add column priority_fees text;
+
+	create table tx_access_lists (
+		tx_hash                  text      not null,
+		included_in_block        integer   not null,
+		tx_index                 integer   not null,
+		entry_index              integer   not null,
+		address                  text      not null,
+		storage_keys             text      not null,
+		created_at               timestamp default current_timestamp
+	);
+
+	create index tx_access_lists_tx_hash_idx on tx_access_lists (tx_hash, entry_index);
+	create index tx_access_lists_address_idx on tx_access_lists (address);
+	create index tx_access_lists_included_in_block_idx on tx_access_lists (included_in_block desc);
+	`,
+
+	// 5: postgres migration 00012
+	`
+	alter table blocks add column withdrawals_root blob;
+	alter table blocks add column number_of_withdrawals integer default 0;
+	alter table blocks add column blob_gas_used text;
+	alter table blocks add column excess_blob_gas text;
+	alter table blocks add column parent_beacon_block_root blob;
+
+	alter table txs add column max_fee_per_blob_gas text;
+	alter table txs add column blob_gas_used text;
+	alter table txs add column blob_gas_price text;
+
+	alter table account_txs add column withdrawal_index integer;
+
+	create table withdrawals (
+		included_in_block        integer   not null,
+		withdrawal_index         integer   not null,
+		validator_index          integer   not null,
+		address                  text      not null,
+		amount                   text      not null,
+		block_creation_time      timestamp,
+		created_at               timestamp default current_timestamp
+	);
+
+	create index withdrawals_included_in_block_idx on withdrawals (included_in_block desc, withdrawal_index);
+	create index withdrawals_address_idx on withdrawals (address, included_in_block desc);
+	create index withdrawals_withdrawal_index_idx on withdrawals (withdrawal_index);
+
+	create table blob_hashes (
+		tx_hash                  text      not null,
+		included_in_block        integer   not null,
+		tx_index                 integer   not null,
+		blob_index               integer   not null,
+		versioned_hash           text      not null,
+		created_at               timestamp default current_timestamp
+	);
+
+	create index blob_hashes_tx_hash_idx on blob_hashes (tx_hash, blob_index);
+	create index blob_hashes_versioned_hash_idx on blob_hashes (versioned_hash);
+	create index blob_hashes_included_in_block_idx on blob_hashes (included_in_block desc);
+	`,
+
+	// 6: postgres migration 00013
+	`
+	create table contract_abis (
+		address                  text      primary key,
+		name                     text      not null default '',
+		abi                      text      not null,
+		created_at               timestamp default current_timestamp
+	);
+	`,
+
+	// 7: postgres migration 00014
+	`
+	alter table txs add column method_selector text;
+
+	update txs set method_selector = lower(hex(substr(msg_payload, 1, 4))) where length(msg_payload) >= 4 and coalesce(length(creates), 0) = 0;
+
+	create index txs_to_method_selector_idx on txs ("to", method_selector, included_in_block desc, tx_index desc);
+
+	create table function_signatures (
+		selector                 text      not null,
+		signature                text      not null,
+		created_at               timestamp default current_timestamp,
+		primary key (selector, signature)
+	);
+	`,
+
+	// 8: postgres migration 00015
+	`
+	create table contracts (
+		address                  text      not null,
+		creator                  text      not null,
+		tx_hash                  text      not null,
+		included_in_block        integer   not null,
+		tx_index                 integer   not null,
+		trace_address            text      not null default '',
+		code_hash                text,
+		block_creation_time      timestamp,
+		created_at               timestamp default current_timestamp
+	);
+
+	create index contracts_address_idx on contracts (address);
+	create index contracts_creator_idx on contracts (creator, included_in_block desc);
+	create index contracts_included_in_block_idx on contracts (included_in_block desc, tx_index desc, trace_address desc);
+
+	insert into contracts (address, creator, tx_hash, included_in_block, tx_index, block_creation_time)
+	select lower(hex(creates)), lower(hex("from")), tx_hash, included_in_block, tx_index, block_creation_time
+	from txs
+	where length(creates) > 0 and msg_status <> '0x0';
+	`,
+
+	// 9: postgres migration 00016
+	`
+	create table webhooks (
+		id                       text      primary key,
+		url                      text      not null,
+		secret                   text      not null,
+		address                  text      not null default '',
+		topic0                   text      not null default '',
+		description              text      not null default '',
+		created_at               timestamp default current_timestamp
+	);
+
+	create table webhook_deliveries (
+		id                       text      primary key,
+		webhook_id               text      not null,
+		kind                     text      not null,
+		block_number             integer   not null,
+		block_hash               text      not null,
+		tx_hash                  text      not null,
+		payload                  text      not null,
+		status                   text      not null default 'pending',
+		attempts                 integer   not null default 0,
+		response_code            integer,
+		error                    text      not null default '',
+		next_attempt_at          timestamp not null,
+		delivered_at             timestamp,
+		created_at               timestamp default current_timestamp
+	);
+
+	create unique index webhook_deliveries_webhook_id_kind_block_hash_tx_hash_idx on webhook_deliveries (webhook_id, kind, block_hash, tx_hash);
+	create index webhook_deliveries_status_idx on webhook_deliveries (status, next_attempt_at);
+	create index webhook_deliveries_webhook_id_idx on webhook_deliveries (webhook_id, id desc);
+	create index webhook_deliveries_block_number_idx on webhook_deliveries (block_number);
+	`,
+
+	// 10: postgres migration 00018
+	`
+	alter table blocks add column storable_versions text;
+	`,
+
+	// 11: postgres migration 00019
+	`
+	create index log_entries_logged_by_idx on log_entries (logged_by, included_in_block desc);
+	create index log_entries_topic_0_idx on log_entries (topic_0, included_in_block desc);
+	create index log_entries_topic_1_idx on log_entries (topic_1, included_in_block desc);
+	create index log_entries_topic_2_idx on log_entries (topic_2, included_in_block desc);
+	create index log_entries_topic_3_idx on log_entries (topic_3, included_in_block desc);
+	`,
+
+	// 12: postgres migration 00020
+	`
+	alter table log_entries add column block_log_index integer;
+	alter table log_entries add column block_hash text;
+	`,
+
+	// 13: postgres migration 00021
+	`
+	create table balance_changes (
+		address                  text      not null,
+		included_in_block        integer   not null,
+		delta                    text      not null,
+		created_at               timestamp default current_timestamp
+	);
+
+	create unique index balance_changes_address_included_in_block_idx on balance_changes (address, included_in_block desc);
+	create index balance_changes_included_in_block_idx on balance_changes (included_in_block desc);
+	`,
+
+	// 14: postgres migration 00022
+	`
+	alter table blocks add column has_traces boolean not null default false;
+
+	create index blocks_untraced_idx on blocks (number) where not has_traces;
+	`,
+}
@@ -0,0 +1,207 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+)
+
+// newTestBackend returns a migrated sqlite backend kept in memory
+func newTestBackend(t *testing.T) Backend {
+	backend, err := New(Config{Driver: DriverSQLite, SQLitePath: SQLiteMemory})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = backend.Migrate()
+	if err != nil {
+		backend.Close()
+		t.Fatal(err)
+	}
+
+	return backend
+}
+
+// storeTestBlock writes a block with one transaction and one log entry
+func storeTestBlock(t *testing.T, backend Backend, number int64) {
+	tx, err := backend.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	err = backend.LockBlock(tx, number)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows := []struct {
+		table   string
+		columns []string
+		values  []interface{}
+	}{
+		{
+			"blocks",
+			[]string{"number", "block_hash", "parent_block_hash", "block_gas_limit", "block_gas_used", "block_difficulty", "total_block_difficulty", "block_mix_hash", "block_nonce", "block_size", "block_logs_bloom"},
+			[]interface{}{number, fmt.Sprintf("%064x", number), fmt.Sprintf("%064x", number-1), "30000000", "21000", "0", "0", "00", "00", 100, "00"},
+		},
+		{
+			"txs",
+			[]string{"tx_hash", "included_in_block", "tx_index", "from", "to", "value", "tx_nonce", "msg_gas_limit", "tx_gas_price", "cumulative_gas_used"},
+			[]interface{}{fmt.Sprintf("%064x", 1000+number), number, 0, "aa", "bb", "1", 0, "21000", "1", "21000"},
+		},
+		{
+			"log_entries",
+			[]string{"tx_hash", "log_index", "logged_by", "included_in_block"},
+			[]interface{}{fmt.Sprintf("%064x", 1000+number), 0, "bb", number},
+		},
+	}
+
+	for _, row := range rows {
+		stmt, err := tx.BulkInsert(row.table, row.columns...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = stmt.Exec(row.values...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = stmt.Exec()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = stmt.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// countRows returns the number of rows of table for block number
+func countRows(t *testing.T, backend Backend, table, column string, number int64) int {
+	var count int
+	err := backend.DB().QueryRow(fmt.Sprintf("select count(*) from %s where %s = $1", table, column), number).Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return count
+}
+
+func TestSQLiteStoreAndDeleteBlock(t *testing.T) {
+	backend := newTestBackend(t)
+	defer backend.Close()
+
+	stats, err := backend.Stats()
+	if err != nil || stats.MigrationsVersion != int64(len(sqliteMigrations)) {
+		t.Fatalf("expected the %d schema steps to be applied, got %d, %v", len(sqliteMigrations), stats.MigrationsVersion, err)
+	}
+
+	highest, err := backend.HighestBlock()
+	if err != nil || highest != 0 {
+		t.Fatalf("expected an empty database, got %d, %v", highest, err)
+	}
+
+	for _, number := range []int64{1, 2} {
+		storeTestBlock(t, backend, number)
+	}
+
+	highest, err = backend.HighestBlock()
+	if err != nil || highest != 2 {
+		t.Fatalf("expected block 2 to be the highest, got %d, %v", highest, err)
+	}
+
+	var hash, parentHash string
+	err = backend.DB().QueryRow("select block_hash, parent_block_hash from blocks where number = $1", 2).Scan(&hash, &parentHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != fmt.Sprintf("%064x", 2) || parentHash != fmt.Sprintf("%064x", 1) {
+		t.Errorf("unexpected hashes %s, %s", hash, parentHash)
+	}
+
+	tx, err := backend.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = backend.DeleteBlock(tx, 2)
+	if err != nil {
+		tx.Rollback()
+		t.Fatal(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	highest, err = backend.HighestBlock()
+	if err != nil || highest != 1 {
+		t.Fatalf("expected block 1 to be the highest after the deletion, got %d, %v", highest, err)
+	}
+
+	// the data derived from the deleted block goes with it, and the other block's stays
+	for _, table := range []string{"txs", "log_entries"} {
+		if count := countRows(t, backend, table, "included_in_block", 2); count != 0 {
+			t.Errorf("expected the %s of block 2 to be deleted, got %d", table, count)
+		}
+		if count := countRows(t, backend, table, "included_in_block", 1); count != 1 {
+			t.Errorf("expected the %s of block 1 to stay, got %d", table, count)
+		}
+	}
+	if count := countRows(t, backend, "blocks", "number", 2); count != 0 {
+		t.Errorf("expected block 2 to be deleted, got %d", count)
+	}
+}
+
+func TestSQLiteExactSum(t *testing.T) {
+	backend := newTestBackend(t)
+	defer backend.Close()
+
+	// deltas past 64 bits, which sqlite's sum would round or overflow
+	for i, delta := range []string{"100000000000000000000000", "-1", "36893488147419103232", "-100000000000000000000000"} {
+		_, err := backend.DB().Exec("insert into balance_changes (address, included_in_block, delta) values ($1, $2, $3)", "aa", i, delta)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		block int64
+		sum   string
+	}{
+		{-1, "0"},
+		{0, "100000000000000000000000"},
+		{2, "100036893488147419103231"},
+		{3, "36893488147419103231"},
+	} {
+		var sum string
+		err := backend.DB().QueryRow("select coalesce(sum(delta), 0) from balance_changes where address = $1 and included_in_block <= $2", "aa", tc.block).Scan(&sum)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if sum != tc.sum {
+			t.Errorf("block %d: expected %s, got %s", tc.block, tc.sum, sum)
+		}
+	}
+
+	_, err := backend.DB().Exec("insert into balance_changes (address, included_in_block, delta) values ('bb', 0, 'x')")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sum string
+	err = backend.DB().QueryRow("select sum(delta) from balance_changes where address = 'bb'").Scan(&sum)
+	if err == nil {
+		t.Errorf("expected the sum of a value that isn't an integer to fail, got %s", sum)
+	}
+}
@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+var log = logrus.WithField("module", "storage")
+
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// BlockTables lists the tables that hold data derived from a block, keyed by the included_in_block column
+// The blocks table itself is keyed by number and is handled separately
+var BlockTables = []string{
+	"uncles",
+	"txs",
+	"log_entries",
+	"account_txs",
+	"internal_txs",
+	"token_transfers",
+	"tx_access_lists",
+	"withdrawals",
+	"blob_hashes",
+	"contracts",
+	"balance_changes",
+}
+
+// AuditTables lists the tables that are not derived from a single block, but are still emptied when the database is
+// truncated
+var AuditTables = []string{
+	"reorgs",
+}
+
+type Config struct {
+	// Driver selects the backend implementation; one of DriverPostgres or DriverSQLite
+	Driver string
+
+	PostgresConnectionString string
+
+	// SQLitePath is the path of the database file used by the sqlite backend; it is created if it doesn't exist
+	// SQLiteMemory keeps the database in memory instead, for as long as the backend is open
+	SQLitePath string
+}
+
+// Backend
+// role: abstracts the database memento writes the indexed data to and reads it back from
+// The read queries (api, dashboard) are executed on DB() and are written in the SQL subset supported by all the
+// backends: positional $n placeholders, no casts with ::, no arrays. Anything that can't be expressed that way is a
+// method of the interface
+type Backend interface {
+	// Driver returns the name of the backend implementation
+	Driver() string
+
+	// DB returns the underlying connection pool, used by the read queries
+	DB() *sql.DB
+
+	// Migrate brings the database schema up to date
+	Migrate() error
+
+	// Begin opens a transaction in which a block is written
+	Begin() (Tx, error)
+
+	// LockBlock makes sure that no other writer works on the same block number until tx is finished
+	LockBlock(tx Tx, number int64) error
+
+	// DeleteBlock removes the block and all the data derived from it
+	DeleteBlock(tx Tx, number int64) error
+
+	// HighestBlock returns the number of the highest block stored or 0 if the database is empty
+	HighestBlock() (int64, error)
+
+	// Truncate removes all the indexed data
+	Truncate() error
+
+	// Stats returns size information about the database
+	Stats() (Stats, error)
+
+	Close() error
+}
+
+// Tx is a database transaction opened by a Backend
+type Tx interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+
+	// BulkInsert returns a statement that inserts rows into the given columns of table
+	// Each call to Exec with arguments adds a row; a final call to Exec without arguments flushes the rows, after
+	// which the statement must be closed
+	BulkInsert(table string, columns ...string) (BulkInserter, error)
+
+	Commit() error
+	Rollback() error
+}
+
+// BulkInserter is the statement returned by Tx.BulkInsert; *sql.Stmt satisfies it
+type BulkInserter interface {
+	Exec(args ...interface{}) (sql.Result, error)
+	Close() error
+}
+
+type Stats struct {
+	// human readable format
+	DataSize, IndexesSize, TotalSize string
+
+	// value in bytes
+	RawDataSize, RawIndexesSize int64
+
+	MigrationsVersion int64
+}
+
+// New opens the backend selected by config.Driver and checks that the database is reachable
+func New(config Config) (Backend, error) {
+	switch config.Driver {
+	case DriverPostgres, "":
+		return newPostgres(config.PostgresConnectionString)
+	case DriverSQLite:
+		return newSQLite(config.SQLitePath)
+	}
+
+	return nil, fmt.Errorf("unknown storage driver %q", config.Driver)
+}
+
+// truncatedTables returns all the tables emptied by Truncate
+func truncatedTables() []string {
+	tables := append([]string{"blocks"}, BlockTables...)
+	return append(tables, AuditTables...)
+}
+
+// prettySize formats a number of bytes the same way pg_size_pretty does
+func prettySize(bytes int64) string {
+	units := []string{"bytes", "kB", "MB", "GB", "TB"}
+
+	size := bytes
+	unit := 0
+	for size >= 10*1024 && unit < len(units)-1 {
+		size = (size + 512) / 1024
+		unit++
+	}
+
+	return fmt.Sprintf("%d %s", size, units[unit])
+}
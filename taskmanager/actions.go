@@ -1,6 +1,11 @@
 package taskmanager
 
-import "github.com/go-redis/redis"
+import (
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+)
 
 // Todo inserts a block into the redis sorted set used for queue management using a ZADD command
 func (m *Manager) Todo(block int64) error {
@@ -11,8 +16,74 @@ func (m *Manager) Todo(block int64) error {
 	}).Err()
 }
 
+// Fail records a failed processing attempt for the block
+// The block is scheduled for a retry after an exponentially increasing delay, or moved to the failed queue along with
+// the error once it reaches the maximum number of attempts
+func (m *Manager) Fail(block int64, cause error) error {
+	log := log.WithField("block", block)
+
+	err := m.redis.ZRem(m.inFlightKey(), block).Err()
+	if err != nil {
+		return err
+	}
+
+	attempts, err := m.redis.HIncrBy(m.attemptsKey(), strconv.FormatInt(block, 10), 1).Result()
+	if err != nil {
+		return err
+	}
+
+	if m.config.MaxAttempts > 0 && attempts >= m.config.MaxAttempts {
+		log.WithField("attempts", attempts).Warn("block exhausted its attempts; moving it to the failed queue")
+
+		err = m.failed.Add(FailedBlock{
+			Number:   block,
+			Attempts: attempts,
+			Error:    cause.Error(),
+			FailedAt: time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+
+		err = m.redis.ZRem(m.retryKey(), block).Err()
+		if err != nil {
+			return err
+		}
+
+		return m.redis.HDel(m.attemptsKey(), strconv.FormatInt(block, 10)).Err()
+	}
+
+	delay := m.retryDelay(attempts)
+	log.WithField("attempts", attempts).Debugf("scheduling retry in %s", delay)
+
+	return m.redis.ZAdd(m.retryKey(), redis.Z{
+		Score:  float64(time.Now().Add(delay).Unix()),
+		Member: block,
+	}).Err()
+}
+
+// Done removes a block that was processed successfully from the in-flight set and clears its attempts counter
+func (m *Manager) Done(block int64) error {
+	err := m.redis.ZRem(m.inFlightKey(), block).Err()
+	if err != nil {
+		return err
+	}
+
+	return m.redis.HDel(m.attemptsKey(), strconv.FormatInt(block, 10)).Err()
+}
+
+// Failed returns the queue holding the blocks that exhausted their attempts
+func (m *Manager) Failed() *FailedQueue {
+	return m.failed
+}
+
 func (m *Manager) Reset() error {
-	err := m.redis.Del(m.config.TodoList).Err()
+	err := m.redis.Del(m.config.TodoList, m.attemptsKey(), m.retryKey(), m.inFlightKey()).Err()
+	if err != nil {
+		return err
+	}
+
+	err = m.failed.Purge()
 	if err != nil {
 		return err
 	}
@@ -22,3 +93,67 @@ func (m *Manager) Reset() error {
 
 	return nil
 }
+
+// retryDelay returns the time to wait before the given attempt is retried: RetryBackoff doubled with each attempt, up
+// to MaxRetryBackoff
+func (m *Manager) retryDelay(attempts int64) time.Duration {
+	delay := m.config.RetryBackoff
+	for i := int64(1); i < attempts && delay < m.config.MaxRetryBackoff; i++ {
+		delay *= 2
+	}
+
+	if m.config.MaxRetryBackoff > 0 && delay > m.config.MaxRetryBackoff {
+		delay = m.config.MaxRetryBackoff
+	}
+
+	return delay
+}
+
+func (m *Manager) attemptsKey() string {
+	return m.config.TodoList + ":attempts"
+}
+
+func (m *Manager) retryKey() string {
+	return m.config.TodoList + ":retry"
+}
+
+// Untracked returns the given blocks that are neither waiting in the todo list, being processed, scheduled for a
+// retry nor in the failed queue
+func (m *Manager) Untracked(blocks []int64) ([]int64, error) {
+	keys := []string{m.config.TodoList, m.inFlightKey(), m.retryKey(), m.failed.key()}
+
+	scores := make([][]*redis.FloatCmd, len(blocks))
+	_, err := m.redis.Pipelined(func(pipe redis.Pipeliner) error {
+		for i, block := range blocks {
+			member := strconv.FormatInt(block, 10)
+			for _, key := range keys {
+				scores[i] = append(scores[i], pipe.ZScore(key, member))
+			}
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	var untracked []int64
+	for i, block := range blocks {
+		tracked := false
+		for _, score := range scores[i] {
+			err := score.Err()
+			if err == nil {
+				tracked = true
+				break
+			}
+			if err != redis.Nil {
+				return nil, err
+			}
+		}
+
+		if !tracked {
+			untracked = append(untracked, block)
+		}
+	}
+
+	return untracked, nil
+}
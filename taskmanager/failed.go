@@ -0,0 +1,149 @@
+package taskmanager
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// FailedBlock is a block that exhausted its processing attempts
+type FailedBlock struct {
+	Number   int64     `json:"number"`
+	Attempts int64     `json:"attempts"`
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failedAt"`
+}
+
+// FailedQueue is the dead-letter queue holding the blocks that could not be processed after the configured number of
+// attempts. The blocks are kept in the "<list>:failed" sorted set (scored by number) and the details of their last
+// failure in the "<list>:failed:info" hash
+// It only needs a redis connection, so it can be used without a running Manager (e.g. from the cli)
+type FailedQueue struct {
+	redis    *redis.Client
+	todoList string
+}
+
+func NewFailedQueue(r *redis.Client, todoList string) *FailedQueue {
+	return &FailedQueue{
+		redis:    r,
+		todoList: todoList,
+	}
+}
+
+func (q *FailedQueue) key() string {
+	return q.todoList + ":failed"
+}
+
+func (q *FailedQueue) infoKey() string {
+	return q.todoList + ":failed:info"
+}
+
+// Add moves a block into the failed queue
+func (q *FailedQueue) Add(block FailedBlock) error {
+	info, err := json.Marshal(block)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.redis.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(q.key(), redis.Z{
+			Score:  float64(block.Number),
+			Member: block.Number,
+		})
+		pipe.HSet(q.infoKey(), strconv.FormatInt(block.Number, 10), info)
+		return nil
+	})
+
+	return err
+}
+
+// Len returns the number of blocks in the failed queue
+func (q *FailedQueue) Len() (int64, error) {
+	return q.redis.ZCard(q.key()).Result()
+}
+
+// List returns all the failed blocks, highest first
+func (q *FailedQueue) List() ([]FailedBlock, error) {
+	members, err := q.redis.ZRevRange(q.key(), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(members) == 0 {
+		return nil, nil
+	}
+
+	infos, err := q.redis.HMGet(q.infoKey(), members...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := make([]FailedBlock, len(members))
+	for i, m := range members {
+		blocks[i].Number, err = strconv.ParseInt(m, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		if info, ok := infos[i].(string); ok {
+			err = json.Unmarshal([]byte(info), &blocks[i])
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return blocks, nil
+}
+
+// Retry moves the given blocks (or all of them if none is specified) from the failed queue back to the todo list
+// It returns the number of blocks that were re-queued
+func (q *FailedQueue) Retry(blocks ...int64) (int, error) {
+	if len(blocks) == 0 {
+		failed, err := q.List()
+		if err != nil {
+			return 0, err
+		}
+
+		for _, b := range failed {
+			blocks = append(blocks, b.Number)
+		}
+	}
+
+	var count int
+	for _, b := range blocks {
+		removed, err := q.redis.ZRem(q.key(), b).Result()
+		if err != nil {
+			return count, err
+		}
+
+		if removed == 0 {
+			continue
+		}
+
+		err = q.redis.HDel(q.infoKey(), strconv.FormatInt(b, 10)).Err()
+		if err != nil {
+			return count, err
+		}
+
+		log.WithField("block", b).Info("moving failed block back to todo")
+		err = q.redis.ZAdd(q.todoList, redis.Z{
+			Score:  float64(b),
+			Member: b,
+		}).Err()
+		if err != nil {
+			return count, err
+		}
+
+		count++
+	}
+
+	return count, nil
+}
+
+// Purge removes all the blocks from the failed queue without re-queueing them
+func (q *FailedQueue) Purge() error {
+	return q.redis.Del(q.key(), q.infoKey()).Err()
+}
@@ -0,0 +1,247 @@
+package taskmanager
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+const (
+	// leaderTTL is how long the leadership lasts if the leader stops renewing it (e.g. because it crashed)
+	leaderTTL = 15 * time.Second
+
+	// popInterval is the delay between two attempts to pop a task from an empty todo list
+	popInterval = 250 * time.Millisecond
+
+	// leaseCheckInterval is how often the in-flight set is checked for expired leases
+	leaseCheckInterval = 5 * time.Second
+)
+
+var errLeaseExpired = errors.New("lease expired before the block was processed")
+
+var errClosed = errors.New("task manager closed")
+
+// popWithLeaseScript pops the highest block from the todo list and adds it to the in-flight set in a single step, so
+// a block can't get lost if the process dies right after popping it
+// KEYS: todo list, in-flight set; ARGV: lease deadline in unix milliseconds
+var popWithLeaseScript = redis.NewScript(`
+local task = redis.call('zpopmax', KEYS[1])
+if #task == 0 then
+	return false
+end
+if task[1] ~= '-1' then
+	redis.call('zadd', KEYS[2], ARGV[1], task[1])
+end
+return task[1]
+`)
+
+// renewLeaderScript extends the leadership only if it's still held by the caller
+// KEYS: leader key; ARGV: instance id, ttl in milliseconds
+var renewLeaderScript = redis.NewScript(`
+if redis.call('get', KEYS[1]) == ARGV[1] then
+	return redis.call('pexpire', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// resignLeaderScript gives up the leadership only if it's held by the caller
+// KEYS: leader key; ARGV: instance id
+var resignLeaderScript = redis.NewScript(`
+if redis.call('get', KEYS[1]) == ARGV[1] then
+	return redis.call('del', KEYS[1])
+end
+return 0
+`)
+
+// IsLeader returns true if this instance is the one producing tasks
+func (m *Manager) IsLeader() bool {
+	return atomic.LoadInt32(&m.leader) == 1
+}
+
+// KeepLease renews the lease of an in-flight block until the returned function is called
+func (m *Manager) KeepLease(block int64) (stop func()) {
+	done := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(m.config.LeaseDuration / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				err := m.redis.ZAddXX(m.inFlightKey(), redis.Z{
+					Score:  float64(m.leaseDeadline()),
+					Member: block,
+				}).Err()
+				if err != nil {
+					log.WithField("block", block).Error("could not renew lease: ", err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+	}
+}
+
+// Release gives back a block that was popped but not processed (e.g. because the process is shutting down), putting
+// it back into the todo list
+func (m *Manager) Release(block int64) error {
+	err := m.redis.ZRem(m.inFlightKey(), block).Err()
+	if err != nil {
+		return err
+	}
+
+	return m.Todo(block)
+}
+
+// popWithLease waits for a task to be available in the todo list and returns it
+func (m *Manager) popWithLease() (string, error) {
+	for {
+		if m.closed {
+			return "", errClosed
+		}
+
+		if atomic.LoadInt32(&m.pausing) == 1 {
+			return "-1", nil
+		}
+
+		task, err := popWithLeaseScript.Run(m.redis, []string{m.config.TodoList, m.inFlightKey()}, m.leaseDeadline()).String()
+		if err == redis.Nil {
+			time.Sleep(popInterval)
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+
+		return task, nil
+	}
+}
+
+// watchLeases re-queues the in-flight blocks whose lease expired, which happens when the instance processing them died
+// Expired leases count as failed attempts, so a block that keeps crashing its workers ends up in the failed queue
+func (m *Manager) watchLeases() {
+	ticker := time.NewTicker(leaseCheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if m.closed {
+			return
+		}
+
+		expired, err := m.redis.ZRangeByScore(m.inFlightKey(), redis.ZRangeBy{
+			Min: "-inf",
+			Max: strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 10),
+		}).Result()
+		if err != nil {
+			log.Error(err)
+			continue
+		}
+
+		for _, member := range expired {
+			block, err := strconv.ParseInt(member, 10, 64)
+			if err != nil {
+				log.Error(err)
+				continue
+			}
+
+			// only the instance that manages to remove the block from the in-flight set re-queues it
+			removed, err := m.redis.ZRem(m.inFlightKey(), member).Result()
+			if err != nil {
+				log.Error(err)
+				continue
+			}
+			if removed == 0 {
+				continue
+			}
+
+			log.WithField("block", block).Warn("lease expired; re-queueing block")
+			err = m.Fail(block, errLeaseExpired)
+			if err != nil {
+				log.Error(err)
+			}
+		}
+	}
+}
+
+// campaign tries to become the leader and keeps the leadership renewed for as long as the manager is running
+func (m *Manager) campaign() {
+	ticker := time.NewTicker(leaderTTL / 3)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if m.closed {
+			return
+		}
+
+		m.tryLead()
+	}
+}
+
+func (m *Manager) tryLead() {
+	if m.IsLeader() {
+		renewed, err := renewLeaderScript.Run(m.redis, []string{m.leaderKey()}, m.id, int64(leaderTTL/time.Millisecond)).Int64()
+		if err != nil {
+			log.Error("could not renew leadership: ", err)
+		}
+
+		if err != nil || renewed == 0 {
+			atomic.StoreInt32(&m.leader, 0)
+			log.Warn("lost leadership; no longer producing tasks")
+		}
+
+		return
+	}
+
+	acquired, err := m.redis.SetNX(m.leaderKey(), m.id, leaderTTL).Result()
+	if err != nil {
+		log.Error("could not acquire leadership: ", err)
+		return
+	}
+
+	if acquired {
+		atomic.StoreInt32(&m.leader, 1)
+		log.WithField("id", m.id).Info("became leader; producing tasks")
+	}
+}
+
+func (m *Manager) resign() error {
+	if !m.IsLeader() {
+		return nil
+	}
+
+	atomic.StoreInt32(&m.leader, 0)
+
+	return resignLeaderScript.Run(m.redis, []string{m.leaderKey()}, m.id).Err()
+}
+
+func (m *Manager) leaseDeadline() int64 {
+	return time.Now().Add(m.config.LeaseDuration).UnixNano() / int64(time.Millisecond)
+}
+
+func (m *Manager) inFlightKey() string {
+	return m.config.TodoList + ":inflight"
+}
+
+func (m *Manager) leaderKey() string {
+	return m.config.TodoList + ":leader"
+}
+
+// instanceID identifies the process in the leader election
+func instanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	return fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), time.Now().UnixNano())
+}
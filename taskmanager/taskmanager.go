@@ -1,8 +1,8 @@
 package taskmanager
 
 import (
-	"math"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/Alethio/memento/metrics"
@@ -20,8 +20,31 @@ type Config struct {
 	RedisPassword   string
 	TodoList        string
 	BackfillEnabled bool
+
+	// MaxAttempts is the number of times a block is tried before it's moved to the failed queue; 0 means no limit
+	MaxAttempts int64
+
+	// RetryBackoff is the delay before a failed block is retried for the first time; it doubles with each attempt
+	// up to MaxRetryBackoff
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+
+	// ConsumerOnly disables the production of tasks: the manager only feeds blocks from the todo list and doesn't take
+	// part in the leader election
+	ConsumerOnly bool
+
+	// LeaseDuration is how long a popped block can stay in-flight without its lease being renewed before it is
+	// re-queued
+	LeaseDuration time.Duration
+
+	// Finality makes the manager queue new blocks only up to the safe or finalized head (bestblock.TagSafe or
+	// bestblock.TagFinalized) instead of following the tip of the chain; the lag is ignored when it's set
+	Finality string
 }
 
+// DefaultLeaseDuration is used when Config.LeaseDuration is not set
+const DefaultLeaseDuration = 2 * time.Minute
+
 type Manager struct {
 	config Config
 	lag    int64
@@ -29,12 +52,18 @@ type Manager struct {
 	metrics *metrics.Provider
 	tracker *bestblock.Tracker
 	redis   *redis.Client
+	failed  *FailedQueue
 
 	paused        bool
+	pausing       int32
 	pause, resume chan bool
 
 	lastBlockAdded int64
 
+	// id identifies the instance in the leader election; only the leader adds tasks to the todo list
+	id     string
+	leader int32
+
 	closed   bool
 	stopChan chan bool
 }
@@ -42,7 +71,16 @@ type Manager struct {
 // New instantiates a new task manager and also takes care of the redis connection management
 // it subscribes to the best block tracker for new blocks which it'll add to the redis queue automatically
 func New(tracker *bestblock.Tracker, lag int64, metrics *metrics.Provider, config Config) (*Manager, error) {
+	if config.LeaseDuration <= 0 {
+		config.LeaseDuration = DefaultLeaseDuration
+	}
+
+	if config.Finality != "" {
+		lag = 0
+	}
+
 	m := &Manager{
+		id:       instanceID(),
 		config:   config,
 		lag:      lag,
 		metrics:  metrics,
@@ -69,7 +107,18 @@ func New(tracker *bestblock.Tracker, lag int64, metrics *metrics.Provider, confi
 
 	log.Info("connected to redis successfully")
 
-	go m.watchNewBlocks()
+	m.failed = NewFailedQueue(m.redis, config.TodoList)
+
+	if !config.ConsumerOnly {
+		m.tryLead()
+		go m.campaign()
+		go m.watchNewBlocks()
+	} else {
+		log.Info("running in consumer-only mode; tasks are produced by the leader")
+	}
+
+	go m.watchRetries()
+	go m.watchLeases()
 
 	return m, nil
 }
@@ -79,6 +128,12 @@ func (m *Manager) Close() error {
 	if !m.paused {
 		m.stopChan <- true
 	}
+
+	err := m.resign()
+	if err != nil {
+		log.Error(err)
+	}
+
 	return m.redis.Close()
 }
 
@@ -86,15 +141,8 @@ func (m *Manager) Pause() {
 	if !m.paused {
 		log.Trace("attempting task manager pause")
 
-		// add a member with value "-1" to the queue in order to unblock the BZPopMax and pause the manager completely
-		err := m.redis.ZAdd(m.config.TodoList, redis.Z{
-			Score:  math.MaxFloat64,
-			Member: "-1",
-		}).Err()
-		if err != nil {
-			log.Error(err)
-			return
-		}
+		// make the pending pop return "-1" instead of waiting for a task, so the manager can be paused completely
+		atomic.StoreInt32(&m.pausing, 1)
 
 		log.Trace("sending pause signal")
 		m.pause <- true
@@ -118,8 +166,9 @@ func (m *Manager) IsPaused() bool {
 	return m.paused
 }
 
-// FeedToChan continuously executes blocking pops from the redis queue and sends the resulting task on the provided channel
-// Highest blocks have priority
+// FeedToChan continuously pops tasks from the redis queue and sends them on the provided channel
+// Highest blocks have priority. Each popped block is leased: it is kept in the in-flight set until it is marked as done
+// or failed, and re-queued if that doesn't happen before the lease expires
 func (m *Manager) FeedToChan(c chan int64) {
 	log.WithField("list", m.config.TodoList).Trace("feeding tasks from redis")
 	for {
@@ -133,6 +182,7 @@ func (m *Manager) FeedToChan(c chan int64) {
 		select {
 		case <-m.pause:
 			m.paused = true
+			atomic.StoreInt32(&m.pausing, 0)
 			log.Trace("task manager is paused")
 			<-m.resume
 			log.Trace("task manager has resumed")
@@ -152,19 +202,22 @@ func (m *Manager) FeedToChan(c chan int64) {
 		doneChan := make(chan bool)
 		var taskInt int64
 		go func() {
-			taskResult, err := m.redis.BZPopMax(0, m.config.TodoList).Result()
+			task, err := m.popWithLease()
 			if err != nil && m.closed {
 				return
 			}
 			if err != nil {
 				log.Error("getting task from redis returned error:", err)
+				time.Sleep(time.Second)
 				doneChan <- false
+				return
 			}
 
-			taskInt, err = strconv.ParseInt(taskResult.Member.(string), 10, 64)
+			taskInt, err = strconv.ParseInt(task, 10, 64)
 			if err != nil {
 				log.Error(err)
 				doneChan <- false
+				return
 			}
 
 			doneChan <- true
@@ -203,10 +256,22 @@ func (m *Manager) watchNewBlocks() {
 
 	for b := range m.tracker.Subscribe() {
 		log := log.WithField("block", b)
+		target := m.Target(b)
+
+		if m.config.Finality != "" {
+			// the finalized head moves in steps and never backwards, so every block up to it is queued
+			if started && target <= m.lastBlockAdded {
+				log.Trace("skipping block because the finality head didn't move")
+				continue
+			}
 
-		if !started || !m.config.BackfillEnabled || b-m.lag <= m.lastBlockAdded {
+			if !started {
+				started = true
+				m.lastBlockAdded = target - 1
+			}
+		} else if !started || !m.config.BackfillEnabled || target <= m.lastBlockAdded {
 			started = true
-			m.lastBlockAdded = b - m.lag - 1
+			m.lastBlockAdded = target - 1
 		}
 
 		if skipBlocks > 0 {
@@ -223,9 +288,16 @@ func (m *Manager) watchNewBlocks() {
 			continue
 		}
 
+		if !m.IsLeader() {
+			// the leader takes care of this block; if it dies, the new leader continues from here
+			log.Trace("skipping block because I'm not the leader")
+			m.lastBlockAdded = target
+			continue
+		}
+
 		log.Trace("got new block")
 
-		for i := m.lastBlockAdded + 1; i <= b-m.lag; i++ {
+		for i := m.lastBlockAdded + 1; i <= target; i++ {
 			err := m.Todo(i)
 			if err != nil {
 				log.Error(err)
@@ -237,3 +309,61 @@ func (m *Manager) watchNewBlocks() {
 		log.Trace("done adding block to todo")
 	}
 }
+
+// Target returns the highest block that should be queued when the best block known is the given one, taking into
+// account the lag or the finality mode
+func (m *Manager) Target(best int64) int64 {
+	switch m.config.Finality {
+	case bestblock.TagSafe:
+		return m.tracker.SafeBlock()
+	case bestblock.TagFinalized:
+		return m.tracker.FinalizedBlock()
+	}
+
+	return best - m.lag
+}
+
+// watchRetries periodically moves the blocks whose retry delay has passed from the retry set back to the todo list
+func (m *Manager) watchRetries() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if m.closed {
+			return
+		}
+
+		due, err := m.redis.ZRangeByScore(m.retryKey(), redis.ZRangeBy{
+			Min: "-inf",
+			Max: strconv.FormatInt(time.Now().Unix(), 10),
+		}).Result()
+		if err != nil {
+			log.Error(err)
+			continue
+		}
+
+		for _, member := range due {
+			block, err := strconv.ParseInt(member, 10, 64)
+			if err != nil {
+				log.Error(err)
+				continue
+			}
+
+			// only the process that manages to remove the block from the retry set re-queues it
+			removed, err := m.redis.ZRem(m.retryKey(), member).Result()
+			if err != nil {
+				log.Error(err)
+				continue
+			}
+			if removed == 0 {
+				continue
+			}
+
+			log.WithField("block", block).Debug("retrying block")
+			err = m.Todo(block)
+			if err != nil {
+				log.Error(err)
+			}
+		}
+	}
+}
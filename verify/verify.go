@@ -0,0 +1,327 @@
+// Package verify audits the indexed data: it looks for the blocks that are missing from the database, that don't match
+// the node's canonical chain, or whose rows were not all stored
+package verify
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Alethio/memento/data/storable"
+	"github.com/sirupsen/logrus"
+)
+
+var log = logrus.WithField("module", "verify")
+
+// Kinds of problems
+const (
+	ProblemMissing     = "missing"
+	ProblemDuplicate   = "duplicate"
+	ProblemHash        = "hash mismatch"
+	ProblemTxs         = "txs"
+	ProblemLogEntries  = "log entries"
+	ProblemUncles      = "uncles"
+	ProblemAccountTxs  = "account txs"
+	ProblemWithdrawals = "withdrawals"
+)
+
+// DefaultChunkSize is the number of blocks checked at once
+const DefaultChunkSize = 1000
+
+// hashWorkers is the number of concurrent requests made to the node
+const hashWorkers = 16
+
+// Problem is an inconsistency found in a block
+type Problem struct {
+	Block  int64
+	Kind   string
+	Detail string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("block %d: %s: %s", p.Block, p.Kind, p.Detail)
+}
+
+type Report struct {
+	From, To int64
+
+	// Stored is the number of blocks of the range that are in the database
+	Stored   int64
+	Problems []Problem
+}
+
+// Blocks returns the numbers of the blocks that have at least one problem, in ascending order
+func (r *Report) Blocks() []int64 {
+	seen := make(map[int64]bool)
+	var blocks []int64
+	for _, p := range r.Problems {
+		if !seen[p.Block] {
+			seen[p.Block] = true
+			blocks = append(blocks, p.Block)
+		}
+	}
+
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i] < blocks[j] })
+
+	return blocks
+}
+
+// HashSource returns the hash of the canonical block with the given number; it's implemented by the scraper
+type HashSource interface {
+	BlockHash(number int64) (string, error)
+}
+
+type Config struct {
+	// Node is used to check the block hashes; they are not checked if it's nil
+	Node HashSource
+
+	// Uncles enables the check of the uncles, which are only stored when their scraping is enabled
+	Uncles bool
+
+	ChunkSize int64
+}
+
+type Verifier struct {
+	db     *sql.DB
+	config Config
+}
+
+func New(db *sql.DB, config Config) *Verifier {
+	if config.ChunkSize <= 0 {
+		config.ChunkSize = DefaultChunkSize
+	}
+
+	return &Verifier{
+		db:     db,
+		config: config,
+	}
+}
+
+// Run checks the blocks between from and to, inclusive
+func (v *Verifier) Run(from, to int64) (*Report, error) {
+	if from > to {
+		return nil, fmt.Errorf("invalid range: %d > %d", from, to)
+	}
+
+	report := &Report{From: from, To: to}
+
+	for lo := from; lo <= to; lo += v.config.ChunkSize {
+		hi := lo + v.config.ChunkSize - 1
+		if hi > to {
+			hi = to
+		}
+
+		err := v.checkChunk(report, lo, hi)
+		if err != nil {
+			return nil, err
+		}
+
+		log.WithField("from", lo).WithField("to", hi).WithField("problems", len(report.Problems)).Info("checked blocks")
+	}
+
+	return report, nil
+}
+
+// storedBlock holds the counts a block was stored with and the ones found in the tables derived from it
+type storedBlock struct {
+	hash        string
+	txs         int64
+	uncles      int64
+	withdrawals int64
+
+	storedTxs         int64
+	expectedLogs      int64
+	storedLogs        int64
+	storedUncles      int64
+	storedAccountTxs  int64
+	storedWithdrawals int64
+}
+
+func (v *Verifier) checkChunk(report *Report, lo, hi int64) error {
+	blocks := make(map[int64]*storedBlock)
+
+	rows, err := v.db.Query(`
+		select number, block_hash, coalesce(number_of_txs, 0), coalesce(number_of_uncles, 0), coalesce(number_of_withdrawals, 0)
+		from blocks where number between $1 and $2
+	`, lo, hi)
+	if err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		var number int64
+		b := &storedBlock{}
+		err := rows.Scan(&number, &b.hash, &b.txs, &b.uncles, &b.withdrawals)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+
+		if _, ok := blocks[number]; ok {
+			report.Problems = append(report.Problems, Problem{number, ProblemDuplicate, "the block is stored more than once"})
+			continue
+		}
+
+		blocks[number] = b
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	report.Stored += int64(len(blocks))
+
+	err = v.count(blocks, `select included_in_block, count(*), coalesce(sum(log_entries_triggered), 0) from txs where included_in_block between $1 and $2 group by included_in_block`, lo, hi, func(b *storedBlock, counts []int64) {
+		b.storedTxs, b.expectedLogs = counts[0], counts[1]
+	})
+	if err != nil {
+		return err
+	}
+
+	err = v.count(blocks, `select included_in_block, count(*) from log_entries where included_in_block between $1 and $2 group by included_in_block`, lo, hi, func(b *storedBlock, counts []int64) {
+		b.storedLogs = counts[0]
+	})
+	if err != nil {
+		return err
+	}
+
+	if v.config.Uncles {
+		err = v.count(blocks, `select included_in_block, count(*) from uncles where included_in_block between $1 and $2 group by included_in_block`, lo, hi, func(b *storedBlock, counts []int64) {
+			b.storedUncles = counts[0]
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// every transaction has an outgoing and an incoming row; the internal transactions only add rows
+	err = v.count(blocks, `
+		select included_in_block,
+			coalesce(sum(case when internal or withdrawal_index is not null then 0 else 1 end), 0),
+			coalesce(sum(case when withdrawal_index is not null then 1 else 0 end), 0)
+		from account_txs where included_in_block between $1 and $2 group by included_in_block
+	`, lo, hi, func(b *storedBlock, counts []int64) {
+		b.storedAccountTxs, b.storedWithdrawals = counts[0], counts[1]
+	})
+	if err != nil {
+		return err
+	}
+
+	var hashes map[int64]string
+	if v.config.Node != nil && len(blocks) > 0 {
+		hashes, err = v.canonicalHashes(blocks)
+		if err != nil {
+			return err
+		}
+	}
+
+	for number := lo; number <= hi; number++ {
+		b, ok := blocks[number]
+		if !ok {
+			report.Problems = append(report.Problems, Problem{number, ProblemMissing, "the block is not stored"})
+			continue
+		}
+
+		if hashes != nil && hashes[number] != b.hash {
+			report.Problems = append(report.Problems, Problem{number, ProblemHash, fmt.Sprintf("stored 0x%s, node has 0x%s", b.hash, hashes[number])})
+		}
+
+		if b.storedTxs != b.txs {
+			report.Problems = append(report.Problems, Problem{number, ProblemTxs, fmt.Sprintf("expected %d, found %d", b.txs, b.storedTxs)})
+		}
+
+		if b.storedLogs != b.expectedLogs {
+			report.Problems = append(report.Problems, Problem{number, ProblemLogEntries, fmt.Sprintf("expected %d, found %d", b.expectedLogs, b.storedLogs)})
+		}
+
+		if v.config.Uncles && b.storedUncles != b.uncles {
+			report.Problems = append(report.Problems, Problem{number, ProblemUncles, fmt.Sprintf("expected %d, found %d", b.uncles, b.storedUncles)})
+		}
+
+		if b.storedAccountTxs != 2*b.txs {
+			report.Problems = append(report.Problems, Problem{number, ProblemAccountTxs, fmt.Sprintf("expected %d, found %d", 2*b.txs, b.storedAccountTxs)})
+		}
+
+		if b.storedWithdrawals != b.withdrawals {
+			report.Problems = append(report.Problems, Problem{number, ProblemWithdrawals, fmt.Sprintf("expected %d account txs, found %d", b.withdrawals, b.storedWithdrawals)})
+		}
+	}
+
+	return nil
+}
+
+// count runs a query returning a block number followed by counts and hands the counts of each stored block to apply;
+// the rows of blocks that are not stored are ignored
+func (v *Verifier) count(blocks map[int64]*storedBlock, query string, lo, hi int64, apply func(*storedBlock, []int64)) error {
+	rows, err := v.db.Query(query, lo, hi)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	var number int64
+	counts := make([]int64, len(columns)-1)
+	dest := []interface{}{&number}
+	for i := range counts {
+		dest = append(dest, &counts[i])
+	}
+
+	for rows.Next() {
+		err := rows.Scan(dest...)
+		if err != nil {
+			return err
+		}
+
+		if b, ok := blocks[number]; ok {
+			apply(b, counts)
+		}
+	}
+
+	return rows.Err()
+}
+
+// canonicalHashes returns the hashes of the node's blocks with the same numbers as the stored ones
+func (v *Verifier) canonicalHashes(blocks map[int64]*storedBlock) (map[int64]string, error) {
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+	)
+
+	hashes := make(map[int64]string)
+	numbers := make(chan int64)
+
+	for i := 0; i < hashWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for number := range numbers {
+				hash, err := v.config.Node.BlockHash(number)
+
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = fmt.Errorf("could not get the hash of block %d: %s", number, err)
+				}
+				hashes[number] = strings.ToLower(storable.Trim0x(hash))
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for number := range blocks {
+		numbers <- number
+	}
+	close(numbers)
+	wg.Wait()
+
+	return hashes, firstErr
+}
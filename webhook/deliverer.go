@@ -0,0 +1,298 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Headers of the delivery requests
+// The signature is the hex encoded HMAC-SHA256 of the timestamp header, a dot and the body, keyed with the secret of
+// the webhook; receivers should reject the requests with an old timestamp to prevent replays
+const (
+	HeaderDelivery  = "X-Memento-Delivery"
+	HeaderEvent     = "X-Memento-Event"
+	HeaderTimestamp = "X-Memento-Timestamp"
+	HeaderSignature = "X-Memento-Signature"
+)
+
+type Config struct {
+	// Interval is how often the queue is checked for deliveries that are due
+	Interval time.Duration
+
+	// Timeout bounds each delivery request
+	Timeout time.Duration
+
+	// MaxAttempts is the number of times a delivery is tried before it's marked as failed
+	MaxAttempts int
+
+	// RetryBackoff is the delay before a failed delivery is retried; it doubles with each attempt, up to MaxRetryBackoff
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+
+	// BatchSize is the maximum number of deliveries sent per check
+	BatchSize int
+}
+
+// Deliverer sends the queued deliveries
+// Each delivery is claimed before it's sent, so several instances can share the queue without sending it twice
+type Deliverer struct {
+	db     *sql.DB
+	config Config
+	client *http.Client
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewDeliverer(db *sql.DB, config Config) *Deliverer {
+	return &Deliverer{
+		db:     db,
+		config: config,
+		client: newClient(config.Timeout, false),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+func (d *Deliverer) Run() {
+	defer close(d.done)
+
+	log.WithField("interval", d.config.Interval).Info("starting webhook deliverer")
+
+	ticker := time.NewTicker(d.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := d.deliverDue()
+			if err != nil {
+				log.Error(err)
+			}
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+// Close stops the deliverer, waiting for the delivery in flight to finish
+func (d *Deliverer) Close() {
+	close(d.stop)
+	<-d.done
+}
+
+// deliverDue sends the pending deliveries whose next attempt is due, oldest first
+func (d *Deliverer) deliverDue() error {
+	rows, err := d.db.Query(`
+		select d.id, d.kind, d.payload, d.attempts, w.url, w.secret
+		from webhook_deliveries d
+		join webhooks w on w.id = d.webhook_id
+		where d.status = $1 and d.next_attempt_at <= $2
+		order by d.id
+		limit $3
+	`, StatusPending, time.Now().UTC(), d.config.BatchSize)
+	if err != nil {
+		return err
+	}
+
+	type due struct {
+		id, kind, payload, url, secret string
+		attempts                       int
+	}
+
+	var deliveries []due
+	for rows.Next() {
+		var dd due
+		err := rows.Scan(&dd.id, &dd.kind, &dd.payload, &dd.attempts, &dd.url, &dd.secret)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+
+		deliveries = append(deliveries, dd)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, dd := range deliveries {
+		select {
+		case <-d.stop:
+			return nil
+		default:
+		}
+
+		claimed, err := d.claim(dd.id, dd.attempts)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+
+		attempts := dd.attempts + 1
+		log := log.WithField("delivery", dd.id).WithField("attempt", attempts)
+
+		code, err := d.send(dd.url, dd.secret, dd.id, dd.kind, []byte(dd.payload))
+		if err == nil {
+			log.Debug("delivered webhook")
+			err = d.markDelivered(dd.id, code)
+		} else {
+			log.Warn("could not deliver webhook: ", err)
+			err = d.markFailed(dd.id, attempts, code, err)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// claim counts the attempt that is about to be made and pushes back the next one for the duration of the request,
+// unless another instance did it first
+func (d *Deliverer) claim(id string, attempts int) (bool, error) {
+	res, err := d.db.Exec(`
+		update webhook_deliveries set attempts = attempts + 1, next_attempt_at = $1
+		where id = $2 and status = $3 and attempts = $4
+	`, time.Now().UTC().Add(2*d.config.Timeout), id, StatusPending, attempts)
+	if err != nil {
+		return false, err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return count == 1, nil
+}
+
+// newClient returns the client the deliveries are sent with
+// A webhook's host can resolve to a different address than when it was validated, so the address is checked again
+// when connecting, and the redirects, which could point anywhere, are not followed: they count as failed deliveries.
+// The proxy settings of the environment are ignored since the proxy would connect on the client's behalf, unchecked
+// allowPrivate disables the address check; it's only set by the tests, whose receivers listen on the loopback
+// interface
+func newClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			ip := net.ParseIP(host)
+			if ip == nil || !isPublicIP(ip) {
+				return fmt.Errorf("refusing to connect to non-public address %s", host)
+			}
+
+			return nil
+		}
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// send POSTs the payload and returns the response code, failing unless it's a 2xx
+func (d *Deliverer) send(url, secret, id, kind string, payload []byte) (*int, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "memento-webhooks")
+	req.Header.Set(HeaderDelivery, id)
+	req.Header.Set(HeaderEvent, kind)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, "sha256="+Sign(secret, timestamp, payload))
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	// drain a bit of the body so the connection can be reused
+	io.Copy(ioutil.Discard, io.LimitReader(res.Body, 4096))
+
+	code := res.StatusCode
+	if code < 200 || code > 299 {
+		return &code, fmt.Errorf("unexpected response status %d", code)
+	}
+
+	return &code, nil
+}
+
+func (d *Deliverer) markDelivered(id string, code *int) error {
+	_, err := d.db.Exec(`
+		update webhook_deliveries set status = $1, response_code = $2, error = '', delivered_at = $3 where id = $4
+	`, StatusDelivered, code, time.Now().UTC(), id)
+
+	return err
+}
+
+func (d *Deliverer) markFailed(id string, attempts int, code *int, cause error) error {
+	status := StatusPending
+	if attempts >= d.config.MaxAttempts {
+		status = StatusFailed
+	}
+
+	_, err := d.db.Exec(`
+		update webhook_deliveries set status = $1, response_code = $2, error = $3, next_attempt_at = $4 where id = $5
+	`, status, code, cause.Error(), time.Now().UTC().Add(d.backoff(attempts)), id)
+
+	return err
+}
+
+// backoff returns the delay before the attempt following the given one
+func (d *Deliverer) backoff(attempts int) time.Duration {
+	delay := d.config.RetryBackoff
+	for i := 1; i < attempts && delay < d.config.MaxRetryBackoff; i++ {
+		delay *= 2
+	}
+
+	if delay > d.config.MaxRetryBackoff {
+		delay = d.config.MaxRetryBackoff
+	}
+
+	return delay
+}
+
+// Sign returns the signature of a delivery, as sent in the signature header
+func Sign(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,472 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"database/sql"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Alethio/memento/feed"
+	"github.com/Alethio/memento/storage"
+)
+
+var (
+	testAddress = strings.Repeat("ab", 20)
+	testSender  = strings.Repeat("01", 20)
+)
+
+// newTestDB returns a migrated sqlite database along with the function that removes it
+func newTestDB(t *testing.T) (*sql.DB, func()) {
+	dir, err := ioutil.TempDir("", "memento-webhook")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backend, err := storage.New(storage.Config{Driver: storage.DriverSQLite, SQLitePath: filepath.Join(dir, "test.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cleanup := func() {
+		backend.Close()
+		os.RemoveAll(dir)
+	}
+
+	err = backend.Migrate()
+	if err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+
+	return backend.DB(), cleanup
+}
+
+// insertWebhook stores a webhook watching testAddress without validating it, since the test receivers listen on the
+// loopback interface
+func insertWebhook(t *testing.T, db *sql.DB, url, secret string) string {
+	id, err := randomHex(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = db.Exec(`
+		insert into webhooks (id, url, secret, address, topic0, description, created_at) values ($1, $2, $3, $4, '', '', $5)
+	`, id, url, secret, testAddress, time.Now().UTC())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return id
+}
+
+func blockEvents(number int64, hash string, txHashes ...string) []feed.Event {
+	events := []feed.Event{{Type: feed.EventBlock, Block: &feed.Block{Number: number, Hash: hash}}}
+	for i, txHash := range txHashes {
+		events = append(events, feed.Event{Type: feed.EventTx, Tx: &feed.Tx{
+			TxHash:          txHash,
+			IncludedInBlock: number,
+			TxIndex:         int64(i),
+			From:            testSender,
+			To:              testAddress,
+			Value:           "1",
+			MsgStatus:       "0x1",
+		}})
+	}
+
+	return events
+}
+
+func newTestDeliverer(db *sql.DB, config Config) *Deliverer {
+	if config.Timeout == 0 {
+		config.Timeout = 5 * time.Second
+	}
+	if config.MaxAttempts == 0 {
+		config.MaxAttempts = 3
+	}
+	if config.BatchSize == 0 {
+		config.BatchSize = 10
+	}
+
+	d := NewDeliverer(db, config)
+	d.client = newClient(config.Timeout, true)
+
+	return d
+}
+
+func deliveries(t *testing.T, db *sql.DB, webhookID string) []Delivery {
+	list, err := Deliveries(db, webhookID, "", "", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return list
+}
+
+func TestDeliverySignature(t *testing.T) {
+	db, cleanup := newTestDB(t)
+	defer cleanup()
+
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	requests := make(chan received, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests <- received{r.Header, body}
+	}))
+	defer server.Close()
+
+	id := insertWebhook(t, db, server.URL, "s3cret")
+
+	err := Enqueue(db, blockEvents(5, "b5", "t1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = newTestDeliverer(db, Config{}).deliverDue()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := <-requests
+
+	signature := strings.TrimPrefix(r.header.Get(HeaderSignature), "sha256=")
+	expected := Sign("s3cret", r.header.Get(HeaderTimestamp), r.body)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		t.Fatalf("signature %s doesn't match the expected %s", signature, expected)
+	}
+
+	if Sign("other", r.header.Get(HeaderTimestamp), r.body) == signature {
+		t.Fatal("the signature doesn't depend on the secret")
+	}
+
+	var p Payload
+	err = json.Unmarshal(r.body, &p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Webhook != id || p.Event != KindAdded || p.Block.Number != 5 || p.Tx.TxHash != "t1" || r.header.Get(HeaderDelivery) != p.ID || r.header.Get(HeaderEvent) != KindAdded {
+		t.Fatalf("unexpected delivery %+v with headers %v", p, r.header)
+	}
+
+	list := deliveries(t, db, id)
+	if len(list) != 1 || list[0].Status != StatusDelivered || list[0].Attempts != 1 || list[0].ResponseCode == nil || *list[0].ResponseCode != 200 || list[0].DeliveredAt == nil {
+		t.Fatalf("unexpected delivery log %+v", list)
+	}
+}
+
+func TestDeliveryRetriesWithBackoff(t *testing.T) {
+	db, cleanup := newTestDB(t)
+	defer cleanup()
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	id := insertWebhook(t, db, server.URL, "secret")
+	err := Enqueue(db, blockEvents(5, "b5", "t1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := newTestDeliverer(db, Config{RetryBackoff: time.Hour, MaxRetryBackoff: 2 * time.Hour})
+
+	before := time.Now().UTC()
+	err = d.deliverDue()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list := deliveries(t, db, id)
+	if len(list) != 1 || list[0].Status != StatusPending || list[0].Attempts != 1 || *list[0].ResponseCode != 500 || list[0].Error == "" {
+		t.Fatalf("unexpected delivery log %+v", list)
+	}
+
+	if list[0].NextAttemptAt.Before(before.Add(time.Hour)) || list[0].NextAttemptAt.After(time.Now().UTC().Add(time.Hour)) {
+		t.Fatalf("the next attempt at %s is not an hour away", list[0].NextAttemptAt)
+	}
+
+	// the retry isn't due yet
+	err = d.deliverDue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Fatalf("the delivery was retried before its backoff")
+	}
+
+	_, err = db.Exec(`update webhook_deliveries set next_attempt_at = $1`, time.Now().UTC().Add(-time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = d.deliverDue()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list = deliveries(t, db, id)
+	if list[0].Status != StatusDelivered || list[0].Attempts != 2 || list[0].Error != "" {
+		t.Fatalf("unexpected delivery log %+v", list)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := NewDeliverer(nil, Config{RetryBackoff: time.Second, MaxRetryBackoff: 10 * time.Second})
+
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+	for i, delay := range expected {
+		if d.backoff(i+1) != delay {
+			t.Errorf("attempt %d: expected %s, got %s", i+1, delay, d.backoff(i+1))
+		}
+	}
+}
+
+func TestDeliveryFailsAfterMaxAttempts(t *testing.T) {
+	db, cleanup := newTestDB(t)
+	defer cleanup()
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	id := insertWebhook(t, db, server.URL, "secret")
+	err := Enqueue(db, blockEvents(5, "b5", "t1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// without a backoff, every check retries the delivery
+	d := newTestDeliverer(db, Config{MaxAttempts: 3})
+	for i := 0; i < 5; i++ {
+		err = d.deliverDue()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if atomic.LoadInt32(&calls) != 3 {
+		t.Fatalf("expected 3 attempts, got %d", calls)
+	}
+
+	list := deliveries(t, db, id)
+	if len(list) != 1 || list[0].Status != StatusFailed || list[0].Attempts != 3 || *list[0].ResponseCode != 503 {
+		t.Fatalf("unexpected delivery log %+v", list)
+	}
+}
+
+func TestDeliveryIsClaimedOnce(t *testing.T) {
+	db, cleanup := newTestDB(t)
+	defer cleanup()
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer server.Close()
+
+	id := insertWebhook(t, db, server.URL, "secret")
+	err := Enqueue(db, blockEvents(5, "b5", "t1", "t2", "t3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list := deliveries(t, db, id)
+	if len(list) != 3 {
+		t.Fatalf("expected 3 deliveries, got %d", len(list))
+	}
+
+	first := newTestDeliverer(db, Config{})
+	second := newTestDeliverer(db, Config{})
+
+	claimed, err := first.claim(list[0].ID, 0)
+	if err != nil || !claimed {
+		t.Fatalf("the first claim failed: %v", err)
+	}
+
+	claimed, err = second.claim(list[0].ID, 0)
+	if err != nil || claimed {
+		t.Fatalf("the delivery was claimed twice (%v)", err)
+	}
+
+	// both deliverers go through the same due deliveries; each of the others must be sent by only one of them
+	_, err = db.Exec(`update webhook_deliveries set next_attempt_at = $1 where id <> $2`, time.Now().UTC().Add(-time.Second), list[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for _, d := range []*Deliverer{first, second} {
+		wg.Add(1)
+		go func(d *Deliverer) {
+			defer wg.Done()
+			if err := d.deliverDue(); err != nil {
+				t.Error(err)
+			}
+		}(d)
+	}
+	wg.Wait()
+
+	if atomic.LoadInt32(&calls) != 2 {
+		t.Fatalf("expected 2 requests, got %d", calls)
+	}
+
+	for _, delivery := range deliveries(t, db, id) {
+		if delivery.ID != list[0].ID && (delivery.Status != StatusDelivered || delivery.Attempts != 1) {
+			t.Fatalf("unexpected delivery %+v", delivery)
+		}
+	}
+}
+
+func TestReorgQueuesRemovedDeliveries(t *testing.T) {
+	db, cleanup := newTestDB(t)
+	defer cleanup()
+	id := insertWebhook(t, db, "https://example.com/hook", "secret")
+
+	var events []feed.Event
+	events = append(events, blockEvents(4, "b4", "t1")...)
+	events = append(events, blockEvents(5, "b5", "t2", "t3")...)
+	events = append(events, blockEvents(6, "b6")...)
+
+	err := Enqueue(db, events)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// queueing the same block again doesn't duplicate its deliveries
+	err = Enqueue(db, blockEvents(5, "b5", "t2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reorg := feed.Event{Type: feed.EventReorg, Reorg: &feed.Reorg{ForkBlock: 4, HeadBlock: 6, Depth: 2}}
+	err = Enqueue(db, append([]feed.Event{reorg}, blockEvents(5, "b5'", "t3")...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	removed := make(map[string]bool)
+	var added []string
+	for _, d := range deliveries(t, db, id) {
+		switch d.Kind {
+		case KindRemoved:
+			if d.BlockHash != "b5" {
+				t.Errorf("removed delivery for block %s", d.BlockHash)
+			}
+
+			var p Payload
+			err := json.Unmarshal([]byte(d.Payload), &p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.Event != KindRemoved || p.Tx.TxHash != d.TxHash || p.Webhook != id {
+				t.Errorf("unexpected removed payload %+v", p)
+			}
+
+			removed[d.TxHash] = true
+		case KindAdded:
+			added = append(added, d.BlockHash+"/"+d.TxHash)
+		}
+	}
+
+	if len(removed) != 2 || !removed["t2"] || !removed["t3"] {
+		t.Fatalf("expected removed deliveries for t2 and t3, got %v", removed)
+	}
+
+	if len(added) != 4 {
+		t.Fatalf("expected 4 added deliveries, got %v", added)
+	}
+}
+
+func TestEnqueueFollowsTheTransaction(t *testing.T) {
+	db, cleanup := newTestDB(t)
+	defer cleanup()
+	id := insertWebhook(t, db, "https://example.com/hook", "secret")
+
+	// the deliveries of a block that fails to be stored are rolled back with it
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Enqueue(tx, blockEvents(5, "b5", "t1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = tx.Rollback()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if list := deliveries(t, db, id); len(list) != 0 {
+		t.Fatalf("expected the deliveries to be rolled back, got %+v", list)
+	}
+
+	tx, err = db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Enqueue(tx, blockEvents(5, "b5", "t1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if list := deliveries(t, db, id); len(list) != 1 || list[0].TxHash != "t1" {
+		t.Fatalf("expected the delivery of t1 to be committed, got %+v", list)
+	}
+}
+
+func TestClientGuards(t *testing.T) {
+	var calls int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer target.Close()
+
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	defer redirect.Close()
+
+	d := NewDeliverer(nil, Config{Timeout: 5 * time.Second})
+
+	// the receivers of the tests listen on the loopback interface, which the deliveries must not reach
+	_, err := d.send(target.URL, "secret", "id", KindAdded, []byte("{}"))
+	if err == nil || !strings.Contains(err.Error(), "non-public address") {
+		t.Fatalf("expected the connection to be refused, got %v", err)
+	}
+
+	d.client = newClient(5*time.Second, true)
+	code, err := d.send(redirect.URL, "secret", "id", KindAdded, []byte("{}"))
+	if err == nil || code == nil || *code != http.StatusTemporaryRedirect {
+		t.Fatalf("expected the redirect to fail the delivery, got %v, %v", code, err)
+	}
+
+	if atomic.LoadInt32(&calls) != 0 {
+		t.Fatal("the redirect was followed")
+	}
+}
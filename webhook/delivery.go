@@ -0,0 +1,238 @@
+package webhook
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Alethio/memento/feed"
+)
+
+// Kinds of deliveries
+const (
+	KindAdded   = "added"
+	KindRemoved = "removed"
+)
+
+// Statuses of a delivery
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+)
+
+// Delivery is a notification sent, or to be sent, to a webhook
+type Delivery struct {
+	ID            string
+	WebhookID     string
+	Kind          string
+	BlockNumber   int64
+	BlockHash     string
+	TxHash        string
+	Payload       string
+	Status        string
+	Attempts      int
+	ResponseCode  *int
+	Error         string
+	NextAttemptAt time.Time
+	DeliveredAt   *time.Time
+}
+
+// Payload is the body POSTed to the webhooks
+type Payload struct {
+	ID      string   `json:"id"`
+	Webhook string   `json:"webhook"`
+	Event   string   `json:"event"`
+	Block   Block    `json:"block"`
+	Tx      *feed.Tx `json:"tx"`
+}
+
+type Block struct {
+	Number int64  `json:"number"`
+	Hash   string `json:"hash"`
+}
+
+const deliveryColumns = `id, webhook_id, kind, block_number, block_hash, tx_hash, payload, status, attempts, response_code, error, next_attempt_at, delivered_at`
+
+// querier is the part of *sql.DB, *sql.Tx and storage.Tx the webhooks and their deliveries are written with
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// Enqueue queues the deliveries produced by the events of the live feed: an "added" delivery for every webhook
+// matching a transaction, and a "removed" delivery for every "added" one of the blocks dropped by a reorg
+// tx must be the transaction the block of the events is stored (or the reorg recorded) in, so the deliveries are
+// committed along with it; none are lost if the process stops after the commit, nor queued for a block that wasn't
+// stored. The block events must precede the events of their transactions, as BlockEvents returns them
+func Enqueue(tx querier, events []feed.Event) error {
+	webhooks, err := List(tx)
+	if err != nil || len(webhooks) == 0 {
+		return err
+	}
+
+	var block Block
+	for _, e := range events {
+		switch e.Type {
+		case feed.EventBlock:
+			block = Block{Number: e.Block.Number, Hash: e.Block.Hash}
+		case feed.EventTx:
+			for _, w := range webhooks {
+				if !w.Match(e.Tx) {
+					continue
+				}
+
+				err = insertDelivery(tx, w.ID, KindAdded, block, e.Tx)
+				if err != nil {
+					return err
+				}
+			}
+		case feed.EventReorg:
+			err = enqueueRemoved(tx, *e.Reorg)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// enqueueRemoved queues a "removed" delivery for each "added" delivery of the blocks dropped by the reorg
+func enqueueRemoved(tx querier, reorg feed.Reorg) error {
+	rows, err := tx.Query(`
+		select webhook_id, payload from webhook_deliveries
+		where kind = $1 and block_number > $2 and block_number <= $3
+	`, KindAdded, reorg.ForkBlock, reorg.HeadBlock)
+	if err != nil {
+		return err
+	}
+
+	var added []Payload
+	for rows.Next() {
+		var webhookID, payload string
+		err := rows.Scan(&webhookID, &payload)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+
+		var p Payload
+		err = json.Unmarshal([]byte(payload), &p)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+
+		p.Webhook = webhookID
+		added = append(added, p)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, p := range added {
+		err = insertDelivery(tx, p.Webhook, KindRemoved, p.Block, p.Tx)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(added) > 0 {
+		log.WithField("fork", reorg.ForkBlock).WithField("count", len(added)).Info("queued removed notifications")
+	}
+
+	return nil
+}
+
+// insertDelivery queues a delivery unless the webhook was already notified of the same transaction of the same block
+func insertDelivery(tx querier, webhookID, kind string, block Block, t *feed.Tx) error {
+	now := time.Now().UTC()
+
+	id, err := deliveryID(now)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(Payload{
+		ID:      id,
+		Webhook: webhookID,
+		Event:   kind,
+		Block:   block,
+		Tx:      t,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		insert into webhook_deliveries (id, webhook_id, kind, block_number, block_hash, tx_hash, payload, next_attempt_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $8)
+		on conflict (webhook_id, kind, block_hash, tx_hash) do nothing
+	`, id, webhookID, kind, block.Number, block.Hash, t.TxHash, string(payload), now)
+
+	return err
+}
+
+// deliveryID returns a random id that sorts in the order the deliveries were queued in
+func deliveryID(t time.Time) (string, error) {
+	suffix, err := randomHex(4)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%016x%s", t.UnixNano(), suffix), nil
+}
+
+// Deliveries returns the deliveries of a webhook, newest first, optionally only the ones with the given status
+// before is the id of the last delivery of the previous page, if any
+func Deliveries(db *sql.DB, webhookID, status, before string, limit int) ([]Delivery, error) {
+	conditions := []string{"webhook_id = $1"}
+	args := []interface{}{webhookID}
+
+	if status != "" {
+		args = append(args, status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+
+	if before != "" {
+		args = append(args, before)
+		conditions = append(conditions, fmt.Sprintf("id < $%d", len(args)))
+	}
+
+	args = append(args, limit)
+
+	rows, err := db.Query(fmt.Sprintf(`select %s from webhook_deliveries where %s order by id desc limit $%d`, deliveryColumns, strings.Join(conditions, " and "), len(args)), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []Delivery
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanDelivery(row scanner) (Delivery, error) {
+	var d Delivery
+	err := row.Scan(&d.ID, &d.WebhookID, &d.Kind, &d.BlockNumber, &d.BlockHash, &d.TxHash, &d.Payload, &d.Status, &d.Attempts, &d.ResponseCode, &d.Error, &d.NextAttemptAt, &d.DeliveredAt)
+
+	return d, err
+}
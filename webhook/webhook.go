@@ -0,0 +1,222 @@
+// Package webhook notifies external services of the transactions that involve watched addresses or emit watched
+// events
+// The transactions of each stored block are matched against the webhooks and a delivery is queued in the database for
+// every match, in the transaction the block is stored in; the Deliverer then POSTs the queued deliveries, retrying the failed ones, so the deliveries table doubles
+// as the delivery log. When blocks are reorged, a "removed" delivery is queued for each "added" one they produced
+package webhook
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Alethio/memento/feed"
+	"github.com/Alethio/memento/utils"
+	"github.com/sirupsen/logrus"
+)
+
+var log = logrus.WithField("module", "webhook")
+
+// Webhook is a subscription to the transactions of an address, to the log entries with a given topic 0, or to the log
+// entries with a given topic 0 emitted by an address if both are set
+type Webhook struct {
+	ID          string
+	URL         string
+	Secret      string
+	Address     string
+	Topic0      string
+	Description string
+	CreatedAt   time.Time
+}
+
+// Validate normalizes the address and topic of the webhook and checks that it can be matched and delivered
+func (w *Webhook) Validate() error {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an absolute http or https url")
+	}
+
+	// the names are checked again against the addresses they resolve to when the deliveries are sent
+	host := strings.ToLower(u.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("url must not point to the local host")
+	}
+	if ip := net.ParseIP(host); ip != nil && !isPublicIP(ip) {
+		return fmt.Errorf("url must not point to a loopback, private or link-local address")
+	}
+
+	w.Address = utils.CleanUpHex(w.Address)
+	if w.Address != "" && len(w.Address) != 40 {
+		return fmt.Errorf("address is malformed")
+	}
+
+	w.Topic0 = utils.CleanUpHex(w.Topic0)
+	if w.Topic0 != "" && len(w.Topic0) != 64 {
+		return fmt.Errorf("topic0 is malformed")
+	}
+
+	if w.Address == "" && w.Topic0 == "" {
+		return fmt.Errorf("at least one of address and topic0 is required")
+	}
+
+	return nil
+}
+
+// Match returns true if the transaction is of interest to the webhook
+// Without a topic, the transaction must be sent from or to the address (contract creations included); with a topic,
+// one of its log entries must have it as topic 0 and, if the address is set, be emitted by the address
+func (w Webhook) Match(tx *feed.Tx) bool {
+	if w.Topic0 == "" {
+		return tx.From == w.Address || tx.To == w.Address || tx.Creates == w.Address
+	}
+
+	for _, l := range tx.Logs {
+		if len(l.Topics) > 0 && l.Topics[0] == w.Topic0 && (w.Address == "" || l.LoggedBy == w.Address) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Create validates the webhook and stores it, generating its id, and its secret if it doesn't have one
+func Create(db *sql.DB, w *Webhook) error {
+	err := w.Validate()
+	if err != nil {
+		return err
+	}
+
+	w.ID, err = randomHex(8)
+	if err != nil {
+		return err
+	}
+
+	if w.Secret == "" {
+		w.Secret, err = randomHex(32)
+		if err != nil {
+			return err
+		}
+	}
+
+	w.CreatedAt = time.Now().UTC()
+
+	_, err = db.Exec(`
+		insert into webhooks (id, url, secret, address, topic0, description, created_at) values ($1, $2, $3, $4, $5, $6, $7)
+	`, w.ID, w.URL, w.Secret, w.Address, w.Topic0, w.Description, w.CreatedAt)
+
+	return err
+}
+
+// Get returns the webhook with the given id; the webhook is nil if there's none
+func Get(db *sql.DB, id string) (*Webhook, error) {
+	w := &Webhook{}
+	err := db.QueryRow(`select id, url, secret, address, topic0, description, created_at from webhooks where id = $1`, id).Scan(&w.ID, &w.URL, &w.Secret, &w.Address, &w.Topic0, &w.Description, &w.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// List returns all the webhooks, oldest first
+func List(db querier) ([]Webhook, error) {
+	rows, err := db.Query(`select id, url, secret, address, topic0, description, created_at from webhooks order by created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var webhooks []Webhook
+	for rows.Next() {
+		var w Webhook
+		err := rows.Scan(&w.ID, &w.URL, &w.Secret, &w.Address, &w.Topic0, &w.Description, &w.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		webhooks = append(webhooks, w)
+	}
+
+	return webhooks, rows.Err()
+}
+
+// Delete removes the webhook with the given id, along with its deliveries, and reports whether there was one
+func Delete(db *sql.DB, id string) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	res, err := tx.Exec(`delete from webhooks where id = $1`, id)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	_, err = tx.Exec(`delete from webhook_deliveries where webhook_id = $1`, id)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	return count > 0, tx.Commit()
+}
+
+// nonPublicNetworks are the ranges, besides the loopback, link-local and multicast ones known to net.IP, that the
+// deliveries must not reach: the private networks of RFC 1918 and RFC 4193, the shared address space of carrier-grade
+// NATs and "this network"
+var nonPublicNetworks = parseCIDRs("0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7")
+
+// isPublicIP returns false for the addresses a webhook could use to reach the services next to the indexer, e.g. the
+// cloud metadata endpoint at 169.254.169.254
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || ip.Equal(net.IPv4bcast) {
+		return false
+	}
+
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	var networks []*net.IPNet
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+
+		networks = append(networks, network)
+	}
+
+	return networks
+}
+
+func randomHex(size int) (string, error) {
+	b := make([]byte, size)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,111 @@
+package webhook
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/Alethio/memento/feed"
+)
+
+func TestValidate(t *testing.T) {
+	address := strings.Repeat("ab", 20)
+	topic := strings.Repeat("cd", 32)
+
+	valid := []Webhook{
+		{URL: "https://example.com/hook", Address: "0x" + strings.ToUpper(address)},
+		{URL: "http://93.184.216.34:8080/hook", Topic0: topic},
+		{URL: "https://[2606:2800:220:1:248:1893:25c8:1946]/hook", Address: address, Topic0: topic},
+	}
+
+	for _, w := range valid {
+		err := w.Validate()
+		if err != nil {
+			t.Errorf("%s: %s", w.URL, err)
+		}
+	}
+
+	w := valid[0]
+	w.Validate()
+	if w.Address != address {
+		t.Errorf("expected the address to be normalized to %s, got %s", address, w.Address)
+	}
+
+	invalid := []Webhook{
+		{URL: "ftp://example.com", Address: address},
+		{URL: "/relative", Address: address},
+		{URL: "https://example.com"},
+		{URL: "https://example.com", Address: "0x1234"},
+		{URL: "https://example.com", Topic0: "0x1234"},
+		{URL: "http://localhost:8080/hook", Address: address},
+		{URL: "http://api.localhost/hook", Address: address},
+		{URL: "http://127.0.0.1/hook", Address: address},
+		{URL: "http://169.254.169.254/latest/meta-data/", Address: address},
+		{URL: "http://10.0.0.1/hook", Address: address},
+		{URL: "http://172.16.5.4/hook", Address: address},
+		{URL: "http://192.168.1.1/hook", Address: address},
+		{URL: "http://100.64.0.1/hook", Address: address},
+		{URL: "http://0.0.0.0/hook", Address: address},
+		{URL: "http://[::1]/hook", Address: address},
+		{URL: "http://[fe80::1]/hook", Address: address},
+		{URL: "http://[fd00::1]/hook", Address: address},
+		{URL: "http://[::ffff:127.0.0.1]/hook", Address: address},
+	}
+
+	for _, w := range invalid {
+		if err := w.Validate(); err == nil {
+			t.Errorf("%s: expected an error", w.URL)
+		}
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	cases := map[string]bool{
+		"8.8.8.8":         true,
+		"172.32.0.1":      true,
+		"2001:4860::8888": true,
+		"127.0.0.53":      false,
+		"169.254.169.254": false,
+		"10.1.2.3":        false,
+		"224.0.0.1":       false,
+		"::":              false,
+	}
+
+	for ip, expected := range cases {
+		if isPublicIP(net.ParseIP(ip)) != expected {
+			t.Errorf("%s: expected %v", ip, expected)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	address := strings.Repeat("ab", 20)
+	other := strings.Repeat("01", 20)
+	topic := strings.Repeat("cd", 32)
+
+	tx := &feed.Tx{
+		From: other,
+		To:   address,
+		Logs: []feed.Log{{LoggedBy: other, Topics: []string{topic}}},
+	}
+
+	cases := []struct {
+		name     string
+		webhook  Webhook
+		expected bool
+	}{
+		{"recipient", Webhook{Address: address}, true},
+		{"sender", Webhook{Address: other}, true},
+		{"unrelated address", Webhook{Address: strings.Repeat("02", 20)}, false},
+		{"topic", Webhook{Topic0: topic}, true},
+		{"topic emitted by the address", Webhook{Address: other, Topic0: topic}, true},
+		{"topic emitted by another address", Webhook{Address: address, Topic0: topic}, false},
+		{"other topic", Webhook{Topic0: strings.Repeat("ef", 32)}, false},
+	}
+
+	for _, c := range cases {
+		if c.webhook.Match(tx) != c.expected {
+			t.Errorf("%s: expected %v", c.name, c.expected)
+		}
+	}
+}